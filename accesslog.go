@@ -0,0 +1,142 @@
+package flotilla
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// AccessLog formats.
+const (
+	CommonLog   = "common"
+	CombinedLog = "combined"
+	JSONLog     = "json"
+)
+
+// AccessEntry is the information recorded for a request by an AccessLogger.
+type AccessEntry struct {
+	Time      time.Time     `json:"time"`
+	ClientIP  string        `json:"client_ip"`
+	Method    string        `json:"method"`
+	Path      string        `json:"path"`
+	Proto     string        `json:"proto"`
+	Status    int           `json:"status"`
+	Bytes     int           `json:"bytes"`
+	Latency   time.Duration `json:"latency"`
+	Route     string        `json:"route,omitempty"`
+	RequestID string        `json:"request_id"`
+	Referer   string        `json:"referer,omitempty"`
+	UserAgent string        `json:"user_agent,omitempty"`
+}
+
+// AccessLogger writes an access log line for each request it manages, in the
+// common, combined, or JSON format.
+type AccessLogger struct {
+	mu       sync.Mutex
+	out      io.Writer
+	format   string
+	disabled map[string]bool
+}
+
+// NewAccessLogger returns an AccessLogger writing the provided format to out,
+// or to os.Stdout if out is nil.
+func NewAccessLogger(out io.Writer, format string) *AccessLogger {
+	if out == nil {
+		out = os.Stdout
+	}
+	return &AccessLogger{out: out, format: format, disabled: make(map[string]bool)}
+}
+
+// Disable stops logging requests for routes of the provided Blueprints.
+func (l *AccessLogger) Disable(bs ...*Blueprint) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, b := range bs {
+		l.disabled[b.Prefix] = true
+	}
+}
+
+// Enable resumes logging requests for routes of the provided Blueprints.
+func (l *AccessLogger) Enable(bs ...*Blueprint) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, b := range bs {
+		delete(l.disabled, b.Prefix)
+	}
+}
+
+func (l *AccessLogger) enabled(rt *Route) bool {
+	if rt == nil || rt.Blueprint == nil {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return !l.disabled[rt.Blueprint.Prefix]
+}
+
+func clientip(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+func accessentry(c Ctx) *AccessEntry {
+	rq := CurrentRequest(c)
+	rw, _ := c.Call("responsewriter")
+	w := rw.(ResponseWriter)
+	e := &AccessEntry{
+		Time:      time.Now(),
+		ClientIP:  clientip(rq.RemoteAddr),
+		Method:    rq.Method,
+		Path:      rq.URL.RequestURI(),
+		Proto:     rq.Proto,
+		Status:    w.Status(),
+		Bytes:     w.Size(),
+		RequestID: RequestID(c),
+		Referer:   rq.Referer(),
+		UserAgent: rq.UserAgent(),
+	}
+	if e.Bytes < 0 {
+		e.Bytes = 0
+	}
+	if start, err := c.Call("started"); err == nil {
+		e.Latency = e.Time.Sub(start.(time.Time))
+	}
+	if rt := CurrentRoute(c); rt != nil {
+		e.Route = rt.Name()
+	}
+	return e
+}
+
+func (l *AccessLogger) write(e *AccessEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	switch l.format {
+	case JSONLog:
+		json.NewEncoder(l.out).Encode(e)
+	case CombinedLog:
+		fmt.Fprintf(l.out, "%s - - [%s] \"%s %s %s\" %d %d %q %q\n",
+			e.ClientIP, e.Time.Format("02/Jan/2006:15:04:05 -0700"),
+			e.Method, e.Path, e.Proto, e.Status, e.Bytes, e.Referer, e.UserAgent)
+	default:
+		fmt.Fprintf(l.out, "%s - - [%s] \"%s %s %s\" %d %d\n",
+			e.ClientIP, e.Time.Format("02/Jan/2006:15:04:05 -0700"),
+			e.Method, e.Path, e.Proto, e.Status, e.Bytes)
+	}
+}
+
+// Manage is a flotilla.Manage function logging the request once its response
+// has been written.
+func (l *AccessLogger) Manage(c Ctx) {
+	if !l.enabled(CurrentRoute(c)) {
+		return
+	}
+	c.Call("pushfinal", func(c Ctx) {
+		l.write(accessentry(c))
+	})
+}
@@ -0,0 +1,55 @@
+package flotilla
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAccessLog(t *testing.T) {
+	var common, js bytes.Buffer
+
+	cl := NewAccessLogger(&common, CommonLog)
+	jl := NewAccessLogger(&js, JSONLog)
+
+	a := New("testAccessLog", Mode("testing", true))
+	mkTestQueues(t, a)
+	a.Use(cl.Manage, jl.Manage)
+
+	quiet := a.NewBlueprint("/quiet")
+	jl.Disable(quiet)
+
+	a.GET("/logged", func(c Ctx) { c.Call("serveplain", 201, "logged body") })
+	quiet.GET("/unlogged", func(c Ctx) {})
+
+	a.Configure()
+
+	exp, _ := NoTanage(201, "GET", "/logged")
+	exp.SetPre(func(t *testing.T, r *http.Request) {
+		r.RemoteAddr = "10.0.0.1:5000"
+		r.Header.Set(RequestIDHeader, "req-1")
+	})
+	SimplePerformer(t, a, exp).Perform()
+
+	ZeroExpectationPerformer(t, a, 200, "GET", "/quiet/unlogged").Perform()
+
+	if !strings.HasPrefix(common.String(), "10.0.0.1 - - [") || !strings.Contains(common.String(), `"GET /logged HTTP/1.1" 201 11`) {
+		t.Errorf("Unexpected common access log output: %s", common.String())
+	}
+
+	if n := strings.Count(common.String(), "\n"); n != 2 {
+		t.Errorf("Expected 2 common access log lines, but found %d: %s", n, common.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(js.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected 1 json access log line from a disabled blueprint, but found %d: %s", len(lines), js.String())
+	}
+	e := &AccessEntry{}
+	json.Unmarshal([]byte(lines[0]), e)
+	if e.Status != 201 || e.Bytes != 11 || e.RequestID != "req-1" || e.Route != `\logged\get` || e.ClientIP != "10.0.0.1" {
+		t.Errorf("Unexpected json access log entry: %+v", e)
+	}
+}
@@ -0,0 +1,101 @@
+package flotilla
+
+import "strings"
+
+const (
+	APIKeyHeader = "X-API-Key"
+	APIKeyQuery  = "api_key"
+	apikeyData   = "apikey"
+)
+
+// APIKey is the principal and scopes resolved from a presented api key.
+type APIKey struct {
+	Principal string
+	Scopes    []string
+}
+
+// HasScope returns a boolean indicating the APIKey is granted the scope.
+func (k *APIKey) HasScope(scope string) bool {
+	return existsIn(scope, k.Scopes)
+}
+
+// KeyStore resolves api keys to an APIKey.
+type KeyStore interface {
+	Lookup(string) (*APIKey, bool)
+}
+
+// StaticKeys is a KeyStore backed by a map of keys.
+type StaticKeys map[string]*APIKey
+
+func (s StaticKeys) Lookup(key string) (*APIKey, bool) {
+	k, ok := s[key]
+	return k, ok
+}
+
+// KeyFunc is a KeyStore backed by a callback, e.g. for a database query.
+type KeyFunc func(string) (*APIKey, bool)
+
+func (fn KeyFunc) Lookup(key string) (*APIKey, bool) {
+	return fn(key)
+}
+
+// StoreKeys returns a KeyStore from api keys in the provided Store, set as
+// e.g. "apikey_alice:abc123" and "apiscopes_alice:read,write", where the
+// principal is alice.
+func StoreKeys(s Store) KeyStore {
+	keys := make(StaticKeys)
+	for k, v := range s {
+		if strings.HasPrefix(k, "APIKEY_") && v.Value != "" {
+			principal := strings.ToLower(strings.TrimPrefix(k, "APIKEY_"))
+			apikey := &APIKey{Principal: principal}
+			if scopes, ok := s["APISCOPES_"+strings.ToUpper(principal)]; ok && scopes.Value != "" {
+				apikey.Scopes = strings.Split(scopes.Value, ",")
+			}
+			keys[v.Value] = apikey
+		}
+	}
+	return keys
+}
+
+func presentedkey(c Ctx) string {
+	rq := CurrentRequest(c)
+	if k := rq.Header.Get(APIKeyHeader); k != "" {
+		return k
+	}
+	return rq.URL.Query().Get(APIKeyQuery)
+}
+
+// APIKeyAuth returns a Manage function authenticating requests by an api key
+// in the X-API-Key header or api_key query parameter against the KeyStore.
+// Requests without a known key receive a 401 status, and requests with a key
+// lacking any of the provided scopes a 403 status. The resolved APIKey is
+// available to later managers with CurrentAPIKey.
+func APIKeyAuth(ks KeyStore, scopes ...string) Manage {
+	return func(c Ctx) {
+		k, ok := ks.Lookup(presentedkey(c))
+		if !ok {
+			Audit(c, AuditPermissionDenied, map[string]string{"reason": "invalid api key"})
+			c.Call("abort", 401)
+			return
+		}
+		for _, s := range scopes {
+			if !k.HasScope(s) {
+				Audit(c, AuditPermissionDenied, map[string]string{"reason": "api key lacks scope " + s, "principal": k.Principal})
+				c.Call("abort", 403)
+				return
+			}
+		}
+		c.Call("set", apikeyData, k)
+	}
+}
+
+// CurrentAPIKey returns the APIKey resolved by APIKeyAuth for the current
+// request, and a boolean indicating its existence.
+func CurrentAPIKey(c Ctx) (*APIKey, bool) {
+	k, err := c.Call("get", apikeyData)
+	if err != nil {
+		return nil, false
+	}
+	apikey, ok := k.(*APIKey)
+	return apikey, ok
+}
@@ -0,0 +1,49 @@
+package flotilla
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestStoreKeys(t *testing.T) {
+	s := defaultStore()
+	s.add("apikey", "alice", "abc123")
+	s.add("apiscopes", "alice", "read,write")
+	k, ok := StoreKeys(s).Lookup("abc123")
+	if !ok || k.Principal != "alice" || !k.HasScope("write") {
+		t.Errorf("StoreKeys did not resolve the store api key correctly: %+v", k)
+	}
+	if _, ok := StoreKeys(s).Lookup("nope"); ok {
+		t.Errorf("StoreKeys resolved an unknown api key")
+	}
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	keys := StaticKeys{
+		"reader": &APIKey{Principal: "reader", Scopes: []string{"read"}},
+		"writer": &APIKey{Principal: "writer", Scopes: []string{"read", "write"}},
+	}
+
+	var principal string
+
+	a := testApp(t, "testAPIKeyAuth")
+
+	a.GET("/read", APIKeyAuth(keys, "read"), func(c Ctx) {
+		if k, ok := CurrentAPIKey(c); ok {
+			principal = k.Principal
+		}
+	})
+	a.GET("/write", APIKeyAuth(keys, "write"), func(c Ctx) {})
+
+	exp, _ := NoTanage(200, "GET", "/read")
+	exp.SetPre(func(t *testing.T, r *http.Request) { r.Header.Set(APIKeyHeader, "reader") })
+	SimplePerformer(t, a, exp).Perform()
+	if principal != "reader" {
+		t.Errorf(`Expected api key principal "reader", but was %q`, principal)
+	}
+
+	ZeroExpectationPerformer(t, a, 200, "GET", "/write?api_key=writer").Perform()
+	ZeroExpectationPerformer(t, a, 403, "GET", "/write?api_key=reader").Perform()
+	ZeroExpectationPerformer(t, a, 401, "GET", "/read?api_key=unknown").Perform()
+	ZeroExpectationPerformer(t, a, 401, "GET", "/read").Perform()
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"io"
+	"io/fs"
 	"io/ioutil"
 	"net/http"
 	"os"
@@ -199,3 +200,41 @@ func (a Assets) GetByte(requested string) ([]byte, error) {
 	}
 	return nil, AssetUnavailable(requested)
 }
+
+// FSAssets returns an AssetFS of the files of fsys below dir, or of all its
+// files if dir is empty, e.g. an embed.FS of static files and templates
+// compiled into the binary.
+func FSAssets(fsys fs.FS, dir string) (AssetFS, error) {
+	if dir != "" && dir != "." {
+		sub, err := fs.Sub(fsys, dir)
+		if err != nil {
+			return nil, err
+		}
+		fsys = sub
+	}
+	asset := func(name string) ([]byte, error) {
+		return fs.ReadFile(fsys, name)
+	}
+	assetDir := func(name string) ([]string, error) {
+		entries, err := fs.ReadDir(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, 0, len(entries))
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		return names, nil
+	}
+	assetNames := func() []string {
+		var names []string
+		fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				names = append(names, name)
+			}
+			return nil
+		})
+		return names
+	}
+	return NewAssetFS(asset, assetDir, assetNames, ""), nil
+}
@@ -1,7 +1,11 @@
 package flotilla
 
 import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
+	"testing/fstest"
 
 	"github.com/thrisp/flotilla/resources"
 )
@@ -21,3 +25,40 @@ func TestAssets(t *testing.T) {
 	//fl, _ := TestAsset.Open(fmt.Sprintf("%s", f))
 	//fmt.Printf("%+v\n", checkff)
 }
+
+func TestFSAssets(t *testing.T) {
+	embedded := fstest.MapFS{
+		"web/static/app.css":      {Data: []byte("embedded css")},
+		"web/templates/page.html": {Data: []byte("embedded page")},
+	}
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "page.html"), []byte("disk page"), 0644)
+
+	serve := func(modes ...Configuration) func(string) string {
+		conf := append(modes, WithFS(embedded, "web"), TemplateDriver("html"), EnvItem("TEMPLATE_DIRECTORIES:"+dir))
+		a := New("testFSAssets", conf...)
+		a.GET("/page", func(c Ctx) { c.Call("rendertemplate", "page.html", nil) })
+		a.Configure()
+		return func(path string) string {
+			rw := httptest.NewRecorder()
+			a.ServeHTTP(rw, httptest.NewRequest("GET", path, nil))
+			return rw.Body.String()
+		}
+	}
+
+	dev := serve(Mode("testing", true))
+	if body := dev("/static/app.css"); body != "embedded css" {
+		t.Errorf("expected a static file of the fs.FS served, got %q", body)
+	}
+	if body := dev("/page"); body != "disk page" {
+		t.Errorf("expected the template directories before the fs.FS in Development mode, got %q", body)
+	}
+	prod := serve(Mode("production", true), Mode("development", false))
+	if body := prod("/page"); body != "embedded page" {
+		t.Errorf("expected the fs.FS before the template directories in Production mode, got %q", body)
+	}
+
+	if _, err := FSAssets(embedded, "../outside"); err == nil {
+		t.Error("expected an error for an invalid fs.FS directory")
+	}
+}
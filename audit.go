@@ -0,0 +1,232 @@
+package flotilla
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/thrisp/flotilla/xrr"
+)
+
+// Audit event kinds recorded by flotilla; applications may use any others.
+const (
+	AuditLogin             = "login"
+	AuditLogout            = "logout"
+	AuditSessionRegenerate = "session_regenerate"
+	AuditSessionDestroy    = "session_destroy"
+	AuditPermissionDenied  = "permission_denied"
+	AuditConfigChange      = "config_change"
+)
+
+// AuditEvent is a single security relevant occurrence in an App.
+type AuditEvent struct {
+	Time   time.Time         `json:"time"`
+	Kind   string            `json:"kind"`
+	Remote string            `json:"remote,omitempty"`
+	Method string            `json:"method,omitempty"`
+	Path   string            `json:"path,omitempty"`
+	Detail map[string]string `json:"detail,omitempty"`
+}
+
+// AuditSink receives audit events, e.g. for writing to a file, syslog, or
+// a remote collector.
+type AuditSink interface {
+	Audit(*AuditEvent) error
+}
+
+// AuditQueueSize is the number of audit events an Auditor holds for its
+// sinks before refusing events.
+var AuditQueueSize = 1024
+
+var (
+	AuditQueueFull = xrr.NewXrror("audit queue is full, %s event dropped").Out
+	AuditorClosed  = xrr.NewXrror("auditor is closed, %s event dropped").Out
+)
+
+// Auditor dispatches audit events to any number of AuditSinks from a bounded
+// queue, so that a slow sink does not hold up requests. Sink errors are sent
+// to OnError, if set.
+type Auditor struct {
+	OnError func(error)
+	mu      sync.Mutex
+	sinks   []AuditSink
+	queue   chan *AuditEvent
+	done    chan struct{}
+	closed  bool
+}
+
+// AddSinks adds AuditSinks to the Auditor.
+func (a *Auditor) AddSinks(sinks ...AuditSink) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.sinks = append(a.sinks, sinks...)
+}
+
+// Audit queues the event for every sink, returning an error if the event was
+// dropped because the queue is full or the Auditor is closed.
+func (a *Auditor) Audit(e *AuditEvent) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return AuditorClosed(e.Kind)
+	}
+	if a.queue == nil {
+		a.queue, a.done = make(chan *AuditEvent, AuditQueueSize), make(chan struct{})
+		go a.dispatch(a.queue, a.done)
+	}
+	select {
+	case a.queue <- e:
+		return nil
+	default:
+		return AuditQueueFull(e.Kind)
+	}
+}
+
+func (a *Auditor) dispatch(queue chan *AuditEvent, done chan struct{}) {
+	defer close(done)
+	for e := range queue {
+		a.mu.Lock()
+		sinks := a.sinks
+		a.mu.Unlock()
+		for _, s := range sinks {
+			if err := s.Audit(e); err != nil && a.OnError != nil {
+				a.OnError(err)
+			}
+		}
+	}
+}
+
+// Close sends any queued events to the sinks, and closes each sink that is
+// an io.Closer, e.g. a FileSink or SyslogSink, returning the last error
+// encountered. Events audited after Close are dropped.
+func (a *Auditor) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	queue, done, sinks := a.queue, a.done, a.sinks
+	a.mu.Unlock()
+	if queue != nil {
+		close(queue)
+		<-done
+	}
+	var err error
+	for _, s := range sinks {
+		if cs, ok := s.(io.Closer); ok {
+			if cerr := cs.Close(); cerr != nil {
+				err = cerr
+			}
+		}
+	}
+	return err
+}
+
+// Audit records the event with the Messaging Auditor, sending any error of a
+// dropped event to the "out" queue.
+func (m *Messaging) Audit(e *AuditEvent) {
+	if m == nil || m.Auditor == nil {
+		return
+	}
+	if err := m.Auditor.Audit(e); err != nil {
+		m.Out(err.Error())
+	}
+}
+
+func (m *Messaging) auditerror(err error) {
+	m.Out(fmt.Sprintf("audit sink error: %s", err))
+}
+
+type writerSink struct {
+	w io.Writer
+}
+
+// WriterSink returns an AuditSink writing each event as a line of JSON.
+func WriterSink(w io.Writer) AuditSink {
+	return &writerSink{w: w}
+}
+
+func (s *writerSink) Audit(e *AuditEvent) error {
+	return json.NewEncoder(s.w).Encode(e)
+}
+
+type fileSink struct {
+	*writerSink
+	f *os.File
+}
+
+// FileSink returns an AuditSink appending JSON lines to the named file,
+// closed with the Auditor.
+func FileSink(name string) (AuditSink, error) {
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &fileSink{writerSink: &writerSink{w: f}, f: f}, nil
+}
+
+func (s *fileSink) Close() error {
+	return s.f.Close()
+}
+
+type httpSink struct {
+	url    string
+	client *http.Client
+}
+
+// HTTPSink returns an AuditSink posting each event as JSON to the provided url.
+func HTTPSink(url string, timeout time.Duration) AuditSink {
+	return &httpSink{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+func (s *httpSink) Audit(e *AuditEvent) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	res, err := s.client.Post(s.url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("audit collector %s returned %s", s.url, res.Status)
+	}
+	return nil
+}
+
+// AuditSinks adds the provided AuditSinks to the App audit log.
+func AuditSinks(sinks ...AuditSink) Configuration {
+	return func(a *App) error {
+		a.Messaging.Auditor.AddSinks(sinks...)
+		return nil
+	}
+}
+
+func auditfunc(a *App) func(*ctx, string, map[string]string) error {
+	return func(c *ctx, kind string, detail map[string]string) error {
+		a.Messaging.Audit(&AuditEvent{
+			Kind:   kind,
+			Remote: c.Request.RemoteAddr,
+			Method: c.Request.Method,
+			Path:   c.Request.URL.Path,
+			Detail: detail,
+		})
+		return nil
+	}
+}
+
+// Audit records an audit event of the provided kind with request information
+// from the Ctx.
+func Audit(c Ctx, kind string, detail map[string]string) {
+	c.Call("audit", kind, detail)
+}
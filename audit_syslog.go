@@ -0,0 +1,36 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package flotilla
+
+import (
+	"encoding/json"
+	"log/syslog"
+)
+
+type syslogSink struct {
+	w *syslog.Writer
+}
+
+// SyslogSink returns an AuditSink writing JSON events to the local syslog
+// daemon with the provided tag, at the auth facility.
+func SyslogSink(tag string) (AuditSink, error) {
+	w, err := syslog.New(syslog.LOG_NOTICE|syslog.LOG_AUTH, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &syslogSink{w: w}, nil
+}
+
+func (s *syslogSink) Audit(e *AuditEvent) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return s.w.Notice(string(b))
+}
+
+// Close closes the connection to the syslog daemon.
+func (s *syslogSink) Close() error {
+	return s.w.Close()
+}
@@ -0,0 +1,46 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package flotilla
+
+import (
+	"io"
+	"log/syslog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyslogSinkClose(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer l.Close()
+	w, err := syslog.Dial("tcp", l.Addr().String(), syslog.LOG_NOTICE|syslog.LOG_AUTH, "flotilla")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	a := &Auditor{}
+	a.AddSinks(&syslogSink{w: w})
+	a.Audit(&AuditEvent{Kind: AuditLogin})
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	b, err := io.ReadAll(conn)
+	if err != nil {
+		t.Errorf("expected the syslog sink to be closed with the Auditor, got %v", err)
+	}
+	if !strings.Contains(string(b), string(AuditLogin)) {
+		t.Errorf("expected the audited event before Close, got %q", b)
+	}
+}
@@ -0,0 +1,130 @@
+package flotilla
+
+import (
+	"bytes"
+	stdcontext "context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAudit(t *testing.T) {
+	var b bytes.Buffer
+
+	a := testApp(t, "testAudit", AuditSinks(WriterSink(&b)), Mode("production", false))
+
+	a.GET("/denied", SignedUrl)
+
+	exp, _ := NewExpectation(
+		200,
+		"POST",
+		"/login",
+		func(t *testing.T) Manage {
+			return func(c Ctx) {
+				Audit(c, AuditLogin, map[string]string{"user": "scully"})
+			}
+		},
+	)
+
+	SimplePerformer(t, a, exp).Perform()
+
+	ZeroExpectationPerformer(t, a, 403, "GET", "/denied").Perform()
+
+	if err := a.Shutdown(stdcontext.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var kinds []string
+	for _, line := range strings.Split(strings.TrimSpace(b.String()), "\n") {
+		e := &AuditEvent{}
+		if err := json.Unmarshal([]byte(line), e); err != nil {
+			t.Fatalf("audit line %q is not a JSON AuditEvent: %s", line, err)
+		}
+		if e.Kind == AuditLogin && (e.Path != "/login" || e.Detail["user"] != "scully") {
+			t.Errorf("login audit event was not recorded correctly: %+v", e)
+		}
+		kinds = append(kinds, e.Kind)
+	}
+
+	for _, k := range []string{AuditConfigChange, AuditLogin, AuditPermissionDenied} {
+		if !existsIn(k, kinds) {
+			t.Errorf("expected an audit event of kind %s, but recorded only %v", k, kinds)
+		}
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	received := make(chan *AuditEvent, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, rq *http.Request) {
+		e := &AuditEvent{}
+		json.NewDecoder(rq.Body).Decode(e)
+		received <- e
+	}))
+	defer srv.Close()
+
+	s := HTTPSink(srv.URL, time.Second)
+	if err := s.Audit(&AuditEvent{Kind: AuditLogout}); err != nil {
+		t.Fatalf("HTTPSink error: %s", err)
+	}
+	if e := <-received; e.Kind != AuditLogout {
+		t.Errorf("HTTPSink posted %+v, expected a logout event", e)
+	}
+}
+
+func TestAuditConfigChange(t *testing.T) {
+	var b bytes.Buffer
+	a := New("testAuditConfigChange", AuditSinks(WriterSink(&b)), Mode("production", false), Mode("testing", true), EnvItem("AUDIT_ITEM:a", "AUDIT_ITEM:a"))
+	a.Configure()
+	a.Messaging.Auditor.Close()
+
+	if lines := strings.Split(strings.TrimSpace(b.String()), "\n"); len(lines) != 2 {
+		t.Errorf("expected config_change events of actual changes only, got %q", lines)
+	}
+}
+
+type blockingSink chan struct{}
+
+func (s blockingSink) Audit(*AuditEvent) error {
+	<-s
+	return nil
+}
+
+func TestAuditorQueue(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "audit.log")
+	fs, err := FileSink(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := make(blockingSink)
+	a := &Auditor{}
+	a.AddSinks(block, fs)
+
+	queued := 0
+	for i := 0; i < AuditQueueSize+2; i++ {
+		if a.Audit(&AuditEvent{Kind: AuditLogin}) == nil {
+			queued++
+		}
+	}
+	if queued < AuditQueueSize || queued > AuditQueueSize+1 {
+		t.Errorf("expected a blocked sink to fill the audit queue, %d events queued", queued)
+	}
+
+	close(block)
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Audit(&AuditEvent{Kind: AuditLogout}); err == nil {
+		t.Error("expected an error for an event audited after Close")
+	}
+	b, _ := os.ReadFile(name)
+	if lines := strings.Count(string(b), "\n"); lines != queued {
+		t.Errorf("expected %d queued events in the file sink, got %d", queued, lines)
+	}
+	if err := fs.(*fileSink).f.Close(); err == nil {
+		t.Error("expected the file sink to be closed with the Auditor")
+	}
+}
@@ -0,0 +1,256 @@
+package flotilla
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thrisp/flotilla/xrr"
+)
+
+var (
+	BodyTooLarge    = xrr.NewXrror("request body exceeds %d bytes").Out
+	InvalidBindType = xrr.NewXrror("cannot bind to %s, a pointer to a struct is required").Out
+	UnsupportedBody = xrr.NewXrror("cannot bind a request body of content type %q").Out
+)
+
+// BindError reports a request value that could not be decoded into a field of
+// a bound struct.
+type BindError struct {
+	Field string
+	Value string
+	Err   error
+}
+
+func (e *BindError) Error() string {
+	return fmt.Sprintf("cannot bind %q to field %s: %s", e.Value, e.Field, e.Err)
+}
+
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+// bodylimit returns the UPLOAD_SIZE limit of request bodies, in bytes.
+func bodylimit(c *ctx) int64 {
+	if item, ok := CheckStore(c, "UPLOAD_SIZE"); ok {
+		return item.Int64()
+	}
+	return 0
+}
+
+// limitbody limits the request body to the UPLOAD_SIZE, rewinding a
+// buffered body.
+func limitbody(c *ctx) int64 {
+	c.rewindbody()
+	limit := bodylimit(c)
+	if limit > 0 {
+		c.Request.Body = http.MaxBytesReader(c.RW, c.Request.Body, limit)
+	}
+	return limit
+}
+
+// bodyerror returns BodyTooLarge for a body over the limit, or err.
+func bodyerror(err error, limit int64) error {
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		return BodyTooLarge(limit)
+	}
+	return err
+}
+
+func bindjson(c *ctx, v interface{}) error {
+	limit := limitbody(c)
+	if err := json.NewDecoder(c.Request.Body).Decode(v); err != nil && err != io.EOF {
+		return bodyerror(err, limit)
+	}
+	return nil
+}
+
+func bindxml(c *ctx, v interface{}) error {
+	limit := limitbody(c)
+	if err := xml.NewDecoder(c.Request.Body).Decode(v); err != nil && err != io.EOF {
+		return bodyerror(err, limit)
+	}
+	return nil
+}
+
+// bindform binds url encoded and multipart form values, including query
+// values, to the struct fields by their form tag, once the form passes any
+// FormGuard.
+func bindform(c *ctx, v interface{}) error {
+	limit := limitbody(c)
+	var err error
+	if ct, _, _ := mime.ParseMediaType(c.Request.Header.Get("Content-Type")); ct == "multipart/form-data" {
+		err = c.Request.ParseMultipartForm(limit)
+	} else {
+		err = c.Request.ParseForm()
+	}
+	if err != nil {
+		return bodyerror(err, limit)
+	}
+	if _, err := c.Call("guardform"); err != nil {
+		return err
+	}
+	return bindvalues(c.Request.Form, v, "form")
+}
+
+// bindquery binds the query values to the struct fields by their query tag.
+func bindquery(c *ctx, v interface{}) error {
+	return bindvalues(c.Request.URL.Query(), v, "query")
+}
+
+// bind binds the request body by its content type, or the query values of a
+// request without a body.
+func bind(c *ctx, v interface{}) error {
+	if c.Request.Body == nil || c.Request.Body == http.NoBody || c.Request.ContentLength == 0 {
+		return bindquery(c, v)
+	}
+	ct, _, _ := mime.ParseMediaType(c.Request.Header.Get("Content-Type"))
+	switch {
+	case ct == "application/json" || strings.HasSuffix(ct, "+json"):
+		return bindjson(c, v)
+	case ct == "application/xml" || ct == "text/xml" || strings.HasSuffix(ct, "+xml"):
+		return bindxml(c, v)
+	case ct == "application/x-www-form-urlencoded" || ct == "multipart/form-data":
+		return bindform(c, v)
+	}
+	return UnsupportedBody(ct)
+}
+
+// bindvalues sets the fields of the struct v points to from values, keyed by
+// the field tag, or the lower case field name, with nested names as for
+// decodeform. A tag of "-" skips the field.
+func bindvalues(values url.Values, v interface{}, tag string) error {
+	return decodeform(values, v, tag)
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func setfield(fv reflect.Value, vs []string) error {
+	if fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			fv.Set(reflect.New(fv.Type().Elem()))
+		}
+		return setfield(fv.Elem(), vs)
+	}
+	if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+		s := reflect.MakeSlice(fv.Type(), len(vs), len(vs))
+		for i, v := range vs {
+			if err := setvalue(s.Index(i), v); err != nil {
+				return err
+			}
+		}
+		fv.Set(s)
+		return nil
+	}
+	return setvalue(fv, vs[0])
+}
+
+func setvalue(fv reflect.Value, v string) error {
+	if fv.Type() == timeType {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(t))
+		return nil
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(v)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if fv.Type() == reflect.TypeOf(time.Duration(0)) {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return err
+			}
+			fv.SetInt(int64(d))
+			return nil
+		}
+		n, err := strconv.ParseInt(v, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(v, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(v, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(n)
+	case reflect.Slice:
+		fv.SetBytes([]byte(v))
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+	return nil
+}
+
+// validated validates v, once bound by fn, against its validate struct tags.
+func validated(fn func(*ctx, interface{}) error) func(*ctx, interface{}) error {
+	return func(c *ctx, v interface{}) error {
+		if err := fn(c, v); err != nil {
+			return err
+		}
+		_, err := c.Call("validate", v)
+		return err
+	}
+}
+
+var bindfxtension = map[string]interface{}{
+	"bind":      validated(bind),
+	"bindjson":  validated(bindjson),
+	"bindxml":   validated(bindxml),
+	"bindform":  validated(bindform),
+	"bindquery": validated(bindquery),
+}
+
+// BindFxtension decodes requests into structs: the body as JSON, XML, or form
+// values, limited to the UPLOAD_SIZE, or the query values. Bound structs are
+// validated by their validate struct tags, returning ValidationErrors. Forms
+// rejected by a FormGuard return a GuardError.
+var BindFxtension Fxtension = MakeFxtension("bindfxtension", bindfxtension)
+
+func callbind(c Ctx, name string, v interface{}) error {
+	_, err := c.Call(name, v)
+	return err
+}
+
+// Bind decodes the request body into v by its content type, or the query
+// values of a request without a body.
+func Bind(c Ctx, v interface{}) error { return callbind(c, "bind", v) }
+
+// BindJSON decodes a JSON request body into v.
+func BindJSON(c Ctx, v interface{}) error { return callbind(c, "bindjson", v) }
+
+// BindXML decodes an XML request body into v.
+func BindXML(c Ctx, v interface{}) error { return callbind(c, "bindxml", v) }
+
+// BindForm sets the fields of the struct v points to from the request form
+// values, by their form tag.
+func BindForm(c Ctx, v interface{}) error { return callbind(c, "bindform", v) }
+
+// BindQuery sets the fields of the struct v points to from the request query
+// values, by their query tag.
+func BindQuery(c Ctx, v interface{}) error { return callbind(c, "bindquery", v) }
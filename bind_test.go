@@ -0,0 +1,74 @@
+package flotilla
+
+import (
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindtarget struct {
+	Name    string        `json:"name" xml:"name" form:"name" query:"q"`
+	Count   int           `json:"count" xml:"count" form:"count" query:"n"`
+	Tags    []string      `form:"tag" query:"tag"`
+	Active  *bool         `form:"active"`
+	When    time.Time     `form:"when"`
+	Timeout time.Duration `form:"timeout"`
+	Skipped string        `form:"-"`
+}
+
+func TestBind(t *testing.T) {
+	a := New("testBind", Mode("testing", true), EnvItem("UPLOAD_SIZE:256"))
+	var bound bindtarget
+	var berr error
+	a.POST("/bind", func(c Ctx) {
+		bound = bindtarget{}
+		berr = Bind(c, &bound)
+	})
+	a.GET("/bind", func(c Ctx) {
+		bound = bindtarget{}
+		berr = Bind(c, &bound)
+	})
+	a.Configure()
+	post := func(ct, body string) {
+		rq := httptest.NewRequest("POST", "/bind", strings.NewReader(body))
+		rq.Header.Set("Content-Type", ct)
+		a.ServeHTTP(httptest.NewRecorder(), rq)
+	}
+
+	post("application/json", `{"name":"json","count":2}`)
+	if berr != nil || bound.Name != "json" || bound.Count != 2 {
+		t.Errorf("unexpected json binding %+v %v", bound, berr)
+	}
+	post("application/xml", `<t><name>xml</name><count>3</count></t>`)
+	if berr != nil || bound.Name != "xml" || bound.Count != 3 {
+		t.Errorf("unexpected xml binding %+v %v", bound, berr)
+	}
+	form := url.Values{"name": {"form"}, "tag": {"a", "b"}, "active": {"true"}, "when": {"2020-01-02T03:04:05Z"}, "timeout": {"2s"}, "Skipped": {"x"}}
+	post("application/x-www-form-urlencoded", form.Encode())
+	if berr != nil || bound.Name != "form" || len(bound.Tags) != 2 || bound.Active == nil || !*bound.Active ||
+		bound.When.Year() != 2020 || bound.Timeout != 2*time.Second || bound.Skipped != "" {
+		t.Errorf("unexpected form binding %+v %v", bound, berr)
+	}
+
+	post("application/x-www-form-urlencoded", "count=many")
+	var be *BindError
+	if !errors.As(berr, &be) || be.Field != "Count" {
+		t.Errorf("expected a BindError for Count, got %v", berr)
+	}
+	post("application/json", `{"name":"`+strings.Repeat("x", 300)+`"}`)
+	if berr == nil || !strings.Contains(berr.Error(), "exceeds 256 bytes") {
+		t.Errorf("expected a body size error, got %v", berr)
+	}
+	post("text/plain", "name")
+	if berr == nil {
+		t.Error("expected an error for an unsupported content type")
+	}
+
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/bind?q=query&n=4&tag=x", nil))
+	if berr != nil || bound.Name != "query" || bound.Count != 4 || len(bound.Tags) != 1 {
+		t.Errorf("unexpected query binding %+v %v", bound, berr)
+	}
+}
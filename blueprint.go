@@ -1,9 +1,13 @@
 package flotilla
 
 import (
+	"fmt"
+	"net/http"
 	"path/filepath"
+	"runtime"
 	"strconv"
 
+	"github.com/thrisp/flotilla/engine"
 	"github.com/thrisp/flotilla/xrr"
 )
 
@@ -20,9 +24,14 @@ type (
 		app      *App
 		children []*Blueprint
 		Prefix   string
+		Host     string
 		Routes
-		Managers []Manage
-		MakeCtx  MakeCtxFunc
+		Managers   []Manage
+		MakeCtx    MakeCtxFunc
+		versions   *versions
+		version    string
+		templator  *blueprinttemplator
+		processors *ctxprocessors
 	}
 )
 
@@ -49,7 +58,7 @@ func (a *App) Blueprints() []*Blueprint {
 // Given any number of Blueprints, RegisterBlueprints registers each with the App.
 func (a *App) RegisterBlueprints(blueprints ...*Blueprint) {
 	for _, blueprint := range blueprints {
-		existing, exists := a.ExistingBlueprint(blueprint.Prefix)
+		existing, exists := a.existingBlueprint(blueprint.Prefix, blueprint.Host)
 		if !exists {
 			blueprint.Register(a)
 			a.children = append(a.children, blueprint)
@@ -63,14 +72,34 @@ func (a *App) RegisterBlueprints(blueprints ...*Blueprint) {
 }
 
 func (a *App) ExistingBlueprint(prefix string) (*Blueprint, bool) {
+	return a.existingBlueprint(prefix, "")
+}
+
+func (a *App) existingBlueprint(prefix, host string) (*Blueprint, bool) {
 	for _, b := range a.Blueprints() {
-		if b.Prefix == prefix {
+		if b.Prefix == prefix && b.Host == host {
 			return b, true
 		}
 	}
 	return nil, false
 }
 
+// Host returns the Blueprint of the App for routes of requests to hosts
+// matching the pattern, e.g. admin.example.com, where any label may be a
+// param, e.g. {tenant}.example.com, available with the route params. Routes
+// of a host take precedence over the same routes of any host.
+func (a *App) Host(pattern string, managers ...Manage) *Blueprint {
+	if b, exists := a.existingBlueprint("/", pattern); exists {
+		b.Use(managers...)
+		return b
+	}
+	b := NewBlueprint("/")
+	b.Host = pattern
+	b.Managers = a.combineManagers(managers)
+	a.RegisterBlueprints(b)
+	return b
+}
+
 var AlreadyRegistered = xrr.NewXrror("only unregistered blueprints may be mounted; %s is already registered").Out
 
 // Mount attaches each provided Blueprint to the given string mount point, optionally
@@ -85,6 +114,7 @@ func (a *App) Mount(point string, blueprints ...*Blueprint) error {
 		newprefix := filepath.ToSlash(filepath.Join(point, blueprint.Prefix))
 
 		nbp := NewBlueprint(newprefix)
+		nbp.Host = blueprint.Host
 		nbp.Managers = a.combineManagers(blueprint.Managers)
 
 		for _, rt := range blueprint.setupstate.held {
@@ -97,6 +127,50 @@ func (a *App) Mount(point string, blueprints ...*Blueprint) error {
 	return nil
 }
 
+// MountBlueprint attaches copies of the routes of an unregistered Blueprint
+// and its children under the Blueprint prefix joined with the point, which may
+// have params, e.g. /orgs/:org, running the Managers of the Blueprint before
+// those of the mounted Blueprint. The mounted Blueprint is left unregistered,
+// so that a feature packaged as a Blueprint can be mounted any number of
+// times.
+func (b *Blueprint) MountBlueprint(point string, blueprint *Blueprint) error {
+	if blueprint.registered {
+		return AlreadyRegistered(blueprint.Prefix)
+	}
+	nbp := b.mount(point, blueprint)
+	b.children = append(b.children, nbp)
+	if b.registered {
+		nbp.registerAll(b.app)
+	}
+	return nil
+}
+
+func (b *Blueprint) mount(point string, blueprint *Blueprint) *Blueprint {
+	nbp := NewBlueprint(b.pathFor(filepath.ToSlash(filepath.Join(point, blueprint.Prefix))))
+	nbp.Host = b.Host
+	nbp.Managers = b.combineManagers(blueprint.Managers)
+	nbp.MakeCtx = blueprint.MakeCtx
+	nbp.processors = &ctxprocessors{parent: b.processors}
+	if blueprint.processors != nil {
+		nbp.processors.fns = blueprint.processors.fns
+	}
+	for _, rt := range blueprint.held {
+		mrt := *rt
+		nbp.Manage(&mrt)
+	}
+	for _, child := range blueprint.children {
+		nbp.children = append(nbp.children, b.mount(point, child))
+	}
+	return nbp
+}
+
+func (b *Blueprint) registerAll(a *App) {
+	b.Register(a)
+	for _, child := range b.children {
+		child.registerAll(a)
+	}
+}
+
 func (b *Blueprint) pathFor(path string) string {
 	joined := filepath.ToSlash(filepath.Join(b.Prefix, path))
 	// Append a '/' if the last component had one, but only if it's not there already
@@ -112,6 +186,7 @@ func NewBlueprint(prefix string) *Blueprint {
 		setupstate: &setupstate{},
 		Prefix:     prefix,
 		Routes:     make(Routes),
+		processors: &ctxprocessors{},
 	}
 }
 
@@ -120,7 +195,11 @@ func (b *Blueprint) NewBlueprint(component string, managers ...Manage) *Blueprin
 	prefix := b.pathFor(component)
 
 	newb := NewBlueprint(prefix)
+	newb.Host = b.Host
 	newb.Managers = b.combineManagers(managers)
+	newb.versions, newb.version = b.versions, b.version
+	newb.templator = b.templator
+	newb.processors = &ctxprocessors{parent: b.processors}
 
 	b.children = append(b.children, newb)
 
@@ -187,9 +266,33 @@ func (b *Blueprint) UseAt(index int, managers ...Manage) {
 	b.Managers = append(before, after...)
 }
 
+// Before inserts managers before the target manager of the Blueprint, or
+// after all if not found, for routes registered afterwards.
+func (b *Blueprint) Before(target Manage, managers ...Manage) {
+	b.Managers = beforeManage(b.Managers, target, managers)
+}
+
+// After inserts managers after the target manager of the Blueprint, or after
+// all if not found, for routes registered afterwards.
+func (b *Blueprint) After(target Manage, managers ...Manage) {
+	b.Managers = afterManage(b.Managers, target, managers)
+}
+
+// Replace replaces the target manager of the Blueprint with managers, if
+// found, for routes registered afterwards.
+func (b *Blueprint) Replace(target Manage, managers ...Manage) {
+	b.Managers = replaceManage(b.Managers, target, managers)
+}
+
+// Skip removes the target manager from the Blueprint for routes registered
+// afterwards.
+func (b *Blueprint) Skip(target Manage) {
+	b.Managers = skipManage(b.Managers, target)
+}
+
 func (b *Blueprint) routeExists(rt *Route) bool {
 	for _, r := range b.Routes {
-		if (rt.Path == r.Path) && (rt.Method == r.Method) {
+		if (rt.Path == r.Path) && (rt.Method == r.Method) && (rt.Host == r.Host) {
 			return true
 		}
 	}
@@ -225,8 +328,10 @@ func (b *Blueprint) mkctxfunc() MakeCtxFunc {
 func registerRouteConf(b *Blueprint) RouteConf {
 	return func(rt *Route) error {
 		rt.Blueprint = b
-		rt.Managers = b.combineManagers(rt.Managers)
+		rt.handlers = rt.Managers
+		rt.compose()
 		rt.Path = b.pathFor(rt.Base)
+		rt.Host = b.Host
 		rt.Registered = true
 		rt.MakeCtx = b.mkctxfunc()
 		return nil
@@ -237,53 +342,147 @@ func registerRouteConf(b *Blueprint) RouteConf {
 func (b *Blueprint) Manage(rt *Route) {
 	register := func() {
 		rt.Configure(registerRouteConf(b))
-		if !b.routeExists(rt) {
+		if b.routeExists(rt) {
+			b.app.conflict(DuplicateRoute(rt.Method, rt.Path))
+			return
+		}
+		if b.handle(rt) {
 			b.add(rt)
-			b.app.Handle(rt.Method, rt.Path, rt.rule)
 		}
 	}
 	b.push(register, rt)
 }
 
-func (b *Blueprint) GET(path string, managers ...Manage) {
-	b.Manage(NewRoute(defaultRouteConf("GET", path, managers)))
+// handle adds the route to the engine of the App, for requests to its host if
+// any, returning false and recording the conflict if the engine reports the
+// route conflicts with those already routed.
+func (a *App) handle(rt *Route) bool {
+	return a.handleRule(rt.Host, rt.Method, rt.Path, rt.rule)
 }
 
-func (b *Blueprint) POST(path string, managers ...Manage) {
-	b.Manage(NewRoute(defaultRouteConf("POST", path, managers)))
+func (a *App) handleRule(host, method, path string, rule engine.Rule) bool {
+	if e, ok := a.Engine.(interface {
+		Conflict(string, string, string) error
+	}); ok {
+		if err := e.Conflict(host, method, path); err != nil {
+			a.conflict(err)
+			return false
+		}
+	}
+	if host == "" {
+		a.Handle(method, path, rule)
+		return true
+	}
+	e, ok := a.Engine.(interface {
+		HandleHost(string, string, string, engine.Rule)
+	})
+	if !ok {
+		panic(fmt.Sprintf("[FLOTILLA] engine %T can not route host %s", a.Engine, host))
+	}
+	e.HandleHost(host, method, path, rule)
+	return true
+}
+
+// route adds a route to the Blueprint, returning it to be named with Rename,
+// e.g. b.GET("/users/:id", h).Rename("user"), for UrlFor and URLFor.
+func (b *Blueprint) route(method, path string, managers []Manage) *Route {
+	rt := NewRoute(defaultRouteConf(method, path, managers))
+	if _, file, line, ok := runtime.Caller(2); ok {
+		rt.source = fmt.Sprintf("%s:%d", file, line)
+	}
+	b.Manage(rt)
+	return rt
 }
 
-func (b *Blueprint) DELETE(path string, managers ...Manage) {
-	b.Manage(NewRoute(defaultRouteConf("DELETE", path, managers)))
+func (b *Blueprint) GET(path string, managers ...Manage) *Route {
+	return b.route("GET", path, managers)
 }
 
-func (b *Blueprint) PATCH(path string, managers ...Manage) {
-	b.Manage(NewRoute(defaultRouteConf("PATCH", path, managers)))
+func (b *Blueprint) POST(path string, managers ...Manage) *Route {
+	return b.route("POST", path, managers)
 }
 
-func (b *Blueprint) PUT(path string, managers ...Manage) {
-	b.Manage(NewRoute(defaultRouteConf("PUT", path, managers)))
+func (b *Blueprint) DELETE(path string, managers ...Manage) *Route {
+	return b.route("DELETE", path, managers)
 }
 
-func (b *Blueprint) OPTIONS(path string, managers ...Manage) {
-	b.Manage(NewRoute(defaultRouteConf("OPTIONS", path, managers)))
+func (b *Blueprint) PATCH(path string, managers ...Manage) *Route {
+	return b.route("PATCH", path, managers)
 }
 
-func (b *Blueprint) HEAD(path string, managers ...Manage) {
-	b.Manage(NewRoute(defaultRouteConf("HEAD", path, managers)))
+func (b *Blueprint) PUT(path string, managers ...Manage) *Route {
+	return b.route("PUT", path, managers)
 }
 
-func (b *Blueprint) STATIC(path string) {
+func (b *Blueprint) OPTIONS(path string, managers ...Manage) *Route {
+	return b.route("OPTIONS", path, managers)
+}
+
+func (b *Blueprint) HEAD(path string, managers ...Manage) *Route {
+	return b.route("HEAD", path, managers)
+}
+
+// STATIC adds the directory of the path to the static directories and serves
+// the files of the static directories and Assets at the path, configured by
+// the StaticOptions, e.g. DirectoryListing(true).
+func (b *Blueprint) STATIC(path string, opts ...StaticOption) {
 	b.push(func() { b.app.StaticDirs(dropTrailing(path, "*filepath")) }, nil)
 	register := func() {
-		rt := NewRoute(staticRouteConf("GET", path, []Manage{b.app.Staticor.Manage}))
+		m := newStaticMount(b.app, opts...)
+		rt := NewRoute(staticRouteConf("GET", path, []Manage{m.Manage}))
 		rt.Configure(registerRouteConf(b))
-		b.add(rt)
-		b.app.Handle(rt.Method, rt.Path, rt.rule)
+		if b.app.handle(rt) {
+			b.add(rt)
+		}
 	}
 	b.push(register, nil)
 }
 
+// Routing applies the engine options, e.g. engine.CaseInsensitiveRouting, to
+// the paths of the Blueprint prefix, for any host.
+func (b *Blueprint) Routing(opts ...engine.Option) {
+	b.scope(opts...)
+}
+
+// scope applies the engine options to the paths of the Blueprint prefix, or
+// to every path for the App Blueprint.
+func (b *Blueprint) scope(opts ...engine.Option) {
+	b.push(func() {
+		if b.Prefix != "/" {
+			opts = []engine.Option{engine.Scoped(b.Prefix, opts...)}
+		}
+		if err := configureEngine(b.app, opts...); err != nil {
+			b.app.Env.Log().Warn("blueprint routing error", "prefix", b.Prefix, "error", err)
+		}
+	}, nil)
+}
+
+// NotFound responds to requests for unrouted paths of the Blueprint prefix,
+// for any host, with a 404 status and the managers, in place of the 404
+// status of the App, e.g. a JSON error for an API version. On the App it
+// applies to every path without a Blueprint NotFound or CatchAll.
+func (b *Blueprint) NotFound(managers ...Manage) {
+	s := newStatus(404, managers...)
+	b.scope(engine.NotFound(func(rw http.ResponseWriter, rq *http.Request, rs *engine.Result) {
+		statusrule(b.app, s)(rw, rq, rs)
+	}))
+}
+
+// CatchAll routes requests of any method for unrouted paths of the Blueprint
+// prefix, for any host, to the managers as a route, e.g. serving the index of
+// a single page application, with the path below the prefix as the param
+// path. Unlike a /*path route, it does not conflict with other routes of the
+// prefix, which take precedence.
+func (b *Blueprint) CatchAll(managers ...Manage) *Route {
+	rt := NewRoute(defaultRouteConf("ANY", "/*path", managers))
+	b.push(func() {
+		rt.Configure(registerRouteConf(b))
+		b.add(rt)
+	}, nil)
+	b.scope(engine.NotFound(rt.rule))
+	return rt
+}
+
 func (b *Blueprint) STATUS(code int, managers ...Manage) {
 	b.push(func() {
 		b.app.Handle("STATUS",
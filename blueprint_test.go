@@ -223,3 +223,116 @@ func TestMountBlueprint(t *testing.T) {
 		mountBlueprint(m, t)
 	}
 }
+
+func TestHostBlueprint(t *testing.T) {
+	a := New("testHostBlueprint", Mode("testing", true))
+	var routed, tenant string
+	a.GET("/", func(c Ctx) { routed = "default" })
+	a.Host("admin.example.com").GET("/", func(c Ctx) { routed = "admin" })
+	tenants := a.Host("{tenant}.example.com")
+	tenants.GET("/", func(c Ctx) {
+		routed = "tenant"
+		v, _ := Param(c, "tenant")
+		tenant = v.(string)
+	})
+	tenants.NewBlueprint("/api").GET("/users/:id", func(c Ctx) {
+		id, _ := c.Call("paramString", "id")
+		routed = "user " + id.(string)
+		v, _ := Param(c, "tenant")
+		tenant = v.(string)
+	})
+	client := a.TestClient()
+
+	if client.Get("http://admin.example.com/"); routed != "admin" {
+		t.Errorf("expected the admin host route, got %q", routed)
+	}
+	if client.Get("http://acme.example.com/"); routed != "tenant" || tenant != "acme" {
+		t.Errorf("expected the tenant host route for acme, got %q %q", routed, tenant)
+	}
+	if client.Get("http://acme.example.com/api/users/7"); routed != "user 7" || tenant != "acme" {
+		t.Errorf("expected the tenant api route, got %q %q", routed, tenant)
+	}
+	if client.Get("/"); routed != "default" {
+		t.Errorf("expected the default route, got %q", routed)
+	}
+	if res := client.Get("/api/users/7"); res.Status != 404 {
+		t.Errorf("expected a 404 for a tenant route on another host, got %d", res.Status)
+	}
+	if a.Host("{tenant}.example.com") != tenants {
+		t.Error("expected the existing blueprint of the host")
+	}
+}
+
+func TestMountBlueprintWithParams(t *testing.T) {
+	a := New("testMountBlueprintWithParams", Mode("testing", true))
+	var trail []string
+	mark := func(s string) Manage {
+		return func(c Ctx) { trail = append(trail, s) }
+	}
+	admin := NewBlueprint("/admin")
+	admin.Use(mark("admin"))
+	admin.GET("/users/:id", func(c Ctx) {
+		org, _ := Param(c, "org")
+		id, _ := Param(c, "id")
+		trail = append(trail, fmt.Sprintf("user %v %v", org, id))
+	})
+	admin.NewBlueprint("/settings", mark("settings")).GET("/", func(c Ctx) { trail = append(trail, "settings") })
+
+	orgs := a.NewBlueprint("/orgs/:org", mark("orgs"))
+	if err := orgs.MountBlueprint("/", admin); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.MountBlueprint("/global", admin); err != nil {
+		t.Fatal(err)
+	}
+	client := a.TestClient()
+
+	for path, expected := range map[string]string{
+		"/orgs/acme/admin/users/7":   "[orgs admin user acme 7]",
+		"/orgs/acme/admin/settings/": "[orgs admin settings settings]",
+		"/global/admin/users/7":      "[admin user <nil> 7]",
+	} {
+		trail = nil
+		if res := client.Get(path); res.Status != 200 || fmt.Sprint(trail) != expected {
+			t.Errorf("%s: unexpected mounted route %d %v", path, res.Status, trail)
+		}
+	}
+	if admin.registered || len(admin.Routes) != 0 {
+		t.Error("expected the mounted blueprint to be left unregistered")
+	}
+	if err := a.MountBlueprint("/again", a.Blueprint); err == nil {
+		t.Error("expected an error mounting a registered blueprint")
+	}
+}
+
+func TestBlueprintNotFoundCatchAll(t *testing.T) {
+	a := New("testBlueprintNotFoundCatchAll", Mode("testing", true))
+	a.NotFound(func(c Ctx) { c.Call("serveplain", 404, "app not found") })
+	api := a.NewBlueprint("/api")
+	api.GET("/items", func(c Ctx) {})
+	api.NotFound(func(c Ctx) { c.Call("serveplain", 404, `{"error":"not found"}`) })
+	spa := a.NewBlueprint("/app")
+	spa.GET("/about", func(c Ctx) { c.Call("serveplain", 200, "about") })
+	spa.CatchAll(func(c Ctx) {
+		v, _ := Param(c, "path")
+		c.Call("serveplain", 200, fmt.Sprintf("index %v", v))
+	})
+	client := a.TestClient()
+
+	for path, expected := range map[string]string{
+		"/nope":           "404 app not found",
+		"/api/nope":       `404 {"error":"not found"}`,
+		"/app/about":      "200 about",
+		"/app/users/7":    "200 index /users/7",
+		"/app":            "200 index /",
+		"/application/x":  "404 app not found",
+		"/api/items/more": `404 {"error":"not found"}`,
+	} {
+		if res := client.Get(path); fmt.Sprintf("%d %s", res.Status, res.Body) != expected {
+			t.Errorf("unexpected response for %s: %d %q, expected %s", path, res.Status, res.Body, expected)
+		}
+	}
+	if _, ok := a.Routes()[`\app\{s}\any`]; !ok {
+		t.Errorf("expected the catch-all route among the routes, got %v", a.Routes())
+	}
+}
@@ -0,0 +1,95 @@
+package flotilla
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+)
+
+const rawbodyData = "rawbody"
+
+// bufferlimit returns the BODY_BUFFER limit of buffered request bodies, in
+// bytes, or the UPLOAD_SIZE if unset.
+func bufferlimit(c *ctx) int64 {
+	if item, ok := CheckStore(c, "BODY_BUFFER"); ok && item.Int64() > 0 {
+		return item.Int64()
+	}
+	return bodylimit(c)
+}
+
+// unbuffered restores a request body read past the buffer limit.
+type unbuffered struct {
+	io.Reader
+	io.Closer
+}
+
+// bufferbody reads the request body, up to limit bytes, to be read again by
+// each manager; a body over the limit is left unbuffered and unread.
+func bufferbody(c *ctx, limit int64) error {
+	if _, ok := c.Data[rawbodyData]; ok {
+		c.rewindbody()
+		return nil
+	}
+	if limit <= 0 {
+		limit = bufferlimit(c)
+	}
+	body := c.Request.Body
+	if body == nil || body == http.NoBody {
+		setdata(c, rawbodyData, []byte{})
+		return nil
+	}
+	raw, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if int64(len(raw)) > limit || err != nil {
+		c.Request.Body = unbuffered{io.MultiReader(bytes.NewReader(raw), body), body}
+		if err != nil {
+			return err
+		}
+		return BodyTooLarge(limit)
+	}
+	body.Close()
+	setdata(c, rawbodyData, raw)
+	c.rewindbody()
+	return nil
+}
+
+// rewindbody restores a buffered request body to be read from its start.
+func (c *ctx) rewindbody() {
+	if raw, ok := c.Data[rawbodyData].([]byte); ok {
+		c.Request.Body = io.NopCloser(bytes.NewReader(raw))
+	}
+}
+
+func rawbody(c *ctx) ([]byte, error) {
+	if err := bufferbody(c, 0); err != nil {
+		return nil, err
+	}
+	return c.Data[rawbodyData].([]byte), nil
+}
+
+// GetRawBody returns the request body, buffering it up to the BODY_BUFFER
+// limit if not already buffered; the request body remains readable from its
+// start by later managers, e.g. a signature check followed by Bind.
+func GetRawBody(c Ctx) ([]byte, error) {
+	ret, err := c.Call("rawbody")
+	if err != nil {
+		return nil, err
+	}
+	return ret.([]byte), nil
+}
+
+// BufferBody returns a Manage function buffering the request body, up to
+// limit bytes or the BODY_BUFFER if limit is 0, so that each later manager
+// reads it from its start. Larger bodies are aborted with a 413 status, and
+// unreadable bodies with a 400 status.
+func BufferBody(limit int64) Manage {
+	return func(c Ctx) {
+		if _, err := c.Call("bufferbody", limit); err != nil {
+			code := 400
+			if errors.Is(err, BodyTooLarge(limit)) {
+				code = 413
+			}
+			c.Call("aborterror", code, err)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package flotilla
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBufferBody(t *testing.T) {
+	a := testApp(t, "bufferbody", EnvItem("BODY_BUFFER:16"))
+	var raw, again, bound string
+	a.POST("/buffered", BufferBody(0),
+		func(c Ctx) {
+			b, _ := io.ReadAll(CurrentRequest(c).Body)
+			raw = string(b)
+		},
+		func(c Ctx) {
+			b, _ := GetRawBody(c)
+			again = string(b)
+			var v struct{ Name string }
+			Bind(c, &v)
+			bound = v.Name
+			c.Call("serveplain", 200, "ok")
+		},
+	)
+	a.POST("/raw", func(c Ctx) {
+		if _, err := GetRawBody(c); err != nil {
+			b, _ := io.ReadAll(CurrentRequest(c).Body)
+			c.Call("serveplain", 200, string(b))
+		}
+	})
+	a.Configure()
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		rq, _ := http.NewRequest("POST", path, strings.NewReader(body))
+		rq.Header.Set("Content-Type", "application/json")
+		a.ServeHTTP(rw, rq)
+		return rw
+	}
+
+	body := `{"Name":"flo"}`
+	if rw := post("/buffered", body); rw.Code != 200 || raw != body || again != body || bound != "flo" {
+		t.Errorf("expected the body read by each manager, got %d %q %q %q", rw.Code, raw, again, bound)
+	}
+
+	large := `{"Name":"flotilla flotilla"}`
+	if rw := post("/buffered", large); rw.Code != 413 {
+		t.Errorf("expected a 413 for a body over the buffer limit, got %d", rw.Code)
+	}
+	if rw := post("/raw", large); rw.Body.String() != large {
+		t.Errorf("expected an unbuffered body to remain readable, got %q", rw.Body.String())
+	}
+}
@@ -0,0 +1,30 @@
+// Package brotli adds the "br" content coding to a flotilla Compression,
+// preferred over gzip when a client accepts both equally, e.g.
+//
+//	cm := flotilla.NewCompression(gzip.DefaultCompression)
+//	brotli.Register(cm, brotli.DefaultCompression)
+//
+// The package depends on github.com/andybalholm/brotli, fetched with
+//
+//	go get github.com/andybalholm/brotli
+package brotli
+
+import (
+	"github.com/andybalholm/brotli"
+	"github.com/thrisp/flotilla"
+)
+
+// Compression levels of brotli, from 0 to 11.
+const (
+	BestSpeed          = brotli.BestSpeed
+	BestCompression    = brotli.BestCompression
+	DefaultCompression = brotli.DefaultCompression
+)
+
+// Register adds the "br" content coding at the provided level to the
+// Compression.
+func Register(cm *flotilla.Compression, level int) {
+	cm.Register("br", func() flotilla.Encoder {
+		return brotli.NewWriterLevel(nil, level)
+	})
+}
@@ -0,0 +1,41 @@
+package brotli
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/thrisp/flotilla"
+)
+
+func TestBrotli(t *testing.T) {
+	large := strings.Repeat("compressible text ", 200)
+
+	cm := flotilla.NewCompression(gzip.BestSpeed)
+	Register(cm, BestSpeed)
+
+	a := flotilla.New("testBrotli", flotilla.Mode("testing", true))
+	a.GET("/large", cm.Manage, func(c flotilla.Ctx) { c.Call("serveplain", 200, large) })
+	client := a.TestClient()
+
+	for accept, expected := range map[string]string{
+		"gzip, br":       "br",
+		"br;q=0.5, gzip": "gzip",
+	} {
+		res := client.Get("/large", "Accept-Encoding", accept)
+		if enc := res.Header.Get("Content-Encoding"); enc != expected {
+			t.Errorf("%q: expected %q encoding, got %q", accept, expected, enc)
+			continue
+		}
+		if expected != "br" {
+			continue
+		}
+		body, err := io.ReadAll(brotli.NewReader(bytes.NewReader(res.Body)))
+		if err != nil || string(body) != large {
+			t.Errorf("expected the decompressed brotli body, got %v", err)
+		}
+	}
+}
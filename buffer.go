@@ -0,0 +1,169 @@
+package flotilla
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/thrisp/flotilla/xrr"
+)
+
+var NotBuffered = xrr.NewXrror("the response is not buffered").Out
+
+const responsebufferData = "responsebuffer"
+
+// maxPooledBuffer is the capacity above which response buffers are left to
+// the garbage collector instead of returned to the pool.
+const maxPooledBuffer = 1 << 16
+
+var responsebuffers = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+// bufferwriter holds a response in a pooled buffer until it is complete, for
+// the managers rewriting a response before it is written: BufferResponse,
+// AutoETag, ResponseCache and Compression. The response is committed once,
+// when finished, flushed, or when the buffer reaches any limit, by the commit
+// function, which sets the headers and writes the buffered response, and
+// later writes pass through to out.
+type bufferwriter struct {
+	http.ResponseWriter
+	status    int
+	buf       *bytes.Buffer
+	limit     int
+	committed bool
+	commit    func(w *bufferwriter, final bool)
+	out       io.Writer
+}
+
+func newbufferwriter(w http.ResponseWriter, commit func(*bufferwriter, bool)) *bufferwriter {
+	return &bufferwriter{ResponseWriter: w, buf: responsebuffers.Get().(*bytes.Buffer), commit: commit, out: w}
+}
+
+func (w *bufferwriter) WriteHeader(code int) {
+	if w.committed {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *bufferwriter) Write(p []byte) (int, error) {
+	if w.committed {
+		return w.out.Write(p)
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.buf.Write(p)
+	if w.limit > 0 && w.buf.Len() >= w.limit {
+		w.commitbuffer(false)
+	}
+	return n, err
+}
+
+// Flush commits the response and writes any later writes directly, for
+// streamed responses.
+func (w *bufferwriter) Flush() {
+	if !w.committed {
+		w.commitbuffer(false)
+	}
+	if f, ok := w.out.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *bufferwriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return hijack(w.ResponseWriter)
+}
+
+func (w *bufferwriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// write writes the status and the buffered body to out.
+func (w *bufferwriter) write() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.buf.Len() > 0 {
+		w.out.Write(w.buf.Bytes())
+	}
+}
+
+func (w *bufferwriter) commitbuffer(final bool) {
+	w.committed = true
+	w.commit(w, final)
+	w.release()
+}
+
+func (w *bufferwriter) release() {
+	if w.buf != nil {
+		if w.buf.Cap() <= maxPooledBuffer {
+			w.buf.Reset()
+			responsebuffers.Put(w.buf)
+		}
+		w.buf = nil
+	}
+}
+
+// finish commits a response not yet committed, if anything was written.
+func (w *bufferwriter) finish() {
+	if w.committed {
+		return
+	}
+	if w.status == 0 && w.buf.Len() == 0 {
+		w.release()
+		return
+	}
+	w.commitbuffer(true)
+}
+
+// contentlength sets the Content-Length of a complete buffered response.
+func contentlength(w *bufferwriter, final bool) {
+	if final && w.buf.Len() > 0 {
+		w.Header().Set("Content-Length", strconv.Itoa(w.buf.Len()))
+	}
+	w.write()
+}
+
+// BufferResponse is a Manage function holding the response of the remaining
+// managers in a pooled buffer until the Ctx completes, so that deferred and
+// final functions may rewrite its headers and body, see ResponseBuffer. The
+// buffered response is written with its Content-Length; a flushed response
+// is written directly from then on.
+func BufferResponse(c Ctx) {
+	var bw *bufferwriter
+	c.Call("wrapwriter", func(w http.ResponseWriter) http.ResponseWriter {
+		bw = newbufferwriter(w, contentlength)
+		return bw
+	})
+	c.Call("set", responsebufferData, bw)
+	c.Call("pushfinal", func(Ctx) { bw.finish() })
+}
+
+func responsebuffer(c *ctx) (*bytes.Buffer, error) {
+	if bw, ok := c.Data[responsebufferData].(*bufferwriter); ok && !bw.committed && bw.buf != nil {
+		return bw.buf, nil
+	}
+	return nil, NotBuffered()
+}
+
+// ResponseBuffer returns the buffered body of a response held by
+// BufferResponse, for rewriting until the Ctx completes, and whether the
+// response is buffered.
+func ResponseBuffer(c Ctx) (*bytes.Buffer, bool) {
+	ret, err := c.Call("responsebuffer")
+	if err != nil {
+		return nil, false
+	}
+	return ret.(*bytes.Buffer), true
+}
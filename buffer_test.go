@@ -0,0 +1,109 @@
+package flotilla
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBufferResponse(t *testing.T) {
+	a := testApp(t, "bufferresponse")
+	var unbuffered bool
+	toolbar := func(c Ctx) {
+		c.Next()
+		c.Call("push", func(c Ctx) {
+			buf, ok := ResponseBuffer(c)
+			if !ok {
+				t.Error("the response was not buffered")
+				return
+			}
+			body := bytes.Replace(buf.Bytes(), []byte("</body>"), []byte("<div>toolbar</div></body>"), 1)
+			buf.Reset()
+			buf.Write(body)
+			c.Call("headermodify", "set", []string{"X-Hash", ETagFor(body)})
+		})
+	}
+	a.GET("/buffered", BufferResponse, toolbar, func(c Ctx) {
+		c.Call("serveplain", 202, "<body></body>")
+	})
+	a.GET("/unbuffered", func(c Ctx) {
+		_, ok := ResponseBuffer(c)
+		unbuffered = !ok
+	})
+	a.Configure()
+
+	rw := httptest.NewRecorder()
+	a.ServeHTTP(rw, httptest.NewRequest("GET", "/buffered", nil))
+	expected := "<body><div>toolbar</div></body>"
+	if rw.Code != 202 || rw.Body.String() != expected {
+		t.Errorf("unexpected buffered response %d %q", rw.Code, rw.Body.String())
+	}
+	if rw.Header().Get("X-Hash") != ETagFor([]byte(expected)) || rw.Header().Get("Content-Length") != strconv.Itoa(len(expected)) {
+		t.Errorf("unexpected buffered headers %v", rw.Header())
+	}
+
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/unbuffered", nil))
+	if !unbuffered {
+		t.Error("a response was buffered without BufferResponse")
+	}
+}
+
+func TestBufferedManagers(t *testing.T) {
+	large := strings.Repeat("buffered text ", 200)
+	var renders int
+
+	rc := NewResponseCache(nil, time.Minute)
+	cm := NewCompression(gzip.BestSpeed)
+
+	a := testApp(t, "bufferedmanagers")
+	a.GET("/stacked", rc.Manage, AutoETag, cm.Manage, func(c Ctx) {
+		renders++
+		c.Call("serveplain", 200, large)
+	})
+	a.GET("/flushed", rc.Manage, AutoETag, func(c Ctx) {
+		renders++
+		rw, _ := c.Call("responsewriter")
+		rw.(http.ResponseWriter).Write([]byte("first "))
+		rw.(http.Flusher).Flush()
+		rw.(http.ResponseWriter).Write([]byte("second"))
+	})
+
+	client := a.TestClient()
+	client.Header.Set("Accept-Encoding", "gzip")
+
+	first, second := client.Get("/stacked"), client.Get("/stacked")
+	if renders != 1 || second.Header.Get("X-Cache") != "HIT" {
+		t.Errorf("expected one render of a cached response, got %d %v", renders, second.Header)
+	}
+	for _, res := range []struct {
+		header http.Header
+		body   []byte
+	}{{first.Header, first.Body}, {second.Header, second.Body}} {
+		zr, err := gzip.NewReader(bytes.NewReader(res.body))
+		if err != nil {
+			t.Fatalf("response was not compressed: %v %v", res.header, err)
+		}
+		body, _ := ioutil.ReadAll(zr)
+		if string(body) != large || res.header.Get("ETag") != ETagFor(first.Body) {
+			t.Errorf("unexpected stacked response %v %q", res.header, body)
+		}
+	}
+	if res := client.Get("/stacked", "If-None-Match", first.Header.Get("ETag"), "Cache-Control", "no-cache"); res.Status != 304 || len(res.Body) != 0 {
+		t.Errorf("expected a 304 for a matching ETag, got %d %q", res.Status, res.Body)
+	}
+
+	renders = 0
+	first, second = client.Get("/flushed"), client.Get("/flushed")
+	if string(first.Body) != "first second" || first.Header.Get("ETag") != "" {
+		t.Errorf("unexpected flushed response %v %q", first.Header, first.Body)
+	}
+	if renders != 2 || second.Header.Get("X-Cache") != "MISS" {
+		t.Errorf("expected a flushed response not to be cached, rendered %d times", renders)
+	}
+}
@@ -0,0 +1,378 @@
+package flotilla
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thrisp/flotilla/session"
+)
+
+// CachedResponse is a response stored by a ResponseCache. A response with a
+// Vary header is stored under a key of the request values of the Vary
+// headers, and an entry with only Vary set, under the key of the request,
+// lists the headers.
+type CachedResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+	Tags   []string
+	Stored time.Time
+	Vary   []string
+}
+
+// CacheStore is a backend storing CachedResponses for a ResponseCache.
+type CacheStore interface {
+	Get(key string) (*CachedResponse, bool)
+	Set(key string, r *CachedResponse, ttl time.Duration) error
+	// Invalidate removes every response stored with any of the provided tags.
+	Invalidate(tags ...string) error
+}
+
+type memoryentry struct {
+	r       *CachedResponse
+	expires time.Time
+}
+
+// MemoryCache is an in-process CacheStore.
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]*memoryentry
+	tags    map[string]map[string]bool
+	clock   clock
+}
+
+// NewMemoryCache returns an empty MemoryCache.
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{
+		entries: make(map[string]*memoryentry),
+		tags:    make(map[string]map[string]bool),
+	}
+}
+
+// SetClock sets the function returning the current time for the expiry of
+// entries, time.Now by default.
+func (m *MemoryCache) SetClock(now func() time.Time) {
+	m.clock.set(now)
+}
+
+func (m *MemoryCache) Get(key string) (*CachedResponse, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if m.clock.Now().After(e.expires) {
+		m.remove(key)
+		return nil, false
+	}
+	return e.r, true
+}
+
+func (m *MemoryCache) Set(key string, r *CachedResponse, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(key)
+	m.entries[key] = &memoryentry{r: r, expires: m.clock.Now().Add(ttl)}
+	for _, tag := range r.Tags {
+		if m.tags[tag] == nil {
+			m.tags[tag] = make(map[string]bool)
+		}
+		m.tags[tag][key] = true
+	}
+	return nil
+}
+
+func (m *MemoryCache) remove(key string) {
+	if e, ok := m.entries[key]; ok {
+		for _, tag := range e.r.Tags {
+			delete(m.tags[tag], key)
+			if len(m.tags[tag]) == 0 {
+				delete(m.tags, tag)
+			}
+		}
+		delete(m.entries, key)
+	}
+}
+
+func (m *MemoryCache) Invalidate(tags ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, tag := range tags {
+		for key := range m.tags[tag] {
+			m.remove(key)
+		}
+	}
+	return nil
+}
+
+// ResponseCache caches successful GET and HEAD responses per route. Responses
+// are keyed by route, request URI, and the values of any KeyHeaders, and are
+// tagged with the route name, any static Tags, and any tags added by handlers
+// with CacheTags, for invalidation by tag.
+//
+// Request Cache-Control no-store bypasses the cache, and no-cache or max-age=0
+// refreshes the cached response. Responses with Cache-Control no-store or
+// private are never stored, and s-maxage or max-age override the default TTL.
+// Requests with an Authorization header or a session holding values bypass
+// the cache, and cookies are never stored or replayed; other responses
+// personalized by cookie should set Cache-Control private. Responses with a
+// Vary header, e.g. from Negotiate, are stored per value of the request
+// headers they vary on, and responses with Vary * are never stored.
+type ResponseCache struct {
+	Store      CacheStore
+	TTL        time.Duration
+	KeyHeaders []string
+	Tags       []string
+}
+
+// NewResponseCache returns a ResponseCache using the provided CacheStore, or a
+// new MemoryCache if nil, with the provided default TTL.
+func NewResponseCache(s CacheStore, ttl time.Duration) *ResponseCache {
+	if s == nil {
+		s = NewMemoryCache()
+	}
+	return &ResponseCache{Store: s, TTL: ttl}
+}
+
+const cachetagsData = "_cachetags"
+
+// CacheTags adds tags to the response of the Ctx if it is cached.
+func CacheTags(c Ctx, tags ...string) {
+	existing, _ := c.Call("get", cachetagsData)
+	t, _ := existing.([]string)
+	c.Call("set", cachetagsData, append(t, tags...))
+}
+
+// Invalidate removes cached responses with any of the provided tags.
+func (rc *ResponseCache) Invalidate(tags ...string) error {
+	return rc.Store.Invalidate(tags...)
+}
+
+func (rc *ResponseCache) key(c Ctx) string {
+	rq := CurrentRequest(c)
+	var b bytes.Buffer
+	if rt := CurrentRoute(c); rt != nil {
+		b.WriteString(rt.Name())
+	}
+	b.WriteString("\n" + rq.URL.RequestURI())
+	for _, h := range rq.Header.Values("Accept-Encoding") {
+		b.WriteString("\n" + h)
+	}
+	for _, h := range rc.KeyHeaders {
+		b.WriteString("\n" + h + ":" + rq.Header.Get(h))
+	}
+	sum := sha256.Sum256(b.Bytes())
+	return hex.EncodeToString(sum[:])
+}
+
+// varyheaders returns the request headers named by the Vary header of a
+// response, other than those already keying the ResponseCache, and false for
+// Vary *.
+func (rc *ResponseCache) varyheaders(h http.Header) ([]string, bool) {
+	keyed := map[string]bool{"Accept-Encoding": true}
+	for _, k := range rc.KeyHeaders {
+		keyed[http.CanonicalHeaderKey(k)] = true
+	}
+	var ret []string
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			switch {
+			case name == "*":
+				return nil, false
+			case name == "" || keyed[name]:
+				continue
+			}
+			keyed[name] = true
+			ret = append(ret, name)
+		}
+	}
+	sort.Strings(ret)
+	return ret, true
+}
+
+// varykey returns the key of the variant of a response for the request values
+// of the vary headers.
+func varykey(key string, vary []string, rq *http.Request) string {
+	b := bytes.NewBufferString(key)
+	for _, h := range vary {
+		b.WriteString("\n" + h + ":" + strings.Join(rq.Header.Values(h), ","))
+	}
+	sum := sha256.Sum256(b.Bytes())
+	return hex.EncodeToString(sum[:])
+}
+
+// lookup returns the stored response for the key, or its variant for the
+// request.
+func (rc *ResponseCache) lookup(key string, rq *http.Request) (*CachedResponse, bool) {
+	r, ok := rc.Store.Get(key)
+	if ok && r.Status == 0 && len(r.Vary) > 0 {
+		r, ok = rc.Store.Get(varykey(key, r.Vary, rq))
+	}
+	return r, ok && r.Status != 0
+}
+
+// personalized reports whether the request carries credentials, an
+// Authorization header or a session holding values, so its response may
+// differ per user.
+func personalized(c Ctx, rq *http.Request) bool {
+	if rq.Header.Get("Authorization") != "" {
+		return true
+	}
+	s, _ := c.Call("session")
+	if ss, ok := s.(session.SessionStore); ok && len(ss.Keys()) > 0 {
+		return true
+	}
+	return false
+}
+
+func cachecontrol(h http.Header) map[string]string {
+	directives := make(map[string]string)
+	for _, v := range h.Values("Cache-Control") {
+		for _, d := range strings.Split(v, ",") {
+			d = strings.TrimSpace(strings.ToLower(d))
+			if d == "" {
+				continue
+			}
+			kv := strings.SplitN(d, "=", 2)
+			if len(kv) == 2 {
+				directives[kv[0]] = strings.Trim(kv[1], `"`)
+			} else {
+				directives[kv[0]] = ""
+			}
+		}
+	}
+	return directives
+}
+
+func (rc *ResponseCache) ttl(h http.Header) (time.Duration, bool) {
+	cc := cachecontrol(h)
+	if _, ok := cc["no-store"]; ok {
+		return 0, false
+	}
+	if _, ok := cc["private"]; ok {
+		return 0, false
+	}
+	for _, d := range []string{"s-maxage", "max-age"} {
+		if v, ok := cc[d]; ok {
+			if secs, err := strconv.Atoi(v); err == nil {
+				return time.Duration(secs) * time.Second, secs > 0
+			}
+		}
+	}
+	return rc.TTL, rc.TTL > 0
+}
+
+// store caches a complete 200 response to a GET request, for its TTL.
+func (rc *ResponseCache) store(c Ctx, key string, w *bufferwriter) {
+	rq := CurrentRequest(c)
+	if w.status != 200 || rq.Method != "GET" {
+		return
+	}
+	header := w.Header()
+	ttl, ok := rc.ttl(header)
+	if !ok {
+		return
+	}
+	vary, ok := rc.varyheaders(header)
+	if !ok {
+		return
+	}
+	h := header.Clone()
+	h.Del("X-Cache")
+	h.Del(RequestIDHeader)
+	h.Del("Set-Cookie")
+	tags := append([]string{}, rc.Tags...)
+	if rt := CurrentRoute(c); rt != nil {
+		tags = append(tags, rt.Name())
+	}
+	if t, err := c.Call("get", cachetagsData); err == nil {
+		tags = append(tags, t.([]string)...)
+	}
+	now := CurrentTime(c)
+	if len(vary) > 0 {
+		rc.Store.Set(key, &CachedResponse{Tags: tags, Stored: now, Vary: vary}, ttl)
+		key = varykey(key, vary, rq)
+	}
+	rc.Store.Set(key, &CachedResponse{
+		Status: w.status,
+		Header: h,
+		Body:   append([]byte(nil), w.buf.Bytes()...),
+		Tags:   tags,
+		Stored: now,
+		Vary:   vary,
+	}, ttl)
+}
+
+func serveCached(c Ctx, r *CachedResponse) {
+	rw, _ := c.Call("responsewriter")
+	w := rw.(ResponseWriter)
+	for k, v := range r.Header {
+		w.Header()[k] = v
+	}
+	w.Header().Set("X-Cache", "HIT")
+	w.Header().Set("Age", strconv.Itoa(int(CurrentTime(c).Sub(r.Stored).Seconds())))
+	w.WriteHeader(r.Status)
+	if CurrentRequest(c).Method != "HEAD" {
+		w.Write(r.Body)
+	} else {
+		w.WriteHeaderNow()
+	}
+}
+
+// Manage is a flotilla.Manage function serving cached responses, or caching
+// the response of the remaining managers, buffered until complete. Flushed
+// responses are not cached.
+func (rc *ResponseCache) Manage(c Ctx) {
+	rq := CurrentRequest(c)
+	if rq.Method != "GET" && rq.Method != "HEAD" {
+		return
+	}
+	if personalized(c, rq) {
+		return
+	}
+	cc := cachecontrol(rq.Header)
+	if _, ok := cc["no-store"]; ok {
+		return
+	}
+	_, refresh := cc["no-cache"]
+	if cc["max-age"] == "0" {
+		refresh = true
+	}
+
+	key := rc.key(c)
+	if !refresh {
+		if r, ok := rc.lookup(key, rq); ok {
+			serveCached(c, r)
+			c.Call("halt")
+			return
+		}
+	}
+
+	rw, _ := c.Call("responsewriter")
+	header := rw.(ResponseWriter).Header()
+	for _, h := range rc.KeyHeaders {
+		header.Add("Vary", h)
+	}
+	header.Set("X-Cache", "MISS")
+
+	var cw *bufferwriter
+	c.Call("wrapwriter", func(w http.ResponseWriter) http.ResponseWriter {
+		cw = newbufferwriter(w, func(w *bufferwriter, final bool) {
+			if final {
+				rc.store(c, key, w)
+			}
+			w.write()
+		})
+		return cw
+	})
+	c.Call("pushfinal", func(Ctx) { cw.finish() })
+}
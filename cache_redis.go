@@ -0,0 +1,94 @@
+package flotilla
+
+import (
+	"bytes"
+	"encoding/gob"
+	"strconv"
+	"time"
+
+	"github.com/thrisp/flotilla/internal/redis"
+)
+
+// RedisCache is a CacheStore keeping responses in a Redis server, for sharing
+// a ResponseCache between App instances. The Addr, Password, DB, and Timeout
+// of the server connection may be changed before first use.
+type RedisCache struct {
+	*redis.Client
+	Prefix string
+}
+
+// NewRedisCache returns a RedisCache for the Redis server at addr, prefixing
+// its keys with "flotilla:cache:".
+func NewRedisCache(addr, password string, db int) *RedisCache {
+	return &RedisCache{
+		Client: redis.NewClient(addr, password, db),
+		Prefix: "flotilla:cache:",
+	}
+}
+
+// redisSetScript atomically sets the response KEYS[1] to ARGV[1] expiring in
+// ARGV[2] milliseconds and adds it to each tag set of KEYS[2:], extending the
+// expiry of a tag set to that of its newest response so tag sets expire.
+const redisSetScript = `
+local ms = tonumber(ARGV[2])
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ms)
+for i = 2, #KEYS do
+	redis.call('SADD', KEYS[i], KEYS[1])
+	if redis.call('PTTL', KEYS[i]) < ms then
+		redis.call('PEXPIRE', KEYS[i], ms)
+	end
+end
+return 1
+`
+
+func (rc *RedisCache) tagkey(tag string) string {
+	return rc.Prefix + "tag:" + tag
+}
+
+func (rc *RedisCache) Get(key string) (*CachedResponse, bool) {
+	reply, err := rc.Do("GET", rc.Prefix+key)
+	b, ok := reply.([]byte)
+	if err != nil || !ok {
+		return nil, false
+	}
+	r := &CachedResponse{}
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(r); err != nil {
+		return nil, false
+	}
+	return r, true
+}
+
+func (rc *RedisCache) Set(key string, r *CachedResponse, ttl time.Duration) error {
+	var b bytes.Buffer
+	if err := gob.NewEncoder(&b).Encode(r); err != nil {
+		return err
+	}
+	args := []string{"EVAL", redisSetScript, strconv.Itoa(1 + len(r.Tags)), rc.Prefix + key}
+	for _, tag := range r.Tags {
+		args = append(args, rc.tagkey(tag))
+	}
+	args = append(args, b.String(), strconv.FormatInt(int64(ttl/time.Millisecond), 10))
+	_, err := rc.Do(args...)
+	return err
+}
+
+func (rc *RedisCache) Invalidate(tags ...string) error {
+	for _, tag := range tags {
+		reply, err := rc.Do("SMEMBERS", rc.tagkey(tag))
+		if err != nil {
+			return err
+		}
+		keys := []string{"DEL", rc.tagkey(tag)}
+		if members, ok := reply.([]interface{}); ok {
+			for _, m := range members {
+				if k, ok := m.([]byte); ok {
+					keys = append(keys, string(k))
+				}
+			}
+		}
+		if _, err := rc.Do(keys...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package flotilla
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thrisp/flotilla/internal/redis"
+)
+
+func TestResponseCache(t *testing.T) {
+	var renders int
+
+	rc := NewResponseCache(nil, time.Minute)
+
+	a := testApp(t, "testResponseCache")
+	a.GET("/cached", rc.Manage, func(c Ctx) {
+		renders++
+		CacheTags(c, "pages")
+		c.Call("serveplain", 200, "cached body")
+	})
+	a.GET("/private", rc.Manage, func(c Ctx) {
+		renders++
+		c.Call("headermodify", "set", []string{"Cache-Control", "private"})
+		c.Call("serveplain", 200, "private body")
+	})
+
+	a.GET("/login", func(c Ctx) {
+		c.Call("setsession", "user", "alice")
+		c.Call("serveplain", 200, "logged in")
+	})
+
+	client := a.TestClient()
+
+	first, second := client.Get("/cached"), client.Get("/cached")
+	if renders != 1 {
+		t.Errorf("Expected 1 render of a cached route, but rendered %d times", renders)
+	}
+	if second.Header.Get("X-Cache") != "HIT" || string(second.Body) != "cached body" {
+		t.Errorf("Cached response was not served: %s %q", second.Header.Get("X-Cache"), second.Body)
+	}
+	if first.Header.Get("X-Cache") != "MISS" {
+		t.Errorf("First response was not a cache miss")
+	}
+
+	client.Get("/cached", "Cache-Control", "no-cache")
+	if renders != 2 {
+		t.Errorf("Request Cache-Control no-cache did not refresh the cached response")
+	}
+
+	rc.Invalidate("pages")
+	client.Get("/cached")
+	if renders != 3 {
+		t.Errorf("Invalidating a tag did not remove the cached response")
+	}
+
+	renders = 0
+	client.Get("/private")
+	client.Get("/private")
+	if renders != 2 {
+		t.Errorf("A private response was cached")
+	}
+
+	renders = 0
+	if res := client.Get("/cached", "Authorization", "Bearer token"); res.Header.Get("X-Cache") == "HIT" {
+		t.Error("Authorization: a cached response was served to a request with credentials")
+	}
+	client.Get("/login")
+	if res := client.Get("/cached"); res.Header.Get("X-Cache") == "HIT" {
+		t.Error("Cookie: a cached response was served to a request with a session holding values")
+	}
+	if renders != 2 {
+		t.Errorf("Expected requests with credentials to bypass the cache, but rendered %d times", renders)
+	}
+}
+
+func TestResponseCacheVary(t *testing.T) {
+	var renders int
+
+	rc := NewResponseCache(nil, time.Minute)
+
+	a := testApp(t, "testResponseCacheVary", EnvItem("JSON_PRETTY:false"))
+	a.GET("/item", rc.Manage, func(c Ctx) {
+		renders++
+		Negotiate(c, 200, "", negotiateitem{Name: "one"})
+	})
+	a.GET("/any", rc.Manage, func(c Ctx) {
+		renders++
+		c.Call("headermodify", "set", []string{"Vary", "*"})
+		c.Call("serveplain", 200, "any")
+	})
+
+	client := a.TestClient()
+
+	cases := []struct {
+		accept, cache, contenttype string
+	}{
+		{"application/json", "MISS", "application/json"},
+		{"application/xml", "MISS", "application/xml"},
+		{"application/json", "HIT", "application/json"},
+		{"application/xml", "HIT", "application/xml"},
+	}
+	for _, tc := range cases {
+		res := client.Get("/item", "Accept", tc.accept)
+		if res.Header.Get("X-Cache") != tc.cache || !strings.HasPrefix(res.Header.Get("Content-Type"), tc.contenttype) {
+			t.Errorf("Accept %s: expected a %s of %s, got %s %q %q", tc.accept, tc.cache, tc.contenttype, res.Header.Get("X-Cache"), res.Header.Get("Content-Type"), res.Body)
+		}
+	}
+	if renders != 2 {
+		t.Errorf("Expected a render per negotiated format, but rendered %d times", renders)
+	}
+
+	renders = 0
+	client.Get("/any")
+	if res := client.Get("/any"); res.Header.Get("X-Cache") == "HIT" || renders != 2 {
+		t.Errorf("A response with Vary * was cached")
+	}
+}
+
+func TestRedisCacheSet(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer l.Close()
+	commands := make(chan []interface{}, 4)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		c := redis.NewConn(conn)
+		for {
+			cmd, err := c.Reply()
+			if err != nil {
+				return
+			}
+			commands <- cmd.([]interface{})
+			conn.Write([]byte(":1\r\n"))
+		}
+	}()
+
+	rc := NewRedisCache(l.Addr().String(), "", 0)
+	if err := rc.Set("key", &CachedResponse{Status: 200, Tags: []string{"a", "b"}}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	cmd := <-commands
+	var args []string
+	for _, arg := range cmd {
+		args = append(args, string(arg.([]byte)))
+	}
+	if len(args) != 8 || args[0] != "EVAL" || args[2] != "3" || args[3] != "flotilla:cache:key" || args[4] != "flotilla:cache:tag:a" || args[7] != "60000" {
+		t.Errorf("expected a single EVAL setting the response and its tags, got %q", args)
+	}
+	select {
+	case cmd := <-commands:
+		t.Errorf("expected a single command, got %q", cmd)
+	default:
+	}
+}
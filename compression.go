@@ -0,0 +1,181 @@
+package flotilla
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Encoder is a resettable compressing writer, e.g. *gzip.Writer or a brotli
+// writer, pooled by Compression between responses.
+type Encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+// Compression transparently compresses responses with the content coding
+// negotiated from the request Accept-Encoding. Only responses of at least
+// MinSize bytes with a content type matching one of Types are compressed.
+// Gzip is available by default; other encodings are added with Register, e.g.
+// "br" with the brotli package.
+type Compression struct {
+	MinSize int
+	Types   []string
+	order   []string
+	pools   map[string]*sync.Pool
+}
+
+// DefaultCompressionTypes are the content types compressed by default.
+var DefaultCompressionTypes = []string{
+	"text/",
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"application/problem+json",
+	"image/svg+xml",
+}
+
+// NewCompression returns a Compression using gzip at the provided level.
+func NewCompression(level int) *Compression {
+	cm := &Compression{
+		MinSize: 1024,
+		Types:   DefaultCompressionTypes,
+		pools:   make(map[string]*sync.Pool),
+	}
+	cm.Register("gzip", func() Encoder {
+		w, err := gzip.NewWriterLevel(nil, level)
+		if err != nil {
+			w = gzip.NewWriter(nil)
+		}
+		return w
+	})
+	return cm
+}
+
+// Register adds the named content coding, preferred over any encodings
+// registered before it when a client accepts both equally.
+func (cm *Compression) Register(encoding string, fn func() Encoder) {
+	if _, exists := cm.pools[encoding]; !exists {
+		cm.order = append([]string{encoding}, cm.order...)
+	}
+	cm.pools[encoding] = &sync.Pool{New: func() interface{} { return fn() }}
+}
+
+// negotiate returns the registered encoding with the highest quality in the
+// Accept-Encoding header, with ties broken by registration preference.
+func (cm *Compression) negotiate(accept string) string {
+	return negotiateencoding(accept, cm.order)
+}
+
+// negotiateencoding returns the encoding of order with the highest quality in
+// the Accept-Encoding header, with ties broken by the order.
+func negotiateencoding(accept string, order []string) string {
+	quality := make(map[string]float64)
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		q := 1.0
+		for _, f := range fields[1:] {
+			f = strings.TrimSpace(f)
+			if strings.HasPrefix(f, "q=") {
+				if v, err := strconv.ParseFloat(f[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if name != "" {
+			quality[name] = q
+		}
+	}
+	candidates := make([]string, 0, len(order))
+	for _, enc := range order {
+		q, ok := quality[enc]
+		if !ok {
+			q, ok = quality["*"]
+		}
+		if ok && q > 0 {
+			quality[enc] = q
+			candidates = append(candidates, enc)
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return quality[candidates[i]] > quality[candidates[j]]
+	})
+	return candidates[0]
+}
+
+func (cm *Compression) compressible(contenttype string) bool {
+	contenttype = strings.ToLower(contenttype)
+	for _, t := range cm.Types {
+		if strings.HasPrefix(contenttype, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// compress sets the Content-Type and Vary headers of the response, and
+// writes it through an encoder when eligible for compression, returning the
+// encoder to close once the response is complete.
+func (cm *Compression) compress(w *bufferwriter, encoding string, final bool) Encoder {
+	var encoder Encoder
+	h := w.Header()
+	ct := h.Get("Content-Type")
+	if ct == "" && w.buf.Len() > 0 {
+		ct = http.DetectContentType(w.buf.Bytes())
+		h.Set("Content-Type", ct)
+	}
+	eligible := w.status != 204 && w.status != 304 && h.Get("Content-Encoding") == "" && cm.compressible(ct)
+	if eligible {
+		h.Add("Vary", "Accept-Encoding")
+	}
+	if eligible && encoding != "" && !(final && w.buf.Len() < cm.MinSize) {
+		h.Set("Content-Encoding", encoding)
+		h.Del("Content-Length")
+		encoder = cm.pools[encoding].Get().(Encoder)
+		encoder.Reset(w.ResponseWriter)
+		w.out = encoder
+	}
+	w.write()
+	return encoder
+}
+
+// Manage is a flotilla.Manage function compressing the response of the
+// remaining managers when the client accepts a registered encoding. Responses
+// eligible for compression, whether compressed or not, vary on
+// Accept-Encoding.
+func (cm *Compression) Manage(c Ctx) {
+	rq := CurrentRequest(c)
+	if rq.Method == "HEAD" || rq.Header.Get("Range") != "" {
+		return
+	}
+	encoding := cm.negotiate(rq.Header.Get("Accept-Encoding"))
+	var cw *bufferwriter
+	var encoder Encoder
+	c.Call("wrapwriter", func(w http.ResponseWriter) http.ResponseWriter {
+		cw = newbufferwriter(w, func(w *bufferwriter, final bool) {
+			encoder = cm.compress(w, encoding, final)
+		})
+		cw.limit = cm.MinSize
+		if cw.limit < 1 {
+			cw.limit = 1
+		}
+		return cw
+	})
+	c.Call("pushfinal", func(Ctx) {
+		cw.finish()
+		if encoder != nil {
+			encoder.Close()
+			encoder.Reset(nil)
+			cm.pools[encoding].Put(encoder)
+		}
+	})
+}
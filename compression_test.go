@@ -0,0 +1,79 @@
+package flotilla
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCompressionNegotiate(t *testing.T) {
+	cm := NewCompression(gzip.DefaultCompression)
+	cm.Register("br", func() Encoder { return gzip.NewWriter(nil) })
+	for accept, expect := range map[string]string{
+		"":                        "",
+		"gzip":                    "gzip",
+		"gzip, br":                "br",
+		"br;q=0.5, gzip":          "gzip",
+		"br;q=0, gzip;q=0.1":      "gzip",
+		"*":                       "br",
+		"identity, deflate":       "",
+		"gzip;q=0, br;q=0, *;q=1": "",
+	} {
+		if enc := cm.negotiate(accept); enc != expect {
+			t.Errorf("Negotiating %q returned %q, expected %q", accept, enc, expect)
+		}
+	}
+}
+
+func TestCompression(t *testing.T) {
+	large := strings.Repeat("compressible text ", 200)
+
+	cm := NewCompression(gzip.BestSpeed)
+
+	a := testApp(t, "testCompression")
+	a.GET("/large", cm.Manage, func(c Ctx) { c.Call("serveplain", 200, large) })
+	a.GET("/small", cm.Manage, func(c Ctx) { c.Call("serveplain", 200, "small") })
+	a.GET("/binary", cm.Manage, func(c Ctx) {
+		c.Call("headerwrite", 200, []string{"Content-Type", "image/png"})
+		c.Call("writetoresponse", large)
+	})
+
+	client := a.TestClient()
+	client.Header.Set("Accept-Encoding", "gzip")
+
+	res := client.Get("/large")
+	if res.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Large text response was not compressed: %v", res.Header)
+	}
+	if res.Header.Get("Vary") != "Accept-Encoding" {
+		t.Errorf("Compressed response Vary header was %q", res.Header.Get("Vary"))
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(res.Body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(zr)
+	if string(body) != large {
+		t.Errorf("Decompressed body did not match the response body")
+	}
+
+	for path, vary := range map[string]string{"/small": "Accept-Encoding", "/binary": ""} {
+		res := client.Get(path)
+		if res.Header.Get("Content-Encoding") != "" {
+			t.Errorf("Response for %s should not be compressed", path)
+		}
+		if res.Header.Get("Vary") != vary {
+			t.Errorf("Expected Vary %q for %s, got %q", vary, path, res.Header.Get("Vary"))
+		}
+		if path == "/small" && string(res.Body) != "small" {
+			t.Errorf("Uncompressed body was %q", res.Body)
+		}
+	}
+
+	res = client.Get("/large", "Accept-Encoding", "identity")
+	if res.Header.Get("Content-Encoding") != "" || res.Header.Get("Vary") != "Accept-Encoding" || string(res.Body) != large {
+		t.Errorf("Expected an uncompressed response varying on Accept-Encoding, got %v", res.Header)
+	}
+}
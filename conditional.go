@@ -0,0 +1,126 @@
+package flotilla
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ETagFor returns a strong ETag of the content.
+func ETagFor(content []byte) string {
+	sum := sha256.Sum256(content)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// WeakETag returns a weak ETag of a modification time and size, as for
+// files.
+func WeakETag(modified time.Time, size int64) string {
+	return fmt.Sprintf(`W/"%x-%x"`, modified.UnixNano(), size)
+}
+
+// etagmatch reports whether the If-None-Match header matches etag by weak
+// comparison.
+func etagmatch(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// notmodified reports whether a GET or HEAD request is satisfied by its
+// cached response, by If-None-Match, or else If-Modified-Since.
+func notmodified(rq *http.Request, etag string, modified time.Time) bool {
+	if rq.Method != "GET" && rq.Method != "HEAD" {
+		return false
+	}
+	if inm := rq.Header.Get("If-None-Match"); inm != "" {
+		return etag != "" && etagmatch(inm, etag)
+	}
+	if ims := rq.Header.Get("If-Modified-Since"); ims != "" && !modified.IsZero() {
+		if t, err := http.ParseTime(ims); err == nil {
+			return !modified.Truncate(time.Second).After(t)
+		}
+	}
+	return false
+}
+
+func writenotmodified(w http.ResponseWriter) {
+	h := w.Header()
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// conditional sets the ETag and Last-Modified headers of the response, and
+// responds with a 304 status, halting the remaining managers, when the
+// request is satisfied by its cached response.
+func conditional(c *ctx, etag string, modified time.Time) bool {
+	h := c.RW.Header()
+	if etag != "" {
+		h.Set("ETag", etag)
+	}
+	if !modified.IsZero() {
+		h.Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+	if notmodified(c.Request, etag, modified) {
+		writenotmodified(c.RW)
+		c.RW.WriteHeaderNow()
+		c.halt()
+		return true
+	}
+	return false
+}
+
+// NotModified sets the ETag and Last-Modified headers, either of which may
+// be empty, and responds with 304 Not Modified if the request is satisfied
+// by its cached response, returning true when the handler should not render.
+func NotModified(c Ctx, etag string, modified time.Time) bool {
+	ret, err := c.Call("notmodified", etag, modified)
+	return err == nil && ret.(bool)
+}
+
+// autoetag sets the ETag of a complete 200 response to a GET or HEAD request,
+// unless set by the handler, responding with 304 Not Modified when the
+// request is satisfied by its cached response.
+func autoetag(rq *http.Request) func(*bufferwriter, bool) {
+	return func(w *bufferwriter, final bool) {
+		h := w.Header()
+		if final && w.status == http.StatusOK && (rq.Method == "GET" || rq.Method == "HEAD") {
+			etag := h.Get("ETag")
+			if etag == "" {
+				etag = ETagFor(w.buf.Bytes())
+				h.Set("ETag", etag)
+			}
+			modified, _ := http.ParseTime(h.Get("Last-Modified"))
+			if notmodified(rq, etag, modified) {
+				writenotmodified(w.ResponseWriter)
+				return
+			}
+		}
+		w.write()
+	}
+}
+
+// AutoETag is a Manage function setting a strong ETag on the 200 responses
+// of the remaining managers, unless set by the handler, and responding with
+// 304 Not Modified to requests with a matching If-None-Match. Responses are
+// buffered to compute the ETag; flushed responses are not tagged.
+func AutoETag(c Ctx) {
+	rq := CurrentRequest(c)
+	if rq.Method != "GET" && rq.Method != "HEAD" {
+		return
+	}
+	var ew *bufferwriter
+	c.Call("wrapwriter", func(w http.ResponseWriter) http.ResponseWriter {
+		ew = newbufferwriter(w, autoetag(rq))
+		return ew
+	})
+	c.Call("pushfinal", func(Ctx) { ew.finish() })
+}
@@ -0,0 +1,64 @@
+package flotilla
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConditional(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "file.txt"), []byte("static content"), 0644)
+	modified := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	a := New("testConditional", Mode("testing", true))
+	rendered := 0
+	a.GET("/item", func(c Ctx) {
+		if NotModified(c, `"v1"`, modified) {
+			return
+		}
+		rendered++
+		c.Call("serveplain", 200, "item")
+	})
+	a.GET("/auto", AutoETag, func(c Ctx) {
+		c.Call("serveplain", 200, "auto content")
+	})
+	a.GET("/file", func(c Ctx) {
+		f, _ := http.Dir(dir).Open("file.txt")
+		defer f.Close()
+		c.Call("servefile", f)
+	})
+	client := a.TestClient()
+
+	res := client.Get("/item")
+	if res.Status != 200 || res.Header.Get("ETag") != `"v1"` || res.Header.Get("Last-Modified") != "Thu, 02 Jan 2020 03:04:05 GMT" {
+		t.Errorf("unexpected response %d %v", res.Status, res.Header)
+	}
+	if res = client.Get("/item", "If-None-Match", `W/"v0", "v1"`); res.Status != 304 || len(res.Body) != 0 || rendered != 1 {
+		t.Errorf("expected 304 for a matching ETag, got %d %q", res.Status, res.Body)
+	}
+	if res = client.Get("/item", "If-Modified-Since", "Fri, 03 Jan 2020 00:00:00 GMT"); res.Status != 304 {
+		t.Errorf("expected 304 when not modified since, got %d", res.Status)
+	}
+	if res = client.Get("/item", "If-None-Match", `"v0"`, "If-Modified-Since", "Fri, 03 Jan 2020 00:00:00 GMT"); res.Status != 200 {
+		t.Errorf("expected If-None-Match to take precedence, got %d", res.Status)
+	}
+
+	res = client.Get("/auto")
+	etag := res.Header.Get("ETag")
+	if res.Status != 200 || etag != ETagFor([]byte("auto content")) || string(res.Body) != "auto content" {
+		t.Errorf("expected an automatic ETag, got %d %q %q", res.Status, etag, res.Body)
+	}
+	if res = client.Get("/auto", "If-None-Match", etag); res.Status != 304 || len(res.Body) != 0 {
+		t.Errorf("expected 304 for the automatic ETag, got %d %q", res.Status, res.Body)
+	}
+
+	res = client.Get("/file")
+	if res.Status != 200 || res.Header.Get("ETag") == "" {
+		t.Errorf("expected a weak ETag for a served file, got %d %v", res.Status, res.Header)
+	}
+	if res = client.Get("/file", "If-None-Match", res.Header.Get("ETag")); res.Status != 304 {
+		t.Errorf("expected 304 for a served file, got %d", res.Status)
+	}
+}
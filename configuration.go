@@ -1,7 +1,11 @@
 package flotilla
 
 import (
+	"io/fs"
+	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/thrisp/flotilla/engine"
 	"github.com/thrisp/flotilla/xrr"
@@ -12,16 +16,20 @@ var configureFirst = []Configuration{
 }
 
 var configureLast = []Configuration{
+	clogger,
 	cstatic,
 	cblueprints,
 	ctemplating,
+	cmarkdown,
 	csession,
+	croutes,
 }
 
 type Config struct {
 	Configured    bool
 	Configuration []Configuration
 	deferred      []Configuration
+	conflicts     []error
 }
 
 type Configuration func(*App) error
@@ -38,6 +46,9 @@ func runConf(a *App, cnf ...Configuration) error {
 	var err error
 	for _, fn := range cnf {
 		err = fn(a)
+		if err != nil && a.Env != nil {
+			a.Env.Log().Warn("configuration error", "error", err)
+		}
 	}
 	return err
 }
@@ -91,10 +102,17 @@ func Mode(mode string, value bool) Configuration {
 	return func(a *App) error {
 		m := strings.Title(mode)
 		if existsIn(m, []string{"Development", "Testing", "Production"}) {
+			before := *a.Env.Mode
 			err := a.SetMode(m, value)
 			if err != nil {
 				return err
 			}
+			if *a.Env.Mode != before {
+				a.Messaging.Audit(&AuditEvent{
+					Kind:   AuditConfigChange,
+					Detail: map[string]string{"mode": m, "value": strconv.FormatBool(value)},
+				})
+			}
 			return nil
 		}
 		return IllegalMode(mode)
@@ -106,12 +124,17 @@ func EnvItem(items ...string) Configuration {
 		for _, item := range items {
 			v := strings.Split(item, ":")
 			k, value := v[0], v[1]
-			sl := strings.Split(k, "_")
-			if len(sl) > 1 {
-				section, label := sl[0], strings.Join(sl[1:], "_")
-				a.Env.Store.add(section, label, value)
-			} else {
-				a.Env.Store.add("", sl[0], value)
+			section, label := "", k
+			if sl := strings.Split(k, "_"); len(sl) > 1 {
+				section, label = sl[0], strings.Join(sl[1:], "_")
+			}
+			existing, ok := a.Env.Store[a.Env.Store.newKey(section, label)]
+			a.Env.Store.add(section, label, value)
+			if !ok || existing.Value != value {
+				a.Messaging.Audit(&AuditEvent{
+					Kind:   AuditConfigChange,
+					Detail: map[string]string{"item": k},
+				})
 			}
 		}
 		return nil
@@ -138,6 +161,9 @@ func WithTemplator(t Templator) Configuration {
 	}
 }
 
+// CtxProcessor adds a ctxprocessor to the App, a function of the Ctx run on
+// each render of a template, its result set in the render data by name, and
+// the keys of a map result merged into the render data where not already set.
 func CtxProcessor(name string, fn interface{}) Configuration {
 	return func(a *App) error {
 		a.AddCtxProcessor(name, fn)
@@ -159,9 +185,101 @@ func WithAssets(ast ...AssetFS) Configuration {
 	}
 }
 
+// WithFS adds the files of fsys below dir, or all its files if dir is empty,
+// to the Assets of the App, e.g. an embed.FS of static files and templates,
+// served after files of the static and template directories in Development
+// mode, and before them in Production mode, so that an App ships as a single
+// binary.
+func WithFS(fsys fs.FS, dir string) Configuration {
+	return func(a *App) error {
+		ast, err := FSAssets(fsys, dir)
+		if err != nil {
+			return err
+		}
+		a.Env.Assets = append(a.Env.Assets, ast)
+		return nil
+	}
+}
+
 func WithQueue(name string, q Queue) Configuration {
 	return func(a *App) error {
 		a.Messaging.Queues[name] = q
 		return nil
 	}
 }
+
+var NotConfigurable = xrr.NewXrror("engine %T can not be configured").Out
+
+func configureEngine(a *App, opts ...engine.Option) error {
+	if e, ok := a.Engine.(interface {
+		Configure(...engine.Option)
+	}); ok {
+		e.Configure(opts...)
+		return nil
+	}
+	return NotConfigurable(a.Engine)
+}
+
+// HandleMethodNotAllowed sets whether a path routed for other methods only
+// is answered with a 405 status and an Allow header of its methods,
+// customized with STATUS 405, or with a 404 status. It is on by default.
+func HandleMethodNotAllowed(on bool) Configuration {
+	return func(a *App) error {
+		return configureEngine(a, engine.MethodNotAllowed(on))
+	}
+}
+
+// HandleOptions sets whether OPTIONS requests for a path without an OPTIONS
+// route are answered with an Allow header of its methods, running any
+// provided Manage in place of the default empty response. It is on by
+// default.
+func HandleOptions(on bool, m ...Manage) Configuration {
+	return func(a *App) error {
+		var r engine.Rule
+		if len(m) > 0 {
+			r = func(rw http.ResponseWriter, rq *http.Request, rs *engine.Result) {
+				c := NewCtx(a.fxtensions, rs)
+				c.reset(rq, rw, m)
+				c.Run()
+				c.Cancel()
+			}
+		}
+		return configureEngine(a, engine.Options(on, r))
+	}
+}
+
+// RedirectTrailingSlash sets whether a path without a route is redirected to
+// the path with or without a trailing slash if routed, with a 301 status for
+// GET requests or 308 otherwise. It is on by default.
+func RedirectTrailingSlash(on bool) Configuration {
+	return func(a *App) error {
+		return configureEngine(a, engine.RedirectTrailingSlash(on))
+	}
+}
+
+// RedirectFixedPath sets whether a path without a route is redirected to the
+// cleaned path of any case if routed, e.g. /Users/ to /users. It is on by
+// default.
+func RedirectFixedPath(on bool) Configuration {
+	return func(a *App) error {
+		return configureEngine(a, engine.RedirectFixedPath(on))
+	}
+}
+
+// CaseInsensitiveRouting sets whether a path without a route is matched to
+// the route of the path in any case, without a redirect.
+func CaseInsensitiveRouting(on bool) Configuration {
+	return func(a *App) error {
+		return configureEngine(a, engine.CaseInsensitiveRouting(on))
+	}
+}
+
+// WithClock sets the function returning the current time of the App, for
+// expiry related functions, e.g. of sessions, secure cookies, tokens, and
+// signed urls.
+func WithClock(now func() time.Time) Configuration {
+	return func(a *App) error {
+		a.Env.clock.set(now)
+		return nil
+	}
+}
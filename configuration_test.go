@@ -1,6 +1,11 @@
 package flotilla
 
-import "testing"
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/thrisp/flotilla/engine"
+)
 
 var fauxconf bool
 
@@ -57,3 +62,86 @@ func TestConfiguration(t *testing.T) {
 		t.Errorf(`Arbitrary Configuration function FauxConf was not properly set or used.`)
 	}
 }
+
+func TestEncryptedSessionProvider(t *testing.T) {
+	a := New("testEncryptedSessionProvider", EnvItem("session_provider:encryptedcookie", "session_keys:new key,old key"))
+	a.Configure()
+
+	cookies, err := a.SessionCookies(map[string]interface{}{"seeded": "value"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := a.SessionValue(cookies, "seeded"); v != "value" {
+		t.Errorf("Encrypted session value was %v, expected value", v)
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	a := New("testMethodNotAllowed", Mode("testing", true))
+	a.GET("/items", func(c Ctx) {})
+	a.POST("/items", func(c Ctx) {})
+	a.STATUS(405, func(c Ctx) { c.Call("serveplain", 405, "not here") })
+	a.Configure()
+	do := func(method, path string) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		a.ServeHTTP(rw, httptest.NewRequest(method, path, nil))
+		return rw
+	}
+
+	if rw := do("DELETE", "/items"); rw.Code != 405 || rw.Header().Get("Allow") != "GET, OPTIONS, POST" || rw.Body.String() != "not here" {
+		t.Errorf("unexpected 405 response %d %q %v", rw.Code, rw.Body.String(), rw.Header())
+	}
+	if rw := do("OPTIONS", "/items"); rw.Code != 200 || rw.Header().Get("Allow") != "GET, OPTIONS, POST" {
+		t.Errorf("unexpected OPTIONS response %d %v", rw.Code, rw.Header())
+	}
+	if rw := do("DELETE", "/nope"); rw.Code != 404 {
+		t.Errorf("expected a 404 for an unrouted path, got %d", rw.Code)
+	}
+}
+
+func TestOptionsConfiguration(t *testing.T) {
+	a := New("testOptionsConfiguration", Mode("testing", true),
+		HandleMethodNotAllowed(false),
+		HandleOptions(true, func(c Ctx) {
+			c.Call("headermodify", "set", []string{"Access-Control-Allow-Origin", "*"})
+			c.Call("serveplain", 204, "")
+		}))
+	a.GET("/items", func(c Ctx) {})
+	a.Configure()
+	do := func(method, path string) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		a.ServeHTTP(rw, httptest.NewRequest(method, path, nil))
+		return rw
+	}
+
+	if rw := do("DELETE", "/items"); rw.Code != 404 {
+		t.Errorf("expected a 404 without method not allowed responses, got %d", rw.Code)
+	}
+	rw := do("OPTIONS", "/items")
+	if rw.Code != 204 || rw.Header().Get("Allow") != "GET, OPTIONS" || rw.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("unexpected custom OPTIONS response %d %v", rw.Code, rw.Header())
+	}
+}
+
+func TestRedirectPolicies(t *testing.T) {
+	a := New("testRedirectPolicies", Mode("testing", true), RedirectFixedPath(false), CaseInsensitiveRouting(true))
+	var routed string
+	a.GET("/users/:id", func(c Ctx) {
+		v, _ := Param(c, "id")
+		routed = v.(string)
+	})
+	admin := a.NewBlueprint("/admin")
+	admin.GET("/users/", func(c Ctx) { routed = "admin" })
+	admin.Routing(engine.CaseInsensitiveRouting(false), engine.RedirectFixedPath(true))
+	client := a.TestClient()
+
+	if res := client.Get("/Users/Gopher"); res.Status != 200 || routed != "Gopher" {
+		t.Errorf("expected a case insensitive match, got %d %q", res.Status, routed)
+	}
+	if res := client.Get("/users/gopher/"); res.Status != 301 || res.Header.Get("Location") != "http://localhost/users/gopher" {
+		t.Errorf("expected a trailing slash redirect, got %d %v", res.Status, res.Header)
+	}
+	if res := client.Get("/ADMIN/Users"); res.Status != 301 || res.Header.Get("Location") != "http://localhost/admin/users/" {
+		t.Errorf("expected a fixed path redirect for the blueprint, got %d %v", res.Status, res.Header)
+	}
+}
@@ -0,0 +1,129 @@
+package flotilla
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Content dispositions of served content.
+const (
+	Inline     = "inline"
+	Attachment = "attachment"
+)
+
+// contentdisposition returns a Content-Disposition header value for the file
+// name, with an RFC 5987 encoded filename* parameter and an ASCII fallback
+// filename for names that are not printable ASCII.
+func contentdisposition(disposition, name string) string {
+	ascii := true
+	for i := 0; i < len(name); i++ {
+		if name[i] < 0x20 || name[i] > 0x7e {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return mime.FormatMediaType(disposition, map[string]string{"filename": name})
+	}
+	var fallback, encoded strings.Builder
+	for _, r := range name {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+			fallback.WriteByte('_')
+		} else {
+			fallback.WriteRune(r)
+		}
+	}
+	for i := 0; i < len(name); i++ {
+		b := name[i]
+		if b < 0x80 && (unicode.IsLetter(rune(b)) || unicode.IsDigit(rune(b)) || strings.IndexByte("!#$&+-.^_`|~", b) >= 0) {
+			encoded.WriteByte(b)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+	return fmt.Sprintf(`%s; filename="%s"; filename*=UTF-8''%s`, disposition, fallback.String(), encoded.String())
+}
+
+// servecontent serves content with http.ServeContent, answering Range,
+// If-Range, and conditional requests, with a weak ETag of its modification
+// time and size unless an ETag is set, and the Content-Disposition if
+// provided. A modification time at or before the Unix epoch is unknown.
+func servecontent(c *ctx, name string, modified time.Time, content io.ReadSeeker, disposition string) error {
+	return sendcontent(c, name, modified, content, disposition, 0)
+}
+
+// sendcontent serves content as servecontent, writing at most rate bytes per
+// second if rate is positive.
+func sendcontent(c *ctx, name string, modified time.Time, content io.ReadSeeker, disposition string, rate int64) error {
+	h := c.RW.Header()
+	if disposition != "" {
+		h.Set("Content-Disposition", contentdisposition(disposition, filepath.Base(name)))
+	}
+	if modified.Unix() <= 0 {
+		modified = time.Time{}
+	}
+	if h.Get("ETag") == "" && !modified.IsZero() {
+		size, err := content.Seek(0, io.SeekEnd)
+		if err != nil {
+			return err
+		}
+		if _, err := content.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		h.Set("ETag", WeakETag(modified, size))
+	}
+	var w http.ResponseWriter = c.RW
+	if rate > 0 {
+		w = newratewriter(c.RW, rate, c.Request.Context().Done())
+	}
+	http.ServeContent(w, c.Request, name, modified, content)
+	c.RW.WriteHeaderNow()
+	return nil
+}
+
+func serveassetfunc(a *App) func(*ctx, string, string) error {
+	return func(c *ctx, name string, disposition string) error {
+		f, err := a.Assets.Get(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		var modified time.Time
+		if fi, err := f.Stat(); err == nil {
+			modified = fi.ModTime()
+		}
+		return servecontent(c, name, modified, f, disposition)
+	}
+}
+
+// ServeContent serves content inline, answering Range and conditional
+// requests, with the content type from the name extension or the content.
+func ServeContent(c Ctx, name string, modified time.Time, content io.ReadSeeker) error {
+	_, err := c.Call("servecontent", name, modified, content, "")
+	return err
+}
+
+// ServeAttachment serves content as an attachment downloaded with the name.
+func ServeAttachment(c Ctx, name string, modified time.Time, content io.ReadSeeker) error {
+	_, err := c.Call("servecontent", name, modified, content, Attachment)
+	return err
+}
+
+// ServeBlob serves in memory data as ServeContent.
+func ServeBlob(c Ctx, name string, modified time.Time, data []byte) error {
+	return ServeContent(c, name, modified, bytes.NewReader(data))
+}
+
+// ServeAsset serves the named file of the App Assets, with the provided
+// disposition, Inline or Attachment, or none if empty.
+func ServeAsset(c Ctx, name, disposition string) error {
+	_, err := c.Call("serveasset", name, disposition)
+	return err
+}
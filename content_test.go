@@ -0,0 +1,40 @@
+package flotilla
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServeContent(t *testing.T) {
+	modified := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	a := New("testServeContent", Mode("testing", true), WithAssets(TestAsset))
+	a.GET("/blob", func(c Ctx) { ServeBlob(c, "data.txt", modified, []byte("0123456789")) })
+	a.GET("/download", func(c Ctx) {
+		ServeAttachment(c, "report.csv", modified, strings.NewReader("a,b\n1,2\n"))
+	})
+	a.GET("/asset", func(c Ctx) { ServeAsset(c, "test_asset.html", Inline) })
+	client := a.TestClient()
+
+	res := client.Get("/blob", "Range", "bytes=2-4")
+	if res.Status != 206 || string(res.Body) != "234" || res.Header.Get("Content-Range") != "bytes 2-4/10" {
+		t.Errorf("expected partial content, got %d %q %v", res.Status, res.Body, res.Header)
+	}
+	etag := res.Header.Get("ETag")
+	if res = client.Get("/blob", "Range", "bytes=2-4", "If-Range", `W/"stale"`); res.Status != 200 || string(res.Body) != "0123456789" {
+		t.Errorf("expected the full content for a stale If-Range, got %d %q", res.Status, res.Body)
+	}
+	if etag == "" {
+		t.Error("expected an ETag for served content")
+	}
+
+	res = client.Get("/download")
+	if res.Header.Get("Content-Disposition") != `attachment; filename=report.csv` || !strings.HasPrefix(res.Header.Get("Content-Type"), "text/csv") {
+		t.Errorf("unexpected attachment headers %v", res.Header)
+	}
+
+	res = client.Get("/asset")
+	if res.Status != 200 || len(res.Body) == 0 || res.Header.Get("Content-Disposition") != "inline; filename=test_asset.html" {
+		t.Errorf("unexpected asset response %d %v", res.Status, res.Header)
+	}
+}
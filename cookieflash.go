@@ -0,0 +1,112 @@
+package flotilla
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// flashcookie names the secure cookie holding cookie flashes.
+const flashcookie = "_flashes"
+
+const cookieflashData = "_cookieflashes"
+
+// cookieflashin reads the flashes of the request flash cookie, and expires the
+// cookie, so that flashes are kept for exactly one request as the session
+// Flasher.
+func cookieflashin(c *ctx) {
+	ck, err := c.Request.Cookie(flashcookie)
+	if err != nil {
+		return
+	}
+	fl := cookieflashstate(c)
+	ring, maxage := securecookiekeys(ctxlookup(c))
+	if v, ok := openvalue(ring, maxage, flashcookie, ck.Value, CurrentTime(c)); ok {
+		json.Unmarshal([]byte(v), &fl.incoming)
+	}
+	savecookieflashes(c, fl)
+}
+
+// cookieflashstate returns the cookie flashes of the current request: those
+// of the request flash cookie, and those flashed during the request.
+func cookieflashstate(c *ctx) *flasher {
+	if fl, ok := c.Data[cookieflashData].(*flasher); ok {
+		return fl
+	}
+	fl := &flasher{}
+	setdata(c, cookieflashData, fl)
+	return fl
+}
+
+// savecookieflashes sets the flash cookie to the flashes flashed during the
+// request and not yet read, or expires the flash cookie of the request when
+// there are none.
+func savecookieflashes(c *ctx, fl *flasher) error {
+	o := CookieOptions{Path: "/", HttpOnly: true, SameSite: http.SameSiteLaxMode}
+	if len(fl.outgoing) == 0 {
+		removecookie(c, flashcookie, o.Path, o.Domain)
+		if _, err := c.Request.Cookie(flashcookie); err == nil {
+			return deletecookie(c, flashcookie, o)
+		}
+		return nil
+	}
+	b, err := json.Marshal(fl.outgoing)
+	if err != nil {
+		return err
+	}
+	removecookie(c, flashcookie, o.Path, o.Domain)
+	return writecookie(c, true, flashcookie, string(b), o)
+}
+
+func cookieflash(c *ctx, category, value string) error {
+	fl := cookieflashstate(c)
+	fl.Flash(category, value)
+	return savecookieflashes(c, fl)
+}
+
+func cookieflashes(c *ctx, categories ...string) Flashes {
+	fl := cookieflashstate(c)
+	var ret Flashes
+	if len(categories) == 0 {
+		ret = fl.WriteAll()
+	} else {
+		ret = make(Flashes)
+		for _, category := range categories {
+			if v := fl.Write(category); v != nil {
+				ret[category] = v
+			}
+		}
+	}
+	savecookieflashes(c, fl)
+	return ret
+}
+
+var cookieflashfxtension = map[string]interface{}{
+	"cookieflash":   cookieflash,
+	"cookieflashes": cookieflashes,
+}
+
+// CookieFlashFxtension provides flash messages kept in a secure cookie rather
+// than the session, for apps not using server side sessions. As session
+// flashes, flashes are kept for the next request only, whether read or not.
+var CookieFlashFxtension Fxtension = MakeFxtension("cookieflashfxtension", cookieflashfxtension)
+
+// CookieFlash stores a flash message under category in the flash cookie.
+func CookieFlash(c Ctx, category, value string) error {
+	_, err := c.Call("cookieflash", category, value)
+	return err
+}
+
+// GetCookieFlashes returns and clears the cookie flash messages for the
+// provided categories, or all cookie flash messages if no categories are
+// provided.
+func GetCookieFlashes(c Ctx, categories ...string) Flashes {
+	args := make([]interface{}, len(categories))
+	for i, category := range categories {
+		args[i] = category
+	}
+	fl, err := c.Call("cookieflashes", args...)
+	if err != nil {
+		return nil
+	}
+	return fl.(Flashes)
+}
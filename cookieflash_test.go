@@ -0,0 +1,53 @@
+package flotilla
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCookieFlashes(t *testing.T) {
+	var read, unread, same Flashes
+	a := New("testCookieFlashes", Mode("testing", true))
+	a.GET("/flash", func(c Ctx) {
+		CookieFlash(c, "info", "saved")
+		CookieFlash(c, "error", "failed")
+	})
+	a.GET("/read", func(c Ctx) { read = GetCookieFlashes(c, "info") })
+	a.GET("/unread", func(c Ctx) { unread = GetCookieFlashes(c) })
+	a.GET("/other", func(c Ctx) { c.Call("serveplain", 200, "other") })
+	a.GET("/same", func(c Ctx) {
+		CookieFlash(c, "info", "now")
+		same = GetCookieFlashes(c)
+	})
+	client := a.TestClient()
+
+	client.Get("/flash")
+	if ck, ok := client.Cookie(flashcookie); !ok || ck.Value == "" {
+		t.Fatal("expected a flash cookie")
+	}
+	client.Get("/read")
+	if !reflect.DeepEqual(read, Flashes{"info": {"saved"}}) {
+		t.Errorf("expected the info flash, got %v", read)
+	}
+	if _, ok := client.Cookie(flashcookie); ok {
+		t.Error("expected the flash cookie cleared on the next request")
+	}
+	client.Get("/unread")
+	if len(unread) != 0 {
+		t.Errorf("expected flashes unread on the next request to be discarded, got %v", unread)
+	}
+
+	client.Get("/flash")
+	client.Get("/other")
+	if client.Get("/unread"); len(unread) != 0 {
+		t.Errorf("expected flashes discarded by a request not reading them, got %v", unread)
+	}
+
+	client.Get("/same")
+	if !reflect.DeepEqual(same, Flashes{"info": {"now"}}) {
+		t.Errorf("expected a flash read in the same request, got %v", same)
+	}
+	if _, ok := client.Cookie(flashcookie); ok {
+		t.Error("expected no flash cookie for flashes read in the same request")
+	}
+}
@@ -2,16 +2,23 @@ package flotilla
 
 import (
 	"bytes"
-	"crypto/hmac"
-	"crypto/sha1"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/binary"
 	"fmt"
 	"net/http"
-	"strconv"
 	"strings"
 	"time"
+
+	"github.com/thrisp/flotilla/session"
+	"github.com/thrisp/flotilla/xrr"
 )
 
+var NoSecretKey = xrr.NewXrror("no secret key is available to seal a secure cookie").Out
+
 var (
 	cNameSanitizer  = strings.NewReplacer("\n", "-", "\r", "-")
 	cValueSanitizer = strings.NewReplacer("\n", " ", "\r", " ", ";", " ")
@@ -25,6 +32,23 @@ func cookies(c *ctx) map[string]*http.Cookie {
 	return ret
 }
 
+// setcookies returns the cookies queued on the response by pending Set-Cookie
+// headers.
+func setcookies(c *ctx) []*http.Cookie {
+	return (&http.Response{Header: c.RW.Header()}).Cookies()
+}
+
+// vetocookie removes every pending Set-Cookie header for the named cookie
+// from the response.
+func vetocookie(c *ctx, name string) error {
+	name = cNameSanitizer.Replace(name)
+	filtercookies(c, func(header string) bool {
+		n, _, _ := strings.Cut(header, "=")
+		return strings.TrimSpace(n) == name
+	})
+	return nil
+}
+
 func readcookies(c *ctx) map[string]string {
 	ret := make(map[string]string)
 	cks := cookies(c)
@@ -34,110 +58,356 @@ func readcookies(c *ctx) map[string]string {
 	return ret
 }
 
+// unpackcookie returns the cookie value, opening a value set with
+// securecookie, or an empty string if the secure value is not valid.
 func unpackcookie(c *ctx, cookie *http.Cookie) string {
 	val := cookie.Value
-	if val == "" {
+	if !strings.HasPrefix(val, securecookieprefix) {
 		return val
 	}
-
-	parts := strings.SplitN(val, "|", 3)
-
-	if len(parts) != 3 {
-		return val
+	ring, maxage := securecookiekeys(ctxlookup(c))
+	if len(ring.Keys()) == 0 {
+		return "cookie value could not be read and/or unpacked"
 	}
+	res, _ := openvalue(ring, maxage, cookie.Name, val, CurrentTime(c))
+	return res
+}
 
-	vs := parts[0]
-	// timestamp := parts[1]
-	sig := parts[2]
+func cookie(c *ctx, secure bool, name string, value string, opts []interface{}) error {
+	return writecookie(c, secure, name, value, cookieoptions(opts...))
+}
 
-	if secret, ok := CheckStore(c, "SECRET_KEY"); ok {
-		h := hmac.New(sha1.New, []byte(secret.Value))
+func securecookie(c *ctx, name string, value string, opts ...interface{}) error {
+	return writecookie(c, true, name, value, cookieoptions(opts...))
+}
 
-		if fmt.Sprintf("%02x", h.Sum(nil)) != sig {
-			return ""
-		}
+func setcookie(c *ctx, name string, value string, o CookieOptions) error {
+	return writecookie(c, false, name, value, o)
+}
 
-		res, _ := base64.URLEncoding.DecodeString(vs)
-		return string(res)
-	}
-	return "cookie value could not be read and/or unpacked"
+func setsecurecookie(c *ctx, name string, value string, o CookieOptions) error {
+	return writecookie(c, true, name, value, o)
 }
 
-func cookie(c *ctx, secure bool, name string, value string, opts []interface{}) error {
+func writecookie(c *ctx, secure bool, name string, value string, o CookieOptions) error {
 	if secure {
-		if secret, ok := CheckStore(c, "SECRET_KEY"); ok {
-			value = securevalue(secret.Value, value)
+		ring, _ := securecookiekeys(ctxlookup(c))
+		var err error
+		if value, err = sealvalue(ring, name, value, CurrentTime(c)); err != nil {
+			return err
 		}
 	}
-	cke := basiccookie(name, value, opts...)
+	cke := basiccookie(name, value, o)
 	headermodify(c, "add", []string{"Set-Cookie", cke})
 	return nil
 }
 
-func securecookie(c *ctx, name string, value string, opts ...interface{}) error {
-	return cookie(c, true, name, value, opts)
+// updatecookie sets a cookie, replacing any cookie with the same name, path,
+// and domain already set on the response.
+func updatecookie(c *ctx, name string, value string, o CookieOptions) error {
+	removecookie(c, name, o.Path, o.Domain)
+	return writecookie(c, false, name, value, o)
 }
 
-func securevalue(secret string, value string) string {
-	vs := base64.URLEncoding.EncodeToString([]byte(value))
-	timestamp := strconv.FormatInt(time.Now().UnixNano(), 10)
-	h := hmac.New(sha1.New, []byte(secret))
-	sig := fmt.Sprintf("%02x", h.Sum(nil))
-	cookie := strings.Join([]string{vs, timestamp, sig}, "|")
-	return cookie
+// deletecookie expires a cookie with the client, and replaces any cookie with
+// the same name, path, and domain already set on the response. The path and
+// domain, and for prefixed or partitioned cookies the Secure and Partitioned
+// attributes, must match the cookie deleted.
+func deletecookie(c *ctx, name string, opts ...CookieOptions) error {
+	var o CookieOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	o.MaxAge, o.Expires = -1, time.Unix(0, 0)
+	removecookie(c, name, o.Path, o.Domain)
+	return writecookie(c, false, name, "", o)
 }
 
-func basiccookie(name string, value string, opts ...interface{}) string {
-	var b bytes.Buffer
-	fmt.Fprintf(&b,
-		"%s=%s",
-		cNameSanitizer.Replace(name),
-		cValueSanitizer.Replace(value))
+// removecookie removes any Set-Cookie header for the named cookie with the
+// path and domain from the response.
+func removecookie(c *ctx, name, path, domain string) {
+	name = cNameSanitizer.Replace(name)
+	filtercookies(c, func(header string) bool {
+		return samecookie(header, name, path, domain)
+	})
+}
+
+// filtercookies removes the Set-Cookie headers matching remove from the
+// response.
+func filtercookies(c *ctx, remove func(header string) bool) {
+	h := c.RW.Header()
+	var keep []string
+	for _, v := range h["Set-Cookie"] {
+		if !remove(v) {
+			keep = append(keep, v)
+		}
+	}
+	if len(keep) == 0 {
+		h.Del("Set-Cookie")
+		return
+	}
+	h["Set-Cookie"] = keep
+}
+
+func samecookie(header, name, path, domain string) bool {
+	parts := strings.Split(header, ";")
+	if n, _, _ := strings.Cut(parts[0], "="); strings.TrimSpace(n) != name {
+		return false
+	}
+	var p, d string
+	for _, part := range parts[1:] {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch strings.ToLower(k) {
+		case "path":
+			p = v
+		case "domain":
+			d = v
+		}
+	}
+	return p == cValueSanitizer.Replace(path) && strings.EqualFold(d, cValueSanitizer.Replace(domain))
+}
+
+// SetCookie sets a cookie with the provided CookieOptions on the response.
+func SetCookie(c Ctx, name, value string, o CookieOptions) error {
+	_, err := c.Call("setcookie", name, value, o)
+	return err
+}
+
+// SetSecureCookie sets a cookie with the provided CookieOptions on the
+// response, its value sealed as with the securecookie extension.
+func SetSecureCookie(c Ctx, name, value string, o CookieOptions) error {
+	_, err := c.Call("setsecurecookie", name, value, o)
+	return err
+}
+
+// PendingCookies returns the cookies queued on the response, e.g. for
+// middleware to inspect cookies set by earlier handlers, replacing them with
+// UpdateCookie or removing them with VetoCookie.
+func PendingCookies(c Ctx) []*http.Cookie {
+	cks, _ := c.Call("setcookies")
+	return cks.([]*http.Cookie)
+}
+
+// VetoCookie removes the named cookie from those queued on the response.
+func VetoCookie(c Ctx, name string) error {
+	_, err := c.Call("vetocookie", name)
+	return err
+}
+
+// UpdateCookie sets a cookie with the provided CookieOptions on the response,
+// replacing a cookie with the same name, path, and domain already set.
+func UpdateCookie(c Ctx, name, value string, o CookieOptions) error {
+	_, err := c.Call("updatecookie", name, value, o)
+	return err
+}
+
+// DeleteCookie expires the named cookie with the client; o provides the path
+// and domain of the cookie.
+func DeleteCookie(c Ctx, name string, o CookieOptions) error {
+	_, err := c.Call("deletecookie", name, o)
+	return err
+}
+
+// securecookieprefix marks cookie values sealed by securecookie, followed by
+// the version of the key sealing the value.
+const securecookieprefix = "s1."
+
+// ctxlookup returns a function looking up StoreItems for the Ctx.
+func ctxlookup(c Ctx) func(string) (*StoreItem, bool) {
+	return func(key string) (*StoreItem, bool) {
+		return CheckStore(c, key)
+	}
+}
+
+// securecookiekeys returns the KeyRing, and the COOKIE_LIFETIME in seconds
+// after which secure cookie values expire.
+func securecookiekeys(lookup func(string) (*StoreItem, bool)) (*KeyRing, time.Duration) {
+	var maxage time.Duration
+	if item, ok := lookup("COOKIE_LIFETIME"); ok {
+		maxage = time.Duration(item.Int64()) * time.Second
+	}
+	return keyring(lookup), maxage
+}
+
+func cookieaead(key string) (cipher.AEAD, error) {
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealvalue encrypts and authenticates value with AES-GCM using the current
+// key of the ring, prefixed with the time now sealed, authenticating the cookie
+// name as additional data so a value cannot be moved to another cookie.
+func sealvalue(ring *KeyRing, name, value string, now time.Time) (string, error) {
+	version, key, ok := ring.Current()
+	if !ok {
+		return "", NoSecretKey()
+	}
+	aead, err := cookieaead(key)
+	if err != nil {
+		return "", err
+	}
+	plain := make([]byte, 8, 8+len(value))
+	binary.BigEndian.PutUint64(plain, uint64(now.Unix()))
+	plain = append(plain, value...)
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, plain, []byte(name))
+	return securecookieprefix + version + "." + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// openvalue returns the value sealed for the named cookie with the key of the
+// recorded version, and whether it is valid and not older than maxage, if
+// maxage is set.
+func openvalue(ring *KeyRing, maxage time.Duration, name, value string, now time.Time) (string, bool) {
+	if !strings.HasPrefix(value, securecookieprefix) {
+		return "", false
+	}
+	version, value, ok := strings.Cut(value[len(securecookieprefix):], ".")
+	if !ok {
+		return "", false
+	}
+	key, ok := ring.Key(version)
+	if !ok {
+		return "", false
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return "", false
+	}
+	aead, err := cookieaead(key)
+	if err != nil {
+		return "", false
+	}
+	ns := aead.NonceSize()
+	if len(sealed) < ns+aead.Overhead()+8 {
+		return "", false
+	}
+	plain, err := aead.Open(nil, sealed[:ns], sealed[ns:], []byte(name))
+	if err != nil {
+		return "", false
+	}
+	sealedat := time.Unix(int64(binary.BigEndian.Uint64(plain[:8])), 0)
+	if maxage > 0 && now.Sub(sealedat) > maxage {
+		return "", false
+	}
+	return string(plain[8:]), true
+}
+
+// CookieOptions are the attributes of a cookie set with the setcookie and
+// setsecurecookie extensions. A MaxAge of 0 omits the Max-Age attribute, and a
+// negative MaxAge deletes the cookie. SameSite None and Partitioned cookies
+// are always Secure.
+type CookieOptions struct {
+	MaxAge      int
+	Path        string
+	Domain      string
+	Secure      bool
+	HttpOnly    bool
+	SameSite    http.SameSite
+	Expires     time.Time
+	Partitioned bool
+}
+
+// cookieoptions adapts the positional options of the cookie and securecookie
+// extensions, in order max age, path, domain, secure, httponly, samesite
+// (an http.SameSite, or "lax", "strict", or "none"), expires (a time.Time),
+// and partitioned, where a max age of 0 or less deletes the cookie.
+func cookieoptions(opts ...interface{}) CookieOptions {
+	var o CookieOptions
 	if len(opts) > 0 {
 		if opt, ok := opts[0].(int); ok {
-			if opt > 0 {
-				fmt.Fprintf(&b, "; Max-Age=%d", opt)
-			} else {
-				fmt.Fprintf(&b, "; Max-Age=0")
+			o.MaxAge = opt
+			if opt <= 0 {
+				o.MaxAge = -1
 			}
 		}
 	}
 	if len(opts) > 1 {
-		if opt, ok := opts[1].(string); ok && len(opt) > 0 {
-			fmt.Fprintf(&b, "; Path=%s", cValueSanitizer.Replace(opt))
-		}
+		o.Path, _ = opts[1].(string)
 	}
 	if len(opts) > 2 {
-		if opt, ok := opts[2].(string); ok && len(opt) > 0 {
-			fmt.Fprintf(&b, "; Domain=%s", cValueSanitizer.Replace(opt))
-		}
+		o.Domain, _ = opts[2].(string)
 	}
-	secure := false
 	if len(opts) > 3 {
-		if opt, ok := opts[3].(bool); ok {
-			secure = opt
-		}
-	}
-	if secure {
-		fmt.Fprintf(&b, "; Secure")
+		o.Secure, _ = opts[3].(bool)
 	}
-	httponly := false
 	if len(opts) > 4 {
-		if opt, ok := opts[4].(bool); ok {
-			httponly = opt
+		o.HttpOnly, _ = opts[4].(bool)
+	}
+	if len(opts) > 5 {
+		switch opt := opts[5].(type) {
+		case http.SameSite:
+			o.SameSite = opt
+		case string:
+			o.SameSite, _ = session.ParseSameSite(opt)
 		}
 	}
-	if httponly {
+	if len(opts) > 6 {
+		o.Expires, _ = opts[6].(time.Time)
+	}
+	if len(opts) > 7 {
+		o.Partitioned, _ = opts[7].(bool)
+	}
+	return o
+}
+
+func basiccookie(name string, value string, o CookieOptions) string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b,
+		"%s=%s",
+		cNameSanitizer.Replace(name),
+		cValueSanitizer.Replace(value))
+	if o.MaxAge > 0 {
+		fmt.Fprintf(&b, "; Max-Age=%d", o.MaxAge)
+	} else if o.MaxAge < 0 {
+		fmt.Fprintf(&b, "; Max-Age=0")
+	}
+	if !o.Expires.IsZero() {
+		fmt.Fprintf(&b, "; Expires=%s", o.Expires.UTC().Format(http.TimeFormat))
+	}
+	if len(o.Path) > 0 {
+		fmt.Fprintf(&b, "; Path=%s", cValueSanitizer.Replace(o.Path))
+	}
+	if len(o.Domain) > 0 {
+		fmt.Fprintf(&b, "; Domain=%s", cValueSanitizer.Replace(o.Domain))
+	}
+	if o.Secure || o.SameSite == http.SameSiteNoneMode || o.Partitioned {
+		fmt.Fprintf(&b, "; Secure")
+	}
+	if o.HttpOnly {
 		fmt.Fprintf(&b, "; HttpOnly")
 	}
+	switch o.SameSite {
+	case http.SameSiteLaxMode:
+		fmt.Fprintf(&b, "; SameSite=Lax")
+	case http.SameSiteStrictMode:
+		fmt.Fprintf(&b, "; SameSite=Strict")
+	case http.SameSiteNoneMode:
+		fmt.Fprintf(&b, "; SameSite=None")
+	}
+	if o.Partitioned {
+		fmt.Fprintf(&b, "; Partitioned")
+	}
 	return b.String()
 }
 
 var cookiefxtension = map[string]interface{}{
-	"cookie":       cookie,
-	"securecookie": securecookie,
-	"cookies":      cookies,
-	"readcookies":  readcookies,
+	"cookie":          cookie,
+	"securecookie":    securecookie,
+	"setcookie":       setcookie,
+	"setsecurecookie": setsecurecookie,
+	"updatecookie":    updatecookie,
+	"deletecookie":    deletecookie,
+	"cookies":         cookies,
+	"setcookies":      setcookies,
+	"vetocookie":      vetocookie,
+	"readcookies":     readcookies,
 }
 
 var CookieFxtension Fxtension = MakeFxtension("cookiefxtension", cookiefxtension)
@@ -1,6 +1,7 @@
 package flotilla
 
 import (
+	stdcontext "context"
 	"errors"
 	"net/http"
 	"sync"
@@ -19,6 +20,15 @@ type Manage func(Ctx)
 type Ctx interface {
 	Extensor
 
+	// A Ctx is a context.Context derived from the request context, canceled
+	// when the client disconnects, the request finishes, or any deadline set
+	// on the Ctx passes.
+	stdcontext.Context
+
+	// Context returns the context.Context of the Ctx, for passing to standard
+	// library and other clients.
+	Context() stdcontext.Context
+
 	// Run is an function that starts and cycles through anything the Ctx needs
 	// to do to complete its functionality.
 	Run()
@@ -30,6 +40,14 @@ type Ctx interface {
 	// Cancel is called to finalize the Ctx in any way needed, e.g.
 	// post-processing, signalling, or logging.
 	Cancel()
+
+	// Errors returns the errors recorded by the Ctx so far, including any
+	// recovered panics, for deferred and final functions to inspect.
+	Errors() xrr.Xrrors
+
+	// Status returns the status code of the response so far, 200 if none has
+	// been set; final functions see the status of the completed response.
+	Status() int
 }
 
 var Canceled = errors.New("flotilla.Ctx canceled")
@@ -39,12 +57,48 @@ func (a *App) Ctx() MakeCtxFunc {
 	return func(rw http.ResponseWriter, rq *http.Request, rs *engine.Result, rt *Route) Ctx {
 		c := NewCtx(a.fxtensions, rs)
 		c.reset(rq, rw, rt.Managers)
+		c.route = rt
+		a.Env.instrument(c)
+		a.Env.limitresponse(c)
 		c.Call("start", a.SessionManager)
 		c.In(c.Session)
+		cookieflashin(c)
 		return c
 	}
 }
 
+// instrument adds any enabled request instrumentation to a route ctx.
+func (env *Env) instrument(c *ctx) {
+	if env.Events != nil {
+		env.Events.watch(c)
+	}
+	if env.RouteStats != nil {
+		c.pushfinal(env.RouteStats.manage)
+	}
+	if env.SlowRequests != nil {
+		env.SlowRequests.watch(c, env.Log())
+	}
+}
+
+// limitresponse applies the RESPONSE_MAXSIZE and RESPONSE_WRITETIMEOUT of the
+// Env to a route ctx, recording any limit exceeded as an error of the ctx.
+func (env *Env) limitresponse(c *ctx) {
+	if item, ok := env.Store["RESPONSE_MAXSIZE"]; ok {
+		c.rw.limit = item.Int64()
+	}
+	if item, ok := env.Store["RESPONSE_WRITETIMEOUT"]; ok && item.Int64() > 0 {
+		c.rw.setdeadline(time.Now().Add(time.Duration(item.Int64()) * time.Second))
+	}
+	c.pushfinal(func(Ctx) {
+		if c.rw.err != nil && c.Result != nil {
+			c.Result.Xrror("%s", xrr.ErrorTypeFlotilla, nil, c.rw.err)
+		}
+		if !c.rw.deadline.IsZero() {
+			c.rw.setdeadline(time.Time{})
+		}
+	})
+}
+
 type context struct {
 	parent   *context
 	mu       sync.Mutex
@@ -52,8 +106,16 @@ type context struct {
 	done     chan struct{}
 	err      error
 	value    *ctx
+	deadline time.Time
+	timer    *time.Timer
+	stop     func() bool
+	values   map[interface{}]interface{}
+	rq       stdcontext.Context
 }
 
+// ctxKey is the context.Context key of the Ctx, see FromContext.
+type ctxKey struct{}
+
 type canceler interface {
 	cancel(removeFromParent bool, err error)
 	Done() <-chan struct{}
@@ -66,17 +128,28 @@ func propagateCancel(p *context, child canceler) {
 	p.mu.Lock()
 	if p.err != nil {
 		// parent has already been canceled
+		p.mu.Unlock()
 		child.cancel(false, p.err)
-	} else {
-		if p.children == nil {
-			p.children = make(map[canceler]bool)
-		}
-		p.children[child] = true
+		return
+	}
+	if p.children == nil {
+		p.children = make(map[canceler]bool)
 	}
+	p.children[child] = true
 	p.mu.Unlock()
 }
 
+// Deadline returns the earlier of the context deadline and any deadline of
+// its parent.
 func (c *context) Deadline() (deadline time.Time, ok bool) {
+	c.mu.Lock()
+	deadline, ok = c.deadline, !c.deadline.IsZero()
+	c.mu.Unlock()
+	if c.parent != nil {
+		if pd, pok := c.parent.Deadline(); pok && (!ok || pd.Before(deadline)) {
+			return pd, true
+		}
+	}
 	return
 }
 
@@ -90,8 +163,71 @@ func (c *context) Err() error {
 	return c.err
 }
 
+// Value returns the value for key set with WithValue on the Ctx or its
+// parents, or else of the request context.
 func (c *context) Value(key interface{}) interface{} {
-	return c.value
+	if _, ok := key.(ctxKey); ok {
+		return c.value
+	}
+	c.mu.Lock()
+	v, ok := c.values[key]
+	c.mu.Unlock()
+	if ok {
+		return v
+	}
+	if c.parent != nil {
+		return c.parent.Value(key)
+	}
+	if c.rq != nil {
+		return c.rq.Value(key)
+	}
+	return nil
+}
+
+func (c *context) setvalue(key, val interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.values == nil {
+		c.values = make(map[interface{}]interface{})
+	}
+	c.values[key] = val
+}
+
+// setdeadline cancels the context at d, unless it has an earlier deadline.
+func (c *context) setdeadline(d time.Time) {
+	c.mu.Lock()
+	if c.err != nil || (!c.deadline.IsZero() && !d.Before(c.deadline)) {
+		c.mu.Unlock()
+		return
+	}
+	c.deadline = d
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	dur := time.Until(d)
+	if dur > 0 {
+		c.timer = time.AfterFunc(dur, func() {
+			c.cancel(true, stdcontext.DeadlineExceeded)
+		})
+	}
+	c.mu.Unlock()
+	if dur <= 0 {
+		c.cancel(true, stdcontext.DeadlineExceeded)
+	}
+}
+
+// watch cancels the context with the request context, taking any deadline of
+// the request context.
+func (c *context) watch(rq stdcontext.Context) {
+	c.rq = rq
+	if d, ok := rq.Deadline(); ok {
+		c.setdeadline(d)
+	}
+	if rq.Done() != nil {
+		c.stop = stdcontext.AfterFunc(rq, func() {
+			c.cancel(true, rq.Err())
+		})
+	}
 }
 
 func (c *context) cancel(removeFromParent bool, err error) {
@@ -99,25 +235,38 @@ func (c *context) cancel(removeFromParent bool, err error) {
 		panic("Ctx.context: internal error: missing cancel error")
 	}
 	c.mu.Lock()
+	if c.err != nil {
+		// already canceled
+		c.mu.Unlock()
+		return
+	}
 	c.err = err
 	close(c.done)
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	if c.stop != nil {
+		c.stop()
+	}
 	for child := range c.children {
 		child.cancel(false, err)
 	}
 	c.children = nil
 	c.mu.Unlock()
 
-	if removeFromParent {
-		if c.children != nil {
-			delete(c.children, c)
-		}
+	if removeFromParent && c.parent != nil {
+		c.parent.mu.Lock()
+		delete(c.parent.children, c)
+		c.parent.mu.Unlock()
 	}
 }
 
 type handlers struct {
 	index    int8
+	aborted  bool
 	managers []Manage
 	deferred []Manage
+	final    []Manage
 }
 
 func defaulthandlers() *handlers {
@@ -131,10 +280,13 @@ type ctx struct {
 	xrr.Xrroror
 	Extensor
 	rw      responseWriter
+	route   *Route
 	RW      ResponseWriter
 	Request *http.Request
 	Session session.SessionStore
-	Data    map[string]interface{}
+	// Data holds values set by managers for later managers, handlers, and
+	// templates of the request, see Set and Get; it is cleared on reset.
+	Data map[string]interface{}
 	Flasher
 }
 
@@ -155,37 +307,171 @@ func NewCtx(fxt map[string]Fxtension, rs *engine.Result) *ctx {
 	return c
 }
 
+// Run runs the managers and deferred functions of the ctx, recovering any
+// panic as an error of the ctx and responding with the 500 status of the App,
+// including any custom 500 status managers, before the final functions run.
 func (c *ctx) Run() {
 	c.push(func(c Ctx) { c.Call("release") })
-	c.Next()
+	c.run()
+	if !CurrentMode(c).Production {
+		c.PostProcess(c.Request, c.RW.Status())
+		logrequest(c)
+	}
+}
+
+// run runs the managers, deferred, and final functions of the ctx.
+func (c *ctx) run() {
+	if !c.try(c.Next) || !c.try(c.rundeferred) {
+		c.panicked()
+	}
+	for i := len(c.final) - 1; i >= 0; i-- {
+		fn := c.final[i]
+		c.try(func() { fn(c) })
+	}
+}
+
+func (c *ctx) rundeferred() {
 	for _, fn := range c.deferred {
+		aborted := c.aborted
 		fn(c)
+		if !aborted && c.aborted {
+			// fn aborted the ctx, replacing the pending deferred functions
+			c.rundeferred()
+			return
+		}
 	}
-	if !CurrentMode(c).Production {
-		c.PostProcess(c.Request, c.RW.Status())
-		c.Call("out", LogFmt(c))
+}
+
+// try runs fn, recording any panic with its stack as an error of the ctx,
+// and reports whether fn completed.
+func (c *ctx) try(fn func()) (ok bool) {
+	defer func() {
+		if rcv := recover(); rcv != nil {
+			c.xrror(xrr.ErrorTypePanic, xrr.Stack(3), rcv)
+		}
+	}()
+	fn()
+	return true
+}
+
+func (c *ctx) xrror(typ uint32, meta interface{}, err interface{}) {
+	if c.Result != nil {
+		c.Result.Xrror("%s", typ, meta, err)
+		return
+	}
+	c.Xrroror.Xrror("%s", typ, meta, err)
+}
+
+// panicked discards the pending deferred functions of a ctx that panicked,
+// and responds with the 500 status, or a bare 500 if the status fails.
+func (c *ctx) panicked() {
+	c.deferred, c.aborted = nil, false
+	var err error
+	if c.try(func() { _, err = c.Call("status", 500) }) && err == nil && c.try(c.rundeferred) {
+		return
+	}
+	if !c.RW.Written() {
+		c.RW.WriteHeader(500)
+		c.RW.WriteHeaderNow()
 	}
 }
 
 func (c *ctx) Next() {
 	c.index++
-	s := int8(len(c.managers))
-	for ; c.index < s; c.index++ {
+	for ; c.index < int8(len(c.managers)); c.index++ {
 		c.managers[c.index](c)
+		c.rewindbody()
 	}
 }
 
+// halt stops running any remaining managers; deferred and final functions
+// still run.
+func (c *ctx) halt() {
+	c.index = int8(len(c.managers))
+}
+
+// abort discards the pending deferred functions, responds with the status
+// code, and stops the remaining managers; functions deferred afterwards do not
+// run, so nothing downstream renders.
+func (c *ctx) abort(code int) error {
+	return c.abortwith(func() error {
+		_, err := c.Call("status", code)
+		return err
+	})
+}
+
+// abortwith aborts the ctx as abort, responding with respond.
+func (c *ctx) abortwith(respond func() error) error {
+	c.deferred = []Manage{func(Ctx) { releasesession(c) }}
+	err := respond()
+	c.halt()
+	c.aborted = true
+	return err
+}
+
 func (c *ctx) Cancel() {
 	c.PostProcess(c.Request, c.RW.Status())
 	c.context.cancel(true, Canceled)
 }
 
+func (c *ctx) Errors() xrr.Xrrors {
+	var ret xrr.Xrrors
+	if c.Result != nil {
+		ret = append(ret, c.Result.Errors()...)
+	}
+	return append(ret, c.Xrroror.Errors()...)
+}
+
+func (c *ctx) Status() int {
+	return c.RW.Status()
+}
+
 func (c *ctx) reset(rq *http.Request, rw http.ResponseWriter, m []Manage) {
 	c.Request = rq
 	c.rw.reset(rw)
 	c.context = &context{done: make(chan struct{}), value: c}
+	if rq != nil {
+		c.context.watch(rq.Context())
+	}
 	c.handlers = defaulthandlers()
 	c.managers = m
+	c.Data = nil
+}
+
+// WithDeadline cancels the Ctx at d, unless it has an earlier deadline, and
+// sets the deadline on the request context for downstream calls.
+func (c *ctx) WithDeadline(d time.Time) {
+	c.context.setdeadline(d)
+	if c.Request != nil {
+		rqc, cancel := stdcontext.WithDeadline(c.Request.Context(), d)
+		c.Request = c.Request.WithContext(rqc)
+		c.pushfinal(func(Ctx) { cancel() })
+	}
+}
+
+// WithTimeout cancels the Ctx after the timeout d.
+func (c *ctx) WithTimeout(d time.Duration) {
+	c.WithDeadline(time.Now().Add(d))
+}
+
+// WithValue sets the value for key on the Ctx, and on the request context for
+// downstream calls.
+func (c *ctx) WithValue(key, val interface{}) {
+	c.context.setvalue(key, val)
+	if c.Request != nil {
+		c.Request = c.Request.WithContext(stdcontext.WithValue(c.Request.Context(), key, val))
+	}
+}
+
+func (c *ctx) Context() stdcontext.Context {
+	return c.context
+}
+
+// FromContext returns the Ctx of a context.Context returned by Ctx.Context,
+// or derived from it.
+func FromContext(cx stdcontext.Context) (Ctx, bool) {
+	c, ok := cx.Value(ctxKey{}).(*ctx)
+	return c, ok && c != nil
 }
 
 func (c *ctx) replicate() *ctx {
@@ -204,10 +490,18 @@ func (c *ctx) rerun(managers ...Manage) {
 	c.Next()
 }
 
+// push defers fn until the managers have run, unless the ctx is aborted.
 func (c *ctx) push(fn Manage) {
+	if c.aborted {
+		return
+	}
 	c.deferred = append(c.deferred, fn)
 }
 
+func (c *ctx) pushfinal(fn Manage) {
+	c.final = append(c.final, fn)
+}
+
 func (c *ctx) bounce(fn Manage) {
 	c.deferred = []Manage{fn}
 }
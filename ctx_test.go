@@ -1,16 +1,26 @@
 package flotilla
 
 import (
+	stdcontext "context"
+	"errors"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/thrisp/flotilla/engine"
+	"github.com/thrisp/flotilla/xrr"
 )
 
 type tc struct {
+	*context
 	h Manage
 }
 
+func (c *tc) Context() stdcontext.Context {
+	return c.context
+}
+
 func (c *tc) Run() {
 	c.Next()
 }
@@ -25,8 +35,12 @@ func (c *tc) Call(name string, vals ...interface{}) (interface{}, error) {
 
 func (c *tc) Cancel() {}
 
+func (c *tc) Errors() xrr.Xrrors { return nil }
+
+func (c *tc) Status() int { return 200 }
+
 func MakeTestCtx(rw http.ResponseWriter, rq *http.Request, rs *engine.Result, rt *Route) Ctx {
-	c := &tc{h: rt.Managers[0]}
+	c := &tc{context: &context{done: make(chan struct{})}, h: rt.Managers[0]}
 	return c
 }
 
@@ -61,3 +75,100 @@ func TestCtx(t *testing.T) {
 		testctx(m, t)
 	}
 }
+
+func TestCtxDeadline(t *testing.T) {
+	a := New("testCtxDeadline", Mode("testing", true))
+	var rqdeadline, ok bool
+	var err error
+	a.GET("/timeout", Timeout(20*time.Millisecond), func(c Ctx) {
+		_, ok = CurrentDeadline(c)
+		rq := CurrentRequest(c)
+		_, rqdeadline = rq.Context().Deadline()
+		select {
+		case <-rq.Context().Done():
+			err = rq.Context().Err()
+		case <-time.After(time.Second):
+		}
+	})
+	a.GET("/none", func(c Ctx) {
+		_, ok = CurrentDeadline(c)
+	})
+	a.Configure()
+
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/timeout", nil))
+	if !ok || !rqdeadline || !errors.Is(err, stdcontext.DeadlineExceeded) {
+		t.Errorf("expected the route timeout to pass, got %v %v %v", ok, rqdeadline, err)
+	}
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/none", nil))
+	if ok {
+		t.Error("expected no deadline without a timeout")
+	}
+
+	parent, cancel := stdcontext.WithCancel(stdcontext.Background())
+	c := NewCtx(a.fxtensions, nil)
+	c.reset(httptest.NewRequest("GET", "/", nil).WithContext(parent), httptest.NewRecorder(), nil)
+	child := c.replicate()
+	cancel()
+	select {
+	case <-child.Done():
+		if child.Err() != stdcontext.Canceled {
+			t.Errorf("expected the request cancellation, got %v", child.Err())
+		}
+	case <-time.After(time.Second):
+		t.Error("expected request cancellation to cancel the Ctx")
+	}
+	c.context.cancel(true, Canceled)
+}
+
+type ctxtestkey string
+
+func TestCtxContext(t *testing.T) {
+	a := New("testCtxContext", Mode("testing", true))
+	var fromrequest, fromctx, fromderived, downstream interface{}
+	var found bool
+	a.GET("/context", func(c Ctx) {
+		fromrequest = c.Value(ctxtestkey("request"))
+		WithValue(c, ctxtestkey("set"), "value")
+		fromctx = c.Value(ctxtestkey("set"))
+		derived, cancel := stdcontext.WithCancel(c.Context())
+		defer cancel()
+		fromderived = derived.Value(ctxtestkey("set"))
+		downstream = CurrentRequest(c).Context().Value(ctxtestkey("set"))
+		_, found = FromContext(derived)
+	})
+	a.Configure()
+
+	rq := httptest.NewRequest("GET", "/context", nil)
+	rq = rq.WithContext(stdcontext.WithValue(rq.Context(), ctxtestkey("request"), "request"))
+	a.ServeHTTP(httptest.NewRecorder(), rq)
+	if fromrequest != "request" || fromctx != "value" || fromderived != "value" || downstream != "value" || !found {
+		t.Errorf("unexpected context values %v %v %v %v %v", fromrequest, fromctx, fromderived, downstream, found)
+	}
+}
+
+func TestCtxErrorsStatus(t *testing.T) {
+	a := testApp(t, "ctxerrorsstatus")
+	var status, deferredstatus int
+	var errs xrr.Xrrors
+	inspect := func(c Ctx) {
+		c.Call("pushfinal", func(c Ctx) {
+			status, errs = c.Status(), c.Errors()
+		})
+	}
+	a.GET("/errors", inspect, func(c Ctx) {
+		RecordError(c, errors.New("handler failed"))
+		c.Call("serveplain", 503, "unavailable")
+		c.Call("push", func(c Ctx) { deferredstatus = c.Status() })
+	})
+	a.Configure()
+
+	rw := httptest.NewRecorder()
+	rq, _ := http.NewRequest("GET", "/errors", nil)
+	a.ServeHTTP(rw, rq)
+	if status != 503 || deferredstatus != 503 {
+		t.Errorf("expected status 503 in deferred and final functions, got %d and %d", deferredstatus, status)
+	}
+	if len(errs) != 1 || errs[0].Error() != "handler failed" || errs[0].Type != xrr.ErrorTypeExternal {
+		t.Errorf("unexpected errors %v", errs)
+	}
+}
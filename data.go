@@ -0,0 +1,57 @@
+package flotilla
+
+func mustgetdata(c *ctx, key string) interface{} {
+	item, err := getdata(c, key)
+	if err != nil {
+		panic(err)
+	}
+	return item
+}
+
+// Set sets a value in the Data of the Ctx, for later managers, handlers, and
+// templates of the request, where it is available by key unless the render
+// data provides the key.
+func Set(c Ctx, key string, value interface{}) {
+	c.Call("set", key, value)
+}
+
+// Get returns the value of the key in the Data of the Ctx, and whether it
+// was set.
+func Get(c Ctx, key string) (interface{}, bool) {
+	ret, err := c.Call("get", key)
+	return ret, err == nil
+}
+
+// MustGet returns the value of the key in the Data of the Ctx, panicking if
+// it was not set, e.g. by a required manager.
+func MustGet(c Ctx, key string) interface{} {
+	ret, err := c.Call("mustget", key)
+	if err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+// GetString returns the string value of the key in the Data of the Ctx, or
+// an empty string if not set or not a string.
+func GetString(c Ctx, key string) string {
+	v, _ := Get(c, key)
+	s, _ := v.(string)
+	return s
+}
+
+// GetInt returns the int value of the key in the Data of the Ctx, or 0 if
+// not set or not an int.
+func GetInt(c Ctx, key string) int {
+	v, _ := Get(c, key)
+	i, _ := v.(int)
+	return i
+}
+
+// GetBool returns the bool value of the key in the Data of the Ctx, or false
+// if not set or not a bool.
+func GetBool(c Ctx, key string) bool {
+	v, _ := Get(c, key)
+	b, _ := v.(bool)
+	return b
+}
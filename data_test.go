@@ -0,0 +1,47 @@
+package flotilla
+
+import "testing"
+
+func TestData(t *testing.T) {
+	c, _ := NewTestCtx(nil, nil)
+	Set(c, "user", "flotilla")
+	Set(c, "count", 3)
+	Set(c, "admin", true)
+
+	if v, ok := Get(c, "user"); !ok || v != "flotilla" {
+		t.Errorf("expected the user value, got %v %t", v, ok)
+	}
+	if _, ok := Get(c, "missing"); ok {
+		t.Error("a missing key was found")
+	}
+	if GetString(c, "user") != "flotilla" || GetInt(c, "count") != 3 || !GetBool(c, "admin") {
+		t.Error("typed values were not returned")
+	}
+	if GetString(c, "count") != "" || GetInt(c, "missing") != 0 {
+		t.Error("mistyped or missing values were not zero")
+	}
+	if MustGet(c, "user") != "flotilla" {
+		t.Error("MustGet did not return the user value")
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("MustGet did not panic for a missing key")
+			}
+		}()
+		MustGet(c, "missing")
+	}()
+
+	td := NewTemplateData(c.(*ctx), map[string]interface{}{"user": "render"})
+	if td["user"] != "render" || td["count"] != 3 {
+		t.Errorf("unexpected template data %v", td)
+	}
+	if d, ok := td["Data"].(map[string]interface{}); !ok || d["user"] != "flotilla" {
+		t.Errorf("Data was not exposed to the template: %v", td["Data"])
+	}
+
+	c.(*ctx).reset(CurrentRequest(c), nil, nil)
+	if _, ok := Get(c, "user"); ok {
+		t.Error("Data was not cleared on reset")
+	}
+}
@@ -0,0 +1,86 @@
+package flotilla
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	rpprof "runtime/pprof"
+	"strings"
+
+	"github.com/thrisp/flotilla/engine"
+)
+
+func httphandler(h http.Handler) Manage {
+	return func(c Ctx) {
+		rw, _ := c.Call("responsewriter")
+		h.ServeHTTP(rw.(ResponseWriter), CurrentRequest(c))
+	}
+}
+
+func pprofmanage(c Ctx) {
+	params, _ := c.Call("params")
+	profile := strings.Trim(params.(engine.Params).ByName("profile"), "/")
+	var h http.Handler
+	switch profile {
+	case "":
+		h = http.HandlerFunc(pprof.Index)
+	case "cmdline":
+		h = http.HandlerFunc(pprof.Cmdline)
+	case "profile":
+		h = http.HandlerFunc(pprof.Profile)
+	case "symbol":
+		h = http.HandlerFunc(pprof.Symbol)
+	case "trace":
+		h = http.HandlerFunc(pprof.Trace)
+	default:
+		if rpprof.Lookup(profile) == nil {
+			c.Call("status", 404)
+			return
+		}
+		h = pprof.Handler(profile)
+	}
+	httphandler(h)(c)
+}
+
+func goroutinedump(c Ctx) {
+	rw, _ := c.Call("responsewriter")
+	w := rw.(ResponseWriter)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(200)
+	rpprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+func routestatsfunc(a *App) Manage {
+	return func(c Ctx) {
+		if a.Env.RouteStats == nil {
+			c.Call("status", 404)
+			return
+		}
+		writejson(c, 200, a.Env.RouteStats.Snapshot())
+	}
+}
+
+// EnableDebug mounts runtime debug endpoints on a Blueprint at the provided
+// prefix, running any provided Manage functions (e.g. authentication) first:
+//
+//	prefix/pprof/      net/http/pprof index and profiles
+//	prefix/vars        expvar variables as JSON
+//	prefix/goroutines  a full goroutine stack dump
+//	prefix/stats       per-route statistics as JSON, when tracked with TrackRoutes
+//	prefix/routes      the route table in Development mode, see RouteTable
+//
+// The endpoints expose process internals, and should be protected or enabled
+// only where appropriate.
+func (a *App) EnableDebug(prefix string, managers ...Manage) *Blueprint {
+	b := a.NewBlueprint(prefix, managers...)
+	b.GET("/pprof/*profile", pprofmanage)
+	b.POST("/pprof/*profile", pprofmanage)
+	b.GET("/vars", httphandler(expvar.Handler()))
+	b.GET("/goroutines", goroutinedump)
+	b.GET("/stats", routestatsfunc(a))
+	b.GET("/routes", RouteTable)
+	if a.Configured {
+		b.Register(a)
+	}
+	return b
+}
@@ -0,0 +1,40 @@
+package flotilla
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEnableDebug(t *testing.T) {
+	a := testApp(t, "testEnableDebug")
+	a.EnableDebug("/debug", func(c Ctx) {
+		if CurrentRequest(c).Header.Get("X-Debug") != "yes" {
+			c.Call("status", 401)
+		}
+	})
+
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/heap", "/debug/vars", "/debug/goroutines"} {
+		ZeroExpectationPerformer(t, a, 401, "GET", path).Perform()
+
+		rw := httptest.NewRecorder()
+		rq, _ := http.NewRequest("GET", path, nil)
+		rq.Header.Set("X-Debug", "yes")
+		a.ServeHTTP(rw, rq)
+		if rw.Code != 200 {
+			t.Errorf("Debug endpoint %s returned %d, expected 200", path, rw.Code)
+		}
+		if path == "/debug/goroutines" && !strings.Contains(rw.Body.String(), "goroutine") {
+			t.Errorf("Goroutine dump did not contain stacks: %s", rw.Body.String())
+		}
+	}
+
+	rw := httptest.NewRecorder()
+	rq, _ := http.NewRequest("GET", "/debug/pprof/nonexistent", nil)
+	rq.Header.Set("X-Debug", "yes")
+	a.ServeHTTP(rw, rq)
+	if rw.Code != 404 {
+		t.Errorf("Unknown profile returned %d, expected 404", rw.Code)
+	}
+}
@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"net/http"
+	"testing"
+)
+
+// deepRoutes is a set of nested api routes with static, named, and
+// catch-all segments.
+var deepRoutes = []string{
+	"/",
+	"/authorizations",
+	"/authorizations/:id",
+	"/applications/:client_id/tokens/:access_token",
+	"/events",
+	"/repos/:owner/:repo/events",
+	"/networks/:owner/:repo/events",
+	"/orgs/:org/events",
+	"/users/:user/received_events",
+	"/users/:user/received_events/public",
+	"/users/:user/events",
+	"/users/:user/events/public",
+	"/users/:user/events/orgs/:org",
+	"/feeds",
+	"/notifications",
+	"/repos/:owner/:repo/notifications",
+	"/notifications/threads/:id",
+	"/notifications/threads/:id/subscription",
+	"/repos/:owner/:repo/stargazers",
+	"/users/:user/starred",
+	"/user/starred",
+	"/user/starred/:owner/:repo",
+	"/repos/:owner/:repo/subscribers",
+	"/users/:user/subscriptions",
+	"/user/subscriptions",
+	"/repos/:owner/:repo/subscription",
+	"/users/:user/gists",
+	"/gists",
+	"/gists/:id",
+	"/gists/:id/star",
+	"/repos/:owner/:repo/git/blobs/:sha",
+	"/repos/:owner/:repo/git/commits/:sha",
+	"/repos/:owner/:repo/git/refs",
+	"/repos/:owner/:repo/git/tags/:sha",
+	"/repos/:owner/:repo/git/trees/:sha",
+	"/repos/:owner/:repo/issues/:number/comments",
+	"/repos/:owner/:repo/issues/:number/events",
+	"/repos/:owner/:repo/pulls/:number/files",
+	"/repos/:owner/:repo/contents/*path",
+	"/static/*filepath",
+}
+
+func deepEngine() *engine {
+	e := DefaultEngine(nil)
+	for _, route := range deepRoutes {
+		e.Handle("GET", route, func(http.ResponseWriter, *http.Request, *Result) {})
+	}
+	return e
+}
+
+var deepLookups = []string{
+	"/user/starred",
+	"/users/gopher/events/orgs/golang",
+	"/repos/thrisp/flotilla/git/commits/a1b2c3",
+	"/repos/thrisp/flotilla/issues/42/comments",
+	"/repos/thrisp/flotilla/contents/engine/tree.go",
+}
+
+func TestFindAllocations(t *testing.T) {
+	e := deepEngine()
+	root := e.trees["GET"]
+	for _, path := range deepLookups {
+		allocs := testing.AllocsPerRun(100, func() {
+			ps := e.getParams()
+			if rule, _ := root.find(path, ps); rule == nil {
+				t.Fatalf("no rule found for %s", path)
+			}
+			e.params.Put(ps)
+		})
+		if allocs != 0 {
+			t.Errorf("lookup of %s allocated %v times", path, allocs)
+		}
+	}
+}
+
+// BenchmarkGetValue looks up the deep routes, allocating params per lookup.
+func BenchmarkGetValue(b *testing.B) {
+	root := deepEngine().trees["GET"]
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, path := range deepLookups {
+			root.getValue(path)
+		}
+	}
+}
+
+// BenchmarkFind looks up the deep routes with pooled params, as the engine.
+func BenchmarkFind(b *testing.B) {
+	e := deepEngine()
+	root := e.trees["GET"]
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, path := range deepLookups {
+			ps := e.getParams()
+			root.find(path, ps)
+			e.params.Put(ps)
+		}
+	}
+}
+
+// BenchmarkServeHTTP routes requests for the deep routes through the engine.
+func BenchmarkServeHTTP(b *testing.B) {
+	e := deepEngine()
+	w := new(mockResponseWriter)
+	rqs := make([]*http.Request, len(deepLookups))
+	for i, path := range deepLookups {
+		rqs[i], _ = http.NewRequest("GET", path, nil)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, rq := range rqs {
+			e.ServeHTTP(w, rq)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/thrisp/flotilla/xrr"
+)
+
+var RouteConflict = xrr.NewXrror("route %s %s conflicts with a registered route: %s").Out
+
+// conflict walks the tree as addRoute would to add the path, without
+// changing it, returning the reason addRoute would refuse the path, if any.
+func (n *node) conflict(path string) string {
+	numParams := countParams(path)
+	if len(n.path) == 0 && len(n.children) == 0 {
+		return insertconflict(numParams, path, "", false)
+	}
+	for {
+		i := 0
+		for max := min(len(path), len(n.path)); i < max && path[i] == n.path[i]; i++ {
+		}
+		split := i < len(n.path)
+		if i == len(path) {
+			if !split && n.rule != nil {
+				return "a Rule is already registered for this path"
+			}
+			return ""
+		}
+		path = path[i:]
+		c := path[0]
+
+		// a split node keeps a single static child, not starting with c
+		if split {
+			if c != ':' && c != '*' {
+				return insertconflict(numParams, path, "", false)
+			}
+			return insertconflict(numParams, path, n.path[:i], true)
+		}
+
+		if n.wildChild {
+			n = n.children[0]
+			numParams--
+			if len(path) >= len(n.path) && n.path == path[:len(n.path)] {
+				if len(n.path) >= len(path) || path[len(n.path)] == '/' {
+					continue
+				}
+			}
+			return "conflict with wildcard route"
+		}
+
+		if n.nType == param && c == '/' && len(n.children) == 1 {
+			n = n.children[0]
+			continue
+		}
+
+		if j := bytes.IndexByte(n.indices, c); j >= 0 {
+			n = n.children[j]
+			continue
+		}
+
+		if c != ':' && c != '*' {
+			return insertconflict(numParams, path, "", false)
+		}
+		return insertconflict(numParams, path, n.path, len(n.children) > 0)
+	}
+}
+
+// insertconflict returns the reason insertChild would refuse to insert the
+// path below a node of the provided path, with children or not.
+func insertconflict(numParams uint8, path, npath string, children bool) string {
+	for i, max := 0, len(path); numParams > 0; i++ {
+		c := path[i]
+		if c == '<' {
+			for i < max-1 && path[i] != '>' {
+				i++
+			}
+			continue
+		}
+		if c != ':' && c != '*' {
+			continue
+		}
+		if children {
+			return "wildcard route conflicts with existing children"
+		}
+		end := i + 1
+		for end < max && path[end] != '/' {
+			end++
+		}
+		if end-i < 2 {
+			return "wildcards must be named with a non-empty name"
+		}
+		if c == '*' {
+			if end != max || numParams > 1 {
+				return "catch-all routes are only allowed at the end of the path"
+			}
+			if len(npath) > 0 && npath[len(npath)-1] == '/' {
+				return "catch-all conflicts with existing handle for the path segment root"
+			}
+			if i == 0 || path[i-1] != '/' {
+				return "no / before catch-all"
+			}
+			return ""
+		}
+		if reason := constraintconflict(path[i+1 : end]); reason != "" {
+			return reason
+		}
+		numParams--
+		npath = ""
+	}
+	return ""
+}
+
+// constraintconflict returns the reason the constraint of a param name would
+// be refused, if any.
+func constraintconflict(name string) (reason string) {
+	defer func() {
+		if rcv := recover(); rcv != nil {
+			reason = fmt.Sprint(rcv)
+		}
+	}()
+	if _, constraint := SplitParam(name); constraint != "" {
+		matcher(constraint)
+	}
+	return ""
+}
+
+// Conflict returns a RouteConflict error if the method and path, of requests
+// to a host matching the pattern if not empty, can not be handled alongside
+// the routes already handled, e.g. a duplicate path, a static segment shadowed
+// by a wildcard, or a param of another name at the same position.
+func (e *engine) Conflict(pattern, method, path string) error {
+	trees := e.trees
+	if pattern != "" {
+		trees = nil
+		for _, h := range e.hosts {
+			if h.pattern == pattern {
+				trees = h.trees
+			}
+		}
+	}
+	if root := trees[method]; root != nil {
+		if reason := root.conflict(path); reason != "" {
+			return RouteConflict(method, path, reason)
+		}
+	}
+	return nil
+}
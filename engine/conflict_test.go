@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRouterConflict(t *testing.T) {
+	router := DefaultEngine(nil)
+	rule := func(http.ResponseWriter, *http.Request, *Result) {}
+	router.Handle("GET", "/users/:id", rule)
+	router.Handle("GET", "/files/*path", rule)
+	router.HandleHost("{tenant}.example.com", "GET", "/", rule)
+
+	tests := []struct {
+		host, method, path string
+		conflict           bool
+	}{
+		{"", "GET", "/users/:id", true},
+		{"", "GET", "/users/new", true},
+		{"", "GET", "/users/:name/posts", true},
+		{"", "GET", "/users/:id/posts", false},
+		{"", "GET", "/files/readme", true},
+		{"", "POST", "/users/new", false},
+		{"", "GET", "/groups/:id", false},
+		{"{tenant}.example.com", "GET", "/", true},
+		{"{tenant}.example.com", "GET", "/users/new", false},
+	}
+	for _, test := range tests {
+		err := router.Conflict(test.host, test.method, test.path)
+		if (err != nil) != test.conflict {
+			t.Errorf("unexpected conflict of %s %s %s: %v", test.host, test.method, test.path, err)
+		}
+	}
+
+	if recv := catchPanic(func() { router.Handle("GET", "/users/:id/posts", rule) }); recv != nil {
+		t.Errorf("unexpected panic adding a route after conflict checks: %v", recv)
+	}
+}
+
+func TestTreeConflict(t *testing.T) {
+	// conflict reports a reason exactly when addRoute would panic, without
+	// changing the tree
+	sequences := [][]string{
+		{"/cmd/:tool/:sub", "/cmd/vet", "/src/*filepath", "/src/*filepathx", "/src/", "/src1/", "/src1/*filepath", "/src2*filepath", "/search/:query", "/search/invalid", "/user_:name", "/user_x", "/user_:name", "/id:id", "/id/:id"},
+		{"/cmd/vet", "/cmd/:tool/:sub", "/src/AUTHORS", "/src/*filepath", "/user_x", "/user_:name", "/id/:id", "/id:id", "/:id", "/*filepath"},
+		{"/", "/doc/", "/src/*filepath", "/search/:query", "/user_:name", "/", "/doc/", "/src/*filepath", "/search/:query", "/user_:name"},
+		{"/user:", "/user:/", "/cmd/:/", "/src/*", "/src/*filepath/x", "/src2/", "/src2/*filepath/x", "/*filepath"},
+		{"/users/:id<int>", "/users/:id<int>/posts", "/users/:id/posts", "/users/:name<slug>", "/tags/:tag<unknown>", "/tags/:tag<re:[a-z>", "/tags/:tag<re:[a-z]+>", "/tags/:tag<re:[a-z]+>"},
+		{"/a/b/c", "/a/bc", "/a/:x", "/a/b/:x", "/a/b/*rest", "/ab", "/a", "/a/"},
+	}
+	for _, routes := range sequences {
+		tree := &node{}
+		for _, route := range routes {
+			reason := tree.conflict(route)
+			recv := catchPanic(func() { tree.addRoute(route, fakeHandler(route)) })
+			if (reason != "") != (recv != nil) {
+				t.Errorf("conflict of %s was %q, adding it panicked with %v", route, reason, recv)
+			}
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package engine
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Constraints match the values of constrained route params by the name of
+// the constraint, e.g. int for /users/:id<int>. A value of a param without a
+// match for its constraint is not found. Constraints must be added before
+// the routes using them are handled.
+var Constraints = map[string]func(string) bool{
+	"int": func(v string) bool {
+		_, err := strconv.ParseInt(v, 10, 64)
+		return err == nil
+	},
+	"uint": func(v string) bool {
+		_, err := strconv.ParseUint(v, 10, 64)
+		return err == nil
+	},
+	"float": func(v string) bool {
+		_, err := strconv.ParseFloat(v, 64)
+		return err == nil
+	},
+	"alpha": func(v string) bool {
+		for i := 0; i < len(v); i++ {
+			if c := v[i] | 0x20; c < 'a' || c > 'z' {
+				return false
+			}
+		}
+		return v != ""
+	},
+	"uuid": regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`).MatchString,
+}
+
+// SplitParam splits a param name, e.g. id<int>, into its key and constraint,
+// with an empty constraint for an unconstrained name.
+func SplitParam(name string) (key, constraint string) {
+	if i := strings.IndexByte(name, '<'); i > 0 && name[len(name)-1] == '>' {
+		return name[:i], name[i+1 : len(name)-1]
+	}
+	return name, ""
+}
+
+// matcher returns the matcher of a constraint, either a regular expression
+// prefixed with re: matched against the whole value, or one of Constraints.
+func matcher(constraint string) func(string) bool {
+	if strings.HasPrefix(constraint, "re:") {
+		return regexp.MustCompile(`^(?:` + constraint[3:] + `)$`).MatchString
+	}
+	if m, ok := Constraints[constraint]; ok {
+		return m
+	}
+	panic("unknown route param constraint " + constraint)
+}
@@ -5,7 +5,10 @@ package engine
 import (
 	"fmt"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/thrisp/flotilla/xrr"
 )
@@ -18,20 +21,112 @@ type Engine interface {
 }
 
 type conf struct {
-	RedirectTrailingSlash bool
-	RedirectFixedPath     bool
+	RedirectTrailingSlash  bool
+	RedirectFixedPath      bool
+	CaseInsensitiveRouting bool
+	HandleMethodNotAllowed bool
+	HandleOPTIONS          bool
+	OptionsRule            Rule
+	NotFound               Rule
+}
+
+// Option configures the default engine.
+type Option func(*engine)
+
+// RedirectTrailingSlash sets whether a path without a rule is redirected to
+// the path with or without a trailing slash if it has a rule, with a 301
+// status for GET requests or 308 otherwise.
+func RedirectTrailingSlash(on bool) Option {
+	return func(e *engine) {
+		e.RedirectTrailingSlash = on
+	}
+}
+
+// RedirectFixedPath sets whether a path without a rule is redirected, as
+// with RedirectTrailingSlash, to the cleaned path of any case with a rule,
+// e.g. /../Users/ to /users.
+func RedirectFixedPath(on bool) Option {
+	return func(e *engine) {
+		e.RedirectFixedPath = on
+	}
+}
+
+// CaseInsensitiveRouting sets whether a path without a rule is matched to the
+// rule of the path of any case directly, before any redirect.
+func CaseInsensitiveRouting(on bool) Option {
+	return func(e *engine) {
+		e.CaseInsensitiveRouting = on
+	}
+}
+
+// Scoped applies the options to paths with the prefix only, e.g. /admin for
+// /admin and /admin/users but not /administrators, matched in any case. The
+// options of the longest prefix apply, with the options of the engine when
+// scoped otherwise.
+func Scoped(prefix string, opts ...Option) Option {
+	return func(e *engine) {
+		prefix = strings.TrimSuffix(prefix, "/")
+		c := *e.conf
+		for _, s := range e.scopes {
+			if strings.EqualFold(s.prefix, prefix) {
+				c = *s.conf
+			}
+		}
+		s := &engine{conf: &c}
+		s.Configure(opts...)
+		e.scopes = append(e.scopes, scope{prefix, s.conf})
+	}
+}
+
+// NotFound sets the Rule of paths without a rule, in place of the 404 status
+// rule, receiving the path, without the prefix of any scope, as the param
+// path; a nil Rule restores the 404 status rule.
+func NotFound(r Rule) Option {
+	return func(e *engine) {
+		e.NotFound = r
+	}
+}
+
+// MethodNotAllowed sets whether a path with rules for other methods only is
+// answered with a 405 status, with an Allow header of the other methods, or
+// a 404 status.
+func MethodNotAllowed(on bool) Option {
+	return func(e *engine) {
+		e.HandleMethodNotAllowed = on
+	}
+}
+
+// Options sets whether OPTIONS requests for a path without an OPTIONS rule
+// are answered with the Allow header of the path, by the provided Rule or an
+// empty 200 response if nil. The path * answers with every method.
+func Options(on bool, r Rule) Option {
+	return func(e *engine) {
+		e.HandleOPTIONS = on
+		e.OptionsRule = r
+	}
+}
+
+type scope struct {
+	prefix string
+	conf   *conf
 }
 
 type engine struct {
 	*conf
+	scopes     []scope
 	trees      map[string]*node
+	hosts      []*host
 	StatusRule Rule
+	maxParams  uint8
+	params     sync.Pool
 }
 
 func defaultConf() *conf {
 	return &conf{
-		RedirectTrailingSlash: true,
-		RedirectFixedPath:     true,
+		RedirectTrailingSlash:  true,
+		RedirectFixedPath:      true,
+		HandleMethodNotAllowed: true,
+		HandleOPTIONS:          true,
 	}
 }
 
@@ -42,35 +137,94 @@ func DefaultEngine(status Rule) *engine {
 	}
 }
 
+// Configure applies the options to the engine.
+func (e *engine) Configure(opts ...Option) {
+	for _, opt := range opts {
+		opt(e)
+	}
+}
+
 func (e *engine) Handle(method string, path string, r Rule) {
+	e.trees = e.handle(e.trees, method, path, r, 0)
+}
+
+// handle adds the rule to the trees, returning the trees, with room in the
+// params of the engine for extra params.
+func (e *engine) handle(trees map[string]*node, method, path string, r Rule, extra uint8) map[string]*node {
 	if method != "STATUS" && path[0] != '/' {
 		panic("path must begin with '/'")
 	}
 
-	if e.trees == nil {
-		e.trees = make(map[string]*node)
+	if trees == nil {
+		trees = make(map[string]*node)
 	}
 
-	root := e.trees[method]
+	root := trees[method]
 
 	if root == nil {
 		root = new(node)
-		e.trees[method] = root
+		trees[method] = root
 	}
 
 	root.addRoute(path, r)
+
+	if n := countParams(path) + extra; n > e.maxParams {
+		e.maxParams = n
+	}
+
+	return trees
+}
+
+// getParams returns a Params from the pool of the engine, with the capacity
+// for the params of any registered path.
+func (e *engine) getParams() *Params {
+	if ps, ok := e.params.Get().(*Params); ok && cap(*ps) >= int(e.maxParams) {
+		*ps = (*ps)[:0]
+		return ps
+	}
+	ps := make(Params, 0, e.maxParams)
+	return &ps
+}
+
+// release returns the params of a Result to the pool once its Rule returns.
+func (e *engine) release(rs *Result) {
+	if rs.params != nil {
+		e.params.Put(rs.params)
+		rs.params, rs.Params = nil, nil
+	}
 }
 
 func (e *engine) lookup(method, path string) *Result {
-	if root := e.trees[method]; root != nil {
-		if rule, params, tsr := root.getValue(path); rule != nil {
-			return NewResult(200, rule, params, tsr)
-		} else if method != "CONNECT" && path != "/" {
+	return e.search(e.trees, nil, method, path)
+}
+
+// search looks up the rule for the method and path in the trees, or the
+// redirect, OPTIONS, or status result, with the params of any matched host
+// preceding the params of the path.
+func (e *engine) search(trees map[string]*node, hp Params, method, path string) *Result {
+	c, prefix := e.scoped(path)
+	if root := trees[method]; root != nil {
+		ps := e.getParams()
+		*ps = append(*ps, hp...)
+		rule, tsr := root.find(path, ps)
+		if rule == nil && c.CaseInsensitiveRouting {
+			if fixedPath, found := root.findCaseInsensitivePath(path, false); found {
+				*ps = append((*ps)[:0], hp...)
+				rule, _ = root.find(string(fixedPath), ps)
+			}
+		}
+		if rule != nil {
+			rs := NewResult(200, rule, *ps, tsr)
+			rs.params = ps
+			return rs
+		}
+		e.params.Put(ps)
+		if method != "CONNECT" && path != "/" {
 			code := 301
 			if method != "GET" {
-				code = 307
+				code = 308
 			}
-			if tsr && e.RedirectTrailingSlash {
+			if tsr && c.RedirectTrailingSlash {
 				var newpath string
 				if path[len(path)-1] == '/' {
 					newpath = path[:len(path)-1]
@@ -82,10 +236,10 @@ func (e *engine) lookup(method, path string) *Result {
 					http.Redirect(rw, rq, rq.URL.String(), code)
 				}, nil, tsr)
 			}
-			if e.RedirectFixedPath {
+			if c.RedirectFixedPath {
 				fixedPath, found := root.findCaseInsensitivePath(
 					CleanPath(path),
-					e.RedirectTrailingSlash,
+					c.RedirectTrailingSlash,
 				)
 				if found {
 					return NewResult(code, func(rw http.ResponseWriter, rq *http.Request, rs *Result) {
@@ -96,18 +250,80 @@ func (e *engine) lookup(method, path string) *Result {
 			}
 		}
 	}
-	for method := range e.trees {
-		if method == method {
-			continue
+	if method == "OPTIONS" && c.HandleOPTIONS {
+		if allow := allowed(trees, path, method, c.HandleOPTIONS); allow != "" {
+			rs := NewResult(200, c.options(), nil, false)
+			rs.Allow = allow
+			return rs
+		}
+	} else if c.HandleMethodNotAllowed {
+		if allow := allowed(trees, path, method, c.HandleOPTIONS); allow != "" {
+			rs := e.status(405)
+			rs.Allow = allow
+			return rs
 		}
-		handle, _, _ := e.trees[method].getValue(path)
-		if handle != nil {
-			return e.status(405)
+	}
+	if c.NotFound != nil {
+		rest := path[prefix:]
+		if !strings.HasPrefix(rest, "/") {
+			rest = "/" + rest
 		}
+		return NewResult(404, c.NotFound, append(hp[:len(hp):len(hp)], Param{Key: "path", Value: rest}), false)
 	}
 	return e.status(404)
 }
 
+// allowed returns the sorted, comma separated methods of the trees other than
+// method with a rule for the path, or every method for the path *, and
+// OPTIONS if handled by the engine.
+func allowed(trees map[string]*node, path, method string, options bool) string {
+	var allow []string
+	for m, root := range trees {
+		if m == "STATUS" || m == method {
+			continue
+		}
+		if path == "*" {
+			allow = append(allow, m)
+		} else if rule, _, _ := root.getValue(path); rule != nil {
+			allow = append(allow, m)
+		}
+	}
+	if len(allow) == 0 {
+		return ""
+	}
+	sort.Strings(allow)
+	if options {
+		if i := sort.SearchStrings(allow, "OPTIONS"); i == len(allow) || allow[i] != "OPTIONS" {
+			allow = append(allow[:i], append([]string{"OPTIONS"}, allow[i:]...)...)
+		}
+	}
+	return strings.Join(allow, ", ")
+}
+
+func defaultOptionsRule(rw http.ResponseWriter, rq *http.Request, rs *Result) {
+	rw.WriteHeader(rs.Code)
+}
+
+func (c *conf) options() Rule {
+	if c.OptionsRule == nil {
+		return defaultOptionsRule
+	}
+	return c.OptionsRule
+}
+
+// scoped returns the conf of the longest scope prefix of the path, or the
+// conf of the engine, and the length of the prefix.
+func (e *engine) scoped(path string) (*conf, int) {
+	c, n := e.conf, 0
+	for _, s := range e.scopes {
+		l := len(s.prefix)
+		if l >= n && len(path) >= l && strings.EqualFold(path[:l], s.prefix) && (len(path) == l || path[l] == '/') {
+			c, n = s.conf, l
+		}
+	}
+	return c, n
+}
+
 func (e *engine) status(code int) *Result {
 	if root := e.trees["STATUS"]; root != nil {
 		if rule, params, tsr := root.getValue(strconv.Itoa(code)); rule != nil {
@@ -139,6 +355,10 @@ func (e *engine) rcvr(rw http.ResponseWriter, rq *http.Request) {
 
 func (e *engine) ServeHTTP(rw http.ResponseWriter, rq *http.Request) {
 	defer e.rcvr(rw, rq)
-	rslt := e.lookup(rq.Method, rq.URL.Path)
+	rslt := e.lookupHost(rq.Host, rq.Method, rq.URL.Path)
+	if rslt.Allow != "" {
+		rw.Header().Set("Allow", rslt.Allow)
+	}
 	rslt.Rule(rw, rq, rslt)
+	e.release(rslt)
 }
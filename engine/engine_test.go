@@ -3,6 +3,7 @@ package engine
 import (
 	"errors"
 	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
 )
@@ -192,6 +193,153 @@ func TestRouterLookup(t *testing.T) {
 	}
 }
 
+func TestRouterMethodNotAllowed(t *testing.T) {
+	router := DefaultEngine(nil)
+	noop := func(http.ResponseWriter, *http.Request, *Result) {}
+	router.Handle("GET", "/path", noop)
+	router.Handle("POST", "/path", noop)
+	router.Handle("DELETE", "/other", noop)
+
+	rslt := router.lookup("PUT", "/path")
+	if rslt.Code != 405 || rslt.Allow != "GET, OPTIONS, POST" {
+		t.Errorf("expected a 405 allowing GET, OPTIONS, POST, got %d %q", rslt.Code, rslt.Allow)
+	}
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest("PUT", "/path", nil))
+	if rw.Code != 405 || rw.Header().Get("Allow") != "GET, OPTIONS, POST" {
+		t.Errorf("unexpected 405 response %d %v", rw.Code, rw.Header())
+	}
+
+	router.Configure(MethodNotAllowed(false))
+	if rslt = router.lookup("PUT", "/path"); rslt.Code != 404 || rslt.Allow != "" {
+		t.Errorf("expected a 404 without method not allowed handling, got %d %q", rslt.Code, rslt.Allow)
+	}
+}
+
+func TestRouterOPTIONS(t *testing.T) {
+	router := DefaultEngine(nil)
+	noop := func(http.ResponseWriter, *http.Request, *Result) {}
+	router.Handle("GET", "/path", noop)
+	router.Handle("POST", "/path", noop)
+	router.Handle("DELETE", "/other", noop)
+
+	options := func(path string) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest("OPTIONS", path, nil))
+		return rw
+	}
+
+	if rw := options("/path"); rw.Code != 200 || rw.Header().Get("Allow") != "GET, OPTIONS, POST" {
+		t.Errorf("unexpected OPTIONS response %d %v", rw.Code, rw.Header())
+	}
+	if rw := options("*"); rw.Header().Get("Allow") != "DELETE, GET, OPTIONS, POST" {
+		t.Errorf("unexpected server OPTIONS response %v", rw.Header())
+	}
+	if rw := options("/nope"); rw.Code != 404 {
+		t.Errorf("expected a 404 for OPTIONS of an unknown path, got %d", rw.Code)
+	}
+
+	custom := false
+	router.Configure(Options(true, func(rw http.ResponseWriter, rq *http.Request, rs *Result) {
+		custom = true
+		rw.WriteHeader(204)
+	}))
+	if rw := options("/path"); !custom || rw.Code != 204 || rw.Header().Get("Allow") == "" {
+		t.Errorf("unexpected custom OPTIONS response %d %v", rw.Code, rw.Header())
+	}
+
+	router.Handle("OPTIONS", "/path", func(rw http.ResponseWriter, rq *http.Request, rs *Result) {
+		rw.WriteHeader(202)
+	})
+	if rw := options("/path"); rw.Code != 202 || rw.Header().Get("Allow") != "" {
+		t.Errorf("expected the OPTIONS rule of the path, got %d %v", rw.Code, rw.Header())
+	}
+
+	router.Configure(Options(false, nil))
+	if rw := options("/other"); rw.Code != 405 || rw.Header().Get("Allow") != "DELETE" {
+		t.Errorf("expected a 405 without OPTIONS handling, got %d %v", rw.Code, rw.Header())
+	}
+}
+
+func TestRouterRedirectPolicies(t *testing.T) {
+	router := DefaultEngine(nil)
+	var routed string
+	rule := func(rw http.ResponseWriter, rq *http.Request, rs *Result) {
+		routed = rs.Params.ByName("id")
+	}
+	router.Handle("GET", "/users/:id", rule)
+	router.Handle("POST", "/users/:id", rule)
+	router.Handle("GET", "/admin/users/:id", rule)
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		routed = ""
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest(method, path, nil))
+		return rw
+	}
+
+	if rw := do("GET", "/Users/Gopher/"); rw.Code != 301 || rw.Header().Get("Location") != "/users/Gopher" {
+		t.Errorf("expected a fixed path redirect, got %d %v", rw.Code, rw.Header())
+	}
+	if rw := do("POST", "/users/gopher/"); rw.Code != 308 || rw.Header().Get("Location") != "/users/gopher" {
+		t.Errorf("expected a permanent redirect keeping the method, got %d %v", rw.Code, rw.Header())
+	}
+
+	router.Configure(RedirectFixedPath(false))
+	if rw := do("GET", "/Users/gopher"); rw.Code != 404 {
+		t.Errorf("expected a 404 without fixed path redirects, got %d", rw.Code)
+	}
+
+	router.Configure(CaseInsensitiveRouting(true))
+	if rw := do("GET", "/USERS/Gopher"); rw.Code != 200 || routed != "Gopher" {
+		t.Errorf("expected a case insensitive match, got %d %q", rw.Code, routed)
+	}
+
+	router.Configure(RedirectTrailingSlash(false))
+	if rw := do("GET", "/users/gopher/"); rw.Code != 404 {
+		t.Errorf("expected a 404 without trailing slash redirects, got %d", rw.Code)
+	}
+
+	router.Configure(Scoped("/admin/", RedirectTrailingSlash(true), CaseInsensitiveRouting(false)))
+	if rw := do("GET", "/admin/users/gopher/"); rw.Code != 301 {
+		t.Errorf("expected a scoped trailing slash redirect, got %d", rw.Code)
+	}
+	if rw := do("GET", "/Admin/USERS/gopher"); rw.Code != 404 {
+		t.Errorf("expected a scoped case sensitive 404, got %d", rw.Code)
+	}
+	if rw := do("GET", "/users/gopher/"); rw.Code != 404 {
+		t.Errorf("expected the engine policy outside the scope, got %d", rw.Code)
+	}
+}
+
+func TestRouterNotFound(t *testing.T) {
+	router := DefaultEngine(nil)
+	noop := func(http.ResponseWriter, *http.Request, *Result) {}
+	var found string
+	notfound := func(name string) Rule {
+		return func(rw http.ResponseWriter, rq *http.Request, rs *Result) {
+			found = name + " " + rs.Params.ByName("path")
+		}
+	}
+	router.Handle("GET", "/api/items", noop)
+	router.Configure(NotFound(notfound("engine")), Scoped("/api", NotFound(notfound("api"))))
+
+	for path, expected := range map[string]string{
+		"/nope":        "engine /nope",
+		"/api/nope":    "api /nope",
+		"/api":         "api /",
+		"/apiary/nope": "engine /apiary/nope",
+	} {
+		found = ""
+		if rslt := router.lookup("GET", path); rslt.Code != 404 {
+			t.Errorf("expected a 404 result for %s, got %d", path, rslt.Code)
+		} else if rslt.Rule(nil, nil, rslt); found != expected {
+			t.Errorf("unexpected not found rule for %s: %q", path, found)
+		}
+	}
+}
+
 type mockFileSystem struct {
 	opened bool
 }
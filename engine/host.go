@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"net"
+	"strings"
+)
+
+// host holds the trees of the routes of requests to hosts matching a pattern.
+type host struct {
+	pattern string
+	labels  []string
+	trees   map[string]*node
+}
+
+func newHost(pattern string) *host {
+	h := &host{pattern: pattern, labels: strings.Split(pattern, ".")}
+	for i, l := range h.labels {
+		if hostParam(l) == "" {
+			if strings.ContainsAny(l, "{}") {
+				panic("host params must be a whole label, e.g. {tenant}.example.com")
+			}
+			h.labels[i] = strings.ToLower(l)
+		}
+	}
+	return h
+}
+
+// hostParam returns the name of a {name} label, or an empty string.
+func hostParam(label string) string {
+	if len(label) > 2 && label[0] == '{' && label[len(label)-1] == '}' {
+		return label[1 : len(label)-1]
+	}
+	return ""
+}
+
+// match returns the params of a lower case host name matching the pattern.
+func (h *host) match(name string) (Params, bool) {
+	if strings.Count(name, ".") != len(h.labels)-1 {
+		return nil, false
+	}
+	var ps Params
+	for _, l := range h.labels {
+		label := name
+		if i := strings.IndexByte(name, '.'); i >= 0 {
+			label, name = name[:i], name[i+1:]
+		}
+		if key := hostParam(l); key != "" && label != "" {
+			ps = append(ps, Param{Key: key, Value: label})
+		} else if l != label {
+			return nil, false
+		}
+	}
+	return ps, true
+}
+
+// HandleHost registers the rule for the method and path of requests to a host
+// matching the pattern, a host name of which any label may be a param, e.g.
+// {tenant}.example.com, with the params of the host preceding those of the
+// path. Hosts are matched in the order registered, before the routes of any
+// host.
+func (e *engine) HandleHost(pattern, method, path string, r Rule) {
+	var h *host
+	for _, x := range e.hosts {
+		if x.pattern == pattern {
+			h = x
+		}
+	}
+	if h == nil {
+		h = newHost(pattern)
+		e.hosts = append(e.hosts, h)
+	}
+	h.trees = e.handle(h.trees, method, path, r, countHostParams(h.labels))
+}
+
+func countHostParams(labels []string) uint8 {
+	var n uint8
+	for _, l := range labels {
+		if hostParam(l) != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// lookupHost looks up the result of the method and path with the routes of
+// the first host matching the request host found, or the routes of any host.
+func (e *engine) lookupHost(hostport, method, path string) *Result {
+	if len(e.hosts) > 0 {
+		name := hostport
+		if h, _, err := net.SplitHostPort(hostport); err == nil {
+			name = h
+		}
+		name = strings.ToLower(strings.TrimSuffix(name, "."))
+		for _, h := range e.hosts {
+			if hp, ok := h.match(name); ok {
+				if rs := e.search(h.trees, hp, method, path); rs.Code != 404 {
+					return rs
+				}
+			}
+		}
+	}
+	return e.lookup(method, path)
+}
@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestHostMatch(t *testing.T) {
+	h := newHost("{tenant}.Example.com")
+	tests := []struct {
+		name  string
+		ps    Params
+		match bool
+	}{
+		{"acme.example.com", Params{Param{"tenant", "acme"}}, true},
+		{"example.com", nil, false},
+		{"a.b.example.com", nil, false},
+		{".example.com", nil, false},
+		{"acme.example.org", nil, false},
+	}
+	for _, test := range tests {
+		ps, ok := h.match(test.name)
+		if ok != test.match || !reflect.DeepEqual(ps, test.ps) {
+			t.Errorf("unexpected match of %s: %v %v", test.name, ps, ok)
+		}
+	}
+	if recv := catchPanic(func() { newHost("api-{tenant}.example.com") }); recv == nil {
+		t.Error("no panic for a partial host label param")
+	}
+}
+
+func TestRouterHost(t *testing.T) {
+	router := DefaultEngine(nil)
+	var routed string
+	var params Params
+	rule := func(name string) Rule {
+		return func(rw http.ResponseWriter, rq *http.Request, rs *Result) {
+			routed = name
+			params = append(Params(nil), rs.Params...)
+		}
+	}
+	router.Handle("GET", "/", rule("default"))
+	router.Handle("GET", "/about", rule("about"))
+	router.HandleHost("admin.example.com", "GET", "/", rule("admin"))
+	router.HandleHost("{tenant}.example.com", "GET", "/", rule("tenant"))
+	router.HandleHost("{tenant}.example.com", "GET", "/users/:id", rule("user"))
+	router.HandleHost("{tenant}.example.com", "POST", "/users/:id", rule("user"))
+
+	get := func(method, host, path string) int {
+		routed, params = "", nil
+		rq := httptest.NewRequest(method, path, nil)
+		rq.Host = host
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, rq)
+		return rw.Code
+	}
+
+	get("GET", "admin.example.com", "/")
+	if routed != "admin" {
+		t.Errorf("expected the admin host route, got %q", routed)
+	}
+	get("GET", "ACME.example.com:8080", "/")
+	if routed != "tenant" || !reflect.DeepEqual(params, Params{Param{"tenant", "acme"}}) {
+		t.Errorf("expected the tenant host route, got %q %v", routed, params)
+	}
+	get("GET", "acme.example.com", "/users/7")
+	if routed != "user" || !reflect.DeepEqual(params, Params{Param{"tenant", "acme"}, Param{"id", "7"}}) {
+		t.Errorf("expected host params before path params, got %q %v", routed, params)
+	}
+	get("GET", "acme.example.com", "/about")
+	if routed != "about" {
+		t.Errorf("expected the route of any host, got %q", routed)
+	}
+	get("GET", "example.org", "/")
+	if routed != "default" {
+		t.Errorf("expected the default route, got %q", routed)
+	}
+	if code := get("GET", "example.org", "/users/7"); code != 404 {
+		t.Errorf("expected a 404 for a route of another host, got %d", code)
+	}
+	if code := get("DELETE", "acme.example.com", "/users/7"); code != 405 {
+		t.Errorf("expected a 405 for a host route, got %d", code)
+	}
+}
@@ -7,6 +7,9 @@ import (
 	"github.com/thrisp/flotilla/xrr"
 )
 
+// Result is the result of an Engine lookup. Params are pooled by the engine
+// and valid until the Rule returns. Allow holds the methods of a 405 or
+// OPTIONS result, set as the Allow header before the Rule runs.
 type Result struct {
 	*Recorder
 	xrr.Xrroror
@@ -14,6 +17,8 @@ type Result struct {
 	Rule   Rule
 	Params Params
 	TSR    bool
+	Allow  string
+	params *Params
 }
 
 func NewResult(code int, rule Rule, params Params, tsr bool) *Result {
@@ -30,6 +30,8 @@ type (
 		children  []*node
 		rule      Rule
 		priority  uint32
+		key       string
+		match     func(string) bool
 	}
 )
 
@@ -52,10 +54,15 @@ func min(a, b int) int {
 func countParams(path string) uint8 {
 	var n uint
 	for i := 0; i < len(path); i++ {
-		if path[i] != ':' && path[i] != '*' {
-			continue
+		switch path[i] {
+		case '<':
+			// skip the constraint of a param
+			for i < len(path) && path[i] != '>' {
+				i++
+			}
+		case ':', '*':
+			n++
 		}
-		n++
 	}
 	if n >= 255 {
 		return 255
@@ -202,6 +209,13 @@ func (n *node) insertChild(numParams uint8, path string, rule Rule) {
 	// find prefix until first wildcard (beginning with ':'' or '*'')
 	for i, max := 0, len(path); numParams > 0; i++ {
 		c := path[i]
+		if c == '<' {
+			// skip the constraint of a param
+			for i < max-1 && path[i] != '>' {
+				i++
+			}
+			continue
+		}
 		if c != ':' && c != '*' {
 			continue
 		}
@@ -243,6 +257,7 @@ func (n *node) insertChild(numParams uint8, path string, rule Rule) {
 			// will be another non-wildcard subpath starting with '/'
 			if end < max {
 				n.path = path[offset:end]
+				n.constrain()
 				offset = end
 
 				child := &node{
@@ -297,10 +312,34 @@ func (n *node) insertChild(numParams uint8, path string, rule Rule) {
 
 	// insert remaining path part and handle to the leaf
 	n.path = path[offset:]
+	if n.nType == param {
+		n.constrain()
+	}
 	n.rule = rule
 }
 
+// constrain sets the key of a param node and the matcher of its constraint,
+// if any, e.g. :id<int> or :slug<re:[a-z-]+>. Constraints can not contain
+// '/', and a path must repeat the constraint of a param it shares.
+func (n *node) constrain() {
+	key, constraint := SplitParam(n.path[1:])
+	n.key = key
+	if constraint != "" {
+		n.match = matcher(constraint)
+	}
+}
+
+// getValue returns the rule registered with the path, its params, and
+// whether a rule exists for the path with or without a trailing slash.
 func (n *node) getValue(path string) (rule Rule, p Params, tsr bool) {
+	rule, tsr = n.find(path, &p)
+	return rule, p, tsr
+}
+
+// find returns the rule registered with the path as getValue, appending its
+// params to ps, which is allocated only if nil, so that lookups with a
+// reused Params do not allocate.
+func (n *node) find(path string, ps *Params) (rule Rule, tsr bool) {
 walk: // Outer loop for walking the tree
 	for {
 		if len(path) > len(n.path) {
@@ -336,15 +375,17 @@ walk: // Outer loop for walking the tree
 						end++
 					}
 
+					// a value not matching the constraint is not found
+					if n.match != nil && !n.match(path[:end]) {
+						return
+					}
+
 					// save param value
-					if p == nil {
+					if *ps == nil {
 						// lazy allocation
-						p = make(Params, 0, n.maxParams)
+						*ps = make(Params, 0, n.maxParams)
 					}
-					i := len(p)
-					p = p[:i+1] // expand slice within preallocated capacity
-					p[i].Key = n.path[1:]
-					p[i].Value = path[:end]
+					*ps = append(*ps, Param{Key: n.key, Value: path[:end]})
 
 					// we need to go deeper!
 					if end < len(path) {
@@ -372,14 +413,11 @@ walk: // Outer loop for walking the tree
 
 				case catchAll:
 					// save param value
-					if p == nil {
+					if *ps == nil {
 						// lazy allocation
-						p = make(Params, 0, n.maxParams)
+						*ps = make(Params, 0, n.maxParams)
 					}
-					i := len(p)
-					p = p[:i+1] // expand slice within preallocated capacity
-					p[i].Key = n.path[2:]
-					p[i].Value = path
+					*ps = append(*ps, Param{Key: n.path[2:], Value: path})
 
 					rule = n.rule
 					return
@@ -459,6 +497,10 @@ func (n *node) findCaseInsensitivePath(path string, fixTrailingSlash bool) (ciPa
 						k++
 					}
 
+					if n.match != nil && !n.match(path[:k]) {
+						return
+					}
+
 					// add param value to case insensitive path
 					ciPath = append(ciPath, path[:k]...)
 
@@ -122,6 +122,9 @@ func TestCountParams(t *testing.T) {
 	if countParams(strings.Repeat("/:param", 256)) != 255 {
 		t.Fail()
 	}
+	if countParams("/:slug<re:[a-z:]+>/*rest") != 2 {
+		t.Fail()
+	}
 }
 
 func TestTreeAddAndGet(t *testing.T) {
@@ -206,6 +209,46 @@ func TestTreeWildcard(t *testing.T) {
 	checkMaxParams(t, tree)
 }
 
+func TestTreeConstraint(t *testing.T) {
+	tree := &node{}
+
+	routes := [...]string{
+		"/users/:id<int>",
+		"/users/:id<int>/posts/:slug<re:[a-z-]+>",
+		"/files/:name<alpha>/*filepath",
+		"/items/:uuid<uuid>",
+	}
+	for _, route := range routes {
+		tree.addRoute(route, fakeHandler(route))
+	}
+
+	checkRequests(t, tree, testRequests{
+		{"/users/42", false, "/users/:id<int>", Params{Param{"id", "42"}}},
+		{"/users/-7", false, "/users/:id<int>", Params{Param{"id", "-7"}}},
+		{"/users/gopher", true, "", nil},
+		{"/users/42/posts/hello-world", false, "/users/:id<int>/posts/:slug<re:[a-z-]+>", Params{Param{"id", "42"}, Param{"slug", "hello-world"}}},
+		{"/users/42/posts/Hello", true, "", Params{Param{"id", "42"}}},
+		{"/files/docs/a/b.txt", false, "/files/:name<alpha>/*filepath", Params{Param{"name", "docs"}, Param{"filepath", "/a/b.txt"}}},
+		{"/files/d0cs/a", true, "", nil},
+		{"/items/123e4567-e89b-12d3-a456-426614174000", false, "/items/:uuid<uuid>", Params{Param{"uuid", "123e4567-e89b-12d3-a456-426614174000"}}},
+		{"/items/123", true, "", nil},
+	})
+
+	if _, found := tree.findCaseInsensitivePath("/USERS/gopher", true); found {
+		t.Error("found a fixed path for a value not matching its constraint")
+	}
+
+	checkPriorities(t, tree)
+	checkMaxParams(t, tree)
+
+	testRoutes(t, []testRoute{
+		{"/users/:id<int>/edit", false},
+		{"/users/:id/delete", true},
+		{"/users/:id<uint>", true},
+		{"/unknown/:id<nope>", true},
+	})
+}
+
 func catchPanic(testFunc func()) (recv interface{}) {
 	defer func() {
 		recv = recover()
@@ -1,10 +1,15 @@
 package flotilla
 
 import (
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"reflect"
+	"sync"
+	"time"
 
 	"github.com/thrisp/flotilla/session"
 	"github.com/thrisp/flotilla/xrr"
@@ -31,6 +36,13 @@ type (
 		Mode *Modes
 		Store
 		SessionManager *session.Manager
+		TLS            *tls.Config
+		Logger         Logger
+		RouteStats     *RouteStats
+		SlowRequests   *SlowRequests
+		Events         *Events
+		Hubs           *Hubs
+		Reverse        *ReverseRoutes
 		Assets
 		Staticor
 		Templator
@@ -38,13 +50,25 @@ type (
 		tplfunctions  map[string]interface{}
 		ctxprocessors map[string]reflect.Value
 		customstatus  map[int]*status
+		staticcache   map[string]string
+		manifest      AssetManifest
+		markdown      *Markdown
+		sessioninit   *session.Manager
+		clock         clock
+		stop          chan struct{}
+		stopped       sync.Once
+		validators    map[string]ValidatorFunc
+		formguard     *FormGuard
 		mkctx         MakeCtxFunc
 	}
 )
 
 func newEnv(a *App) *Env {
-	e := &Env{Mode: defaultModes(), Store: defaultStore()}
+	e := &Env{Mode: defaultModes(), Store: defaultStore(), Events: newEvents(), Hubs: newHubs(), Reverse: newReverseRoutes(), stop: make(chan struct{})}
 	e.AddFxtensions(BuiltInExtensions(a)...)
+	e.AddTplFunc("urlfor", a.URLFor)
+	e.AddTplFunc("asset", e.AssetURL)
+	e.AddTplFunc("markdown", e.markdownhtml)
 	return e
 }
 
@@ -65,6 +89,12 @@ func (env *Env) SetMode(mode string, value bool) error {
 	return SetModeError(mode)
 }
 
+// assetsfirst reports whether Assets are preferred to files of the static and
+// template directories, in Production mode.
+func (env *Env) assetsfirst() bool {
+	return env.Mode.Production
+}
+
 // CurrentMode returns Modes specific to the App the provided Ctx is running within.
 func CurrentMode(c Ctx) *Modes {
 	m, _ := c.Call("mode")
@@ -98,6 +128,9 @@ func (env *Env) AddFxtensions(fxs ...Fxtension) error {
 			return err
 		}
 		env.fxtensions[fx.Name()] = fx
+		if tfx, ok := fx.(TplFxtension); ok {
+			env.AddTplFuncs(tfx.TplFuncs())
+		}
 	}
 	return err
 }
@@ -117,28 +150,126 @@ func (env *Env) AddTplFuncs(fns map[string]interface{}) {
 	}
 }
 
+// encryptedsessionconfig configures the encryptedcookie session provider with
+// the SESSION_KEYS list, newest first, or the keys of the Env KeyRing.
+func (env *Env) encryptedsessionconfig() string {
+	cookiename := env.Store["SESSION_COOKIENAME"].Value
+	keys := env.KeyRing().Keys()
+	if item, ok := env.Store["SESSION_KEYS"]; ok && item.Value != "" {
+		keys = item.List()
+	}
+	pc, _ := json.Marshal(map[string]interface{}{
+		"cookieName": cookiename,
+		"keys":       keys,
+		"maxage":     env.Store["SESSION_LIFETIME"].Int64(),
+		"sameSite":   env.Store["SESSION_SAMESITE"].Value,
+	})
+	c, _ := json.Marshal(map[string]interface{}{
+		"cookieName":        cookiename,
+		"enableSetCookie":   false,
+		"gclifetime":        3600,
+		"maxLifetime":       env.Store["SESSION_LIFETIME"].Int64(),
+		"idleLifetime":      env.Store["SESSION_IDLELIFETIME"].Int64(),
+		"absoluteLifetime":  env.Store["SESSION_ABSOLUTELIFETIME"].Int64(),
+		"sameSite":          env.Store["SESSION_SAMESITE"].Value,
+		"maxPayloadBytes":   env.Store["SESSION_MAXPAYLOADBYTES"].Int(),
+		"enableCompression": env.Store["SESSION_ENABLECOMPRESSION"].Bool(),
+		"ProviderConfig":    string(pc),
+	})
+	return string(c)
+}
+
+// defaultsessionconfig configures the cookie session provider, signing with
+// the current key derived from the Env KeyRing and verifying with any key; the
+// provider derives its block keys and security names from the same keys.
 func (env *Env) defaultsessionconfig() string {
-	secret := env.Store["SECRET_KEY"].Value
+	keys := env.KeyRing().Derive("flotilla session").Keys()
+	var secret string
+	if len(keys) > 0 {
+		secret, keys = keys[0], keys[1:]
+	}
 	cookie_name := env.Store["SESSION_COOKIENAME"].Value
 	session_lifetime := env.Store["SESSION_LIFETIME"].Int64()
-	prvdrcfg := fmt.Sprintf(`"ProviderConfig":"{\"maxage\": %d,\"cookieName\":\"%s\",\"securityKey\":\"%s\"}"`, session_lifetime, cookie_name, secret)
-	return fmt.Sprintf(`{"cookieName":"%s","enableSetCookie":false,"gclifetime":3600, %s}`, cookie_name, prvdrcfg)
+	samesite := env.Store["SESSION_SAMESITE"].Value
+	pc, _ := json.Marshal(map[string]interface{}{
+		"maxage":       session_lifetime,
+		"cookieName":   cookie_name,
+		"securityKey":  secret,
+		"securityKeys": keys,
+		"sameSite":     samesite,
+	})
+	qpc, _ := json.Marshal(string(pc))
+	prvdrcfg := fmt.Sprintf(`"ProviderConfig":%s`, qpc)
+	idle := env.Store["SESSION_IDLELIFETIME"].Int64()
+	absolute := env.Store["SESSION_ABSOLUTELIFETIME"].Int64()
+	maxpayload := env.Store["SESSION_MAXPAYLOADBYTES"].Int()
+	compression := env.Store["SESSION_ENABLECOMPRESSION"].Bool()
+	return fmt.Sprintf(`{"cookieName":"%s","enableSetCookie":false,"gclifetime":3600,"idleLifetime":%d,"absoluteLifetime":%d,"sameSite":"%s","maxPayloadBytes":%d,"enableCompression":%t, %s}`, cookie_name, idle, absolute, samesite, maxpayload, compression, prvdrcfg)
 }
 
 func (env *Env) defaultsessionmanager() *session.Manager {
-	d, err := session.NewManager("cookie", env.defaultsessionconfig())
+	provider, config := "cookie", env.defaultsessionconfig()
+	if item, ok := env.Store["SESSION_PROVIDER"]; ok && item.Value == "encryptedcookie" {
+		provider, config = item.Value, env.encryptedsessionconfig()
+	}
+	d, err := session.NewManager(provider, config)
 	if err != nil {
+		env.Log().Error("default session manager", "error", err)
 		panic(fmt.Sprintf("Problem with [FLOTILLA] default session manager: %s", err))
 	}
 	return d
 }
 
-// SessionInit intializes the SessionManager stored with the Env.
+// SessionInit intializes the SessionManager stored with the Env, starting
+// session gc once for each Manager and stopping gc of any previous Manager.
 func (env *Env) SessionInit() {
 	if env.SessionManager == nil {
 		env.SessionManager = env.defaultsessionmanager()
 	}
-	go env.SessionManager.GC()
+	env.SessionManager.SetLogger(env.Log())
+	env.SessionManager.SetClock(env.Now)
+	if env.sessioninit != env.SessionManager {
+		if env.sessioninit != nil {
+			env.sessioninit.StopGC()
+		}
+		env.sessioninit = env.SessionManager
+		env.SessionManager.OnCreate(env.sessioncreated)
+		go env.SessionManager.GC()
+	}
+}
+
+// Now returns the current time of the Env, for expiry related functions, see
+// WithClock.
+func (env *Env) Now() time.Time {
+	return env.clock.Now()
+}
+
+// done returns a channel closed when the App shuts down, for goroutines of
+// the Env to stop.
+func (env *Env) done() <-chan struct{} {
+	return env.stop
+}
+
+// shutdown stops the session gc started by SessionInit, and any goroutines
+// waiting on done.
+func (env *Env) shutdown() {
+	if env.sessioninit != nil {
+		env.sessioninit.StopGC()
+	}
+	if env.stop != nil {
+		env.stopped.Do(func() { close(env.stop) })
+	}
+}
+
+// sessioncreated sends SessionCreated to the Events from the Ctx carried by
+// the request, see startsession.
+func (env *Env) sessioncreated(r *http.Request, s session.SessionStore) {
+	if r == nil {
+		return
+	}
+	if c, ok := FromContext(r.Context()); ok {
+		env.Events.Send(c, SessionCreated, s)
+	}
 }
 
 // CustomStatus sets a custom status keyed by integer within the Env reference.
@@ -0,0 +1,121 @@
+package flotilla
+
+import "sync"
+
+// Events sent by an App.
+const (
+	// RequestStarted is sent with a nil payload before the managers of a
+	// route run.
+	RequestStarted = "request_started"
+
+	// RequestFinished is sent with a nil payload once the response of a route
+	// has been written.
+	RequestFinished = "request_finished"
+
+	// GotError is sent with each error, including recovered panics, recorded
+	// while handling a route.
+	GotError = "got_error"
+
+	// TemplateRendered is sent with a *RenderedTemplate after a template is
+	// rendered to the response.
+	TemplateRendered = "template_rendered"
+
+	// SessionCreated is sent with the new session.SessionStore when a request
+	// starts a new session.
+	SessionCreated = "session_created"
+)
+
+// RenderedTemplate is the payload of a TemplateRendered event.
+type RenderedTemplate struct {
+	Name string
+	Data TemplateData
+}
+
+// A Receiver is a function receiving an event with the Ctx sending it and an
+// event specific payload.
+type Receiver func(Ctx, interface{})
+
+// Events is an Env level event bus; applications and extensions connect
+// Receivers to named events, adding cross-cutting behavior without modifying
+// any manager chain. Receivers run synchronously, in the order connected.
+type Events struct {
+	mu        sync.RWMutex
+	receivers map[string][]Receiver
+}
+
+func newEvents() *Events {
+	return &Events{receivers: make(map[string][]Receiver)}
+}
+
+// Connect adds Receivers for the named event.
+func (e *Events) Connect(event string, rs ...Receiver) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.receivers[event] = append(e.receivers[event], rs...)
+}
+
+// Connected reports whether any Receivers are connected for the named event.
+func (e *Events) Connected(event string) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return len(e.receivers[event]) > 0
+}
+
+// Send sends the named event to its Receivers.
+func (e *Events) Send(c Ctx, event string, payload interface{}) {
+	if e == nil {
+		return
+	}
+	e.mu.RLock()
+	rs := e.receivers[event]
+	e.mu.RUnlock()
+	for _, r := range rs {
+		r(c, payload)
+	}
+}
+
+// OnEvent connects Receivers for the named event to the App Events.
+func OnEvent(event string, rs ...Receiver) Configuration {
+	return func(a *App) error {
+		a.Env.Events.Connect(event, rs...)
+		return nil
+	}
+}
+
+func eventfunc(a *App) func(*ctx, string, interface{}) error {
+	return func(c *ctx, event string, payload interface{}) error {
+		a.Env.Events.Send(c, event, payload)
+		return nil
+	}
+}
+
+// SendEvent sends the named event and payload to the App Events from the Ctx.
+func SendEvent(c Ctx, event string, payload interface{}) {
+	c.Call("event", event, payload)
+}
+
+func (e *Events) watch(c *ctx) {
+	e.Send(c, RequestStarted, nil)
+	c.pushfinal(func(fc Ctx) {
+		e.senderrors(c)
+		e.Send(fc, RequestFinished, nil)
+	})
+}
+
+// recovered sends the errors of a status ctx, finishing the request of any
+// route that panicked before its own final functions could run.
+func (e *Events) recovered(c *ctx) {
+	if e == nil {
+		return
+	}
+	e.senderrors(c)
+	if len(panics(c)) > 0 {
+		e.Send(c, RequestFinished, nil)
+	}
+}
+
+func (e *Events) senderrors(c *ctx) {
+	for _, err := range c.Result.Errors() {
+		e.Send(c, GotError, err)
+	}
+}
@@ -0,0 +1,83 @@
+package flotilla
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/thrisp/flotilla/session"
+)
+
+func TestEvents(t *testing.T) {
+	var received []string
+
+	record := func(event string) Receiver {
+		return func(c Ctx, payload interface{}) {
+			switch event {
+			case TemplateRendered:
+				event = event + ":" + payload.(*RenderedTemplate).Name
+			case GotError:
+				if payload == nil {
+					t.Errorf("GotError event was sent without an error")
+				}
+			}
+			received = append(received, event)
+		}
+	}
+
+	a := New("testEvents", WithTemplator(&testtemplator{}))
+	for _, event := range []string{RequestStarted, RequestFinished, GotError, TemplateRendered, SessionCreated} {
+		a.Events.Connect(event, record(event))
+	}
+	a.GET("/rendered", func(c Ctx) { c.Call("rendertemplate", "test.html", nil) })
+	a.GET("/panics", func(c Ctx) { panic("event panic") })
+	a.Configure()
+
+	ZeroExpectationPerformer(t, a, 200, "GET", "/rendered").Perform()
+	expect := []string{RequestStarted, SessionCreated, TemplateRendered + ":test.html", RequestFinished}
+	if !reflect.DeepEqual(received, expect) {
+		t.Errorf("Expected events %v, but received %v", expect, received)
+	}
+
+	received = nil
+	ZeroExpectationPerformer(t, a, 500, "GET", "/panics").Perform()
+	expect = []string{RequestStarted, SessionCreated, GotError, RequestFinished}
+	if !reflect.DeepEqual(received, expect) {
+		t.Errorf("Expected events %v, but received %v", expect, received)
+	}
+}
+
+func TestSessionCreatedEvent(t *testing.T) {
+	m, err := session.NewManager("flotillamemory", `{"cookieName":"s","gclifetime":3600,"enableSetCookie":true}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var created []string
+	a := New("testSessionCreatedEvent", Mode("testing", true))
+	a.SessionManager = m
+	a.Events.Connect(SessionCreated, func(c Ctx, payload interface{}) {
+		created = append(created, payload.(session.SessionStore).SessionID())
+	})
+	a.GET("/", func(c Ctx) { c.Call("serveplain", 200, "ok") })
+	a.Configure()
+
+	rw := httptest.NewRecorder()
+	a.ServeHTTP(rw, httptest.NewRequest("GET", "/", nil))
+	if len(created) != 1 {
+		t.Fatalf("expected one created session, got %v", created)
+	}
+
+	rq := httptest.NewRequest("GET", "/", nil)
+	rq.Header.Set("Cookie", rw.Header().Get("Set-Cookie"))
+	a.ServeHTTP(httptest.NewRecorder(), rq)
+	if len(created) != 1 {
+		t.Errorf("expected no session created for an existing session, got %v", created)
+	}
+
+	rq = httptest.NewRequest("GET", "/", nil)
+	rq.Header.Set("Cookie", "s=stale")
+	a.ServeHTTP(httptest.NewRecorder(), rq)
+	if len(created) != 2 || created[1] == "stale" {
+		t.Errorf("expected a session created in place of a stale session cookie, got %v", created)
+	}
+}
@@ -1,9 +1,13 @@
 package flotilla
 
 import (
+	stdcontext "context"
+	"io"
 	"mime/multipart"
 	"net/http"
+	"net/url"
 	"reflect"
+	"time"
 
 	"github.com/thrisp/flotilla/engine"
 	"github.com/thrisp/flotilla/session"
@@ -40,9 +44,19 @@ type Fxtension interface {
 	Set(map[string]reflect.Value)
 }
 
+// TplFxtension is a Fxtension declaring template functions, added to the Env
+// template functions with the Fxtension. Template functions of the current
+// request take the Ctx of the template data first, e.g.
+// {{ current_user .Ctx }}.
+type TplFxtension interface {
+	Fxtension
+	TplFuncs() map[string]interface{}
+}
+
 type fxtension struct {
-	name string
-	fns  map[string]interface{}
+	name   string
+	fns    map[string]interface{}
+	tplfns map[string]interface{}
 }
 
 var InvalidExtension = xrr.NewXrror("%q is not a valid Fxtension.").Out
@@ -71,25 +85,91 @@ func (fx *fxtension) Set(rv map[string]reflect.Value) {
 	}
 }
 
+// WithTplFuncs declares the template functions of the Fxtension.
+func (fx *fxtension) WithTplFuncs(fns map[string]interface{}) *fxtension {
+	fx.tplfns = fns
+	return fx
+}
+
+// TplFuncs returns the template functions of the Fxtension.
+func (fx *fxtension) TplFuncs() map[string]interface{} {
+	return fx.tplfns
+}
+
 var responsefxtension = map[string]interface{}{
 	"abort":           abort,
+	"bufferbody":      bufferbody,
+	"rawbody":         rawbody,
+	"aborted":         aborted,
+	"aborterror":      aborterror,
+	"halt":            halt,
 	"headernow":       headernow,
 	"headerwrite":     headerwrite,
 	"headermodify":    headermodify,
+	"httperror":       httperror,
+	"httpmiddleware":  httpmiddleware,
 	"iswritten":       iswritten,
+	"notmodified":     conditional,
 	"redirect":        redirect,
+	"redirecttoroute": redirecttoroute,
+	"responsebuffer":  responsebuffer,
+	"servecontent":    servecontent,
+	"responselimit":   responselimit,
+	"routelimits":     routelimits,
 	"servefile":       servefile,
 	"serveplain":      serveplain,
+	"stream":          stream,
+	"wrapwriter":      wrapwriter,
+	"writetimeout":    writetimeout,
 	"writetoresponse": writetoresponse,
 }
 
 var ResponseFxtension Fxtension = MakeFxtension("responsefxtension", responsefxtension)
 
 func abort(c *ctx, code int) error {
-	if code >= 0 {
-		c.RW.WriteHeader(code)
-		c.RW.WriteHeaderNow()
-	}
+	return c.abort(code)
+}
+
+func aborterror(c *ctx, code int, err error) error {
+	c.xrror(xrr.ErrorTypeExternal, nil, err)
+	return c.abort(code)
+}
+
+func aborted(c *ctx) bool {
+	return c.aborted
+}
+
+// Abort responds with the status code of the App, including any custom status
+// managers, and stops the Ctx: remaining managers do not run, and render
+// functions deferred before or after Abort are discarded, so handlers
+// downstream of e.g. authentication never run or render.
+func Abort(c Ctx, code int) error {
+	_, err := c.Call("abort", code)
+	return err
+}
+
+// AbortWithError records err as an error of the Ctx and aborts it with the
+// status code.
+func AbortWithError(c Ctx, code int, err error) error {
+	_, cerr := c.Call("aborterror", code, err)
+	return cerr
+}
+
+// IsAborted reports whether the Ctx has been aborted.
+func IsAborted(c Ctx) bool {
+	ret, err := c.Call("aborted")
+	return err == nil && ret.(bool)
+}
+
+func halt(c *ctx) error {
+	c.halt()
+	return nil
+}
+
+// wrapwriter replaces the http.ResponseWriter underlying the Ctx ResponseWriter
+// with the result of fn, e.g. to capture or transform the response body.
+func wrapwriter(c *ctx, fn func(http.ResponseWriter) http.ResponseWriter) error {
+	c.rw.ResponseWriter = fn(c.rw.ResponseWriter)
 	return nil
 }
 
@@ -149,18 +229,73 @@ func serveplain(c *ctx, code int, data string) error {
 
 func servefile(c *ctx, f http.File) error {
 	fi, err := f.Stat()
-	if err == nil {
-		http.ServeContent(c.RW, c.Request, fi.Name(), fi.ModTime(), f)
+	if err != nil {
+		return err
+	}
+	return servecontent(c, fi.Name(), fi.ModTime(), f, "")
+}
+
+// stream writes the header and calls step with the response writer, flushing
+// after each call, until step returns false or the client disconnects.
+func stream(c *ctx, step func(io.Writer) bool) error {
+	done := c.Request.Context().Done()
+	c.RW.WriteHeaderNow()
+	for {
+		select {
+		case <-done:
+			return c.Request.Context().Err()
+		default:
+		}
+		more := step(c.RW)
+		c.RW.Flush()
+		if !more {
+			return nil
+		}
 	}
+}
+
+// Stream calls step repeatedly with the response writer, flushing written
+// data to the client between calls, until step returns false. An error is
+// returned if the client disconnects before streaming ends.
+func Stream(c Ctx, step func(io.Writer) bool) error {
+	_, err := c.Call("stream", step)
 	return err
 }
 
+func responselimit(c *ctx, n int64) error {
+	c.rw.limit = n
+	return nil
+}
+
+func writetimeout(c *ctx, d time.Duration) error {
+	c.rw.setdeadline(time.Now().Add(d))
+	return nil
+}
+
+// MaxResponseSize returns a Manage function limiting the response body of a
+// route to n bytes; writes past the limit fail with ResponseTooLarge.
+func MaxResponseSize(n int64) Manage {
+	return func(c Ctx) {
+		c.Call("responselimit", n)
+	}
+}
+
+// WriteTimeout returns a Manage function limiting the time to write the
+// response of a route; writes past the deadline fail with
+// WriteDeadlineExceeded.
+func WriteTimeout(d time.Duration) Manage {
+	return func(c Ctx) {
+		c.Call("writetimeout", d)
+	}
+}
+
 func writetoresponse(c *ctx, data string) error {
 	c.RW.Write([]byte(data))
 	return nil
 }
 
 var sessionfxtension = map[string]interface{}{
+	"currentuser":   currentuser,
 	"deletesession": deletesession,
 	"getsession":    getsession,
 	"release":       releasesession,
@@ -169,7 +304,11 @@ var sessionfxtension = map[string]interface{}{
 	"start":         startsession,
 }
 
-var SessionFxtension Fxtension = MakeFxtension("sessionfxtension", sessionfxtension)
+var sessiontplfuncs = map[string]interface{}{
+	"current_user": CurrentUser,
+}
+
+var SessionFxtension Fxtension = MakeFxtension("sessionfxtension", sessionfxtension).WithTplFuncs(sessiontplfuncs)
 
 func deletesession(c *ctx, key string) error {
 	return c.Session.Delete(key)
@@ -179,6 +318,26 @@ func getsession(c *ctx, key string) interface{} {
 	return c.Session.Get(key)
 }
 
+// currentuser returns the user set by any login manager, or else held in the
+// session under UserSessionKey.
+func currentuser(c *ctx) interface{} {
+	if user, ok := c.Data[userData]; ok {
+		return user
+	}
+	if c.Session != nil {
+		return c.Session.Get(UserSessionKey)
+	}
+	return nil
+}
+
+// CurrentUser returns the user of the current request, set by any login
+// manager or held in the session under UserSessionKey, or nil. It is
+// available to templates as "current_user", e.g. {{ current_user .Ctx }}.
+func CurrentUser(c Ctx) interface{} {
+	user, _ := c.Call("currentuser")
+	return user
+}
+
 func Session(c Ctx) session.SessionStore {
 	s, _ := c.Call("session")
 	return s.(session.SessionStore)
@@ -198,28 +357,45 @@ func releasesession(c *ctx) error {
 	return nil
 }
 
+// setsession regenerates the session before the authenticated session key,
+// SESSION_AUTHENTICATEDKEY, is first set, preventing session fixation.
 func setsession(c *ctx, key string, value interface{}) error {
+	if item, ok := CheckStore(c, "SESSION_AUTHENTICATEDKEY"); ok && item.Value == key && c.Session.Get(key) == nil {
+		if _, err := c.Call("regeneratesession"); err != nil {
+			return err
+		}
+	}
 	return c.Session.Set(key, value)
 }
 
 func startsession(c *ctx, s *session.Manager) error {
 	var err error
+	// the request carries the Ctx to the SessionCreated hook of the Manager
+	c.Request = c.Request.WithContext(stdcontext.WithValue(c.Request.Context(), ctxKey{}, c))
 	c.Session, err = s.SessionStart(c.RW, c.Request)
 	if err != nil {
 		return err
 	}
+	// a locked session not released with the response is unlocked when done
+	if u, ok := c.Session.(session.Unlocker); ok {
+		c.pushfinal(func(Ctx) { u.Unlock() })
+	}
 	return nil
 }
 
 var flashfxtension = map[string]interface{}{
 	"flasher": flshr,
 	"flash":   flash,
+	"flashes": flashes,
 }
 
 var FlashFxtension Fxtension = MakeFxtension("flashfxtension", flashfxtension)
 
 type Flashes map[string][]string
 
+// Flasher holds flash messages: values set with Flash are stored in the
+// session for exactly one subsequent request, and cleared once read with
+// Write or WriteAll.
 type Flasher interface {
 	Write(string) []string
 	WriteAll() Flashes
@@ -233,50 +409,64 @@ func NewFlasher() Flasher {
 }
 
 type flasher struct {
-	readOnce bool
-	flashes  Flashes
+	incoming Flashes
+	outgoing Flashes
 }
 
+// Write returns and clears the flashes for key, from the previous request or
+// flashed during the current request.
 func (f *flasher) Write(key string) []string {
-	if ret, ok := f.flashes[key]; ok {
-		f.readOnce = true
-		return ret
+	ret := append(f.incoming[key], f.outgoing[key]...)
+	delete(f.incoming, key)
+	delete(f.outgoing, key)
+	if len(ret) == 0 {
+		return nil
 	}
-	return nil
+	return ret
 }
 
+// WriteAll returns and clears all flashes.
 func (f *flasher) WriteAll() Flashes {
 	ret := make(Flashes)
-	for k, v := range f.flashes {
-		ret[k] = v
+	for _, fl := range []Flashes{f.incoming, f.outgoing} {
+		for k, v := range fl {
+			ret[k] = append(ret[k], v...)
+		}
 	}
-	f.readOnce = true
-	f.flashes = nil
+	f.incoming, f.outgoing = nil, nil
 	return ret
 }
 
 func (f *flasher) In(s session.SessionStore) bool {
 	if in := s.Get("_flashes"); in != nil {
 		if inf, ok := in.(Flashes); ok {
-			f.flashes = inf
+			f.incoming = inf
 			return true
 		}
 	}
 	return false
 }
 
+// Out stores flashes set during the current request and not yet read for the
+// next request; flashes received from the previous request are discarded.
 func (f *flasher) Out(s session.SessionStore) bool {
-	if err := s.Set("_flashes", f.flashes); err != nil {
+	if len(f.outgoing) == 0 {
+		if s.Get("_flashes") != nil {
+			return s.Delete("_flashes") != nil
+		}
+		return false
+	}
+	if err := s.Set("_flashes", f.outgoing); err != nil {
 		return true
 	}
 	return false
 }
 
 func (f *flasher) Flash(key, value string) {
-	if f.flashes == nil {
-		f.flashes = make(Flashes)
+	if f.outgoing == nil {
+		f.outgoing = make(Flashes)
 	}
-	f.flashes[key] = append(f.flashes[key], value)
+	f.outgoing[key] = append(f.outgoing[key], value)
 }
 
 func Flshr(c Ctx) Flasher {
@@ -293,31 +483,113 @@ func flash(c *ctx, category, value string) error {
 	return nil
 }
 
+func flashes(c *ctx, categories ...string) Flashes {
+	if len(categories) == 0 {
+		return c.Flasher.WriteAll()
+	}
+	ret := make(Flashes)
+	for _, category := range categories {
+		if v := c.Flasher.Write(category); v != nil {
+			ret[category] = v
+		}
+	}
+	return ret
+}
+
+// Flash stores a flash message under category for the next request.
+func Flash(c Ctx, category, value string) {
+	c.Call("flash", category, value)
+}
+
+// GetFlashes returns and clears the flash messages for the provided
+// categories, or all flash messages if no categories are provided.
+func GetFlashes(c Ctx, categories ...string) Flashes {
+	args := make([]interface{}, len(categories))
+	for i, category := range categories {
+		args[i] = category
+	}
+	fl, err := c.Call("flashes", args...)
+	if err != nil {
+		return nil
+	}
+	return fl.(Flashes)
+}
+
 // MakeCtxFxtension creates a utility Fxtension with miscellaneous functions.
 func MakeCtxFxtension(a *App) Fxtension {
 	ctxfxtension := map[string]interface{}{
-		"env":            envqueryfunc(a),
-		"files":          files,
-		"get":            getdata,
-		"mode":           currentmodefunc(a),
-		"out":            out(a),
-		"emit":           emit(a),
-		"panics":         panics,
-		"panicsignal":    panicsignalfunc(a),
-		"params":         currentparams,
-		"paramString":    paramString,
-		"push":           push,
-		"rendertemplate": rendertemplatefunc(a),
-		"request":        currentrequest,
-		"set":            setdata,
-		"status":         statusfunc(a),
-		"store":          storequeryfunc(a),
-		"urlfor":         urlforfunc(a),
+		"attachment":        attachmentfunc(a),
+		"audit":             auditfunc(a),
+		"deadline":          currentdeadline,
+		"env":               envqueryfunc(a),
+		"files":             files,
+		"get":               getdata,
+		"mustget":           mustgetdata,
+		"guardform":         guardformfunc(a),
+		"hub":               hubfunc(a),
+		"include":           includefunc(a),
+		"logger":            loggerfunc(a),
+		"mode":              currentmodefunc(a),
+		"negotiate":         negotiatefunc(a),
+		"now":               nowfunc(a),
+		"out":               out(a),
+		"emit":              emit(a),
+		"event":             eventfunc(a),
+		"panics":            panics,
+		"panicsignal":       panicsignalfunc(a),
+		"params":            currentparams,
+		"paramString":       paramString,
+		"push":              push,
+		"pushfinal":         pushfinal,
+		"regeneratesession": regeneratesessionfunc(a),
+		"requestid":         requestid,
+		"responsewriter":    currentresponsewriter,
+		"route":             currentroute,
+		"routes":            routesfunc(a),
+		"rendermarkdown":    rendermarkdownfunc(a),
+		"renderpartial":     renderpartialfunc(a),
+		"rendertemplate":    rendertemplatefunc(a),
+		"sendfile":          sendfilefunc(a),
+		"serveasset":        serveassetfunc(a),
+		"request":           currentrequest,
+		"set":               setdata,
+		"signedurlfor":      signedurlfor,
+		"started":           started,
+		"status":            statusfunc(a),
+		"timeout":           timeout,
+		"store":             storequeryfunc(a),
+		"urlfor":            urlforfunc(a),
+		"withvalue":         withvalue,
+		"validate":          validatefunc(a),
+		"xrror":             recorderror,
 	}
 
 	return MakeFxtension("ctxfxtension", ctxfxtension)
 }
 
+func regeneratesessionfunc(a *App) func(*ctx) error {
+	return func(c *ctx) error {
+		s, err := a.SessionManager.RegenerateSession(c.RW, c.Request, c.Session)
+		if err != nil {
+			return err
+		}
+		c.Session = s
+		// the regenerated session holds the locks of both ids until done
+		if u, ok := s.(session.Unlocker); ok {
+			c.pushfinal(func(Ctx) { u.Unlock() })
+		}
+		c.Call("audit", AuditSessionRegenerate, map[string]string(nil))
+		return nil
+	}
+}
+
+// RegenerateSession moves the session of the current request to a new id,
+// keeping its values, e.g. on any change of privilege.
+func RegenerateSession(c Ctx) error {
+	_, err := c.Call("regeneratesession")
+	return err
+}
+
 func envqueryfunc(a *App) func(*ctx, string) interface{} {
 	return func(c *ctx, item string) interface{} {
 		switch item {
@@ -336,7 +608,7 @@ func envqueryfunc(a *App) func(*ctx, string) interface{} {
 type RequestFiles map[string][]*multipart.FileHeader
 
 func files(c *ctx) RequestFiles {
-	if c.Request.MultipartForm.File != nil {
+	if c.Request.MultipartForm != nil && c.Request.MultipartForm.File != nil {
 		return c.Request.MultipartForm.File
 	}
 	return nil
@@ -358,12 +630,35 @@ func currentmodefunc(a *App) func(c *ctx) *Modes {
 	}
 }
 
+func nowfunc(a *App) func(c *ctx) time.Time {
+	return func(c *ctx) time.Time {
+		return a.Env.Now()
+	}
+}
+
+// CurrentTime returns the current time of the App, see WithClock.
+func CurrentTime(c Ctx) time.Time {
+	now, _ := c.Call("now")
+	return now.(time.Time)
+}
+
 func currentparams(c *ctx) engine.Params {
 	return c.Params
 }
 
 func panics(c *ctx) xrr.Xrrors {
-	return c.Result.Errors().ByType(xrr.ErrorTypePanic)
+	return c.Errors().ByType(xrr.ErrorTypePanic)
+}
+
+func recorderror(c *ctx, err error) error {
+	c.xrror(xrr.ErrorTypeExternal, nil, err)
+	return nil
+}
+
+// RecordError records err as an error of the Ctx, for inspection by deferred
+// and final functions with Ctx.Errors, without changing the response.
+func RecordError(c Ctx, err error) {
+	c.Call("xrror", err)
 }
 
 func panicsignalfunc(a *App) func(*ctx, string) error {
@@ -401,6 +696,36 @@ func push(c *ctx, m Manage) error {
 	return nil
 }
 
+// pushfinal adds a Manage function run after all deferred functions, once the
+// response has been written. Final functions run in reverse of the order they
+// were added, so those added by earlier managers see the completed response.
+func pushfinal(c *ctx, m Manage) error {
+	c.pushfinal(m)
+	return nil
+}
+
+// CurrentRoute returns the Route the Ctx is running for, or nil for status
+// and other non-route Ctx.
+func CurrentRoute(c Ctx) *Route {
+	rt, err := c.Call("route")
+	if err != nil || rt == nil {
+		return nil
+	}
+	return rt.(*Route)
+}
+
+func currentroute(c *ctx) *Route {
+	return c.route
+}
+
+func currentresponsewriter(c *ctx) ResponseWriter {
+	return c.RW
+}
+
+func started(c *ctx) time.Time {
+	return c.Result.RStart
+}
+
 func CurrentRequest(c Ctx) *http.Request {
 	req, _ := c.Call("request")
 	return req.(*http.Request)
@@ -410,11 +735,58 @@ func currentrequest(c *ctx) *http.Request {
 	return c.Request
 }
 
+var NoDeadline = xrr.NewXrror("the Ctx has no deadline").Out
+
+func currentdeadline(c *ctx) (time.Time, error) {
+	if d, ok := c.Deadline(); ok {
+		return d, nil
+	}
+	return time.Time{}, NoDeadline()
+}
+
+func timeout(c *ctx, d time.Duration) error {
+	c.WithTimeout(d)
+	return nil
+}
+
+func withvalue(c *ctx, key, val interface{}) error {
+	c.WithValue(key, val)
+	return nil
+}
+
+// WithValue sets the value for key on the Ctx context, and on the request
+// context of downstream calls.
+func WithValue(c Ctx, key, val interface{}) {
+	c.Call("withvalue", key, val)
+}
+
+// CurrentDeadline returns the deadline of the Ctx, set by a Timeout or the
+// request context, and a boolean indicating its existence.
+func CurrentDeadline(c Ctx) (time.Time, bool) {
+	d, err := c.Call("deadline")
+	if err != nil {
+		return time.Time{}, false
+	}
+	return d.(time.Time), true
+}
+
+// Timeout returns a Manage function setting a per route timeout, canceling
+// the Ctx and the request context of downstream calls once it passes, e.g.
+// a.GET("/report", Timeout(5*time.Second), h).
+func Timeout(d time.Duration) Manage {
+	return func(c Ctx) {
+		c.Call("timeout", d)
+	}
+}
+
 func rendertemplatefunc(a *App) func(*ctx, string, interface{}) error {
 	return func(c *ctx, name string, data interface{}) error {
 		c.push(func(pc Ctx) {
 			td := NewTemplateData(c, data)
-			a.Templator.Render(c.RW, name, td)
+			t := currenttemplator(a, c)
+			if rendertemplated(a, c, name, func(w io.Writer) error { return t.Render(w, name, td) }) {
+				a.Env.Events.Send(pc, TemplateRendered, &RenderedTemplate{Name: name, Data: td})
+			}
 		})
 		return nil
 	}
@@ -452,20 +824,20 @@ var NoUrl = xrr.NewXrror("Unable to get url for route %s with params %s.").Out
 
 func urlforfunc(a *App) func(*ctx, string, bool, []string) (string, error) {
 	return func(c *ctx, route string, external bool, params []string) (string, error) {
-		if route, ok := a.Routes()[route]; ok {
-			routeurl, _ := route.Url(params...)
-			if routeurl != nil {
-				if external {
-					routeurl.Host = c.Request.Host
-				}
-				return routeurl.String(), nil
-			}
+		u, err := a.URLFor(route, params...)
+		if err != nil || !external {
+			return u, err
+		}
+		routeurl, err := url.Parse(u)
+		if err != nil {
+			return "", err
 		}
-		return "", NoUrl(route, params)
+		routeurl.Host = c.Request.Host
+		return routeurl.String(), nil
 	}
 }
 
-var readyextensions = []Fxtension{CookieFxtension, FlashFxtension, ResponseFxtension, SessionFxtension}
+var readyextensions = []Fxtension{BindFxtension, CookieFxtension, CookieFlashFxtension, CryptoFxtension, FlashFxtension, JSONFxtension, JWTFxtension, ParamFxtension, ResponseFxtension, SecurityFxtension, SessionFxtension, TLSFxtension, UploadFxtension, WebSocketFxtension}
 
 func BuiltInExtensions(a *App) []Fxtension {
 	var ret []Fxtension
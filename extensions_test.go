@@ -1,11 +1,20 @@
 package flotilla
 
 import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/thrisp/flotilla/session"
 )
@@ -48,7 +57,7 @@ func TestCookieExtension(t *testing.T) {
 	)
 
 	exp.Request().AddCookie(&http.Cookie{Name: "GetCookie1", Value: "cookie value"})
-	v := securevalue("Flotilla;Secret;Key;1", "cookie value")
+	v, _ := sealvalue(NewKeyRing("Flotilla;Secret;Key;1"), "GetCookie2", "cookie value", time.Now())
 	exp.Request().AddCookie(&http.Cookie{Name: "GetCookie2", Value: v})
 
 	app := testApp(t, "testCookieExtension")
@@ -56,6 +65,143 @@ func TestCookieExtension(t *testing.T) {
 	SimplePerformer(t, app, exp).Perform()
 }
 
+func TestCookieOptions(t *testing.T) {
+	a := New("testCookieOptions", Mode("testing", true))
+	expires := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	a.GET("/options", func(c Ctx) {
+		SetCookie(c, "options", "value", CookieOptions{
+			MaxAge:   60,
+			Path:     "/path",
+			Secure:   true,
+			HttpOnly: true,
+			SameSite: http.SameSiteStrictMode,
+			Expires:  expires,
+		})
+		SetSecureCookie(c, "sealed", "sealed value", CookieOptions{SameSite: http.SameSiteNoneMode})
+		c.Call("cookie", false, "positional", "value", []interface{}{0, "/"})
+		c.Call("cookie", false, "modern", "value", []interface{}{60, "/", "", false, true, "none", expires, true})
+	})
+	a.Configure()
+	rw := httptest.NewRecorder()
+	a.ServeHTTP(rw, httptest.NewRequest("GET", "/options", nil))
+
+	set := rw.Header()["Set-Cookie"]
+	if len(set) < 4 {
+		t.Fatalf("expected 4 cookies and the session cookie, got %v", set)
+	}
+	if set[0] != "options=value; Max-Age=60; Expires=Wed, 02 Jan 2030 03:04:05 GMT; Path=/path; Secure; HttpOnly; SameSite=Strict" {
+		t.Errorf("unexpected cookie from CookieOptions: %s", set[0])
+	}
+	if !strings.HasSuffix(set[1], "; Secure; SameSite=None") {
+		t.Errorf("expected a SameSite=None cookie to be secure: %s", set[1])
+	}
+	if set[2] != "positional=value; Max-Age=0; Path=/" {
+		t.Errorf("unexpected cookie from positional options: %s", set[2])
+	}
+	if set[3] != "modern=value; Max-Age=60; Expires=Wed, 02 Jan 2030 03:04:05 GMT; Path=/; Secure; HttpOnly; SameSite=None; Partitioned" {
+		t.Errorf("unexpected cookie from positional samesite, expires, and partitioned options: %s", set[3])
+	}
+	for _, ck := range rw.Result().Cookies() {
+		if ck.Name == "sealed" {
+			if v, ok := a.ReadSecureCookie(ck); !ok || v != "sealed value" {
+				t.Errorf("secure cookie with options was not read: %q %t", v, ok)
+			}
+		}
+	}
+}
+
+func TestDeleteAndUpdateCookie(t *testing.T) {
+	a := New("testDeleteAndUpdateCookie", Mode("testing", true))
+	a.GET("/cookies", func(c Ctx) {
+		SetCookie(c, "updated", "first", CookieOptions{Path: "/"})
+		SetCookie(c, "updated", "other path", CookieOptions{Path: "/other"})
+		UpdateCookie(c, "updated", "second", CookieOptions{Path: "/"})
+		SetCookie(c, "deleted", "value", CookieOptions{Path: "/", Domain: "example.com"})
+		DeleteCookie(c, "deleted", CookieOptions{Path: "/", Domain: "example.com"})
+	})
+	a.Configure()
+	rw := httptest.NewRecorder()
+	a.ServeHTTP(rw, httptest.NewRequest("GET", "/cookies", nil))
+
+	var set []string
+	for _, v := range rw.Header()["Set-Cookie"] {
+		if !strings.HasPrefix(v, "session=") {
+			set = append(set, v)
+		}
+	}
+	expected := []string{
+		"updated=other path; Path=/other",
+		"updated=second; Path=/",
+		"deleted=; Max-Age=0; Expires=Thu, 01 Jan 1970 00:00:00 GMT; Path=/; Domain=example.com",
+	}
+	if !reflect.DeepEqual(set, expected) {
+		t.Errorf("expected cookies %v, got %v", expected, set)
+	}
+}
+
+func TestPendingCookies(t *testing.T) {
+	a := New("testPendingCookies", Mode("testing", true))
+	var pending []*http.Cookie
+	a.GET("/pending", func(c Ctx) {
+		SetCookie(c, "kept", "value", CookieOptions{Path: "/", MaxAge: 60})
+		SetCookie(c, "vetoed", "value", CookieOptions{Path: "/"})
+		SetCookie(c, "vetoed", "value", CookieOptions{Path: "/other"})
+		pending = PendingCookies(c)
+		VetoCookie(c, "vetoed")
+	})
+	a.Configure()
+	rw := httptest.NewRecorder()
+	a.ServeHTTP(rw, httptest.NewRequest("GET", "/pending", nil))
+
+	if len(pending) != 3 || pending[0].Name != "kept" || pending[0].MaxAge != 60 || pending[2].Path != "/other" {
+		t.Errorf("unexpected pending cookies %v", pending)
+	}
+	for _, ck := range rw.Result().Cookies() {
+		if ck.Name == "vetoed" {
+			t.Errorf("vetoed cookie was set: %v", ck)
+		}
+	}
+}
+
+func TestSecureCookieValues(t *testing.T) {
+	now := time.Now()
+	v, err := sealvalue(NewKeyRing("old key"), "name", "cookie value", time.Unix(1000000, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(v, "cookie value") {
+		t.Error("secure cookie value contains the plain value")
+	}
+	rotated := NewKeyRing("new key", "old key")
+	if res, ok := openvalue(rotated, 0, "name", v, now); !ok || res != "cookie value" {
+		t.Errorf("rotated keys could not open a value sealed with an older key: %q %t", res, ok)
+	}
+	if !strings.HasPrefix(v, securecookieprefix+KeyVersion("old key")+".") {
+		t.Errorf("secure cookie value does not record the key version: %s", v)
+	}
+	if _, ok := openvalue(NewKeyRing("new key"), 0, "name", v, now); ok {
+		t.Error("a value sealed with a removed key was opened")
+	}
+	if _, ok := openvalue(rotated, 0, "other", v, now); ok {
+		t.Error("a value sealed for another cookie name was opened")
+	}
+	if _, ok := openvalue(rotated, time.Hour, "name", v, now); ok {
+		t.Error("an expired value was opened")
+	}
+}
+
+func TestSecureCookieNoSecretKey(t *testing.T) {
+	a := New("testSecureCookieNoSecretKey", Mode("testing", true), EnvItem("SECRET_KEY:"))
+	var err error
+	a.GET("/", func(c Ctx) { _, err = c.Call("securecookie", "name", "plain value") })
+	a.Configure()
+	rw := httptest.NewRecorder()
+	a.ServeHTTP(rw, httptest.NewRequest("GET", "/", nil))
+	if err == nil || strings.Contains(rw.Header().Get("Set-Cookie"), "plain value") {
+		t.Errorf("expected an error and no plain secure cookie without secret keys, got %v %q", err, rw.Header().Get("Set-Cookie"))
+	}
+}
+
 func TestResponseExtension(t *testing.T) {
 	app := testApp(t, "testResponseExtension")
 	exp1, _ := NewExpectation(
@@ -141,6 +287,56 @@ func TestResponseExtension(t *testing.T) {
 	MultiPerformer(t, app, exp1, exp2, exp3, exp4, exp5).Perform()
 }
 
+type streamrecorder struct {
+	*httptest.ResponseRecorder
+	flushes  []string
+	hijacked bool
+}
+
+func (r *streamrecorder) Flush() {
+	r.flushes = append(r.flushes, r.Body.String())
+	r.ResponseRecorder.Flush()
+}
+
+func (r *streamrecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	r.hijacked = true
+	return nil, nil, nil
+}
+
+func TestStream(t *testing.T) {
+	a := New("testStream", Mode("testing", true))
+	var serr, herr error
+	a.GET("/stream", func(c Ctx) {
+		c.Call("headerwrite", 202)
+		n := 0
+		serr = Stream(c, func(w io.Writer) bool {
+			n++
+			fmt.Fprintf(w, "%d;", n)
+			return n < 3
+		})
+	})
+	a.GET("/hijack", func(c Ctx) {
+		rw, _ := c.Call("responsewriter")
+		_, _, herr = http.NewResponseController(rw.(http.ResponseWriter)).Hijack()
+	})
+	a.Configure()
+
+	rw := &streamrecorder{ResponseRecorder: httptest.NewRecorder()}
+	a.ServeHTTP(rw, httptest.NewRequest("GET", "/stream", nil))
+	if serr != nil || rw.Code != 202 || rw.Body.String() != "1;2;3;" {
+		t.Errorf("unexpected stream response %d %q %v", rw.Code, rw.Body.String(), serr)
+	}
+	if !reflect.DeepEqual(rw.flushes, []string{"1;", "1;2;", "1;2;3;"}) {
+		t.Errorf("expected a flush after each step, got %v", rw.flushes)
+	}
+
+	rw = &streamrecorder{ResponseRecorder: httptest.NewRecorder()}
+	a.ServeHTTP(rw, httptest.NewRequest("GET", "/hijack", nil))
+	if herr != nil || !rw.hijacked {
+		t.Errorf("expected the connection to be hijacked, got %v", herr)
+	}
+}
+
 func TestSessionExtension(t *testing.T) {
 	app := testApp(t, "testSessionExtension")
 	exp, _ := NewExpectation(
@@ -325,3 +521,228 @@ func TestAddedExtension(t *testing.T) {
 
 	SimplePerformer(t, app, exp).Perform()
 }
+
+func TestFlashesOneRequest(t *testing.T) {
+	a := New("testFlashes", Mode("testing", true))
+	a.GET("/set", func(c Ctx) {
+		Flash(c, "info", "saved")
+		Flash(c, "error", "failed")
+	})
+	a.GET("/noop", func(c Ctx) {})
+	a.GET("/read", func(c Ctx) {
+		c.Call("serveplain", 200, fmt.Sprintf("%v %v", GetFlashes(c, "info"), GetFlashes(c)))
+	})
+
+	client := a.TestClient()
+
+	client.Get("/set")
+	client.Get("/read").AssertBodyContains(t, "map[info:[saved]] map[error:[failed]]")
+	client.Get("/read").AssertBodyContains(t, "map[] map[]")
+
+	client.Get("/set")
+	client.Get("/noop")
+	client.Get("/read").AssertBodyContains(t, "map[] map[]")
+}
+
+type memoryprovider struct {
+	mu       sync.Mutex
+	sessions map[string]*testsession
+}
+
+func (p *memoryprovider) SessionInit(int64, string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sessions = make(map[string]*testsession)
+	return nil
+}
+
+func (p *memoryprovider) SessionRead(sid string) (session.SessionStore, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.sessions[sid]; !ok {
+		p.sessions[sid] = &testsession{sid: sid, values: make(map[interface{}]interface{})}
+	}
+	return p.sessions[sid], nil
+}
+
+func (p *memoryprovider) SessionExist(sid string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.sessions[sid]
+	return ok
+}
+
+func (p *memoryprovider) SessionRegenerate(oldsid, sid string) (session.SessionStore, error) {
+	p.mu.Lock()
+	if s, ok := p.sessions[oldsid]; ok {
+		delete(p.sessions, oldsid)
+		s.sid = sid
+		p.sessions[sid] = s
+	}
+	p.mu.Unlock()
+	return p.SessionRead(sid)
+}
+
+func (p *memoryprovider) SessionDestroy(sid string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.sessions, sid)
+	return nil
+}
+
+func (p *memoryprovider) SessionIDs() ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var ret []string
+	for sid := range p.sessions {
+		ret = append(ret, sid)
+	}
+	return ret, nil
+}
+
+func (p *memoryprovider) SessionAll() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.sessions)
+}
+
+func (p *memoryprovider) SessionGC() {}
+
+func init() {
+	session.Register("flotillamemory", &memoryprovider{})
+}
+
+func memorySessions(a *App) error {
+	m, err := session.NewManager("flotillamemory", `{"cookieName":"session","gclifetime":3600}`)
+	a.Env.SessionManager = m
+	return err
+}
+
+func TestRegenerateSessionOnLogin(t *testing.T) {
+	a := New("testRegenerateSession", Mode("testing", true), memorySessions)
+	a.GET("/cart", func(c Ctx) { c.Call("setsession", "cart", "items") })
+	a.GET("/login", func(c Ctx) { c.Call("setsession", UserSessionKey, "scully") })
+	a.GET("/whoami", func(c Ctx) {
+		s := CurrentSession(c)
+		user, _ := s.GetString(UserSessionKey)
+		cart, _ := s.GetString("cart")
+		c.Call("serveplain", 200, user+" "+cart)
+	})
+
+	client := a.TestClient()
+	client.Get("/cart")
+	before, _ := client.Cookie("session")
+	client.Get("/login")
+	after, _ := client.Cookie("session")
+
+	if before.Value == after.Value {
+		t.Error("session id was not regenerated on login")
+	}
+	if a.SessionManager.GetActiveSession() != 1 {
+		t.Error("session with the previous id was not replaced")
+	}
+	client.Get("/whoami").AssertBodyContains(t, "scully items")
+}
+
+func TestResponseLimits(t *testing.T) {
+	var recorded []string
+	a := New(
+		"testResponseLimits",
+		Mode("testing", true),
+		EnvItem("RESPONSE_MAXSIZE:10"),
+		OnEvent(GotError, func(c Ctx, payload interface{}) {
+			recorded = append(recorded, payload.(error).Error())
+		}),
+	)
+	var errs []error
+	write := func(c Ctx, s string) {
+		rw, _ := c.Call("responsewriter")
+		_, err := rw.(ResponseWriter).Write([]byte(s))
+		errs = append(errs, err)
+	}
+	a.GET("/app", func(c Ctx) { write(c, "12345"); write(c, "123456") })
+	a.GET("/route", MaxResponseSize(3), func(c Ctx) { write(c, "1234") })
+	a.GET("/slow", WriteTimeout(time.Millisecond), func(c Ctx) {
+		time.Sleep(5 * time.Millisecond)
+		write(c, "late")
+	})
+	client := a.TestClient()
+
+	res := client.Get("/app")
+	if string(res.Body) != "12345" || errs[0] != nil || errs[1] == nil || errs[1].Error() != "response exceeds 10 bytes" {
+		t.Errorf("expected the App response limit, got %q %v", res.Body, errs)
+	}
+	if len(recorded) != 1 || recorded[0] != "response exceeds 10 bytes" {
+		t.Errorf("expected the exceeded limit recorded as a Ctx error, got %v", recorded)
+	}
+	errs = nil
+	if res = client.Get("/route"); len(res.Body) != 0 || errs[0] == nil {
+		t.Errorf("expected the route response limit, got %q %v", res.Body, errs)
+	}
+	errs = nil
+	if res = client.Get("/slow"); len(res.Body) != 0 || errs[0] == nil || errs[0].Error() != "response write deadline exceeded" {
+		t.Errorf("expected the write deadline to pass, got %q %v", res.Body, errs)
+	}
+}
+
+func TestAbort(t *testing.T) {
+	a := testApp(t, "abort")
+	var downstream, aborted bool
+	var errs int
+	a.GET("/abort",
+		func(c Ctx) {
+			c.Call("serveplain", 200, "rendered")
+			c.Call("pushfinal", func(c Ctx) { aborted, errs = IsAborted(c), len(c.Errors()) })
+			AbortWithError(c, 401, errors.New("denied"))
+			c.Call("serveplain", 200, "rendered after abort")
+		},
+		func(c Ctx) { downstream = true },
+	)
+	a.Configure()
+
+	rw := httptest.NewRecorder()
+	rq, _ := http.NewRequest("GET", "/abort", nil)
+	a.ServeHTTP(rw, rq)
+	if rw.Code != 401 || rw.Body.String() != "401 Unauthorized" {
+		t.Errorf("expected the 401 status, got %d %q", rw.Code, rw.Body.String())
+	}
+	if downstream {
+		t.Error("a manager ran after Abort")
+	}
+	if !aborted || errs != 1 {
+		t.Errorf("expected an aborted Ctx with 1 error, got %t and %d", aborted, errs)
+	}
+}
+
+func TestTplFxtension(t *testing.T) {
+	tpls := t.TempDir()
+	os.WriteFile(filepath.Join(tpls, "page.html"), []byte(`{{ greeting .Ctx }} {{ current_user .Ctx }} {{ if csp_nonce .Ctx }}nonced{{ end }}`), 0644)
+	greeter := MakeFxtension("greeterfxtension", map[string]interface{}{
+		"greet": func(c *ctx, name string) string { return "hello " + name },
+	}).WithTplFuncs(map[string]interface{}{
+		"greeting": func(c Ctx) string {
+			g, _ := c.Call("greet", "reader")
+			return g.(string)
+		},
+	})
+	a := New(
+		"testTplFxtension",
+		Mode("testing", true),
+		memorySessions,
+		Extensions(greeter),
+		TemplateDriver("html"),
+		EnvItem("TEMPLATE_DIRECTORIES:"+tpls),
+	)
+	a.GET("/login", func(c Ctx) { c.Call("setsession", UserSessionKey, "scully") })
+	a.GET("/page", func(c Ctx) { c.Call("rendertemplate", "page.html", nil) })
+
+	client := a.TestClient()
+	client.Get("/login")
+	client.Get("/page").AssertBodyContains(t, "hello reader scully nonced")
+
+	for _, name := range []string{"greeting", "current_user", "csp_nonce"} {
+		if _, ok := a.Env.TplFuncs()[name]; !ok {
+			t.Errorf("expected template function %q of a Fxtension", name)
+		}
+	}
+}
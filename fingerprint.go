@@ -0,0 +1,157 @@
+package flotilla
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// AssetManifestFile is the name of the manifest written by FingerprintStatic.
+const AssetManifestFile = "manifest.json"
+
+// AssetManifest maps the logical names of static files, slash separated paths
+// relative to their static directory, e.g. "css/site.css", to their
+// fingerprinted names, e.g. "css/site.3f2a1b9c.css".
+type AssetManifest map[string]string
+
+// fingerprinted returns the name with the content hash before its extension.
+func fingerprinted(name string, content []byte) string {
+	sum := sha256.Sum256(content)
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hex.EncodeToString(sum[:4]) + ext
+}
+
+// variantof returns the name of the file a precompressed variant name, e.g.
+// "css/site.css.gz", is of, and its suffix.
+func variantof(name string) (string, string, bool) {
+	for _, p := range precompressed {
+		if strings.HasSuffix(name, p.suffix) {
+			return strings.TrimSuffix(name, p.suffix), p.suffix, true
+		}
+	}
+	return "", "", false
+}
+
+// FingerprintStatic copies each file of the src directory to the dst
+// directory as name.contenthash.ext, with any precompressed variants of a
+// file copied alongside it, and writes the AssetManifest of the copied files
+// to dst as AssetManifestFile, so that fingerprinted files may be cached
+// indefinitely.
+func FingerprintStatic(src, dst string) (AssetManifest, error) {
+	var names []string
+	err := filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err == nil && rel != AssetManifestFile {
+			names = append(names, filepath.ToSlash(rel))
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	have := make(map[string]bool, len(names))
+	for _, name := range names {
+		have[name] = true
+	}
+	copyto := func(to string, content []byte) error {
+		out := filepath.Join(dst, filepath.FromSlash(to))
+		if err := os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(out, content, 0644)
+	}
+
+	m := make(AssetManifest)
+	var variants []string
+	for _, name := range names {
+		if base, _, ok := variantof(name); ok && have[base] {
+			variants = append(variants, name)
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(src, filepath.FromSlash(name)))
+		if err != nil {
+			return nil, err
+		}
+		m[name] = fingerprinted(name, content)
+		if err := copyto(m[name], content); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range variants {
+		base, suffix, _ := variantof(name)
+		content, err := os.ReadFile(filepath.Join(src, filepath.FromSlash(name)))
+		if err != nil {
+			return nil, err
+		}
+		if err := copyto(m[base]+suffix, content); err != nil {
+			return nil, err
+		}
+	}
+
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return nil, err
+	}
+	return m, os.WriteFile(filepath.Join(dst, AssetManifestFile), b, 0644)
+}
+
+// ReadAssetManifest reads the named AssetManifest of the filesystem, e.g.
+// os.DirFS of the dst directory of FingerprintStatic or an embedded
+// filesystem.
+func ReadAssetManifest(fsys fs.FS, name string) (AssetManifest, error) {
+	b, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	m := make(AssetManifest)
+	return m, json.Unmarshal(b, &m)
+}
+
+// WithAssetManifest sets the AssetManifest the "asset" template function
+// resolves logical names with.
+func WithAssetManifest(m AssetManifest) Configuration {
+	return func(a *App) error {
+		a.Env.manifest = m
+		return nil
+	}
+}
+
+// Fingerprint fingerprints the files of the src directory to the dst
+// directory with FingerprintStatic, adding dst to the static directories and
+// using its AssetManifest.
+func Fingerprint(src, dst string) Configuration {
+	return func(a *App) error {
+		m, err := FingerprintStatic(src, dst)
+		if err != nil {
+			return err
+		}
+		a.Env.manifest = m
+		a.StaticDirs(dst)
+		return nil
+	}
+}
+
+// AssetURL returns the url of the static file of the logical name, as the
+// fingerprinted name of the AssetManifest if listed, under STATIC_URL. It is
+// available to templates as "asset", e.g. {{ asset "css/site.css" }}.
+func (env *Env) AssetURL(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if f, ok := env.manifest[name]; ok {
+		name = f
+	}
+	return strings.TrimSuffix(env.Store["STATIC_URL"].Value, "/") + "/" + name
+}
@@ -0,0 +1,49 @@
+package flotilla
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	src, dst, tpls := t.TempDir(), t.TempDir(), t.TempDir()
+	os.MkdirAll(filepath.Join(src, "css"), 0755)
+	os.WriteFile(filepath.Join(src, "css", "site.css"), []byte("body{}"), 0644)
+	os.WriteFile(filepath.Join(src, "css", "site.css.gz"), []byte("gzipped"), 0644)
+	os.WriteFile(filepath.Join(tpls, "page.html"), []byte(`{{ asset "css/site.css" }} {{ asset "js/app.js" }}`), 0644)
+
+	a := New(
+		"testFingerprint",
+		Mode("testing", true),
+		Fingerprint(src, dst),
+		TemplateDriver("html"),
+		EnvItem("TEMPLATE_DIRECTORIES:"+tpls),
+	)
+	a.GET("/page", func(c Ctx) { c.Call("rendertemplate", "page.html", nil) })
+	client := a.TestClient()
+
+	body := string(client.Get("/page").Body)
+	m := regexp.MustCompile(`^(/static/css/site\.[0-9a-f]{8}\.css) /static/js/app\.js$`).FindStringSubmatch(body)
+	if m == nil {
+		t.Fatalf("expected fingerprinted asset urls, got %q", body)
+	}
+
+	if res := client.Get(m[1]); res.Status != 200 || string(res.Body) != "body{}" {
+		t.Errorf("expected the fingerprinted static file served, got %d %q", res.Status, res.Body)
+	}
+	if res := client.Get(m[1], "Accept-Encoding", "gzip"); res.Header.Get("Content-Encoding") != "gzip" || string(res.Body) != "gzipped" {
+		t.Errorf("expected the precompressed variant of the fingerprinted file, got %q", res.Body)
+	}
+
+	manifest, err := ReadAssetManifest(os.DirFS(dst), AssetManifestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := AssetManifest{"css/site.css": m[1][len("/static/"):]}
+	if !reflect.DeepEqual(manifest, expected) {
+		t.Errorf("expected manifest %v, got %v", expected, manifest)
+	}
+}
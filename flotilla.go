@@ -1,8 +1,9 @@
 package flotilla
 
 import (
-	"fmt"
+	stdcontext "context"
 	"net/http"
+	"sync"
 
 	"github.com/thrisp/flotilla/engine"
 )
@@ -14,6 +15,9 @@ type App struct {
 	*Env
 	*Messaging
 	*Blueprint
+	mu         sync.Mutex
+	servers    []*http.Server
+	testclient sync.Once
 }
 
 // Empty returns an App instance with nothing but a name.
@@ -26,7 +30,7 @@ func Base(name string, conf ...Configuration) *App {
 	app := Empty(name)
 	runConf(app, conf...)
 	app.Env = newEnv(app)
-	app.Messaging = newMessaging()
+	app.Messaging = newMessaging(app.Env)
 	runConf(app, configureFirst...)
 	return app
 }
@@ -45,16 +49,69 @@ func (a *App) Name() string {
 }
 
 func (a *App) ServeHTTP(rw http.ResponseWriter, rq *http.Request) {
+	if item, ok := a.Env.Store["METHOD_OVERRIDE"]; ok && item.Value != "" {
+		overridemethod(rq, item.List())
+	}
 	a.Engine.ServeHTTP(rw, rq)
 }
 
-func (a *App) Run(addr string) {
+func (a *App) configured() bool {
+	return a.configure() == nil
+}
+
+// configure configures the App if needed, logging any configuration error.
+func (a *App) configure() error {
 	if !a.Configured {
-		if err := a.Configure(a.Configuration...); err != nil {
-			panic(fmt.Sprintf("[FLOTILLA] app could not be configured properly: %s", err))
+		if err := a.Configure(); err != nil {
+			a.Env.Log().Error("app could not be configured properly", "app", a.name, "error", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// Run configures the App if needed and listens for HTTP connections on the
+// provided address, logging and returning any configuration or listener
+// error.
+func (a *App) Run(addr string) error {
+	if err := a.configure(); err != nil {
+		return err
+	}
+	srv := &http.Server{Addr: addr, Handler: a}
+	return a.listen(srv, srv.ListenAndServe)
+}
+
+// listen runs the listener of srv until it fails or the App is shut down,
+// logging and returning any listener error.
+func (a *App) listen(srv *http.Server, listener func() error, fields ...interface{}) error {
+	a.mu.Lock()
+	a.servers = append(a.servers, srv)
+	a.mu.Unlock()
+	a.Env.Log().Info("listening", append([]interface{}{"app", a.name, "addr", srv.Addr}, fields...)...)
+	err := listener()
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	a.Env.Log().Error("listener stopped", "app", a.name, "error", err)
+	return err
+}
+
+// Shutdown gracefully shuts down any servers started with Run or RunTLS, and
+// stops the session gc of the App.
+func (a *App) Shutdown(ctx stdcontext.Context) error {
+	a.mu.Lock()
+	servers := a.servers
+	a.servers = nil
+	a.mu.Unlock()
+	var err error
+	for _, srv := range servers {
+		if serr := srv.Shutdown(ctx); serr != nil {
+			err = serr
 		}
 	}
-	if err := http.ListenAndServe(addr, a); err != nil {
-		panic(err)
+	a.Env.shutdown()
+	if aerr := a.Messaging.Auditor.Close(); aerr != nil {
+		err = aerr
 	}
+	return err
 }
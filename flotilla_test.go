@@ -1,6 +1,12 @@
 package flotilla
 
-import "testing"
+import (
+	stdcontext "context"
+	"testing"
+	"time"
+
+	"github.com/thrisp/flotilla/session"
+)
 
 func testApp(t *testing.T, name string, conf ...Configuration) *App {
 	conf = append(conf, Mode("testing", true))
@@ -113,3 +119,38 @@ func TestRouteNotOK(t *testing.T) {
 		testRouteNotOK(m, t)
 	}
 }
+
+func TestRunError(t *testing.T) {
+	a := New("testRunError", Mode("testing", true))
+	if err := a.Run("127.0.0.1:-1"); err == nil {
+		t.Error("expected an error from a listener that could not start")
+	}
+	a.Shutdown(stdcontext.Background())
+}
+
+func TestShutdownStopsSessionGC(t *testing.T) {
+	stopped := func(m *session.Manager) bool {
+		ctx, cancel := stdcontext.WithTimeout(stdcontext.Background(), 50*time.Millisecond)
+		defer cancel()
+		m.GCContext(ctx)
+		return ctx.Err() == nil
+	}
+
+	a := New("testShutdownStopsSessionGC", Mode("testing", true))
+	a.Configure()
+	first := a.SessionManager
+	a.SessionManager = nil
+	a.Env.SessionInit()
+	second := a.SessionManager
+	if first == second || !stopped(first) {
+		t.Error("expected session gc of the previous session manager to be stopped")
+	}
+	if stopped(second) {
+		t.Error("expected session gc of the current session manager to run")
+	}
+
+	a.Shutdown(stdcontext.Background())
+	if !stopped(second) {
+		t.Error("expected session gc to be stopped on shutdown")
+	}
+}
@@ -0,0 +1,149 @@
+package flotilla
+
+import (
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxFormIndex limits slice indexes in form value names, so that a request
+// cannot allocate an arbitrarily large slice.
+const maxFormIndex = 1000
+
+// formpath splits a form value name into its segments, by dots and brackets,
+// e.g. "items[0].name" or "items.0.name" into items, 0, and name. An empty
+// bracket, as in "tags[]", adds no segment.
+func formpath(name string) []string {
+	var ret []string
+	for _, part := range strings.Split(strings.Replace(strings.Replace(name, "]", "", -1), "[", ".", -1), ".") {
+		if part != "" {
+			ret = append(ret, part)
+		}
+	}
+	return ret
+}
+
+// decodeform sets the fields of the struct v points to from values, with
+// names of nested struct fields, slice indexes, and map keys separated by
+// dots or brackets, e.g. "address.city", "items[0][name]", or "meta[key]".
+// Fields are named by the tag, or the lower case field name; a tag of "-"
+// skips the field. Repeated values fill a slice of the last segment.
+func decodeform(values url.Values, v interface{}, tag string) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return InvalidBindType(reflect.TypeOf(v))
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		vs := values[name]
+		if len(vs) == 0 {
+			continue
+		}
+		if err := decodepath(rv.Elem(), formpath(name), vs, tag, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodepath(rv reflect.Value, path []string, vs []string, tag, field string) error {
+	if rv.Kind() == reflect.Ptr {
+		if len(path) == 0 {
+			return decodevalue(rv, vs, field)
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return decodepath(rv.Elem(), path, vs, tag, field)
+	}
+	if len(path) == 0 || rv.Type() == timeType {
+		if len(path) != 0 {
+			return nil
+		}
+		return decodevalue(rv, vs, field)
+	}
+	switch rv.Kind() {
+	case reflect.Struct:
+		fv, name, ok := formfield(rv, path[0], tag)
+		if !ok {
+			return nil
+		}
+		return decodepath(fv, path[1:], vs, tag, joinfield(field, name))
+	case reflect.Slice:
+		i, err := strconv.Atoi(path[0])
+		if err != nil || i < 0 || i >= maxFormIndex {
+			return nil
+		}
+		for rv.Len() <= i {
+			rv.Set(reflect.Append(rv, reflect.Zero(rv.Type().Elem())))
+		}
+		return decodepath(rv.Index(i), path[1:], vs, tag, joinfield(field, path[0]))
+	case reflect.Map:
+		key := reflect.New(rv.Type().Key()).Elem()
+		if err := setvalue(key, path[0]); err != nil {
+			return &BindError{Field: field, Value: path[0], Err: err}
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMap(rv.Type()))
+		}
+		elem := reflect.New(rv.Type().Elem()).Elem()
+		if existing := rv.MapIndex(key); existing.IsValid() {
+			elem.Set(existing)
+		}
+		if err := decodepath(elem, path[1:], vs, tag, joinfield(field, path[0])); err != nil {
+			return err
+		}
+		rv.SetMapIndex(key, elem)
+	}
+	return nil
+}
+
+func decodevalue(fv reflect.Value, vs []string, field string) error {
+	if err := setfield(fv, vs); err != nil {
+		return &BindError{Field: field, Value: strings.Join(vs, ","), Err: err}
+	}
+	return nil
+}
+
+func joinfield(field, name string) string {
+	if field == "" {
+		return name
+	}
+	return field + "." + name
+}
+
+// formfield returns the field of the struct named by the tag or lower case
+// field name, searching embedded structs, and the field name.
+func formfield(rv reflect.Value, name, tag string) (reflect.Value, string, bool) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		fv := rv.Field(i)
+		if f.Anonymous && fv.Kind() == reflect.Struct {
+			if ret, fname, ok := formfield(fv, name, tag); ok {
+				return ret, fname, true
+			}
+			continue
+		}
+		n := strings.Split(f.Tag.Get(tag), ",")[0]
+		if n == "-" {
+			continue
+		}
+		if n == "" {
+			n = strings.ToLower(f.Name)
+		}
+		if n == name {
+			return fv, f.Name, true
+		}
+	}
+	return reflect.Value{}, "", false
+}
@@ -0,0 +1,66 @@
+package flotilla
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+)
+
+type formaddress struct {
+	City string
+	Zip  int `form:"zip"`
+}
+
+type formitem struct {
+	Name string
+	Qty  int
+}
+
+type formtarget struct {
+	Name    string
+	Address formaddress
+	Billing *formaddress
+	Items   []formitem
+	Tags    []string
+	Meta    map[string]string
+	Counts  map[string]int
+}
+
+func TestDecodeForm(t *testing.T) {
+	values := url.Values{
+		"name":            {"flotilla"},
+		"address.city":    {"Boston"},
+		"address[zip]":    {"2110"},
+		"billing.city":    {"Salem"},
+		"items[1].name":   {"second"},
+		"items[0][name]":  {"first"},
+		"items.0.qty":     {"2"},
+		"tags[]":          {"a", "b"},
+		"meta[color]":     {"blue"},
+		"counts[x]":       {"3"},
+		"items[5000].qty": {"1"},
+		"unknown.field":   {"x"},
+	}
+	var v formtarget
+	if err := decodeform(values, &v, "form"); err != nil {
+		t.Fatal(err)
+	}
+	if v.Name != "flotilla" || v.Address.City != "Boston" || v.Address.Zip != 2110 {
+		t.Errorf("unexpected nested struct values %+v", v)
+	}
+	if v.Billing == nil || v.Billing.City != "Salem" {
+		t.Errorf("unexpected pointer struct values %+v", v.Billing)
+	}
+	if len(v.Items) != 2 || v.Items[0].Name != "first" || v.Items[0].Qty != 2 || v.Items[1].Name != "second" {
+		t.Errorf("unexpected slice values %+v", v.Items)
+	}
+	if len(v.Tags) != 2 || v.Meta["color"] != "blue" || v.Counts["x"] != 3 {
+		t.Errorf("unexpected slice or map values %+v %+v %+v", v.Tags, v.Meta, v.Counts)
+	}
+
+	err := decodeform(url.Values{"items[0].qty": {"many"}}, &v, "form")
+	var be *BindError
+	if !errors.As(err, &be) || be.Field != "Items.0.Qty" {
+		t.Errorf("expected a BindError for Items.0.Qty, got %v", err)
+	}
+}
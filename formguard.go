@@ -0,0 +1,205 @@
+package flotilla
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thrisp/flotilla/xrr"
+)
+
+const (
+	FormTimeField    = "_formtime"
+	CaptchaFormField = "captcha_response"
+	formguardData    = "formguard"
+)
+
+// CaptchaVerifier verifies a CAPTCHA response with a provider, given the
+// response submitted with a form and the client ip.
+type CaptchaVerifier interface {
+	VerifyCaptcha(response, remoteip string) (bool, error)
+}
+
+// CaptchaFunc is a CaptchaVerifier backed by a function.
+type CaptchaFunc func(string, string) (bool, error)
+
+func (fn CaptchaFunc) VerifyCaptcha(response, remoteip string) (bool, error) {
+	return fn(response, remoteip)
+}
+
+// FormGuard rejects automated form submissions. A non-empty Honeypot field, a
+// form submitted sooner than MinSubmit after being rendered, or a failed
+// Captcha verification all reject the submission. A FormGuard runs as route
+// middleware with Manage, or when binding forms, with Guard or FormGuarded.
+type FormGuard struct {
+	Honeypot     string
+	MinSubmit    time.Duration
+	Captcha      CaptchaVerifier
+	CaptchaField string
+}
+
+// GuardError is returned binding a form rejected by a FormGuard.
+type GuardError struct {
+	Err error
+}
+
+func (e *GuardError) Error() string {
+	return fmt.Sprintf("form rejected: %s", e.Err)
+}
+
+func (e *GuardError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	HoneypotFilled      = xrr.NewXrror("form honeypot field %s was filled").Out
+	InvalidFormTime     = xrr.NewXrror("form render time is missing or invalid").Out
+	SubmittedTooQuickly = xrr.NewXrror("form submitted %s after render, minimum is %s").Out
+	CaptchaFailed       = xrr.NewXrror("captcha verification failed").Out
+	NoFormTimeKey       = xrr.NewXrror("form render times are not signed with the default secret key, set SECRET_KEYS").Out
+)
+
+func formtimesignature(secret, ts string) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(ts))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// formtimekeys returns the keys signing form render times, derived from the
+// KeyRing of the App, or nil for a KeyRing of only the DefaultSecretKey.
+func formtimekeys(c Ctx) *KeyRing {
+	ring := keyring(ctxlookup(c))
+	if ring.Default() {
+		return nil
+	}
+	return ring.Derive("flotilla form time")
+}
+
+// FormTimestamp returns a render timestamp signed with the current key of the
+// App KeyRing, to be included in a form as the hidden field FormTimeField
+// when a FormGuard uses MinSubmit. Apps with only the DefaultSecretKey can not
+// sign timestamps, and return an empty string.
+func FormTimestamp(c Ctx) string {
+	ring := formtimekeys(c)
+	if ring == nil {
+		return ""
+	}
+	_, key, ok := ring.Current()
+	if !ok {
+		return ""
+	}
+	ts := strconv.FormatInt(CurrentTime(c).UnixNano(), 10)
+	return ts + "|" + formtimesignature(key, ts)
+}
+
+// formrendered returns the render time of a timestamp signed with any key of
+// the ring.
+func formrendered(ring *KeyRing, value string) (time.Time, bool) {
+	parts := strings.SplitN(value, "|", 2)
+	if len(parts) != 2 {
+		return time.Time{}, false
+	}
+	valid := false
+	for _, key := range ring.Keys() {
+		if hmac.Equal([]byte(parts[1]), []byte(formtimesignature(key, parts[0]))) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return time.Time{}, false
+	}
+	ns, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, ns), true
+}
+
+// Check returns an error if the current request form fails any guard.
+func (g *FormGuard) Check(c Ctx) error {
+	rq := CurrentRequest(c)
+	if g.Honeypot != "" && rq.FormValue(g.Honeypot) != "" {
+		return HoneypotFilled(g.Honeypot)
+	}
+	if g.MinSubmit > 0 {
+		ring := formtimekeys(c)
+		if ring == nil {
+			return NoFormTimeKey()
+		}
+		rendered, ok := formrendered(ring, rq.FormValue(FormTimeField))
+		if !ok {
+			return InvalidFormTime()
+		}
+		if elapsed := CurrentTime(c).Sub(rendered); elapsed < g.MinSubmit {
+			return SubmittedTooQuickly(elapsed, g.MinSubmit)
+		}
+	}
+	if g.Captcha != nil {
+		field := g.CaptchaField
+		if field == "" {
+			field = CaptchaFormField
+		}
+		ip, _, err := net.SplitHostPort(rq.RemoteAddr)
+		if err != nil {
+			ip = rq.RemoteAddr
+		}
+		ok, err := g.Captcha.VerifyCaptcha(rq.FormValue(field), ip)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return CaptchaFailed()
+		}
+	}
+	return nil
+}
+
+// Manage is a flotilla.Manage function rejecting guarded form submissions
+// with a 400 status.
+func (g *FormGuard) Manage(c Ctx) {
+	if err := g.Check(c); err != nil {
+		Audit(c, AuditPermissionDenied, map[string]string{"reason": err.Error()})
+		c.Call("abort", 400)
+	}
+}
+
+// Guard is a flotilla.Manage function checking forms bound with Bind or
+// BindForm on the route against the FormGuard, in place of any FormGuarded
+// guard of the App.
+func (g *FormGuard) Guard(c Ctx) {
+	c.Call("set", formguardData, g)
+}
+
+// FormGuarded is a Configuration checking forms bound with Bind or BindForm on
+// every route against the FormGuard.
+func FormGuarded(g *FormGuard) Configuration {
+	return func(a *App) error {
+		a.Env.formguard = g
+		return nil
+	}
+}
+
+// guardformfunc checks the bound form against the route or App FormGuard,
+// returning a GuardError for a rejected form.
+func guardformfunc(a *App) func(*ctx) error {
+	return func(c *ctx) error {
+		g, ok := c.Data[formguardData].(*FormGuard)
+		if !ok {
+			g = a.Env.formguard
+		}
+		if g == nil {
+			return nil
+		}
+		if err := g.Check(c); err != nil {
+			Audit(c, AuditPermissionDenied, map[string]string{"reason": err.Error()})
+			return &GuardError{Err: err}
+		}
+		return nil
+	}
+}
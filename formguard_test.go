@@ -0,0 +1,120 @@
+package flotilla
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func formbody(v url.Values) func(*testing.T, *http.Request) {
+	return func(t *testing.T, r *http.Request) {
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.Body = ioutil.NopCloser(strings.NewReader(v.Encode()))
+	}
+}
+
+func TestFormGuard(t *testing.T) {
+	var stamp string
+
+	captcha := CaptchaFunc(func(response, ip string) (bool, error) {
+		return response == "human", nil
+	})
+
+	g := &FormGuard{Honeypot: "website", MinSubmit: 10 * time.Millisecond, Captcha: captcha}
+
+	a := testApp(t, "testFormGuard", EnvItem("SECRET_KEYS:new,old"))
+
+	a.GET("/form", func(c Ctx) { stamp = FormTimestamp(c) })
+	a.POST("/form", g.Manage, func(c Ctx) { c.Call("serveplain", 200, "accepted") })
+
+	ZeroExpectationPerformer(t, a, 200, "GET", "/form").Perform()
+
+	submit := func(code int, v url.Values) {
+		exp, _ := NoTanage(code, "POST", "/form")
+		exp.SetPre(formbody(v))
+		SimplePerformer(t, a, exp).Perform()
+	}
+
+	submit(400, url.Values{FormTimeField: {stamp}, CaptchaFormField: {"human"}})
+
+	time.Sleep(20 * time.Millisecond)
+
+	submit(200, url.Values{FormTimeField: {stamp}, CaptchaFormField: {"human"}})
+	submit(400, url.Values{FormTimeField: {stamp}, CaptchaFormField: {"human"}, "website": {"spam.example"}})
+	submit(400, url.Values{FormTimeField: {stamp}, CaptchaFormField: {"robot"}})
+	submit(400, url.Values{FormTimeField: {"12345|forged"}, CaptchaFormField: {"human"}})
+
+	old := testApp(t, "testFormGuardOld", EnvItem("SECRET_KEYS:old"))
+	old.GET("/form", func(c Ctx) { stamp = FormTimestamp(c) })
+	ZeroExpectationPerformer(t, old, 200, "GET", "/form").Perform()
+	time.Sleep(20 * time.Millisecond)
+	submit(200, url.Values{FormTimeField: {stamp}, CaptchaFormField: {"human"}})
+
+	forged := "1|" + formtimesignature(NewKeyRing(DefaultSecretKey).Derive("flotilla form time").Keys()[0], "1")
+	submit(400, url.Values{FormTimeField: {forged}, CaptchaFormField: {"human"}})
+
+	d := testApp(t, "testFormGuardDefault")
+	d.GET("/form", func(c Ctx) { stamp = FormTimestamp(c) })
+	d.POST("/form", g.Manage, func(c Ctx) { c.Call("serveplain", 200, "accepted") })
+	ZeroExpectationPerformer(t, d, 200, "GET", "/form").Perform()
+	if stamp != "" {
+		t.Errorf("a form time was signed with the default secret key: %q", stamp)
+	}
+	exp, _ := NoTanage(400, "POST", "/form")
+	exp.SetPre(formbody(url.Values{FormTimeField: {forged}, CaptchaFormField: {"human"}}))
+	SimplePerformer(t, d, exp).Perform()
+}
+
+func TestFormGuardBind(t *testing.T) {
+	type signup struct {
+		Name string `form:"name" validate:"required"`
+	}
+
+	var stamp string
+
+	g := &FormGuard{Honeypot: "website", MinSubmit: 10 * time.Millisecond}
+	route := &FormGuard{Honeypot: "url"}
+
+	a := testApp(t, "testFormGuardBind", EnvItem("SECRET_KEYS:guard"), FormGuarded(g))
+
+	bound := func(c Ctx) {
+		var s signup
+		err := Bind(c, &s)
+		var ge *GuardError
+		switch {
+		case errors.As(err, &ge):
+			c.Call("serveplain", 400, ge.Error())
+		case err != nil:
+			c.Call("serveplain", 422, err.Error())
+		default:
+			c.Call("serveplain", 200, s.Name)
+		}
+	}
+
+	a.GET("/form", func(c Ctx) { stamp = FormTimestamp(c) })
+	a.POST("/signup", bound)
+	a.POST("/comment", route.Guard, bound)
+
+	client := a.TestClient()
+	client.Get("/form")
+
+	expect := func(path string, code int, v url.Values) {
+		if rsp := client.PostForm(path, v); rsp.Status != code {
+			t.Errorf("POST %s %v: expected %d, got %d %s", path, v, code, rsp.Status, rsp.Body)
+		}
+	}
+
+	expect("/signup", 400, url.Values{FormTimeField: {stamp}, "name": {"early"}})
+
+	time.Sleep(20 * time.Millisecond)
+
+	expect("/signup", 200, url.Values{FormTimeField: {stamp}, "name": {"human"}})
+	expect("/signup", 400, url.Values{FormTimeField: {stamp}, "name": {"bot"}, "website": {"spam.example"}})
+	expect("/signup", 422, url.Values{FormTimeField: {stamp}})
+	expect("/comment", 200, url.Values{"name": {"human"}, "website": {"ignored"}})
+	expect("/comment", 400, url.Values{"name": {"bot"}, "url": {"spam.example"}})
+}
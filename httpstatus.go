@@ -51,6 +51,7 @@ pre p:nth-child(even){background-color: rgba(216,216,216,0.25); margin: 0;}
 type (
 	status struct {
 		code     int
+		custom   bool
 		managers []Manage
 	}
 )
@@ -110,13 +111,16 @@ func IsWritten(c Ctx) bool {
 	return ret.(bool)
 }
 
+// panics renders the panics of the ctx with their stacks in Development mode,
+// unless the 500 status has custom managers, and signals each panic.
 func (s status) panics(c Ctx) {
-	if s.code == 500 && !IsWritten(c) {
-		if !CurrentMode(c).Production {
-			panicserve(c, panictobuffer(c))
-			panicsignal(c)
-		}
+	if s.code != 500 {
+		return
+	}
+	if m := CurrentMode(c); m.Development && !m.Production && !s.custom && !IsWritten(c) {
+		panicserve(c, panictobuffer(c))
 	}
+	panicsignal(c)
 }
 
 func (s status) last(c Ctx) {
@@ -134,6 +138,7 @@ func StatusRule(a *App) engine.Rule {
 		c := NewCtx(a.fxtensions, rs)
 		c.reset(rq, rw, s.managers)
 		c.Run()
+		a.Env.Events.recovered(c)
 		c.Cancel()
 	}
 }
@@ -143,17 +148,23 @@ func StatusRule(a *App) engine.Rule {
 func CustomStatusRule(a *App, code int, m ...Manage) engine.Rule {
 	s := newStatus(code, m...)
 	a.CustomStatus(s)
+	return statusrule(a, s)
+}
+
+// statusrule returns an engine Rule responding with the status s.
+func statusrule(a *App, s *status) engine.Rule {
 	return func(rw http.ResponseWriter, rq *http.Request, rs *engine.Result) {
 		c := NewCtx(a.fxtensions, rs)
 		c.reset(rq, rw, s.managers)
 		c.Call("start", a.SessionManager)
 		c.Run()
+		a.Env.Events.recovered(c)
 		c.Cancel()
 	}
 }
 
 func newStatus(code int, m ...Manage) *status {
-	s := &status{code: code}
+	s := &status{code: code, custom: len(m) > 0}
 	s.managers = []Manage{s.first}
 	s.managers = append(s.managers, m...)
 	s.managers = append(s.managers, s.panics, s.last)
@@ -3,6 +3,7 @@ package flotilla
 import (
 	"bytes"
 	"fmt"
+	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
@@ -125,3 +126,59 @@ func TestCustomStatus(t *testing.T) {
 		customStatus(t, m, "I AM TEAPOT :: 418", 418, Custom418)
 	}
 }
+
+func TestPanicRecovery(t *testing.T) {
+	a := testApp(t, "panicrecovery")
+	var finished bool
+	a.GET("/handler", func(c Ctx) {
+		c.Call("pushfinal", func(Ctx) { finished = true })
+		panic("handler panic")
+	})
+	a.GET("/deferred", func(c Ctx) {
+		c.Call("push", func(Ctx) { panic("deferred panic") })
+	})
+	client := a.TestClient()
+
+	res := client.Get("/handler")
+	if body := string(res.Body); res.Status != 500 || !strings.Contains(body, "handler panic") || !strings.Contains(body, "httpstatus_test.go") {
+		t.Errorf("expected a 500 with the panic stack, got %d %q", res.Status, body)
+	}
+	if !finished {
+		t.Error("final functions did not run after a handler panic")
+	}
+
+	res = client.Get("/deferred")
+	if res.Status != 500 || !strings.Contains(string(res.Body), "deferred panic") {
+		t.Errorf("expected a 500 for a deferred panic, got %d %q", res.Status, res.Body)
+	}
+
+}
+
+func TestPanicCustomStatus(t *testing.T) {
+	a := testApp(t, "paniccustomstatus")
+	a.GET("/handler", func(c Ctx) { panic("handler panic") })
+	a.STATUS(500, func(c Ctx) {
+		c.Call("serveplain", 500, fmt.Sprintf("recovered %d", len(Panics(c))))
+	})
+	a.Configure()
+
+	rw := httptest.NewRecorder()
+	rq, _ := http.NewRequest("GET", "/handler", nil)
+	a.ServeHTTP(rw, rq)
+	if rw.Code != 500 || rw.Body.String() != "recovered 1" {
+		t.Errorf("expected the custom 500 status, got %d %q", rw.Code, rw.Body.String())
+	}
+}
+
+func TestPanicProduction(t *testing.T) {
+	a := testApp(t, "panicproduction", Mode("production", true))
+	a.GET("/handler", func(c Ctx) { panic("hidden panic") })
+	a.Configure()
+
+	rw := httptest.NewRecorder()
+	rq, _ := http.NewRequest("GET", "/handler", nil)
+	a.ServeHTTP(rw, rq)
+	if rw.Code != 500 || strings.Contains(rw.Body.String(), "hidden panic") {
+		t.Errorf("expected a 500 without the panic in production, got %d %q", rw.Code, rw.Body.String())
+	}
+}
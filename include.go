@@ -0,0 +1,120 @@
+package flotilla
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"github.com/thrisp/flotilla/engine"
+	"github.com/thrisp/flotilla/xrr"
+)
+
+var NoRoute = xrr.NewXrror("no route named %s").Out
+
+// Captured is the response of a route run with Include, held in memory
+// instead of written to the client.
+type Captured struct {
+	Status int
+	Body   bytes.Buffer
+	header http.Header
+}
+
+func newCaptured() *Captured {
+	return &Captured{Status: http.StatusOK, header: make(http.Header)}
+}
+
+func (w *Captured) Header() http.Header {
+	return w.header
+}
+
+func (w *Captured) WriteHeader(code int) {
+	w.Status = code
+}
+
+func (w *Captured) Write(p []byte) (int, error) {
+	return w.Body.Write(p)
+}
+
+// routeparams returns the params of the route path matched by the url path.
+func routeparams(route, path string) engine.Params {
+	var ret engine.Params
+	rs, ps := strings.Split(route, "/"), strings.Split(path, "/")
+	for i, r := range rs {
+		if i >= len(ps) {
+			break
+		}
+		switch {
+		case strings.HasPrefix(r, ":"):
+			key, _ := engine.SplitParam(r[1:])
+			ret = append(ret, engine.Param{Key: key, Value: ps[i]})
+		case strings.HasPrefix(r, "*"):
+			ret = append(ret, engine.Param{Key: r[1:], Value: "/" + strings.Join(ps[i:], "/")})
+			return ret
+		}
+	}
+	return ret
+}
+
+// include runs the managers of rt in a replica of the ctx, sharing its
+// session, with a request for the route url and a copy of its Data, capturing
+// the response. Session release and the instrumentation of the ctx are left
+// to the including ctx.
+func (c *ctx) include(rt *Route, params []string) (*Captured, error) {
+	u, err := rt.Url(params...)
+	if err != nil {
+		return nil, err
+	}
+	sub := c.replicate()
+	defer sub.context.cancel(true, Canceled)
+	sub.context.value = sub
+
+	rq := c.Request.Clone(sub.context)
+	rq.Method = rt.Method
+	rq.URL.Path, rq.URL.RawPath, rq.URL.RawQuery = u.Path, "", u.RawQuery
+	rq.RequestURI = u.String()
+	rq.Body, rq.ContentLength = http.NoBody, 0
+	sub.Request = rq
+
+	w := newCaptured()
+	sub.rw = responseWriter{}
+	sub.rw.reset(w)
+	sub.RW = &sub.rw
+	sub.Result = engine.NewResult(http.StatusOK, nil, routeparams(rt.Path, u.Path), false)
+	sub.Xrroror = xrr.NewXrroror()
+	sub.handlers = defaulthandlers()
+	sub.managers = rt.Managers
+	sub.route = rt
+	sub.Data = make(map[string]interface{}, len(c.Data))
+	for k, v := range c.Data {
+		sub.Data[k] = v
+	}
+	delete(sub.Data, rawbodyData)
+	if e, ok := c.Extensor.(*extensor); ok {
+		sub.Extensor = &extensor{ext: e.ext, ctx: sub}
+	}
+	sub.run()
+	sub.RW.WriteHeaderNow()
+	return w, nil
+}
+
+func includefunc(a *App) func(*ctx, string, []string) (*Captured, error) {
+	return func(c *ctx, route string, params []string) (*Captured, error) {
+		rt, ok := a.Env.Reverse.Lookup(a.Routes(), route)
+		if !ok {
+			return nil, NoRoute(route)
+		}
+		return c.include(rt, params)
+	}
+}
+
+// Include runs the managers of the named route, with the provided params as
+// for UrlFor, inside the Ctx and captures the response instead of writing it
+// to the client, e.g. for server side includes or internal redirects. The
+// route shares the session of the Ctx and receives a copy of its Data.
+func Include(c Ctx, route string, params ...string) (*Captured, error) {
+	ret, err := c.Call("include", route, params)
+	if err != nil {
+		return nil, err
+	}
+	return ret.(*Captured), nil
+}
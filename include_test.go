@@ -0,0 +1,41 @@
+package flotilla
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestInclude(t *testing.T) {
+	a := New("testInclude", Mode("testing", true), RouteName("item", "get", "/items/:id"), RouteName("broken", "get", "/broken"))
+	a.GET("/items/:id", func(c Ctx) {
+		id, _ := c.Call("paramString", "id")
+		c.Call("headerwrite", -1, []string{"X-Item", id.(string)})
+		c.Call("serveplain", 201, "item "+id.(string)+" "+GetString(c, "user"))
+	})
+	a.GET("/broken", func(c Ctx) { panic("broken include") })
+	var included, broken *Captured
+	var ierr, merr error
+	a.GET("/page", func(c Ctx) {
+		Set(c, "user", "flo")
+		included, ierr = Include(c, "item", "5")
+		broken, _ = Include(c, "broken")
+		_, merr = Include(c, "missing")
+		c.Call("serveplain", 200, "page:"+included.Body.String())
+	})
+	a.Configure()
+
+	rw := httptest.NewRecorder()
+	a.ServeHTTP(rw, httptest.NewRequest("GET", "/page", nil))
+	if ierr != nil || included.Status != 201 || included.Header().Get("X-Item") != "5" {
+		t.Errorf("unexpected included response %v %+v", ierr, included)
+	}
+	if rw.Code != 200 || rw.Body.String() != "page:item 5 flo" {
+		t.Errorf("unexpected page response %d %q", rw.Code, rw.Body.String())
+	}
+	if broken == nil || broken.Status != 500 {
+		t.Errorf("expected a captured 500 for a panicking route, got %+v", broken)
+	}
+	if merr == nil {
+		t.Error("expected an error including an unknown route")
+	}
+}
@@ -0,0 +1,159 @@
+// Package redis is a minimal Redis client of the commands used by the
+// flotilla response cache and session locks.
+package redis
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// Client sends commands to the Redis server at Addr, keeping a small pool of
+// connections.
+type Client struct {
+	Addr     string
+	Password string
+	DB       int
+	Timeout  time.Duration
+	pool     chan *Conn
+}
+
+// NewClient returns a Client for the Redis server at addr.
+func NewClient(addr, password string, db int) *Client {
+	return &Client{
+		Addr:     addr,
+		Password: password,
+		DB:       db,
+		Timeout:  5 * time.Second,
+		pool:     make(chan *Conn, 8),
+	}
+}
+
+// Conn is a connection to a Redis server, or from a Redis client for a fake
+// server in tests.
+type Conn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// NewConn returns a Conn of conn.
+func NewConn(conn net.Conn) *Conn {
+	return &Conn{Conn: conn, r: bufio.NewReader(conn)}
+}
+
+func (c *Client) dial() (*Conn, error) {
+	conn, err := net.DialTimeout("tcp", c.Addr, c.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	rc := NewConn(conn)
+	if c.Password != "" {
+		if _, err := rc.Do(c.Timeout, "AUTH", c.Password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if c.DB != 0 {
+		if _, err := rc.Do(c.Timeout, "SELECT", strconv.Itoa(c.DB)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return rc, nil
+}
+
+// Do sends the command args to the server, returning its reply: a string,
+// int64, []byte, []interface{}, or nil, or an Error.
+func (c *Client) Do(args ...string) (interface{}, error) {
+	var rc *Conn
+	select {
+	case rc = <-c.pool:
+	default:
+		var err error
+		if rc, err = c.dial(); err != nil {
+			return nil, err
+		}
+	}
+	reply, err := rc.Do(c.Timeout, args...)
+	if _, rerr := err.(Error); err != nil && !rerr {
+		rc.Close()
+		return nil, err
+	}
+	select {
+	case c.pool <- rc:
+	default:
+		rc.Close()
+	}
+	return reply, err
+}
+
+// Error is an error reply of the server.
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+// Do sends the command args, returning the reply.
+func (c *Conn) Do(timeout time.Duration, args ...string) (interface{}, error) {
+	c.SetDeadline(time.Now().Add(timeout))
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := c.Write(b.Bytes()); err != nil {
+		return nil, err
+	}
+	return c.Reply()
+}
+
+var ProtocolError = errors.New("redis: invalid reply")
+
+// Reply reads a reply, or a command sent to a fake server.
+func (c *Conn) Reply() (interface{}, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 {
+		return nil, ProtocolError
+	}
+	line = line[:len(line)-2]
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, Error(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = c.Reply(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, ProtocolError
+}
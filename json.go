@@ -0,0 +1,117 @@
+package flotilla
+
+import (
+	"encoding/json"
+	"io"
+	"regexp"
+)
+
+// jsonsettings are the JSON_PRETTY printing of rendered JSON, by default in
+// Development mode, the JSON_PREFIX written before JSON to prevent hijacking,
+// e.g. ")]}',\n", and the JSON_CALLBACK query parameter naming a JSONP
+// callback.
+type jsonsettings struct {
+	pretty   bool
+	prefix   string
+	callback string
+}
+
+func jsonconfig(c *ctx) *jsonsettings {
+	s := &jsonsettings{pretty: CurrentMode(c).Development, callback: "callback"}
+	if item, ok := CheckStore(c, "JSON_PRETTY"); ok && item.Value != "" {
+		s.pretty = item.Bool()
+	}
+	if item, ok := CheckStore(c, "JSON_PREFIX"); ok {
+		s.prefix = item.Value
+	}
+	if item, ok := CheckStore(c, "JSON_CALLBACK"); ok && item.Value != "" {
+		s.callback = item.Value
+	}
+	return s
+}
+
+// prefixwriter writes a prefix before the first write to its Writer, so that
+// nothing is written for data failing to encode.
+type prefixwriter struct {
+	io.Writer
+	prefix  string
+	written bool
+}
+
+func (w *prefixwriter) Write(p []byte) (int, error) {
+	if !w.written {
+		w.written = true
+		if _, err := io.WriteString(w.Writer, w.prefix); err != nil {
+			return 0, err
+		}
+	}
+	return w.Writer.Write(p)
+}
+
+// encodejson streams data to the response with a json.Encoder, after the
+// prefix, or responds with a 500 status if data cannot be encoded.
+func encodejson(c *ctx, s *jsonsettings, code int, contenttype, prefix, suffix string, data interface{}) {
+	headerwrite(c, code, []string{"Content-Type", contenttype})
+	w := &prefixwriter{Writer: c.RW, prefix: prefix}
+	enc := json.NewEncoder(w)
+	if s.pretty {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(data); err != nil {
+		CurrentLogger(c).Error("json encoding failed", "error", err)
+		if !w.written {
+			c.RW.WriteHeader(500)
+			c.RW.WriteHeaderNow()
+		}
+		return
+	}
+	io.WriteString(c.RW, suffix)
+}
+
+func renderjson(c *ctx, code int, data interface{}) error {
+	s := jsonconfig(c)
+	c.push(func(pc Ctx) {
+		encodejson(c, s, code, "application/json; charset=utf-8", s.prefix, "", data)
+	})
+	return nil
+}
+
+var jsonpcallback = regexp.MustCompile(`^[a-zA-Z_$][0-9a-zA-Z_$.]*$`)
+
+// renderjsonp renders data as a call of the JSONP callback named by the
+// request, or as JSON without a valid callback.
+func renderjsonp(c *ctx, code int, data interface{}) error {
+	s := jsonconfig(c)
+	callback := c.Request.URL.Query().Get(s.callback)
+	if !jsonpcallback.MatchString(callback) {
+		return renderjson(c, code, data)
+	}
+	c.push(func(pc Ctx) {
+		c.RW.Header().Set("X-Content-Type-Options", "nosniff")
+		encodejson(c, s, code, "application/javascript; charset=utf-8", "/**/"+callback+"(", ");", data)
+	})
+	return nil
+}
+
+var jsonfxtension = map[string]interface{}{
+	"json":  renderjson,
+	"jsonp": renderjsonp,
+}
+
+// JSONFxtension renders JSON responses, pretty printed in Development mode
+// or by the JSON_PRETTY setting, after any JSON_PREFIX.
+var JSONFxtension Fxtension = MakeFxtension("jsonfxtension", jsonfxtension)
+
+// JSON renders data as a JSON response with the status code.
+func JSON(c Ctx, code int, data interface{}) error {
+	_, err := c.Call("json", code, data)
+	return err
+}
+
+// JSONP renders data as a call of the callback named by the JSON_CALLBACK
+// query parameter, "callback" by default, or as JSON if the request names no
+// valid callback.
+func JSONP(c Ctx, code int, data interface{}) error {
+	_, err := c.Call("jsonp", code, data)
+	return err
+}
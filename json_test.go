@@ -0,0 +1,43 @@
+package flotilla
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	data := map[string]interface{}{"name": "one"}
+	render := func(conf []Configuration, path string, fn func(Ctx)) *httptest.ResponseRecorder {
+		a := New("testJSON", append([]Configuration{Mode("testing", true)}, conf...)...)
+		a.GET("/json", fn)
+		a.Configure()
+		rw := httptest.NewRecorder()
+		a.ServeHTTP(rw, httptest.NewRequest("GET", path, nil))
+		return rw
+	}
+
+	rw := render(nil, "/json", func(c Ctx) { JSON(c, 201, data) })
+	if rw.Code != 201 || rw.Body.String() != "{\n  \"name\": \"one\"\n}\n" {
+		t.Errorf("expected pretty printed JSON in development mode, got %d %q", rw.Code, rw.Body.String())
+	}
+
+	rw = render([]Configuration{EnvItem("JSON_PRETTY:false", "JSON_PREFIX:)]}',")}, "/json", func(c Ctx) { JSON(c, 200, data) })
+	if rw.Body.String() != ")]}',{\"name\":\"one\"}\n" {
+		t.Errorf("expected prefixed compact JSON, got %q", rw.Body.String())
+	}
+
+	rw = render([]Configuration{EnvItem("JSON_PRETTY:false")}, "/json?callback=app.load", func(c Ctx) { JSONP(c, 200, data) })
+	if rw.Header().Get("Content-Type") != "application/javascript; charset=utf-8" || rw.Body.String() != "/**/app.load({\"name\":\"one\"}\n);" {
+		t.Errorf("unexpected JSONP response %q %q", rw.Header().Get("Content-Type"), rw.Body.String())
+	}
+
+	rw = render([]Configuration{EnvItem("JSON_PRETTY:false")}, "/json?callback=alert(1)", func(c Ctx) { JSONP(c, 200, data) })
+	if rw.Header().Get("Content-Type") != "application/json; charset=utf-8" {
+		t.Errorf("expected JSON for an invalid callback, got %q", rw.Body.String())
+	}
+
+	rw = render(nil, "/json", func(c Ctx) { JSON(c, 200, func() {}) })
+	if rw.Code != 500 || rw.Body.Len() != 0 {
+		t.Errorf("expected a 500 status for data failing to encode, got %d %q", rw.Code, rw.Body.String())
+	}
+}
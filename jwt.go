@@ -0,0 +1,268 @@
+package flotilla
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/thrisp/flotilla/xrr"
+)
+
+const jwtData = "jwt"
+
+// Claims are the claims of a JSON web token.
+type Claims map[string]interface{}
+
+// Subject returns the "sub" claim.
+func (c Claims) Subject() string {
+	s, _ := c["sub"].(string)
+	return s
+}
+
+var (
+	InvalidToken = xrr.NewXrror("invalid token: %s").Out
+	NoToken      = xrr.NewXrror("no bearer token was presented").Out
+	NoJWTKey     = xrr.NewXrror("tokens are not signed with the default secret key, set JWT_KEYS or SECRET_KEYS").Out
+)
+
+type jwtheader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid"`
+}
+
+// jwtsettings are the signing keys, from the JWT_KEYS list or derived from
+// the KeyRing, the JWT_ISSUER and JWT_AUDIENCE, if set, and the JWT_LIFETIME
+// in seconds of issued tokens, issued and checked at the time now, or the
+// current time if unset. Keys derived from a KeyRing of only the
+// DefaultSecretKey are refused.
+type jwtsettings struct {
+	ring     *KeyRing
+	refused  bool
+	issuer   string
+	audience string
+	lifetime time.Duration
+	now      time.Time
+}
+
+func jwtconfig(lookup func(string) (*StoreItem, bool)) *jwtsettings {
+	s := &jwtsettings{}
+	if item, ok := lookup("JWT_KEYS"); ok && item.Value != "" {
+		s.ring = NewKeyRing(item.List()...)
+	} else {
+		ring := keyring(lookup)
+		s.ring, s.refused = ring.Derive("flotilla jwt"), ring.Default()
+	}
+	if item, ok := lookup("JWT_ISSUER"); ok {
+		s.issuer = item.Value
+	}
+	if item, ok := lookup("JWT_AUDIENCE"); ok {
+		s.audience = item.Value
+	}
+	if item, ok := lookup("JWT_LIFETIME"); ok {
+		s.lifetime = time.Duration(item.Int64()) * time.Second
+	}
+	return s
+}
+
+func (s *jwtsettings) current() time.Time {
+	if s.now.IsZero() {
+		return time.Now()
+	}
+	return s.now
+}
+
+func jwtsignature(key, signed string) string {
+	h := hmac.New(sha256.New, []byte(key))
+	h.Write([]byte(signed))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// sign returns an HS256 token of the claims, signed with the current key and
+// recording its version as the key id, setting the iss, aud, iat, and exp
+// claims when not provided.
+func (s *jwtsettings) sign(claims Claims) (string, error) {
+	if s.refused {
+		return "", NoJWTKey()
+	}
+	version, key, ok := s.ring.Current()
+	if !ok {
+		return "", NoSecretKey()
+	}
+	now := s.current()
+	set := Claims{"iat": now.Unix()}
+	if s.issuer != "" {
+		set["iss"] = s.issuer
+	}
+	if s.audience != "" {
+		set["aud"] = s.audience
+	}
+	if s.lifetime > 0 {
+		set["exp"] = now.Add(s.lifetime).Unix()
+	}
+	for k, v := range claims {
+		set[k] = v
+	}
+	h, err := json.Marshal(jwtheader{Alg: "HS256", Typ: "JWT", Kid: version})
+	if err != nil {
+		return "", err
+	}
+	p, err := json.Marshal(set)
+	if err != nil {
+		return "", err
+	}
+	signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(p)
+	return signed + "." + jwtsignature(key, signed), nil
+}
+
+// verify returns the claims of an HS256 token signed with a key of the ring,
+// checking the exp and nbf claims, and the iss and aud claims if configured.
+func (s *jwtsettings) verify(token string) (Claims, error) {
+	if s.refused {
+		return nil, NoJWTKey()
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, InvalidToken("malformed")
+	}
+	hb, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, InvalidToken("malformed header")
+	}
+	var h jwtheader
+	if err := json.Unmarshal(hb, &h); err != nil || h.Alg != "HS256" {
+		return nil, InvalidToken("unsupported algorithm")
+	}
+	key, ok := s.ring.Key(h.Kid)
+	if !ok {
+		return nil, InvalidToken("unknown key")
+	}
+	if !hmac.Equal([]byte(jwtsignature(key, parts[0]+"."+parts[1])), []byte(parts[2])) {
+		return nil, InvalidToken("bad signature")
+	}
+	pb, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, InvalidToken("malformed claims")
+	}
+	var claims Claims
+	if err := json.Unmarshal(pb, &claims); err != nil {
+		return nil, InvalidToken("malformed claims")
+	}
+	now := float64(s.current().Unix())
+	if exp, ok := claims["exp"].(float64); ok && now >= exp {
+		return nil, InvalidToken("expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now < nbf {
+		return nil, InvalidToken("not yet valid")
+	}
+	if s.issuer != "" && claims["iss"] != s.issuer {
+		return nil, InvalidToken("wrong issuer")
+	}
+	if s.audience != "" && !jwtaudience(claims["aud"], s.audience) {
+		return nil, InvalidToken("wrong audience")
+	}
+	return claims, nil
+}
+
+func jwtaudience(aud interface{}, audience string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == audience
+	case []interface{}:
+		for _, a := range v {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func bearertoken(c *ctx) string {
+	auth := c.Request.Header.Get("Authorization")
+	if len(auth) > 7 && strings.EqualFold(auth[:7], "bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	return ""
+}
+
+func jwtsign(c *ctx, claims Claims) (string, error) {
+	s := jwtconfig(ctxlookup(c))
+	s.now = CurrentTime(c)
+	return s.sign(claims)
+}
+
+func jwtverify(c *ctx, token string) (Claims, error) {
+	s := jwtconfig(ctxlookup(c))
+	s.now = CurrentTime(c)
+	return s.verify(token)
+}
+
+// jwtclaims verifies the bearer token of the request, setting its claims in
+// the Ctx data.
+func jwtclaims(c *ctx) (Claims, error) {
+	token := bearertoken(c)
+	if token == "" {
+		return nil, NoToken()
+	}
+	claims, err := jwtverify(c, token)
+	if err != nil {
+		return nil, err
+	}
+	setdata(c, jwtData, claims)
+	return claims, nil
+}
+
+var jwtfxtension = map[string]interface{}{
+	"jwtsign":   jwtsign,
+	"jwtverify": jwtverify,
+	"jwtclaims": jwtclaims,
+}
+
+// JWTFxtension signs and verifies HS256 JSON web tokens with the JWT_KEYS
+// list, or the App KeyRing, for stateless authentication.
+var JWTFxtension Fxtension = MakeFxtension("jwtfxtension", jwtfxtension)
+
+// SignJWT returns a signed token of the claims, with the configured issuer,
+// audience, and expiry unless the claims provide them.
+func SignJWT(c Ctx, claims Claims) (string, error) {
+	token, err := c.Call("jwtsign", claims)
+	if err != nil {
+		return "", err
+	}
+	return token.(string), nil
+}
+
+// VerifyJWT returns the claims of a valid token.
+func VerifyJWT(c Ctx, token string) (Claims, error) {
+	claims, err := c.Call("jwtverify", token)
+	if err != nil {
+		return nil, err
+	}
+	return claims.(Claims), nil
+}
+
+// JWTAuth is a Manage function authenticating requests by a bearer token in
+// the Authorization header. Requests without a valid token receive a 401
+// status. The token claims are available to later managers with
+// CurrentClaims.
+func JWTAuth(c Ctx) {
+	if _, err := c.Call("jwtclaims"); err != nil {
+		Audit(c, AuditPermissionDenied, map[string]string{"reason": err.Error()})
+		c.Call("abort", 401)
+	}
+}
+
+// CurrentClaims returns the claims of the bearer token verified by JWTAuth
+// for the current request, and a boolean indicating their existence.
+func CurrentClaims(c Ctx) (Claims, bool) {
+	v, err := c.Call("get", jwtData)
+	if err != nil {
+		return nil, false
+	}
+	claims, ok := v.(Claims)
+	return claims, ok
+}
@@ -0,0 +1,98 @@
+package flotilla
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestJWT(t *testing.T) {
+	a := New("testJWT", Mode("testing", true), EnvItem("SECRET_KEYS:new,old", "JWT_ISSUER:flotilla", "JWT_AUDIENCE:api"))
+	var issued string
+	var verified Claims
+	var verr error
+	a.GET("/token", func(c Ctx) {
+		issued, _ = SignJWT(c, Claims{"sub": "alice"})
+	})
+	a.GET("/verify", func(c Ctx) {
+		verified, verr = VerifyJWT(c, CurrentRequest(c).URL.Query().Get("token"))
+	})
+	a.GET("/api", JWTAuth, func(c Ctx) {
+		claims, _ := CurrentClaims(c)
+		c.Call("serveplain", 200, claims.Subject())
+	})
+	a.Configure()
+
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/token", nil))
+	if issued == "" {
+		t.Fatal("no token was issued")
+	}
+
+	rw := httptest.NewRecorder()
+	rq := httptest.NewRequest("GET", "/api", nil)
+	rq.Header.Set("Authorization", "Bearer "+issued)
+	a.ServeHTTP(rw, rq)
+	if rw.Code != 200 || rw.Body.String() != "alice" {
+		t.Errorf("expected the token subject, got %d %q", rw.Code, rw.Body.String())
+	}
+
+	rw = httptest.NewRecorder()
+	a.ServeHTTP(rw, httptest.NewRequest("GET", "/api", nil))
+	if rw.Code != 401 {
+		t.Errorf("expected 401 without a token, got %d", rw.Code)
+	}
+
+	verify := func(token string) error {
+		a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/verify?token="+token, nil))
+		return verr
+	}
+	if err := verify(issued); err != nil || verified["iss"] != "flotilla" || verified["aud"] != "api" {
+		t.Errorf("expected issuer and audience claims, got %v %v", verified, err)
+	}
+	if err := verify(issued[:len(issued)-2] + "xx"); err == nil {
+		t.Error("a token with a bad signature was verified")
+	}
+
+	settings := &jwtsettings{ring: NewKeyRing("old").Derive("flotilla jwt"), issuer: "flotilla", audience: "other"}
+	if other, _ := settings.sign(Claims{}); verify(other) == nil {
+		t.Error("a token for another audience was verified")
+	}
+	settings.audience = "api"
+	if rotated, _ := settings.sign(Claims{}); verify(rotated) != nil {
+		t.Errorf("a token signed with a rotated key was not verified: %v", verr)
+	}
+
+	if raw, _ := (&jwtsettings{ring: NewKeyRing("new")}).sign(Claims{}); verify(raw) == nil {
+		t.Error("a token signed with a secret key instead of the derived key was verified")
+	}
+
+	restore := a.FreezeTime(time.Now().Add(2 * time.Hour))
+	defer restore()
+	if err := verify(issued); err == nil {
+		t.Error("an expired token was verified")
+	}
+}
+
+func TestJWTDefaultSecretKey(t *testing.T) {
+	a := New("testJWTDefaultSecretKey", Mode("testing", true))
+	var err error
+	a.GET("/token", func(c Ctx) {
+		_, err = SignJWT(c, Claims{"sub": "alice"})
+	})
+	a.Configure()
+
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/token", nil))
+	if err == nil {
+		t.Error("a token was signed with the default secret key")
+	}
+
+	s := jwtconfig(func(key string) (*StoreItem, bool) {
+		if key == "JWT_KEYS" {
+			return &StoreItem{Value: DefaultSecretKey}, true
+		}
+		return nil, false
+	})
+	if _, err := s.sign(Claims{}); err != nil {
+		t.Errorf("expected explicit JWT_KEYS to sign tokens, got %v", err)
+	}
+}
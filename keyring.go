@@ -0,0 +1,99 @@
+package flotilla
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// DefaultSecretKey is the SECRET_KEY of an App not configured with its own
+// secret keys. It is public, and not suitable to sign tokens.
+const DefaultSecretKey = "Flotilla;Secret;Key;1"
+
+// KeyRing holds the secret keys of an App, newest first. Values are signed or
+// encrypted with the newest key and record its version, and are verified
+// with any key in the ring, so keys are rotated by prepending a new key to
+// the SECRET_KEYS list and later dropping the oldest.
+type KeyRing struct {
+	keys     []string
+	versions map[string]string
+}
+
+// NewKeyRing returns a KeyRing for the provided keys, newest first.
+func NewKeyRing(keys ...string) *KeyRing {
+	k := &KeyRing{versions: make(map[string]string)}
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		k.keys = append(k.keys, key)
+		k.versions[KeyVersion(key)] = key
+	}
+	return k
+}
+
+// KeyVersion returns the version recorded with values using key, a short
+// digest of the key.
+func KeyVersion(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:4])
+}
+
+// Keys returns the keys of the KeyRing, newest first.
+func (k *KeyRing) Keys() []string {
+	return append([]string(nil), k.keys...)
+}
+
+// Current returns the version and value of the newest key, or false for an
+// empty KeyRing.
+func (k *KeyRing) Current() (string, string, bool) {
+	if len(k.keys) == 0 {
+		return "", "", false
+	}
+	return KeyVersion(k.keys[0]), k.keys[0], true
+}
+
+// Key returns the key with the provided version, if it is in the KeyRing.
+func (k *KeyRing) Key(version string) (string, bool) {
+	key, ok := k.versions[version]
+	return key, ok
+}
+
+// Default returns whether the only key of the KeyRing is the
+// DefaultSecretKey.
+func (k *KeyRing) Default() bool {
+	return len(k.keys) == 1 && k.keys[0] == DefaultSecretKey
+}
+
+// Derive returns a KeyRing of keys derived for purpose from the keys of the
+// KeyRing, in the same order, so values signed for one purpose share no key
+// material with values sealed for another.
+func (k *KeyRing) Derive(purpose string) *KeyRing {
+	derived := make([]string, 0, len(k.keys))
+	for _, key := range k.keys {
+		h := hmac.New(sha256.New, []byte(key))
+		h.Write([]byte(purpose))
+		derived = append(derived, hex.EncodeToString(h.Sum(nil)))
+	}
+	return NewKeyRing(derived...)
+}
+
+// keyring returns a KeyRing of the SECRET_KEYS list, or the SECRET_KEY.
+func keyring(lookup func(string) (*StoreItem, bool)) *KeyRing {
+	if item, ok := lookup("SECRET_KEYS"); ok && item.Value != "" {
+		return NewKeyRing(item.List()...)
+	}
+	if item, ok := lookup("SECRET_KEY"); ok {
+		return NewKeyRing(item.Value)
+	}
+	return NewKeyRing()
+}
+
+// KeyRing returns the KeyRing of the Env SECRET_KEYS list, newest first, or
+// the SECRET_KEY.
+func (env *Env) KeyRing() *KeyRing {
+	return keyring(func(key string) (*StoreItem, bool) {
+		item, ok := env.Store[key]
+		return item, ok
+	})
+}
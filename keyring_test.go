@@ -0,0 +1,90 @@
+package flotilla
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestKeyRing(t *testing.T) {
+	k := NewKeyRing("new", "", "old")
+	if keys := k.Keys(); len(keys) != 2 || keys[0] != "new" {
+		t.Errorf("expected keys newest first without empty keys, got %v", keys)
+	}
+	if version, key, ok := k.Current(); !ok || key != "new" || version != KeyVersion("new") {
+		t.Errorf("unexpected current key %s %s %t", version, key, ok)
+	}
+	if key, ok := k.Key(KeyVersion("old")); !ok || key != "old" {
+		t.Errorf("expected the old key by version, got %q %t", key, ok)
+	}
+	if _, _, ok := NewKeyRing().Current(); ok {
+		t.Error("expected no current key for an empty KeyRing")
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	old := New("testKeyRotationOld", Mode("testing", true), EnvItem("SECRET_KEY:old"))
+	old.GET("/set", func(c Ctx) {
+		c.Call("securecookie", "sealed", "sealed value")
+	})
+	old.Configure()
+	rw := httptest.NewRecorder()
+	old.ServeHTTP(rw, httptest.NewRequest("GET", "/set", nil))
+
+	var sealed *http.Cookie
+	for _, ck := range rw.Result().Cookies() {
+		if ck.Name == "sealed" {
+			sealed = ck
+		}
+	}
+	if sealed == nil {
+		t.Fatal("secure cookie was not set")
+	}
+
+	rotated := New("testKeyRotation", Mode("testing", true), EnvItem("SECRET_KEYS:new,old"))
+	rotated.Configure()
+	if v, ok := rotated.ReadSecureCookie(sealed); !ok || v != "sealed value" {
+		t.Errorf("a cookie sealed with a rotated key was not read: %q %t", v, ok)
+	}
+
+	replaced := New("testKeyRotationReplaced", Mode("testing", true), EnvItem("SECRET_KEYS:new"))
+	replaced.Configure()
+	if _, ok := replaced.ReadSecureCookie(sealed); ok {
+		t.Error("a cookie sealed with a removed key was read")
+	}
+}
+
+func TestSessionKeyRotation(t *testing.T) {
+	app := func(name, keys string) *App {
+		a := New(name, Mode("testing", true), EnvItem("SECRET_KEYS:"+keys))
+		a.GET("/set", func(c Ctx) { c.Call("setsession", "user", "alice") })
+		a.GET("/get", func(c Ctx) {
+			user, _ := c.Call("getsession", "user")
+			s, _ := user.(string)
+			c.Call("serveplain", 200, s)
+		})
+		a.Configure()
+		return a
+	}
+	get := func(a *App, cookie *http.Cookie) string {
+		client := a.TestClient()
+		client.SetCookies(cookie)
+		return string(client.Get("/get").Body)
+	}
+
+	cookies := app("testSessionKeyRotationOld", "old").TestClient().Get("/set").Cookies
+	if len(cookies) == 0 {
+		t.Fatal("session cookie was not set")
+	}
+	session := cookies[len(cookies)-1]
+
+	if user := get(app("testSessionKeyRotationRestarted", "old"), session); user != "alice" {
+		t.Errorf("a session cookie was not read with the same keys, got %q", user)
+	}
+	if user := get(app("testSessionKeyRotation", "new,old"), session); user != "alice" {
+		t.Errorf("a session cookie sealed with a rotated key was not read, got %q", user)
+	}
+	if user := get(app("testSessionKeyRotationReplaced", "new"), session); user == "alice" {
+		t.Error("a session cookie sealed with a removed key was read")
+	}
+}
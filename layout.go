@@ -0,0 +1,82 @@
+package flotilla
+
+import (
+	"html/template"
+	"io"
+	"regexp"
+
+	"github.com/thrisp/flotilla/xrr"
+)
+
+var (
+	PartialUnsupported = xrr.NewXrror("templator %T can not render partial templates").Out
+	ExtendsCycle       = xrr.NewXrror("template %s extends itself").Out
+)
+
+// PartialTemplator is a Templator able to render a block of a template, e.g.
+// a fragment for an htmx or ajax response, without the layouts it extends.
+type PartialTemplator interface {
+	Templator
+	RenderPartial(w io.Writer, name, block string, data interface{}) error
+}
+
+var extendsdirective = regexp.MustCompile(`{{-?\s*extends\s+"([^"]+)"\s*-?}}`)
+
+// layouttemplate parses the named template with the layouts it extends, each
+// with an {{ extends "layout.html" }} directive, the outermost layout first,
+// so that the blocks a template defines replace those of its layouts and the
+// outermost layout is rendered. As with html/template, a template called
+// without a pipeline receives no data; pass it explicitly, e.g.
+// {{ template "rows" . }}.
+func layouttemplate(load func(string) (string, error), funcs map[string]interface{}, name string) (*template.Template, error) {
+	var chain []string
+	seen := make(map[string]bool)
+	for n := name; n != ""; {
+		if seen[n] {
+			return nil, ExtendsCycle(n)
+		}
+		seen[n] = true
+		src, err := load(n)
+		if err != nil {
+			return nil, err
+		}
+		n = ""
+		if m := extendsdirective.FindStringSubmatch(src); m != nil {
+			n = m[1]
+			src = extendsdirective.ReplaceAllString(src, "")
+		}
+		chain = append([]string{src}, chain...)
+	}
+	t := template.New(name).Funcs(funcs)
+	for _, src := range chain {
+		if _, err := t.Parse(src); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+func renderpartialfunc(a *App) func(*ctx, string, string, interface{}) error {
+	return func(c *ctx, name, block string, data interface{}) error {
+		pt, ok := currenttemplator(a, c).(PartialTemplator)
+		if !ok {
+			return PartialUnsupported(currenttemplator(a, c))
+		}
+		c.push(func(pc Ctx) {
+			td := NewTemplateData(c, data)
+			if rendertemplated(a, c, name, func(w io.Writer) error { return pt.RenderPartial(w, name, block, td) }) {
+				a.Env.Events.Send(pc, TemplateRendered, &RenderedTemplate{Name: name, Data: td})
+			}
+		})
+		return nil
+	}
+}
+
+// RenderPartial renders the named block of a template, a {{ define }} or
+// {{ block }} of the template or the layouts it extends, with data and
+// without the surrounding layout, e.g. the rows of a table for an htmx
+// request.
+func RenderPartial(c Ctx, name, block string, data interface{}) error {
+	_, err := c.Call("renderpartial", name, block, data)
+	return err
+}
@@ -0,0 +1,45 @@
+package flotilla
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLayoutsAndPartials(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "base.html"), []byte(`<html>{{ block "content" . }}default{{ end }}</html>`), 0644)
+	os.WriteFile(filepath.Join(dir, "list.html"), []byte(`{{ extends "base.html" }}
+{{ define "content" }}<ul>{{ template "rows" . }}</ul>{{ end }}
+{{ define "rows" }}<li>{{ .Any }}</li>{{ end }}`), 0644)
+	os.WriteFile(filepath.Join(dir, "loop.html"), []byte(`{{ extends "loop.html" }}`), 0644)
+
+	for _, driver := range []string{"html", "djinn"} {
+		a := New("testLayouts"+driver, Mode("testing", true), TemplateDriver(driver), EnvItem("TEMPLATE_DIRECTORIES:"+dir))
+		a.GET("/list", func(c Ctx) { c.Call("rendertemplate", "list.html", "item") })
+		a.GET("/rows", func(c Ctx) { RenderPartial(c, "list.html", "rows", "item") })
+		a.GET("/loop", func(c Ctx) { RenderPartial(c, "loop.html", "content", nil) })
+		client := a.TestClient()
+
+		if body := string(client.Get("/list").Body); !strings.Contains(body, "<html><ul><li>item</li></ul></html>") {
+			t.Errorf("%s: expected the block of the template rendered in its layout, got %q", driver, body)
+		}
+		if body := string(client.Get("/rows").Body); driver == "html" && body != "<li>item</li>" {
+			t.Errorf("%s: expected only the partial rendered, got %q", driver, body)
+		} else if driver == "djinn" && !strings.Contains(body, "can not render partial templates") {
+			t.Errorf("%s: expected the error page of a templator without partials, got %q", driver, body)
+		}
+		if body := string(client.Get("/loop").Body); driver == "html" && !strings.Contains(body, "loop.html extends itself") {
+			t.Errorf("%s: expected the error page of a template extending itself, got %q", driver, body)
+		}
+	}
+
+	c := New("testPartialUnsupported", Mode("testing", true), WithTemplator(&testtemplator{}))
+	var err error
+	c.GET("/rows", func(ctx Ctx) { err = RenderPartial(ctx, "list.html", "rows", nil) })
+	c.TestClient().Get("/rows")
+	if err == nil {
+		t.Error("expected an error rendering a partial with a Templator unable to")
+	}
+}
@@ -0,0 +1,108 @@
+package flotilla
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/thrisp/flotilla/xrr"
+)
+
+var UnsupportedContentType = xrr.NewXrror("content type %q is not allowed").Out
+
+// RouteLimits are the limits of a route enforced before any of its managers,
+// including those of its Blueprint, run.
+type RouteLimits struct {
+	// Timeout cancels the Ctx and the request context after the duration.
+	Timeout time.Duration
+	// MaxBodyBytes limits the request body, responding with a 413 status to
+	// a larger Content-Length or failing reads past the limit.
+	MaxBodyBytes int64
+	// ContentTypes are the media types allowed for a request with a body,
+	// which may be a type wildcard such as image/*, responding with a 415
+	// status to any other.
+	ContentTypes []string
+}
+
+func (l RouteLimits) empty() bool {
+	return l.Timeout <= 0 && l.MaxBodyBytes <= 0 && len(l.ContentTypes) == 0
+}
+
+// allowed reports whether the media type is one of the ContentTypes.
+func (l RouteLimits) allowed(mediatype string) bool {
+	for _, t := range l.ContentTypes {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == mediatype || t == "*/*" || (strings.HasSuffix(t, "/*") && strings.HasPrefix(mediatype, t[:len(t)-1])) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasbody(rq *http.Request) bool {
+	return rq.ContentLength > 0 || (rq.ContentLength < 0 && rq.Body != nil && rq.Body != http.NoBody)
+}
+
+func routelimits(c *ctx, l RouteLimits) error {
+	if l.Timeout > 0 {
+		c.WithTimeout(l.Timeout)
+	}
+	rq := c.Request
+	if len(l.ContentTypes) > 0 && hasbody(rq) {
+		ct := rq.Header.Get("Content-Type")
+		mt, _, err := mime.ParseMediaType(ct)
+		if err != nil || !l.allowed(mt) {
+			return aborterror(c, 415, UnsupportedContentType(ct))
+		}
+	}
+	if l.MaxBodyBytes > 0 {
+		if rq.ContentLength > l.MaxBodyBytes {
+			return aborterror(c, 413, BodyTooLarge(l.MaxBodyBytes))
+		}
+		if rq.Body != nil && rq.Body != http.NoBody {
+			rq.Body = http.MaxBytesReader(c.RW, rq.Body, l.MaxBodyBytes)
+		}
+	}
+	return nil
+}
+
+// limitmanager returns a Manage enforcing the limits of the route.
+func (rt *Route) limitmanager() Manage {
+	return func(c Ctx) {
+		c.Call("routelimits", rt.Limits)
+	}
+}
+
+func (rt *Route) limit(fn func(*RouteLimits)) *Route {
+	fn(&rt.Limits)
+	if rt.Registered {
+		rt.compose()
+	}
+	return rt
+}
+
+// Timeout sets a timeout on the route, canceling its Ctx and the request
+// context of downstream calls once it passes.
+func (rt *Route) Timeout(d time.Duration) *Route {
+	return rt.limit(func(l *RouteLimits) { l.Timeout = d })
+}
+
+// MaxBodyBytes limits the request body of the route to n bytes.
+func (rt *Route) MaxBodyBytes(n int64) *Route {
+	return rt.limit(func(l *RouteLimits) { l.MaxBodyBytes = n })
+}
+
+// ContentTypes sets the media types allowed for request bodies of the route,
+// e.g. ContentTypes("application/json").
+func (rt *Route) ContentTypes(types ...string) *Route {
+	return rt.limit(func(l *RouteLimits) { l.ContentTypes = types })
+}
+
+// WithLimits is a RouteConf setting the limits of a route.
+func WithLimits(l RouteLimits) RouteConf {
+	return func(rt *Route) error {
+		rt.Limits = l
+		return nil
+	}
+}
@@ -0,0 +1,55 @@
+package flotilla
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRouteLimits(t *testing.T) {
+	a := New("testRouteLimits", Mode("testing", true))
+	var ran bool
+	var read int
+	var readerr error
+	var deadline bool
+	a.Use(func(c Ctx) { ran = true })
+	a.POST("/upload", func(c Ctx) {
+		var b []byte
+		b, readerr = io.ReadAll(CurrentRequest(c).Body)
+		read = len(b)
+		_, deadline = CurrentDeadline(c)
+	}).MaxBodyBytes(8).ContentTypes("application/json", "text/*").Timeout(time.Second)
+	a.Configure()
+	post := func(body, contenttype string, length int64) int {
+		ran, read, readerr, deadline = false, 0, nil, false
+		rq := httptest.NewRequest("POST", "/upload", strings.NewReader(body))
+		rq.Header.Set("Content-Type", contenttype)
+		if length != 0 {
+			rq.ContentLength = length
+		}
+		rw := httptest.NewRecorder()
+		a.ServeHTTP(rw, rq)
+		return rw.Code
+	}
+
+	if code := post(`{"a":1}`, "application/json; charset=utf-8", 0); code != 200 || read != 7 || !deadline {
+		t.Errorf("expected an allowed body read within the timeout, got %d %d %t", code, read, deadline)
+	}
+	if code := post("hello", "text/plain", 0); code != 200 || read != 5 {
+		t.Errorf("expected a wildcard content type allowed, got %d %d", code, read)
+	}
+	if code := post("<a/>", "application/xml", 0); code != 415 || ran {
+		t.Errorf("expected a 415 before any manager ran, got %d %t", code, ran)
+	}
+	if code := post(`{"a":"too long"}`, "application/json", 0); code != 413 || ran {
+		t.Errorf("expected a 413 for a Content-Length over the limit, got %d %t", code, ran)
+	}
+	if post(`{"a":"too long"}`, "application/json", -1); readerr == nil || read > 8 {
+		t.Errorf("expected reads of an unknown length body to fail past the limit, got %d %v", read, readerr)
+	}
+	if code := post("", "", 0); code != 200 || !ran {
+		t.Errorf("expected a request without a body to pass content types, got %d", code)
+	}
+}
@@ -0,0 +1,136 @@
+package flotilla
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Logger is a leveled, structured logger. Fields are alternating key and
+// value pairs, e.g. logger.Info("started", "addr", ":8080").
+type Logger interface {
+	Debug(msg string, fields ...interface{})
+	Info(msg string, fields ...interface{})
+	Warn(msg string, fields ...interface{})
+	Error(msg string, fields ...interface{})
+
+	// With returns a Logger including the provided fields in every message.
+	With(fields ...interface{}) Logger
+}
+
+type slogger struct {
+	*slog.Logger
+}
+
+// NewLogger returns the default slog based Logger writing to w at the provided
+// level ("debug", "info", "warn", or "error"), as JSON or as text.
+func NewLogger(w io.Writer, level string, json bool) Logger {
+	opts := &slog.HandlerOptions{Level: loglevel(level)}
+	var h slog.Handler
+	if json {
+		h = slog.NewJSONHandler(w, opts)
+	} else {
+		h = slog.NewTextHandler(w, opts)
+	}
+	return &slogger{slog.New(h)}
+}
+
+// SlogLogger returns a Logger backed by an existing *slog.Logger.
+func SlogLogger(l *slog.Logger) Logger {
+	return &slogger{l}
+}
+
+func loglevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+func (l *slogger) With(fields ...interface{}) Logger {
+	return &slogger{l.Logger.With(fields...)}
+}
+
+// WithLogger sets the Logger used by the App.
+func WithLogger(l Logger) Configuration {
+	return func(a *App) error {
+		a.Env.Logger = l
+		return nil
+	}
+}
+
+func (env *Env) defaultlogger() Logger {
+	level, format := "info", "text"
+	if item, ok := env.Store["LOG_LEVEL"]; ok {
+		level = item.Value
+	}
+	if item, ok := env.Store["LOG_FORMAT"]; ok {
+		format = item.Value
+	}
+	return NewLogger(os.Stdout, level, strings.ToLower(format) == "json")
+}
+
+// LoggerInit intializes the default Logger if none is listed with the Env.
+func (env *Env) LoggerInit() {
+	if env.Logger == nil {
+		env.Logger = env.defaultlogger()
+	}
+}
+
+// Log returns the Env Logger, initializing the default Logger if needed.
+func (env *Env) Log() Logger {
+	env.LoggerInit()
+	return env.Logger
+}
+
+func clogger(a *App) error {
+	a.Env.LoggerInit()
+	return nil
+}
+
+const (
+	loggerData = "_logger"
+	userData   = "user"
+)
+
+func sessionhash(sid string) string {
+	h := sha256.Sum256([]byte(sid))
+	return hex.EncodeToString(h[:8])
+}
+
+func loggerfunc(a *App) func(*ctx) Logger {
+	return func(c *ctx) Logger {
+		if l, ok := c.Data[loggerData].(Logger); ok {
+			return l
+		}
+		fields := []interface{}{"request_id", requestid(c)}
+		if c.route != nil {
+			fields = append(fields, "route", c.route.Name())
+		}
+		if c.Session != nil {
+			fields = append(fields, "session", sessionhash(c.Session.SessionID()))
+		}
+		if user, ok := c.Data[userData]; ok {
+			fields = append(fields, "user", user)
+		}
+		l := a.Env.Log().With(fields...)
+		setdata(c, loggerData, l)
+		return l
+	}
+}
+
+// CurrentLogger returns the Env Logger including correlation fields for the
+// current request: the request id, route, a hash of the session id, and the
+// user set by any login manager. The Logger is built once per Ctx.
+func CurrentLogger(c Ctx) Logger {
+	l, _ := c.Call("logger")
+	return l.(Logger)
+}
@@ -0,0 +1,93 @@
+package flotilla
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type logbuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (l *logbuffer) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.b.Write(p)
+}
+
+func (l *logbuffer) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.b.String()
+}
+
+func (l *logbuffer) waitFor(s string) bool {
+	for i := 0; i < 100; i++ {
+		if strings.Contains(l.String(), s) {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return false
+}
+
+func TestLogger(t *testing.T) {
+	var b logbuffer
+	l := NewLogger(&b, "warn", true).With("app", "test")
+	l.Info("not logged")
+	l.Warn("logged", "key", "value")
+	out := b.String()
+	if strings.Contains(out, "not logged") {
+		t.Errorf("Logger at warn level logged an info message: %s", out)
+	}
+	for _, expect := range []string{`"msg":"logged"`, `"key":"value"`, `"app":"test"`} {
+		if !strings.Contains(out, expect) {
+			t.Errorf("Logger output %s does not contain %s", out, expect)
+		}
+	}
+}
+
+func TestFrameworkLogging(t *testing.T) {
+	var b logbuffer
+
+	a := New("testFrameworkLogging", WithLogger(NewLogger(&b, "debug", false)), Mode("nonexistent", true))
+	a.GET("/panics", func(c Ctx) { panic("logged panic") })
+	a.Configure()
+
+	if !b.waitFor("configuration error") {
+		t.Errorf("Configuration error was not logged: %s", b.String())
+	}
+
+	ZeroExpectationPerformer(t, a, 500, "GET", "/panics").Perform()
+
+	if !b.waitFor("logged panic") {
+		t.Errorf("Handler panic was not logged: %s", b.String())
+	}
+}
+
+func TestCurrentLogger(t *testing.T) {
+	var b logbuffer
+
+	a := New("testCurrentLogger", WithLogger(NewLogger(&b, "info", false)))
+	a.GET("/logged", func(c Ctx) {
+		c.Call("set", "user", "alice")
+		CurrentLogger(c).Info("handled")
+	})
+	a.Configure()
+
+	exp, _ := NoTanage(200, "GET", "/logged")
+	exp.SetPre(func(t *testing.T, r *http.Request) { r.Header.Set(RequestIDHeader, "correlated") })
+	SimplePerformer(t, a, exp).Perform()
+
+	out := b.String()
+	for _, expect := range []string{"msg=handled", "request_id=correlated", "route=", "session=", "user=alice"} {
+		if !strings.Contains(out, expect) {
+			t.Errorf("CurrentLogger output %s does not contain %s", out, expect)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package flotilla
+
+// Manage ordering
+//
+// The managers of a route run in a deterministic order: those of the App,
+// then those of each Blueprint from the outermost to the Blueprint of the
+// route, as combined when each Blueprint is created or mounted, then any
+// added with Route.Use, then the managers the route was defined with. Before,
+// After, Replace, and Skip of a Blueprint edit its managers for routes
+// registered afterwards; those of a Route edit its complete managers in the
+// order called, whenever called.
+
+func indexManage(ms []Manage, target Manage) int {
+	for i, m := range ms {
+		if equalFunc(m, target) {
+			return i
+		}
+	}
+	return -1
+}
+
+func insertManage(ms []Manage, i int, m []Manage) []Manage {
+	ret := make([]Manage, 0, len(ms)+len(m))
+	ret = append(ret, ms[:i]...)
+	ret = append(ret, m...)
+	return append(ret, ms[i:]...)
+}
+
+// beforeManage inserts m before target, or at the end of ms if not found.
+func beforeManage(ms []Manage, target Manage, m []Manage) []Manage {
+	i := indexManage(ms, target)
+	if i < 0 {
+		i = len(ms)
+	}
+	return insertManage(ms, i, m)
+}
+
+// afterManage inserts m after target, or at the end of ms if not found.
+func afterManage(ms []Manage, target Manage, m []Manage) []Manage {
+	i := indexManage(ms, target)
+	if i < 0 {
+		i = len(ms) - 1
+	}
+	return insertManage(ms, i+1, m)
+}
+
+// replaceManage replaces target with m, if found.
+func replaceManage(ms []Manage, target Manage, m []Manage) []Manage {
+	i := indexManage(ms, target)
+	if i < 0 {
+		return ms
+	}
+	return insertManage(append(ms[:i:i], ms[i+1:]...), i, m)
+}
+
+// skipManage removes every occurrence of target from ms.
+func skipManage(ms []Manage, target Manage) []Manage {
+	ret := make([]Manage, 0, len(ms))
+	for _, m := range ms {
+		if !equalFunc(m, target) {
+			ret = append(ret, m)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,139 @@
+package flotilla
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/thrisp/flotilla/xrr"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+var (
+	UnknownMarkdownExtension = xrr.NewXrror("markdown extension %q is not available").Out
+	UnknownMarkdownPolicy    = xrr.NewXrror("markdown sanitizer policy %q is not available").Out
+	NoMarkdown               = xrr.NewXrror("markdown is not configured").Out
+)
+
+// MarkdownExtensions are the goldmark extensions available by name to the
+// MARKDOWN_EXTENSIONS list.
+var MarkdownExtensions = map[string]goldmark.Extender{
+	"gfm":            extension.GFM,
+	"table":          extension.Table,
+	"strikethrough":  extension.Strikethrough,
+	"linkify":        extension.Linkify,
+	"tasklist":       extension.TaskList,
+	"footnote":       extension.Footnote,
+	"definitionlist": extension.DefinitionList,
+	"typographer":    extension.Typographer,
+}
+
+// MarkdownPolicies are the bluemonday sanitizer policies available by name to
+// MARKDOWN_POLICY.
+var MarkdownPolicies = map[string]func() *bluemonday.Policy{
+	"ugc":    bluemonday.UGCPolicy,
+	"strict": bluemonday.StrictPolicy,
+}
+
+// Markdown converts Markdown, e.g. user content, to HTML sanitized by a
+// bluemonday Policy. Raw HTML of the Markdown is kept for the Policy to
+// sanitize.
+type Markdown struct {
+	md     goldmark.Markdown
+	policy *bluemonday.Policy
+}
+
+// NewMarkdown returns a Markdown with the goldmark extensions and sanitizer
+// Policy.
+func NewMarkdown(policy *bluemonday.Policy, exts ...goldmark.Extender) *Markdown {
+	return &Markdown{
+		md:     goldmark.New(goldmark.WithExtensions(exts...), goldmark.WithRendererOptions(html.WithUnsafe())),
+		policy: policy,
+	}
+}
+
+// Render returns the sanitized HTML of the Markdown source.
+func (m *Markdown) Render(src string) (template.HTML, error) {
+	var buf bytes.Buffer
+	if err := m.md.Convert([]byte(src), &buf); err != nil {
+		return "", err
+	}
+	return template.HTML(m.policy.SanitizeBytes(buf.Bytes())), nil
+}
+
+// WithMarkdown sets the Markdown of the App, instead of the Markdown of the
+// MARKDOWN_EXTENSIONS and MARKDOWN_POLICY, e.g. for a custom Policy.
+func WithMarkdown(m *Markdown) Configuration {
+	return func(a *App) error {
+		a.Env.markdown = m
+		return nil
+	}
+}
+
+func cmarkdown(a *App) error {
+	if a.Env.markdown != nil {
+		return nil
+	}
+	m, err := storemarkdown(a.Env.Store)
+	if err != nil {
+		return err
+	}
+	a.Env.markdown = m
+	return nil
+}
+
+// storemarkdown returns the Markdown of the MARKDOWN_EXTENSIONS and
+// MARKDOWN_POLICY of the Store.
+func storemarkdown(s Store) (*Markdown, error) {
+	var exts []goldmark.Extender
+	for _, name := range strings.Split(s["MARKDOWN_EXTENSIONS"].Value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		ext, ok := MarkdownExtensions[name]
+		if !ok {
+			return nil, UnknownMarkdownExtension(name)
+		}
+		exts = append(exts, ext)
+	}
+	policy, ok := MarkdownPolicies[s["MARKDOWN_POLICY"].Value]
+	if !ok {
+		return nil, UnknownMarkdownPolicy(s["MARKDOWN_POLICY"].Value)
+	}
+	return NewMarkdown(policy(), exts...), nil
+}
+
+// markdownhtml returns the sanitized HTML of the Markdown source with the
+// Markdown of the Env. It is available to templates as "markdown", e.g.
+// {{ markdown .Post.Body }}.
+func (env *Env) markdownhtml(src string) (template.HTML, error) {
+	if env.markdown == nil {
+		return "", NoMarkdown()
+	}
+	return env.markdown.Render(src)
+}
+
+func rendermarkdownfunc(a *App) func(*ctx, int, string) error {
+	return func(c *ctx, code int, src string) error {
+		h, err := a.Env.markdownhtml(src)
+		if err != nil {
+			return err
+		}
+		c.push(func(pc Ctx) {
+			headerwrite(c, code, []string{"Content-Type", "text/html; charset=utf-8"})
+			c.RW.Write([]byte(h))
+		})
+		return nil
+	}
+}
+
+// RenderMarkdown responds with the code and the sanitized HTML of the
+// Markdown source.
+func RenderMarkdown(c Ctx, code int, src string) error {
+	_, err := c.Call("rendermarkdown", code, src)
+	return err
+}
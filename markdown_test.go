@@ -0,0 +1,56 @@
+package flotilla
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testmarkdown = "# Title\n\n**bold** ~~gone~~ <script>alert(1)</script> <a href=\"javascript:alert(2)\">link</a>\n"
+
+func TestMarkdown(t *testing.T) {
+	tpls := t.TempDir()
+	os.WriteFile(filepath.Join(tpls, "post.html"), []byte(`<div>{{ markdown .Body }}</div>`), 0644)
+	a := New(
+		"testMarkdown",
+		Mode("testing", true),
+		TemplateDriver("html"),
+		EnvItem("TEMPLATE_DIRECTORIES:"+tpls),
+	)
+	a.GET("/post", func(c Ctx) {
+		c.Call("rendertemplate", "post.html", map[string]interface{}{"Body": testmarkdown})
+	})
+	a.GET("/raw", func(c Ctx) { RenderMarkdown(c, 200, testmarkdown) })
+
+	client := a.TestClient()
+	for _, path := range []string{"/post", "/raw"} {
+		body := string(client.Get(path).Body)
+		for _, expected := range []string{"<h1>Title</h1>", "<strong>bold</strong>", "<del>gone</del>"} {
+			if !strings.Contains(body, expected) {
+				t.Errorf("%s: expected %q in %q", path, expected, body)
+			}
+		}
+		if strings.Contains(body, "alert") {
+			t.Errorf("%s: expected sanitized html, got %q", path, body)
+		}
+	}
+
+	strict, err := storemarkdown(Store{
+		"MARKDOWN_EXTENSIONS": &StoreItem{Value: ""},
+		"MARKDOWN_POLICY":     &StoreItem{Value: "strict"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h, _ := strict.Render(testmarkdown); strings.Contains(string(h), "<") || !strings.Contains(string(h), "Title") {
+		t.Errorf("expected text only of the strict policy, got %q", h)
+	}
+
+	if _, err := storemarkdown(Store{
+		"MARKDOWN_EXTENSIONS": &StoreItem{Value: "gfm,nope"},
+		"MARKDOWN_POLICY":     &StoreItem{Value: "ugc"},
+	}); err == nil {
+		t.Error("expected an error for an unknown markdown extension")
+	}
+}
@@ -20,15 +20,18 @@ type Messaging struct {
 	Signals chan Signal
 	Queues  map[string]Queue
 	Logger  *log.Logger
+	Auditor *Auditor
+	env     *Env
 }
 
 var FlotillaPanic = []byte("flotilla-panic")
 
-func newMessaging() *Messaging {
-	m := &Messaging{}
+func newMessaging(env *Env) *Messaging {
+	m := &Messaging{env: env}
 	m.Logger = log.New(os.Stdout, "[Flotilla]", 0)
 	m.Queues = m.defaultqueues()
 	m.Signals = make(Signals, 100)
+	m.Auditor = &Auditor{OnError: m.auditerror}
 	return m
 }
 
@@ -40,8 +43,14 @@ func (m *Messaging) Out(message string) {
 	m.Send("out", message)
 }
 
-// Out sends the provided string to messaging logger.
+// DefaultOut sends the provided string to the Env Logger, or the messaging
+// logger when no Env is available. Requests are logged with fields of the
+// request Logger rather than as messages.
 func (m *Messaging) DefaultOut(message string) {
+	if m.env != nil {
+		m.env.Log().Info(message)
+		return
+	}
 	m.Logger.Printf(" %s", message)
 }
 
@@ -51,7 +60,13 @@ func (m *Messaging) Panic(message string) {
 	m.Send("panic", message)
 }
 
+// DefaultPanic sends the provided string to the Env Logger at error level, or
+// the standard logger when no Env is available.
 func (m *Messaging) DefaultPanic(message string) {
+	if m.env != nil {
+		m.env.Log().Error("panic", "message", message)
+		return
+	}
 	log.Println(fmt.Errorf("[Flotilla Panic] %s", message))
 }
 
@@ -0,0 +1,109 @@
+package flotilla
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+
+	"github.com/thrisp/flotilla/engine"
+)
+
+// middlewarewriter is the http.ResponseWriter passed to net/http middleware,
+// writing through the Ctx ResponseWriter until the middleware wraps it for
+// the remaining managers, and directly to the underlying writer after.
+type middlewarewriter struct {
+	rw      *responseWriter
+	w       http.ResponseWriter
+	wrapped bool
+}
+
+func (m *middlewarewriter) Header() http.Header {
+	return m.w.Header()
+}
+
+func (m *middlewarewriter) WriteHeader(code int) {
+	if m.wrapped {
+		m.w.WriteHeader(code)
+		return
+	}
+	m.rw.WriteHeader(code)
+}
+
+func (m *middlewarewriter) Write(p []byte) (int, error) {
+	if m.wrapped {
+		return m.w.Write(p)
+	}
+	return m.rw.Write(p)
+}
+
+func (m *middlewarewriter) Flush() {
+	if f, ok := m.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (m *middlewarewriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return hijack(m.w)
+}
+
+func (m *middlewarewriter) Unwrap() http.ResponseWriter {
+	return m.w
+}
+
+// httpmiddleware runs the remaining managers and the deferred functions of
+// the ctx as the next handler of the middleware, so that it sees the
+// rendered response, with the request and any response writer it passes on,
+// halting the ctx if the middleware does not call the next handler.
+func httpmiddleware(c *ctx, mw func(http.Handler) http.Handler) error {
+	rq, w := c.Request, c.rw.ResponseWriter
+	mww := &middlewarewriter{rw: &c.rw, w: w}
+	var called bool
+	next := http.HandlerFunc(func(nw http.ResponseWriter, nrq *http.Request) {
+		called = true
+		c.Request = nrq
+		if nw != http.ResponseWriter(mww) {
+			mww.wrapped = true
+			c.rw.ResponseWriter = nw
+		}
+		c.Next()
+		deferred := c.deferred
+		c.deferred = nil
+		for _, fn := range deferred {
+			fn(c)
+		}
+	})
+	mw(next).ServeHTTP(mww, rq)
+	c.Request, c.rw.ResponseWriter = rq, w
+	if !called {
+		c.halt()
+	}
+	return nil
+}
+
+// WrapHTTPMiddleware returns a Manage running net/http middleware, e.g.
+// func(http.Handler) http.Handler, with the remaining managers of the Ctx as
+// its next handler, rendering the response before the middleware returns.
+// Any request or response writer the middleware passes on is used by the
+// remaining managers; a middleware responding without calling the next
+// handler stops them.
+func WrapHTTPMiddleware(mw func(http.Handler) http.Handler) Manage {
+	return func(c Ctx) {
+		c.Call("httpmiddleware", mw)
+	}
+}
+
+// ManageToHTTP returns net/http middleware running the Manage functions in a
+// Ctx of the App before the next handler, which receives the Ctx
+// ResponseWriter and request, unless a Manage aborts or halts the Ctx.
+func (a *App) ManageToHTTP(m ...Manage) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		managers := append(m[:len(m):len(m)], httphandler(next))
+		return http.HandlerFunc(func(rw http.ResponseWriter, rq *http.Request) {
+			c := NewCtx(a.fxtensions, engine.NewResult(200, nil, nil, false))
+			c.reset(rq, rw, managers)
+			c.Call("start", a.SessionManager)
+			c.Run()
+			c.Cancel()
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package flotilla
+
+import (
+	stdcontext "context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type middlewareKey struct{}
+
+func TestWrapHTTPMiddleware(t *testing.T) {
+	a := New("testWrapHTTPMiddleware", Mode("testing", true))
+	header := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, rq *http.Request) {
+			rw.Header().Set("X-Middleware", "yes")
+			next.ServeHTTP(rw, rq.WithContext(stdcontext.WithValue(rq.Context(), middlewareKey{}, "value")))
+		})
+	}
+	upper := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, rq *http.Request) {
+			rec := httptest.NewRecorder()
+			next.ServeHTTP(rec, rq)
+			rw.WriteHeader(rec.Code)
+			rw.Write([]byte(strings.ToUpper(rec.Body.String())))
+		})
+	}
+	deny := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, rq *http.Request) {
+			http.Error(rw, "denied", 403)
+		})
+	}
+	var ran bool
+	handler := func(c Ctx) {
+		ran = true
+		v, _ := CurrentRequest(c).Context().Value(middlewareKey{}).(string)
+		c.Call("serveplain", 201, "handled "+v)
+	}
+	a.GET("/wrapped", WrapHTTPMiddleware(header), handler)
+	a.GET("/upper", WrapHTTPMiddleware(upper), handler)
+	a.GET("/denied", WrapHTTPMiddleware(deny), handler)
+	client := a.TestClient()
+
+	if res := client.Get("/wrapped"); res.Status != 201 || string(res.Body) != "handled value" || res.Header.Get("X-Middleware") != "yes" {
+		t.Errorf("unexpected wrapped response %d %q %v", res.Status, res.Body, res.Header)
+	}
+	if res := client.Get("/upper"); res.Status != 201 || string(res.Body) != "HANDLED " {
+		t.Errorf("unexpected rewritten response %d %q", res.Status, res.Body)
+	}
+	ran = false
+	if res := client.Get("/denied"); ran || res.Status != 403 {
+		t.Errorf("expected the middleware response only, got %d, handler ran %v", res.Status, ran)
+	}
+}
+
+func TestManageToHTTP(t *testing.T) {
+	a := New("testManageToHTTP", Mode("testing", true))
+	a.Configure()
+	mw := a.ManageToHTTP(func(c Ctx) {
+		if CurrentRequest(c).Header.Get("Authorization") == "" {
+			c.Call("abort", 401)
+			return
+		}
+		c.Call("headermodify", "set", []string{"X-Managed", "yes"})
+	})
+	h := mw(http.HandlerFunc(func(rw http.ResponseWriter, rq *http.Request) {
+		rw.Write([]byte("ok"))
+	}))
+
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest("GET", "/", nil))
+	if rw.Code != 401 || rw.Body.String() == "ok" {
+		t.Errorf("expected an aborted response, got %d %q", rw.Code, rw.Body.String())
+	}
+
+	rw = httptest.NewRecorder()
+	rq := httptest.NewRequest("GET", "/", nil)
+	rq.Header.Set("Authorization", "token")
+	h.ServeHTTP(rw, rq)
+	if rw.Code != 200 || rw.Body.String() != "ok" || rw.Header().Get("X-Managed") != "yes" {
+		t.Errorf("unexpected managed response %d %q %v", rw.Code, rw.Body.String(), rw.Header())
+	}
+}
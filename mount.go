@@ -0,0 +1,44 @@
+package flotilla
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/thrisp/flotilla/engine"
+)
+
+// mountedMethods are the methods routed to a mounted http.Handler.
+var mountedMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS", "HEAD"}
+
+// mounthandler returns a Manage serving h with a copy of the request with the
+// path of the mounted param, i.e. without the mount prefix.
+func mounthandler(h http.Handler) Manage {
+	return func(c Ctx) {
+		params, _ := c.Call("params")
+		rq := CurrentRequest(c)
+		mrq := new(http.Request)
+		*mrq = *rq
+		mrq.URL = new(url.URL)
+		*mrq.URL = *rq.URL
+		mrq.URL.Path = params.(engine.Params).ByName("mounted")
+		mrq.URL.RawPath = ""
+		rw, _ := c.Call("responsewriter")
+		h.ServeHTTP(rw.(ResponseWriter), mrq)
+	}
+}
+
+// MountHandler routes every method of the paths under the prefix to h, with
+// the prefix stripped from the request path, e.g. MountHandler("/metrics",
+// promhttp.Handler()), running the Managers of the Blueprint and any provided
+// before h in the ctx of the route, as Mount does for Blueprints. The prefix
+// without a trailing slash is redirected to the prefix with one.
+func (b *Blueprint) MountHandler(prefix string, h http.Handler, managers ...Manage) []*Route {
+	path := strings.TrimSuffix(prefix, "/") + "/*mounted"
+	m := append(managers[:len(managers):len(managers)], mounthandler(h))
+	var rts []*Route
+	for _, method := range mountedMethods {
+		rts = append(rts, b.route(method, path, m))
+	}
+	return rts
+}
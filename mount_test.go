@@ -0,0 +1,46 @@
+package flotilla
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMountHandler(t *testing.T) {
+	a := New("testMountHandler", Mode("testing", true))
+	var managed bool
+	a.Use(func(c Ctx) { managed = true })
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hello", func(rw http.ResponseWriter, rq *http.Request) {
+		fmt.Fprintf(rw, "%s %s", rq.Method, rq.URL.Path)
+	})
+	mux.HandleFunc("/", func(rw http.ResponseWriter, rq *http.Request) {
+		fmt.Fprintf(rw, "index %s", rq.URL.Path)
+	})
+	a.MountHandler("/legacy/", mux)
+	a.NewBlueprint("/api").MountHandler("/v0", http.HandlerFunc(func(rw http.ResponseWriter, rq *http.Request) {
+		rw.WriteHeader(202)
+		fmt.Fprint(rw, rq.URL.Path)
+	}))
+	a.Configure()
+	do := func(method, path string) *httptest.ResponseRecorder {
+		managed = false
+		rw := httptest.NewRecorder()
+		a.ServeHTTP(rw, httptest.NewRequest(method, path, nil))
+		return rw
+	}
+
+	if rw := do("POST", "/legacy/hello"); rw.Code != 200 || rw.Body.String() != "POST /hello" || !managed {
+		t.Errorf("unexpected mounted response %d %q, managed %v", rw.Code, rw.Body.String(), managed)
+	}
+	if rw := do("GET", "/legacy/"); rw.Body.String() != "index /" {
+		t.Errorf("unexpected mounted index %q", rw.Body.String())
+	}
+	if rw := do("GET", "/legacy"); rw.Code != 301 || rw.Header().Get("Location") != "/legacy/" {
+		t.Errorf("expected a redirect to the mount prefix, got %d %v", rw.Code, rw.Header())
+	}
+	if rw := do("DELETE", "/api/v0/items/1"); rw.Code != 202 || rw.Body.String() != "/items/1" {
+		t.Errorf("unexpected blueprint mounted response %d %q", rw.Code, rw.Body.String())
+	}
+}
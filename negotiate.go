@@ -0,0 +1,165 @@
+package flotilla
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/thrisp/flotilla/xrr"
+)
+
+const negotiateData = "negotiate"
+
+var NotAcceptable = xrr.NewXrror("no acceptable response format for %q").Out
+
+// negotiatetypes are the media types of each negotiated format.
+var negotiatetypes = map[string][]string{
+	"json": {"application/json"},
+	"xml":  {"application/xml", "text/xml"},
+	"html": {"text/html"},
+	"text": {"text/plain"},
+}
+
+var negotiatecontent = map[string]string{
+	"json": "application/json; charset=utf-8",
+	"xml":  "application/xml; charset=utf-8",
+	"html": "text/html; charset=utf-8",
+	"text": "text/plain; charset=utf-8",
+}
+
+// acceptquality returns the quality the Accept header gives mediatype,
+// from the most specific matching range, and whether any range matched.
+func acceptquality(accept, mediatype string) (float64, bool) {
+	major := strings.SplitN(mediatype, "/", 2)[0] + "/*"
+	best, matched, found := -1, 0.0, false
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		var specificity int
+		switch name {
+		case mediatype:
+			specificity = 2
+		case major:
+			specificity = 1
+		case "*/*":
+			specificity = 0
+		default:
+			continue
+		}
+		q := 1.0
+		for _, f := range fields[1:] {
+			f = strings.TrimSpace(f)
+			if strings.HasPrefix(f, "q=") {
+				if v, err := strconv.ParseFloat(f[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if specificity > best {
+			best, matched, found = specificity, q, true
+		}
+	}
+	return matched, found
+}
+
+// negotiateformat returns the offered format with the highest quality in the
+// Accept header, with ties broken by the order of offers. The first offer is
+// returned for a request without an Accept header.
+func negotiateformat(accept string, offers []string) (string, bool) {
+	if len(offers) == 0 {
+		return "", false
+	}
+	if strings.TrimSpace(accept) == "" {
+		return offers[0], true
+	}
+	var format string
+	var quality float64
+	for _, offer := range offers {
+		for _, mediatype := range negotiatetypes[offer] {
+			if q, ok := acceptquality(accept, mediatype); ok && q > quality {
+				format, quality = offer, q
+			}
+		}
+	}
+	return format, format != ""
+}
+
+// negotiateoffers returns the formats offered by the route, set with
+// Negotiates, or the NEGOTIATE_FORMATS of the App. The html format is only
+// offered with a template name.
+func negotiateoffers(c *ctx, template string) []string {
+	offers, ok := c.Data[negotiateData].([]string)
+	if !ok {
+		if item, exists := CheckStore(c, "NEGOTIATE_FORMATS"); exists {
+			offers = item.List()
+		}
+	}
+	ret := make([]string, 0, len(offers))
+	for _, offer := range offers {
+		offer = strings.ToLower(strings.TrimSpace(offer))
+		if _, known := negotiatetypes[offer]; !known || (offer == "html" && template == "") {
+			continue
+		}
+		ret = append(ret, offer)
+	}
+	return ret
+}
+
+func negotiatebody(format string, data interface{}) ([]byte, error) {
+	switch format {
+	case "xml":
+		return xml.Marshal(data)
+	}
+	return []byte(fmt.Sprint(data)), nil
+}
+
+func negotiatefunc(a *App) func(*ctx, int, string, interface{}) error {
+	return func(c *ctx, code int, template string, data interface{}) error {
+		c.RW.Header().Add("Vary", "Accept")
+		accept := c.Request.Header.Get("Accept")
+		format, ok := negotiateformat(accept, negotiateoffers(c, template))
+		if !ok {
+			c.Call("status", 406)
+			return NotAcceptable(accept)
+		}
+		switch format {
+		case "json":
+			_, err := c.Call("json", code, data)
+			return err
+		case "html":
+			headerwrite(c, -1, []string{"Content-Type", negotiatecontent[format]})
+			c.push(func(pc Ctx) {
+				c.RW.WriteHeader(code)
+			})
+			_, err := c.Call("rendertemplate", template, data)
+			return err
+		}
+		body, err := negotiatebody(format, data)
+		if err != nil {
+			return err
+		}
+		c.push(func(pc Ctx) {
+			headerwrite(c, code, []string{"Content-Type", negotiatecontent[format]})
+			c.RW.Write(body)
+		})
+		return nil
+	}
+}
+
+// Negotiate renders data in the format best matching the request Accept
+// header, as JSON, XML, plain text, or with the named template as HTML, from
+// the formats offered by the route or the App NEGOTIATE_FORMATS, the first
+// being the default. An empty template name does not offer HTML.
+func Negotiate(c Ctx, code int, template string, data interface{}) error {
+	_, err := c.Call("negotiate", code, template, data)
+	return err
+}
+
+// Negotiates returns a Manage function overriding the formats offered by
+// Negotiate on a route, e.g. a.GET("/items", Negotiates("html", "json"), h).
+func Negotiates(formats ...string) Manage {
+	return func(c Ctx) {
+		c.Call("set", negotiateData, formats)
+	}
+}
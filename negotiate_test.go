@@ -0,0 +1,42 @@
+package flotilla
+
+import (
+	"strings"
+	"testing"
+)
+
+type negotiateitem struct {
+	Name string `json:"name" xml:"name"`
+}
+
+func (n negotiateitem) String() string { return "item " + n.Name }
+
+func TestNegotiate(t *testing.T) {
+	a := New("testNegotiate", Mode("testing", true), EnvItem("JSON_PRETTY:false"), WithTemplator(&testtemplator{}))
+	item := negotiateitem{Name: "one"}
+	a.GET("/item", func(c Ctx) { Negotiate(c, 201, "item.html", item) })
+	a.GET("/api", Negotiates("json"), func(c Ctx) { Negotiate(c, 200, "item.html", item) })
+	client := a.TestClient()
+
+	cases := []struct {
+		path, accept, contenttype, body string
+		code                            int
+	}{
+		{"/item", "", "application/json", `{"name":"one"}`, 201},
+		{"/item", "application/xml", "application/xml", "<negotiateitem><name>one</name></negotiateitem>", 201},
+		{"/item", "text/html,application/xhtml+xml,*/*;q=0.8", "text/html", "test templator", 201},
+		{"/item", "text/*;q=0.5, text/xml;q=0.1, application/json;q=0.4", "text/html", "test templator", 201},
+		{"/item", "text/plain", "text/plain", "item one", 201},
+		{"/api", "text/html, */*;q=0.1", "application/json", `{"name":"one"}`, 200},
+	}
+	for _, tc := range cases {
+		res := client.Get(tc.path, "Accept", tc.accept)
+		if res.Status != tc.code || !strings.HasPrefix(res.Header.Get("Content-Type"), tc.contenttype) || strings.TrimSpace(string(res.Body)) != tc.body {
+			t.Errorf("%s %q: got %d %q %q", tc.path, tc.accept, res.Status, res.Header.Get("Content-Type"), res.Body)
+		}
+	}
+
+	if res := client.Get("/api", "Accept", "text/html"); res.Status != 406 {
+		t.Errorf("expected 406 for an unacceptable format, got %d", res.Status)
+	}
+}
@@ -0,0 +1,52 @@
+package flotilla
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// MethodOverrideHeader is the request header overriding the method of a POST
+// request, for clients and proxies restricted to GET and POST.
+const MethodOverrideHeader = "X-HTTP-Method-Override"
+
+// MethodOverrideField is the form field overriding the method of a POST
+// request with a url encoded form, for HTML forms.
+const MethodOverrideField = "_method"
+
+// overridemethod sets the method of a POST request to the allowed method of
+// its MethodOverrideHeader, or else its MethodOverrideField, before routing.
+func overridemethod(rq *http.Request, allowed []string) {
+	if rq.Method != "POST" {
+		return
+	}
+	method := rq.Header.Get(MethodOverrideHeader)
+	if method == "" {
+		if ct, _, _ := mime.ParseMediaType(rq.Header.Get("Content-Type")); ct == "application/x-www-form-urlencoded" {
+			method = rq.PostFormValue(MethodOverrideField)
+		}
+	}
+	method = strings.ToUpper(strings.TrimSpace(method))
+	if method != "" && existsIn(method, allowed) {
+		rq.Method = method
+	}
+}
+
+// MethodOverride enables overriding the method of POST requests with the
+// MethodOverrideHeader or the MethodOverrideField of url encoded forms, to one
+// of the provided methods, or PUT, PATCH, and DELETE if none, so that HTML
+// forms and restricted clients may use them. It sets METHOD_OVERRIDE of the
+// Store, which is empty and disabled by default.
+func MethodOverride(methods ...string) Configuration {
+	return func(a *App) error {
+		allowed := []string{"PUT", "PATCH", "DELETE"}
+		if len(methods) > 0 {
+			allowed = allowed[:0]
+			for _, m := range methods {
+				allowed = append(allowed, strings.ToUpper(m))
+			}
+		}
+		a.Env.Store.add("method", "override", strings.Join(allowed, ","))
+		return nil
+	}
+}
@@ -0,0 +1,52 @@
+package flotilla
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMethodOverride(t *testing.T) {
+	a := New("testMethodOverride", Mode("testing", true), MethodOverride("put", "delete"))
+	var routed, name string
+	a.PUT("/items/:id", func(c Ctx) {
+		routed = "put"
+		name = CurrentRequest(c).PostFormValue("name")
+	})
+	a.DELETE("/items/:id", func(c Ctx) { routed = "delete" })
+	a.POST("/items/:id", func(c Ctx) { routed = "post" })
+	a.Configure()
+	post := func(body string, headers ...string) string {
+		routed, name = "", ""
+		rq := httptest.NewRequest("POST", "/items/1", strings.NewReader(body))
+		for i := 0; i+1 < len(headers); i += 2 {
+			rq.Header.Set(headers[i], headers[i+1])
+		}
+		a.ServeHTTP(httptest.NewRecorder(), rq)
+		return routed
+	}
+
+	if r := post("", MethodOverrideHeader, "DELETE"); r != "delete" {
+		t.Errorf("expected a header override to DELETE, got %q", r)
+	}
+	if r := post("_method=put&name=gopher", "Content-Type", "application/x-www-form-urlencoded"); r != "put" || name != "gopher" {
+		t.Errorf("expected a form override to PUT with the form, got %q %q", r, name)
+	}
+	if r := post("", MethodOverrideHeader, "PATCH"); r != "post" {
+		t.Errorf("expected no override to a method not allowed, got %q", r)
+	}
+	if r := post("_method=delete"); r != "post" {
+		t.Errorf("expected no override without a form content type, got %q", r)
+	}
+
+	d := New("testMethodOverrideDisabled", Mode("testing", true))
+	d.DELETE("/items/:id", func(c Ctx) { routed = "delete" })
+	d.POST("/items/:id", func(c Ctx) { routed = "post" })
+	d.Configure()
+	rq := httptest.NewRequest("POST", "/items/1", nil)
+	rq.Header.Set(MethodOverrideHeader, "DELETE")
+	d.ServeHTTP(httptest.NewRecorder(), rq)
+	if routed != "post" {
+		t.Errorf("expected no override by default, got %q", routed)
+	}
+}
@@ -0,0 +1,264 @@
+package flotilla
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thrisp/flotilla/engine"
+)
+
+// ParamError reports a missing route parameter, or a route parameter or query
+// value that could not be read as the requested type.
+type ParamError struct {
+	Key    string
+	Value  string
+	Reason string
+}
+
+func (e *ParamError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("missing route parameter %s", e.Key)
+	}
+	return fmt.Sprintf("invalid value %q for %s: %s", e.Value, e.Key, e.Reason)
+}
+
+// MissingParam returns a ParamError for the missing route parameter key.
+func MissingParam(key string) error {
+	return &ParamError{Key: key}
+}
+
+// InvalidParam returns a ParamError for the value v of key, invalid for the
+// provided reason.
+func InvalidParam(v, key string, reason interface{}) error {
+	return &ParamError{Key: key, Value: v, Reason: fmt.Sprint(reason)}
+}
+
+func routeparam(c *ctx, key string) (string, bool) {
+	for _, v := range c.Params {
+		if v.Key == key {
+			return v.Value, true
+		}
+	}
+	return "", false
+}
+
+func queryvalue(c *ctx, key string) (string, bool) {
+	q := c.Request.URL.Query()
+	if _, ok := q[key]; !ok {
+		return "", false
+	}
+	return q.Get(key), true
+}
+
+func parseint(key, v string, def int) (int, error) {
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return def, InvalidParam(v, key, "not an integer")
+	}
+	return n, nil
+}
+
+// paramint returns the route parameter key as an int, or def with an error
+// for a missing or invalid parameter.
+func paramint(c *ctx, key string, def int) (int, error) {
+	v, ok := routeparam(c, key)
+	if !ok {
+		return def, MissingParam(key)
+	}
+	return parseint(key, v, def)
+}
+
+// paramconstraint returns the constraint of the route parameter key in the
+// path of the ctx route, e.g. int for /users/:id<int>.
+func paramconstraint(c *ctx, key string) string {
+	if c.route == nil {
+		return ""
+	}
+	for _, s := range strings.Split(c.route.Path, "/") {
+		if strings.HasPrefix(s, ":") {
+			if k, constraint := engine.SplitParam(s[1:]); k == key {
+				return constraint
+			}
+		}
+	}
+	return ""
+}
+
+// paramvalue returns the route parameter key typed by its constraint, an
+// int64 for int, a uint64 for uint, a float64 for float, or a string.
+func paramvalue(c *ctx, key string) (interface{}, error) {
+	v, ok := routeparam(c, key)
+	if !ok {
+		return nil, MissingParam(key)
+	}
+	var err error
+	var ret interface{} = v
+	switch paramconstraint(c, key) {
+	case "int":
+		ret, err = strconv.ParseInt(v, 10, 64)
+	case "uint":
+		ret, err = strconv.ParseUint(v, 10, 64)
+	case "float":
+		ret, err = strconv.ParseFloat(v, 64)
+	}
+	if err != nil {
+		return nil, InvalidParam(v, key, err)
+	}
+	return ret, nil
+}
+
+var uuidpattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// paramuuid returns the route parameter key as a lower case UUID string.
+func paramuuid(c *ctx, key string) (string, error) {
+	v, ok := routeparam(c, key)
+	if !ok {
+		return "", MissingParam(key)
+	}
+	if !uuidpattern.MatchString(v) {
+		return "", InvalidParam(v, key, "not a UUID")
+	}
+	return strings.ToLower(v), nil
+}
+
+// queryint returns the query value key as an int, or def for a missing value,
+// or def with an error for an invalid value.
+func queryint(c *ctx, key string, def int) (int, error) {
+	v, ok := queryvalue(c, key)
+	if !ok || v == "" {
+		return def, nil
+	}
+	return parseint(key, v, def)
+}
+
+// querybool returns the query value key as a bool, where a key without a
+// value, e.g. "?verbose", is true.
+func querybool(c *ctx, key string, def bool) (bool, error) {
+	v, ok := queryvalue(c, key)
+	if !ok {
+		return def, nil
+	}
+	if v == "" {
+		return true, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return def, InvalidParam(v, key, "not a boolean")
+	}
+	return b, nil
+}
+
+// querytime returns the query value key as a time, in RFC 3339 or date only
+// format.
+func querytime(c *ctx, key string, def time.Time) (time.Time, error) {
+	v, ok := queryvalue(c, key)
+	if !ok || v == "" {
+		return def, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, nil
+		}
+	}
+	return def, InvalidParam(v, key, "not an RFC 3339 time or date")
+}
+
+var paramfxtension = map[string]interface{}{
+	"param":     paramvalue,
+	"paramint":  paramint,
+	"paramuuid": paramuuid,
+	"queryint":  queryint,
+	"querybool": querybool,
+	"querytime": querytime,
+}
+
+// ParamFxtension converts route parameters and query values to typed values.
+var ParamFxtension Fxtension = MakeFxtension("paramfxtension", paramfxtension)
+
+// Param returns the named route parameter typed by the constraint of the
+// route, e.g. an int64 for /users/:id<int>, or a string if unconstrained.
+func Param(c Ctx, key string) (interface{}, error) {
+	return c.Call("param", key)
+}
+
+// ParamInt returns the named route parameter as an int, or def and an error
+// if the parameter is missing or not an integer.
+func ParamInt(c Ctx, key string, def int) (int, error) {
+	v, err := c.Call("paramint", key, def)
+	if err != nil {
+		return def, err
+	}
+	return v.(int), nil
+}
+
+// ParamUUID returns the named route parameter as a lower case UUID, or an
+// error if the parameter is missing or not a UUID.
+func ParamUUID(c Ctx, key string) (string, error) {
+	v, err := c.Call("paramuuid", key)
+	if err != nil {
+		return "", err
+	}
+	return v.(string), nil
+}
+
+// QueryInt returns the named query value as an int, def if not provided, or
+// def and an error if not an integer.
+func QueryInt(c Ctx, key string, def int) (int, error) {
+	v, err := c.Call("queryint", key, def)
+	if err != nil {
+		return def, err
+	}
+	return v.(int), nil
+}
+
+// QueryBool returns the named query value as a bool, def if not provided, or
+// def and an error if not a boolean. A key without a value is true.
+func QueryBool(c Ctx, key string, def bool) (bool, error) {
+	v, err := c.Call("querybool", key, def)
+	if err != nil {
+		return def, err
+	}
+	return v.(bool), nil
+}
+
+// QueryTime returns the named query value as a time, in RFC 3339 or date
+// only format, def if not provided, or def and an error if invalid.
+func QueryTime(c Ctx, key string, def time.Time) (time.Time, error) {
+	v, err := c.Call("querytime", key, def)
+	if err != nil {
+		return def, err
+	}
+	return v.(time.Time), nil
+}
+
+// ParamLoader resolves the value of a route parameter, e.g. a tenant from its
+// id, returning an error if none is found.
+type ParamLoader func(c Ctx, value string) (interface{}, error)
+
+// Param requires the named route parameter of the routes of the Blueprint,
+// e.g. :tenant of a /t/:tenant prefix, running the loader before the managers of
+// each route registered with the Blueprint and setting what it resolves in
+// the Data of the Ctx under the name. A missing parameter or loader error
+// responds with a 404 status, unless the loader already aborted the Ctx.
+func (b *Blueprint) Param(name string, loader ParamLoader) {
+	b.Use(func(c Ctx) {
+		var err error = MissingParam(name)
+		params, _ := c.Call("params")
+		for _, p := range params.(engine.Params) {
+			if p.Key == name {
+				var item interface{}
+				if item, err = loader(c, p.Value); err == nil {
+					Set(c, name, item)
+					return
+				}
+				break
+			}
+		}
+		if !IsAborted(c) {
+			NotFound(c, err)
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package flotilla
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTypedParams(t *testing.T) {
+	a := New("testTypedParams", Mode("testing", true))
+	var id, page int
+	var uid string
+	var verbose bool
+	var since time.Time
+	var errs []error
+	a.GET("/items/:id/:uid", func(c Ctx) {
+		errs = nil
+		collect := func(err error) {
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+		var err error
+		id, err = ParamInt(c, "id", -1)
+		collect(err)
+		uid, err = ParamUUID(c, "uid")
+		collect(err)
+		page, err = QueryInt(c, "page", 1)
+		collect(err)
+		verbose, err = QueryBool(c, "verbose", false)
+		collect(err)
+		since, err = QueryTime(c, "since", time.Time{})
+		collect(err)
+	})
+	client := a.TestClient()
+
+	client.Get("/items/42/6BA7B810-9DAD-11D1-80B4-00C04FD430C8?page=3&verbose&since=2020-01-02")
+	if len(errs) != 0 || id != 42 || uid != "6ba7b810-9dad-11d1-80b4-00c04fd430c8" || page != 3 || !verbose || since.Year() != 2020 {
+		t.Errorf("unexpected typed values %d %s %d %v %v %v", id, uid, page, verbose, since, errs)
+	}
+
+	client.Get("/items/42/6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	if len(errs) != 0 || page != 1 || verbose || !since.IsZero() {
+		t.Errorf("expected defaults for missing query values, got %d %v %v %v", page, verbose, since, errs)
+	}
+
+	client.Get("/items/many/nope?page=two&verbose=maybe&since=yesterday")
+	if len(errs) != 5 || id != -1 || page != 1 {
+		t.Errorf("expected an error for each invalid value, got %v", errs)
+	}
+	if len(errs) > 0 && !strings.Contains(errs[0].Error(), `invalid value "many" for id`) {
+		t.Errorf("unexpected error message %q", errs[0])
+	}
+}
+
+func TestParamConstraints(t *testing.T) {
+	a := New("testParamConstraints", Mode("testing", true))
+	var id, slug interface{}
+	a.GET("/users/:id<int>/posts/:slug<re:[a-z-]+>", func(c Ctx) {
+		id, _ = Param(c, "id")
+		slug, _ = Param(c, "slug")
+	}).Rename("post")
+	client := a.TestClient()
+
+	if res := client.Get("/users/42/posts/hello-world"); res.Status != 200 || id != int64(42) || slug != "hello-world" {
+		t.Errorf("unexpected typed params %d %#v %#v", res.Status, id, slug)
+	}
+	for _, path := range []string{"/users/gopher/posts/hello", "/users/42/posts/Hello"} {
+		id = nil
+		if res := client.Get(path); res.Status != 404 || id != nil {
+			t.Errorf("expected a 404 before the handler for %s, got %d", path, res.Status)
+		}
+	}
+	if u, _ := a.URLFor("post", "7", "first-post"); u != "/users/7/posts/first-post" {
+		t.Errorf("unexpected url %q for a constrained route", u)
+	}
+}
+
+func TestBlueprintParamLoader(t *testing.T) {
+	a := New("testBlueprintParamLoader", Mode("testing", true))
+	tenants := map[string]string{"acme": "Acme Corp"}
+	var loaded int
+	var tenant interface{}
+	b := a.NewBlueprint("/t/:tenant")
+	b.Param("tenant", func(c Ctx, value string) (interface{}, error) {
+		loaded++
+		if name, ok := tenants[value]; ok {
+			return name, nil
+		}
+		return nil, MissingParam("tenant")
+	})
+	b.GET("/dashboard", func(c Ctx) { tenant, _ = Get(c, "tenant") })
+	client := a.TestClient()
+
+	if res := client.Get("/t/acme/dashboard"); res.Status != 200 || tenant != "Acme Corp" {
+		t.Errorf("expected the loaded tenant in the Ctx Data, got %d %v", res.Status, tenant)
+	}
+	tenant = nil
+	if res := client.Get("/t/nobody/dashboard"); res.Status != 404 || tenant != nil {
+		t.Errorf("expected a 404 before the handler for an unknown tenant, got %d %v", res.Status, tenant)
+	}
+	if loaded != 2 {
+		t.Errorf("expected the loader run once a request, got %d", loaded)
+	}
+}
@@ -0,0 +1,204 @@
+package flotilla
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/thrisp/flotilla/xrr"
+)
+
+// PasswordHasher hashes and verifies passwords with either bcrypt or argon2id,
+// using parameters read from an App Store.
+type PasswordHasher struct {
+	Algorithm    string
+	BcryptCost   int
+	ArgonTime    uint32
+	ArgonMemory  uint32
+	ArgonThreads uint8
+	ArgonKeyLen  uint32
+	SaltLen      int
+}
+
+// PasswordCheck is the result of verifying a password against a stored hash.
+// When the stored hash was made with weaker parameters or another algorithm
+// than currently configured, Rehashed holds a fresh hash to store in its place.
+type PasswordCheck struct {
+	Valid    bool
+	Rehashed string
+}
+
+// NeedsRehash returns a boolean indicating a new hash was generated for storage.
+func (p *PasswordCheck) NeedsRehash() bool {
+	return p.Rehashed != ""
+}
+
+func storeInt(s Store, key string, dflt int) int {
+	if item, ok := s[key]; ok {
+		if i := item.Int(); i > 0 {
+			return i
+		}
+	}
+	return dflt
+}
+
+// NewPasswordHasher returns a PasswordHasher configured from the provided Store.
+func NewPasswordHasher(s Store) *PasswordHasher {
+	algorithm := "bcrypt"
+	if item, ok := s["PASSWORD_ALGORITHM"]; ok && item.Value != "" {
+		algorithm = strings.ToLower(item.Value)
+	}
+	return &PasswordHasher{
+		Algorithm:    algorithm,
+		BcryptCost:   storeInt(s, "PASSWORD_BCRYPTCOST", bcrypt.DefaultCost),
+		ArgonTime:    uint32(storeInt(s, "PASSWORD_ARGONTIME", 1)),
+		ArgonMemory:  uint32(storeInt(s, "PASSWORD_ARGONMEMORY", 64*1024)),
+		ArgonThreads: uint8(storeInt(s, "PASSWORD_ARGONTHREADS", 4)),
+		ArgonKeyLen:  uint32(storeInt(s, "PASSWORD_ARGONKEYLENGTH", 32)),
+		SaltLen:      storeInt(s, "PASSWORD_SALTLENGTH", 16),
+	}
+}
+
+var UnknownHashAlgorithm = xrr.NewXrror("unknown password hash algorithm %q").Out
+
+var MalformedHash = xrr.NewXrror("malformed password hash: %s").Out
+
+// Hash returns an encoded hash of the password using the configured algorithm.
+func (p *PasswordHasher) Hash(password string) (string, error) {
+	switch p.Algorithm {
+	case "bcrypt":
+		h, err := bcrypt.GenerateFromPassword([]byte(password), p.BcryptCost)
+		return string(h), err
+	case "argon2id":
+		salt := make([]byte, p.SaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt, p.ArgonTime, p.ArgonMemory, p.ArgonThreads, p.ArgonKeyLen)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+			argon2.Version,
+			p.ArgonMemory, p.ArgonTime, p.ArgonThreads,
+			base64.RawStdEncoding.EncodeToString(salt),
+			base64.RawStdEncoding.EncodeToString(key),
+		), nil
+	}
+	return "", UnknownHashAlgorithm(p.Algorithm)
+}
+
+// Verify checks the password against the encoded hash. A valid password with
+// a hash weaker than, or different from, the current configuration is rehashed.
+func (p *PasswordHasher) Verify(hash, password string) (*PasswordCheck, error) {
+	var valid, stale bool
+	var err error
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		valid, stale, err = p.verifyArgon(hash, password)
+	case strings.HasPrefix(hash, "$2"):
+		valid, stale, err = p.verifyBcrypt(hash, password)
+	default:
+		return &PasswordCheck{}, MalformedHash("unrecognized prefix")
+	}
+	if err != nil || !valid {
+		return &PasswordCheck{}, err
+	}
+	check := &PasswordCheck{Valid: true}
+	if stale {
+		check.Rehashed, err = p.Hash(password)
+	}
+	return check, err
+}
+
+func (p *PasswordHasher) verifyBcrypt(hash, password string) (bool, bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+	cost, _ := bcrypt.Cost([]byte(hash))
+	stale := p.Algorithm != "bcrypt" || cost < p.BcryptCost
+	return true, stale, nil
+}
+
+func (p *PasswordHasher) verifyArgon(hash, password string) (bool, bool, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return false, false, MalformedHash("wrong number of segments")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return false, false, MalformedHash(err)
+	}
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false, false, MalformedHash(err)
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, false, MalformedHash(err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false, false, MalformedHash(err)
+	}
+	other := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return false, false, nil
+	}
+	stale := p.Algorithm != "argon2id" ||
+		version < argon2.Version ||
+		memory < p.ArgonMemory ||
+		time < p.ArgonTime ||
+		threads < p.ArgonThreads ||
+		uint32(len(key)) < p.ArgonKeyLen
+	return true, stale, nil
+}
+
+func passwordhasher(c *ctx) *PasswordHasher {
+	s, _ := c.Call("env", "store")
+	if store, ok := s.(Store); ok {
+		return NewPasswordHasher(store)
+	}
+	return NewPasswordHasher(defaultStore())
+}
+
+var cryptofxtension = map[string]interface{}{
+	"hashpassword":   hashpassword,
+	"verifypassword": verifypassword,
+}
+
+var CryptoFxtension Fxtension = MakeFxtension("cryptofxtension", cryptofxtension)
+
+func hashpassword(c *ctx, password string) (string, error) {
+	return passwordhasher(c).Hash(password)
+}
+
+func verifypassword(c *ctx, hash, password string) (*PasswordCheck, error) {
+	return passwordhasher(c).Verify(hash, password)
+}
+
+// HashPassword returns a hash of the password using the App Store password settings.
+func HashPassword(c Ctx, password string) (string, error) {
+	h, err := c.Call("hashpassword", password)
+	if err != nil {
+		return "", err
+	}
+	return h.(string), nil
+}
+
+// VerifyPassword checks a password against a stored hash using the App Store
+// password settings, returning a PasswordCheck that may include a rehash.
+func VerifyPassword(c Ctx, hash, password string) (*PasswordCheck, error) {
+	pc, err := c.Call("verifypassword", hash, password)
+	if pc == nil {
+		return &PasswordCheck{}, err
+	}
+	return pc.(*PasswordCheck), err
+}
@@ -0,0 +1,68 @@
+package flotilla
+
+import "testing"
+
+func TestPasswordHasher(t *testing.T) {
+	s := defaultStore()
+	for _, algo := range []string{"bcrypt", "argon2id"} {
+		s.add("password", "algorithm", algo)
+		p := NewPasswordHasher(s)
+		h, err := p.Hash("secret password")
+		if err != nil {
+			t.Fatalf("%s hash error: %s", algo, err)
+		}
+		pc, err := p.Verify(h, "secret password")
+		if err != nil || !pc.Valid {
+			t.Errorf("%s hash did not verify: %+v, %v", algo, pc, err)
+		}
+		if pc.NeedsRehash() {
+			t.Errorf("%s hash with current parameters should not need a rehash", algo)
+		}
+		pc, _ = p.Verify(h, "wrong password")
+		if pc.Valid {
+			t.Errorf("%s hash verified an incorrect password", algo)
+		}
+	}
+}
+
+func TestPasswordRehash(t *testing.T) {
+	s := defaultStore()
+	s.add("password", "bcryptcost", "4")
+	weak, _ := NewPasswordHasher(s).Hash("secret password")
+
+	s.add("password", "algorithm", "argon2id")
+	pc, err := NewPasswordHasher(s).Verify(weak, "secret password")
+	if err != nil || !pc.Valid {
+		t.Fatalf("weak hash did not verify: %+v, %v", pc, err)
+	}
+	if !pc.NeedsRehash() {
+		t.Errorf("bcrypt hash verified under argon2id configuration should be rehashed")
+	}
+	if pc, _ = NewPasswordHasher(s).Verify(pc.Rehashed, "secret password"); !pc.Valid || pc.NeedsRehash() {
+		t.Errorf("rehashed password did not verify cleanly: %+v", pc)
+	}
+}
+
+func TestCryptoExtension(t *testing.T) {
+	exp, _ := NewExpectation(
+		200,
+		"GET",
+		"/password",
+		func(t *testing.T) Manage {
+			return func(c Ctx) {
+				h, err := HashPassword(c, "secret password")
+				if err != nil {
+					t.Errorf("hashpassword error: %s", err)
+				}
+				pc, err := VerifyPassword(c, h, "secret password")
+				if err != nil || !pc.Valid {
+					t.Errorf("verifypassword did not verify: %+v, %v", pc, err)
+				}
+			}
+		},
+	)
+
+	app := testApp(t, "testCryptoExtension", EnvItem("password_bcryptcost:4"))
+
+	SimplePerformer(t, app, exp).Perform()
+}
@@ -0,0 +1,97 @@
+package flotilla
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/thrisp/flotilla/xrr"
+)
+
+// Problem is an RFC 7807 problem details response.
+type Problem struct {
+	Type     string `json:"type,omitempty"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+// wantsproblem reports whether the Accept header prefers a JSON response
+// to an HTML one.
+func wantsproblem(accept string) bool {
+	if strings.TrimSpace(accept) == "" {
+		return false
+	}
+	var problem float64
+	for _, mediatype := range []string{"application/problem+json", "application/json"} {
+		if q, ok := acceptquality(accept, mediatype); ok && q > problem {
+			problem = q
+		}
+	}
+	html, _ := acceptquality(accept, "text/html")
+	return problem > html
+}
+
+// httperror records err as an error of the ctx, and aborts it with the status
+// code, responding with application/problem+json for requests preferring
+// JSON, or else the status of the App, including any custom status managers.
+// The detail of server errors is only provided outside Production mode.
+func httperror(c *ctx, code int, err error) error {
+	if err != nil {
+		c.xrror(xrr.ErrorTypeExternal, nil, err)
+	}
+	if !wantsproblem(c.Request.Header.Get("Accept")) {
+		return c.abort(code)
+	}
+	p := &Problem{Type: "about:blank", Title: http.StatusText(code), Status: code, Instance: c.Request.URL.Path}
+	if err != nil && (code < 500 || !CurrentMode(c).Production) {
+		p.Detail = err.Error()
+	}
+	return c.abortwith(func() error {
+		s := jsonconfig(c)
+		c.push(func(Ctx) {
+			encodejson(c, s, code, "application/problem+json", s.prefix, "", p)
+		})
+		return nil
+	})
+}
+
+// HTTPError aborts the Ctx with the status code as Abort, recording err, if
+// any, as an error of the Ctx. Requests preferring JSON receive an RFC 7807
+// application/problem+json response, with the error as its detail; others
+// receive the status of the App, including any custom status managers.
+func HTTPError(c Ctx, code int, err error) error {
+	_, cerr := c.Call("httperror", code, err)
+	return cerr
+}
+
+// BadRequest aborts the Ctx with a 400 status, as HTTPError.
+func BadRequest(c Ctx, err error) error { return HTTPError(c, http.StatusBadRequest, err) }
+
+// Unauthorized aborts the Ctx with a 401 status, as HTTPError.
+func Unauthorized(c Ctx, err error) error { return HTTPError(c, http.StatusUnauthorized, err) }
+
+// Forbidden aborts the Ctx with a 403 status, as HTTPError.
+func Forbidden(c Ctx, err error) error { return HTTPError(c, http.StatusForbidden, err) }
+
+// NotFound aborts the Ctx with a 404 status, as HTTPError.
+func NotFound(c Ctx, err error) error { return HTTPError(c, http.StatusNotFound, err) }
+
+// MethodNotAllowed aborts the Ctx with a 405 status, as HTTPError.
+func MethodNotAllowed(c Ctx, err error) error { return HTTPError(c, http.StatusMethodNotAllowed, err) }
+
+// Conflict aborts the Ctx with a 409 status, as HTTPError.
+func Conflict(c Ctx, err error) error { return HTTPError(c, http.StatusConflict, err) }
+
+// UnprocessableEntity aborts the Ctx with a 422 status, as HTTPError.
+func UnprocessableEntity(c Ctx, err error) error {
+	return HTTPError(c, http.StatusUnprocessableEntity, err)
+}
+
+// TooManyRequests aborts the Ctx with a 429 status, as HTTPError.
+func TooManyRequests(c Ctx, err error) error { return HTTPError(c, http.StatusTooManyRequests, err) }
+
+// InternalServerError aborts the Ctx with a 500 status, as HTTPError.
+func InternalServerError(c Ctx, err error) error {
+	return HTTPError(c, http.StatusInternalServerError, err)
+}
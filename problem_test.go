@@ -0,0 +1,37 @@
+package flotilla
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestHTTPError(t *testing.T) {
+	a := testApp(t, "httperror")
+	var errs int
+	a.GET("/items/:id", func(c Ctx) {
+		c.Call("pushfinal", func(c Ctx) { errs = len(c.Errors()) })
+		NotFound(c, errors.New("no item 7"))
+		c.Call("serveplain", 200, "found")
+	})
+	client := a.TestClient()
+
+	res := client.Get("/items/7", "Accept", "application/json")
+	var p Problem
+	if err := json.Unmarshal(res.Body, &p); err != nil {
+		t.Fatalf("invalid problem response %q: %s", res.Body, err)
+	}
+	if res.Status != 404 || res.Header.Get("Content-Type") != "application/problem+json" ||
+		p.Status != 404 || p.Title != "Not Found" || p.Detail != "no item 7" || p.Instance != "/items/7" {
+		t.Errorf("unexpected problem response %d %v %+v", res.Status, res.Header, p)
+	}
+	if errs != 1 {
+		t.Errorf("expected 1 recorded error, got %d", errs)
+	}
+
+	for _, accept := range []string{"", "text/html,application/json;q=0.9"} {
+		if res = client.Get("/items/7", "Accept", accept); res.Status != 404 || string(res.Body) != "404 Not Found" {
+			t.Errorf("expected the status page for %q, got %d %q", accept, res.Status, res.Body)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package flotilla
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+	requestidData   = "_requestid"
+)
+
+// requestid returns the id of the current request, taken from an incoming
+// X-Request-ID header or generated on first use, and echoed in the response.
+func requestid(c *ctx) string {
+	if id, ok := c.Data[requestidData].(string); ok {
+		return id
+	}
+	id := c.Request.Header.Get(RequestIDHeader)
+	if id == "" || len(id) > 128 {
+		b := make([]byte, 16)
+		rand.Read(b)
+		id = hex.EncodeToString(b)
+	}
+	setdata(c, requestidData, id)
+	c.RW.Header().Set(RequestIDHeader, id)
+	return id
+}
+
+// RequestID returns the id of the current request.
+func RequestID(c Ctx) string {
+	id, _ := c.Call("requestid")
+	return id.(string)
+}
@@ -3,9 +3,12 @@ package flotilla
 import (
 	"bufio"
 	"errors"
+	"time"
 
 	"net"
 	"net/http"
+
+	"github.com/thrisp/flotilla/xrr"
 )
 
 const (
@@ -27,15 +30,52 @@ type (
 
 	responseWriter struct {
 		http.ResponseWriter
-		status int
-		size   int
+		status   int
+		size     int
+		limit    int64
+		deadline time.Time
+		err      error
 	}
 )
 
+var (
+	ResponseTooLarge      = xrr.NewXrror("response exceeds %d bytes").Out
+	WriteDeadlineExceeded = xrr.NewXrror("response write deadline exceeded").Out
+)
+
 func (w *responseWriter) reset(writer http.ResponseWriter) {
 	w.ResponseWriter = writer
 	w.status = 200
 	w.size = NotWritten
+	w.limit = 0
+	w.deadline = time.Time{}
+	w.err = nil
+}
+
+// setdeadline sets the write deadline of the response, and of the underlying
+// connection where supported.
+func (w *responseWriter) setdeadline(d time.Time) {
+	w.deadline = d
+	http.NewResponseController(w.ResponseWriter).SetWriteDeadline(d)
+}
+
+// check returns an error for a write of n bytes exceeding the response limit
+// or after the write deadline; once exceeded, all further writes fail.
+func (w *responseWriter) check(n int) error {
+	if w.err != nil {
+		return w.err
+	}
+	written := w.size
+	if written < 0 {
+		written = 0
+	}
+	switch {
+	case w.limit > 0 && int64(written+n) > w.limit:
+		w.err = ResponseTooLarge(w.limit)
+	case !w.deadline.IsZero() && !time.Now().Before(w.deadline):
+		w.err = WriteDeadlineExceeded()
+	}
+	return w.err
 }
 
 func (w *responseWriter) WriteHeader(code int) {
@@ -52,6 +92,9 @@ func (w *responseWriter) WriteHeaderNow() {
 }
 
 func (w *responseWriter) Write(data []byte) (n int, err error) {
+	if err = w.check(len(data)); err != nil {
+		return 0, err
+	}
 	w.WriteHeaderNow()
 	n, err = w.ResponseWriter.Write(data)
 	w.size += n
@@ -71,7 +114,12 @@ func (w *responseWriter) Written() bool {
 }
 
 func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
-	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	return hijack(w.ResponseWriter)
+}
+
+// hijack hijacks the connection of w, for response writers wrapping another.
+func hijack(w http.ResponseWriter) (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.(http.Hijacker)
 	if !ok {
 		return nil, nil, errors.New("the ResponseWriter doesn't support the Hijacker interface")
 	}
@@ -79,12 +127,24 @@ func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 }
 
 func (w *responseWriter) CloseNotify() <-chan bool {
-	return w.ResponseWriter.(http.CloseNotifier).CloseNotify()
+	if notifier, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return notifier.CloseNotify()
+	}
+	return nil
 }
 
+// Flush writes the header, if not yet written, and flushes any buffered data
+// to the client.
 func (w *responseWriter) Flush() {
+	w.WriteHeaderNow()
 	flusher, ok := w.ResponseWriter.(http.Flusher)
 	if ok {
 		flusher.Flush()
 	}
 }
+
+// Unwrap returns the underlying http.ResponseWriter, for use with an
+// http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
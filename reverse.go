@@ -0,0 +1,90 @@
+package flotilla
+
+import (
+	"strings"
+	"sync"
+)
+
+type routekey struct {
+	method string
+	path   string
+}
+
+// ReverseRoutes maps names to routes by method and path, for building urls
+// and redirects without hard coding paths. Names are resolved against the
+// registered routes when used, so routes may be named before registration.
+type ReverseRoutes struct {
+	mu    sync.RWMutex
+	names map[string]routekey
+}
+
+func newReverseRoutes() *ReverseRoutes {
+	return &ReverseRoutes{names: make(map[string]routekey)}
+}
+
+// Name names the route with the method and full path, e.g.
+// Name("user", "GET", "/users/:id").
+func (r *ReverseRoutes) Name(name, method, path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.names[name] = routekey{strings.ToUpper(method), path}
+}
+
+// Lookup returns the route with the name, or the generated name of a route.
+func (r *ReverseRoutes) Lookup(routes Routes, name string) (*Route, bool) {
+	r.mu.RLock()
+	key, ok := r.names[name]
+	r.mu.RUnlock()
+	if !ok {
+		rt, exists := routes[name]
+		return rt, exists
+	}
+	for _, rt := range routes {
+		if rt.Method == key.method && rt.Path == key.path {
+			return rt, true
+		}
+	}
+	return nil, false
+}
+
+// RouteName is a Configuration naming the route with the method and full
+// path in the App ReverseRoutes.
+func RouteName(name, method, path string) Configuration {
+	return func(a *App) error {
+		a.Env.Reverse.Name(name, method, path)
+		return nil
+	}
+}
+
+// URLFor returns the url path of the named route, built with the provided
+// params, for use outside a Ctx; it is also the urlfor template function.
+func (a *App) URLFor(name string, params ...string) (string, error) {
+	if rt, ok := a.Env.Reverse.Lookup(a.Routes(), name); ok {
+		if u, err := rt.Url(params...); err == nil {
+			return u.String(), nil
+		}
+	}
+	return "", NoUrl(name, params)
+}
+
+// redirecttoroute redirects to the url of the named route with params.
+func redirecttoroute(c *ctx, code int, route string, params []string) error {
+	u, err := c.Call("urlfor", route, false, params)
+	if err != nil {
+		return err
+	}
+	return redirect(c, code, u.(string))
+}
+
+// Redirect redirects the request to location with the 3xx status code.
+func Redirect(c Ctx, code int, location string) error {
+	_, err := c.Call("redirect", code, location)
+	return err
+}
+
+// RedirectToRoute redirects the request to the url of the named route,
+// built with the provided params, with the 3xx status code.
+func RedirectToRoute(c Ctx, code int, route string, params ...string) error {
+	_, err := c.Call("redirecttoroute", code, route, params)
+	return err
+}
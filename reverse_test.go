@@ -0,0 +1,58 @@
+package flotilla
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedirectToRoute(t *testing.T) {
+	a := New("testRedirectToRoute", Mode("testing", true), RouteName("user", "get", "/users/:id"))
+	var rerr error
+	a.GET("/users/:id", func(c Ctx) {})
+	a.GET("/old/:id", func(c Ctx) {
+		id, _ := c.Call("paramString", "id")
+		rerr = RedirectToRoute(c, 301, "user", id.(string))
+	})
+	a.GET("/missing", func(c Ctx) { rerr = RedirectToRoute(c, 302, "nope") })
+	a.GET("/plain", func(c Ctx) { rerr = Redirect(c, 303, "/elsewhere") })
+	client := a.TestClient()
+
+	if res := client.Get("/old/7"); rerr != nil || res.Status != 301 || res.Header.Get("Location") != "/users/7" {
+		t.Errorf("expected a redirect to the named route, got %d %q %v", res.Status, res.Header.Get("Location"), rerr)
+	}
+	if client.Get("/missing"); rerr == nil {
+		t.Error("expected an error for an unknown route name")
+	}
+	if res := client.Get("/plain"); rerr != nil || res.Status != 303 || res.Header.Get("Location") != "/elsewhere" {
+		t.Errorf("unexpected redirect %d %q %v", res.Status, res.Header.Get("Location"), rerr)
+	}
+}
+
+func TestURLFor(t *testing.T) {
+	a := New("testURLFor", Mode("testing", true))
+	a.GET("/users/:id/posts/:post", func(c Ctx) {}).Rename("post")
+	var external string
+	a.GET("/", func(c Ctx) {
+		u, _ := c.Call("urlfor", "post", true, []string{"1", "2"})
+		external = u.(string)
+	})
+	a.Configure()
+
+	if u, err := a.URLFor("post", "1", "2"); err != nil || u != "/users/1/posts/2" {
+		t.Errorf("unexpected url %q %v", u, err)
+	}
+	if _, err := a.URLFor("missing"); err == nil {
+		t.Error("expected an error for an unknown route name")
+	}
+	rq := httptest.NewRequest("GET", "/", nil)
+	rq.Host = "example.com"
+	a.ServeHTTP(httptest.NewRecorder(), rq)
+	if external != "//example.com/users/1/posts/2" {
+		t.Errorf("unexpected external url %q", external)
+	}
+	if fn, ok := a.Env.tplfunctions["urlfor"].(func(string, ...string) (string, error)); !ok {
+		t.Error("urlfor is not a template function")
+	} else if u, _ := fn("post", "3", "4"); u != "/users/3/posts/4" {
+		t.Errorf("unexpected template url %q", u)
+	}
+}
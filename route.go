@@ -32,10 +32,16 @@ type Route struct {
 	Blueprint  *Blueprint
 	Static     bool
 	Method     string
+	Host       string
 	Base       string
 	Path       string
 	Managers   []Manage
 	MakeCtx    MakeCtxFunc
+	Limits     RouteLimits
+	handlers   []Manage
+	use        []Manage
+	edits      []func([]Manage) []Manage
+	source     string
 }
 
 func (rt *Route) rule(rw http.ResponseWriter, rq *http.Request, rs *engine.Result) {
@@ -45,7 +51,7 @@ func (rt *Route) rule(rw http.ResponseWriter, rq *http.Request, rs *engine.Resul
 }
 
 // NewRoute returns a new, non-static, route instance with the given method, path,and Manage functions.
-//func NewRoute(method string, path string, managers []Manage) *Route {
+// func NewRoute(method string, path string, managers []Manage) *Route {
 func NewRoute(conf ...RouteConf) *Route {
 	rt := &Route{}
 	err := rt.Configure(conf...)
@@ -79,6 +85,64 @@ func staticRouteConf(method string, path string, managers []Manage) RouteConf {
 		return nil
 	}
 }
+
+// compose sets the managers of the route registered with its Blueprint: those
+// of the Blueprint, those added with Use, and the managers of the route
+// definition, edited by any Before, After, Replace, or Skip in order, and
+// preceded by the enforcement of any Limits of the route.
+func (rt *Route) compose() {
+	ms := append(rt.use[:len(rt.use):len(rt.use)], rt.handlers...)
+	ms = rt.Blueprint.combineManagers(ms)
+	for _, fn := range rt.edits {
+		ms = fn(ms)
+	}
+	if !rt.Limits.empty() {
+		ms = append([]Manage{rt.limitmanager()}, ms...)
+	}
+	rt.Managers = ms
+}
+
+func (rt *Route) edit(fn func([]Manage) []Manage) *Route {
+	rt.edits = append(rt.edits, fn)
+	if rt.Registered {
+		rt.compose()
+	}
+	return rt
+}
+
+// Use adds managers to the route, running after those of its Blueprint and
+// before those the route was defined with.
+func (rt *Route) Use(managers ...Manage) *Route {
+	rt.use = append(rt.use, managers...)
+	if rt.Registered {
+		rt.compose()
+	}
+	return rt
+}
+
+// Before inserts managers before the target manager of the route, including
+// the managers of its Blueprint, or after all if not found.
+func (rt *Route) Before(target Manage, managers ...Manage) *Route {
+	return rt.edit(func(ms []Manage) []Manage { return beforeManage(ms, target, managers) })
+}
+
+// After inserts managers after the target manager of the route, or after all
+// if not found.
+func (rt *Route) After(target Manage, managers ...Manage) *Route {
+	return rt.edit(func(ms []Manage) []Manage { return afterManage(ms, target, managers) })
+}
+
+// Replace replaces the target manager of the route with managers, if found.
+func (rt *Route) Replace(target Manage, managers ...Manage) *Route {
+	return rt.edit(func(ms []Manage) []Manage { return replaceManage(ms, target, managers) })
+}
+
+// Skip removes the target manager from the route, e.g. an authentication
+// manager of its Blueprint for a public route.
+func (rt *Route) Skip(target Manage) *Route {
+	return rt.edit(func(ms []Manage) []Manage { return skipManage(ms, target) })
+}
+
 func (rt *Route) Configure(conf ...RouteConf) error {
 	var err error
 	for _, c := range conf {
@@ -95,12 +159,17 @@ func (rt *Route) Name() string {
 	return rt.name
 }
 
+// Rename names the route, for building its url by name with UrlFor, URLFor,
+// or the urlfor template function.
 func (rt *Route) Rename(name string) {
 	rt.name = name
 }
 
 func Named(rt *Route) string {
 	n := strings.Split(rt.Path, "/")
+	if rt.Host != "" {
+		n[0] = rt.Host
+	}
 	n = append(n, strings.ToLower(rt.Method))
 	for index, value := range n {
 		if regSplat.MatchString(value) {
@@ -113,7 +182,7 @@ func Named(rt *Route) string {
 	return strings.Join(n, `\`)
 }
 
-var regParam = regexp.MustCompile(`:[^/#?()\.\\]+|\(\?P<[a-zA-Z0-9]+>.*\)`)
+var regParam = regexp.MustCompile(`:[^/#?()\.\\<]+(<[^/]*>)?|\(\?P<[a-zA-Z0-9]+>.*\)`)
 var regSplat = regexp.MustCompile(`\*[^/#?()\.\\]+|\(\?P<[a-zA-Z0-9]+>.*\)`)
 
 // Url returns a url for the route, provided the string parameters.
@@ -67,3 +67,41 @@ func TestRoute(t *testing.T) {
 		t.Errorf(`Urls were [%s], but should be [/one/parameter_one/,/two/parameter_two/,/stc/static/file/path/splat,/random/route/with/a_parameter]`, urls)
 	}
 }
+
+var ordering []string
+
+func orderA(c Ctx) { ordering = append(ordering, "a") }
+
+func orderB(c Ctx) { ordering = append(ordering, "b") }
+
+func orderC(c Ctx) { ordering = append(ordering, "c") }
+
+func orderD(c Ctx) { ordering = append(ordering, "d") }
+
+func orderH(c Ctx) { ordering = append(ordering, "h") }
+
+func TestManageOrdering(t *testing.T) {
+	a := New("testManageOrdering", Mode("testing", true))
+	a.Use(orderA)
+	b := a.NewBlueprint("/b", orderB)
+	b.Before(orderB, orderC)
+	a.GET("/used", orderH).Use(orderD)
+	b.GET("/skipped", orderH).Skip(orderA)
+	b.GET("/replaced", orderH).Replace(orderB, orderD, orderD).After(orderH, orderA)
+	late := b.GET("/late", orderH)
+	a.Configure()
+	late.Before(orderH, orderD).Skip(orderC)
+	client := a.TestClient()
+
+	for path, expected := range map[string]string{
+		"/used":       "adh",
+		"/b/skipped":  "cbh",
+		"/b/replaced": "acddha",
+		"/b/late":     "abdh",
+	} {
+		ordering = nil
+		if client.Get(path); strings.Join(ordering, "") != expected {
+			t.Errorf("managers of %s ran in order %q, expected %q", path, strings.Join(ordering, ""), expected)
+		}
+	}
+}
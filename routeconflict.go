@@ -0,0 +1,52 @@
+package flotilla
+
+import (
+	"strconv"
+
+	"github.com/thrisp/flotilla/xrr"
+)
+
+var (
+	DuplicateRoute = xrr.NewXrror("route %s %s is already registered").Out
+	RouteConflicts = xrr.NewXrror("%d route conflicts, the first: %s").Out
+)
+
+// conflict records and logs a route that could not be registered.
+func (a *App) conflict(err error) {
+	if a.Config != nil {
+		a.conflicts = append(a.conflicts, err)
+	}
+	if a.Env != nil {
+		a.Env.Log().Warn("route conflict", "app", a.name, "error", err)
+	}
+}
+
+// Conflicts returns the conflicts of routes that could not be registered, e.g.
+// duplicate paths, static segments shadowed by wildcards, or params of
+// different names at the same position, in the order encountered. The first
+// route registered is kept.
+func (a *App) Conflicts() []error {
+	if a.Config == nil {
+		return nil
+	}
+	return a.conflicts
+}
+
+// croutes fails the configuration of an App with route conflicts if
+// ROUTES_STRICT is true.
+func croutes(a *App) error {
+	if item, ok := a.Env.Store["ROUTES_STRICT"]; ok && item.Bool() && len(a.conflicts) > 0 {
+		return RouteConflicts(len(a.conflicts), a.conflicts[0])
+	}
+	return nil
+}
+
+// StrictRoutes sets ROUTES_STRICT of the Store, failing Configure, and so
+// Run, if any route conflicts are found when registering the routes of the
+// App, rather than only logging and skipping them.
+func StrictRoutes(on bool) Configuration {
+	return func(a *App) error {
+		a.Env.Store.add("routes", "strict", strconv.FormatBool(on))
+		return nil
+	}
+}
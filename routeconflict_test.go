@@ -0,0 +1,36 @@
+package flotilla
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouteConflicts(t *testing.T) {
+	a := New("testRouteConflicts", Mode("testing", true))
+	var routed string
+	a.GET("/users/:id", func(c Ctx) { routed = "id" })
+	a.GET("/users/:id", func(c Ctx) { routed = "duplicate" })
+	a.GET("/users/new", func(c Ctx) { routed = "new" })
+	b := a.NewBlueprint("/users")
+	b.GET("/:name/posts", func(c Ctx) { routed = "posts" })
+	if err := a.Configure(); err != nil {
+		t.Fatalf("expected conflicts not to fail configuration by default, got %s", err)
+	}
+	if n := len(a.Conflicts()); n != 3 {
+		t.Errorf("expected 3 conflicts, got %d: %v", n, a.Conflicts())
+	}
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/new", nil))
+	if routed != "id" {
+		t.Errorf("expected the first route registered kept, got %q", routed)
+	}
+
+	s := New("testStrictRoutes", Mode("testing", true), StrictRoutes(true))
+	s.GET("/users/:id", func(c Ctx) {})
+	s.GET("/users/new", func(c Ctx) {})
+	if err := s.Configure(); err == nil {
+		t.Error("expected strict routes to fail configuration on a conflict")
+	}
+	if s.configured() {
+		t.Error("expected an App with strict route conflicts not to run")
+	}
+}
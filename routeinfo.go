@@ -0,0 +1,101 @@
+package flotilla
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// RouteInfo describes a registered route, for debugging and generating
+// documentation.
+type RouteInfo struct {
+	Method    string   `json:"method"`
+	Path      string   `json:"path"`
+	Host      string   `json:"host,omitempty"`
+	Name      string   `json:"name"`
+	Blueprint string   `json:"blueprint"`
+	Static    bool     `json:"static,omitempty"`
+	Managers  []string `json:"managers"`
+	Source    string   `json:"source,omitempty"`
+}
+
+// managerName returns the package qualified function name of a manager.
+func managerName(m Manage) string {
+	if fn := runtime.FuncForPC(reflect.ValueOf(m).Pointer()); fn != nil {
+		return fn.Name()
+	}
+	return "unknown"
+}
+
+// Info returns the RouteInfo of the route, with the source location of its
+// definition, e.g. the call of GET, if known.
+func (rt *Route) Info() RouteInfo {
+	ri := RouteInfo{
+		Method: rt.Method,
+		Path:   rt.Path,
+		Host:   rt.Host,
+		Name:   rt.Name(),
+		Static: rt.Static,
+		Source: rt.source,
+	}
+	if rt.Blueprint != nil {
+		ri.Blueprint = rt.Blueprint.Prefix
+	}
+	for _, m := range rt.Managers {
+		ri.Managers = append(ri.Managers, managerName(m))
+	}
+	return ri
+}
+
+// Info returns the RouteInfo of the routes, sorted by host, path, and method.
+func (rs Routes) Info() []RouteInfo {
+	ret := make([]RouteInfo, 0, len(rs))
+	for _, rt := range rs {
+		ret = append(ret, rt.Info())
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Host != ret[j].Host {
+			return ret[i].Host < ret[j].Host
+		}
+		if ret[i].Path != ret[j].Path {
+			return ret[i].Path < ret[j].Path
+		}
+		return ret[i].Method < ret[j].Method
+	})
+	return ret
+}
+
+func routesfunc(a *App) func(*ctx) []RouteInfo {
+	return func(c *ctx) []RouteInfo {
+		return a.Routes().Info()
+	}
+}
+
+// RouteTable is a Manage function rendering the routes of the App, as JSON if
+// accepted, or as a text table, in Development mode only, responding 404
+// otherwise.
+func RouteTable(c Ctx) {
+	if m := CurrentMode(c); !m.Development || m.Production {
+		c.Call("status", 404)
+		return
+	}
+	ri, _ := c.Call("routes")
+	routes := ri.([]RouteInfo)
+	if strings.Contains(CurrentRequest(c).Header.Get("Accept"), "application/json") {
+		writejson(c, 200, routes)
+		return
+	}
+	rw, _ := c.Call("responsewriter")
+	w := rw.(ResponseWriter)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(200)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tPATH\tNAME\tBLUEPRINT\tMANAGERS\tSOURCE")
+	for _, r := range routes {
+		fmt.Fprintf(tw, "%s\t%s%s\t%s\t%s\t%s\t%s\n", r.Method, r.Host, r.Path, r.Name, r.Blueprint, strings.Join(r.Managers, ", "), r.Source)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,56 @@
+package flotilla
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRouteInfo(t *testing.T) {
+	a := New("testRouteInfo", Mode("testing", true))
+	a.GET("/users/:id", one, two).Rename("user")
+	a.NewBlueprint("/admin", three).POST("/items", two)
+	a.GET("/routes", RouteTable)
+	a.Configure()
+
+	var user RouteInfo
+	for _, ri := range a.Routes().Info() {
+		if ri.Name == "user" {
+			user = ri
+		}
+	}
+	if user.Method != "GET" || user.Path != "/users/:id" || user.Blueprint != "/" {
+		t.Errorf("unexpected route info %+v", user)
+	}
+	if len(user.Managers) != 2 || !strings.HasSuffix(user.Managers[0], ".one") || !strings.HasSuffix(user.Managers[1], ".two") {
+		t.Errorf("unexpected manager names %v", user.Managers)
+	}
+	if !strings.Contains(user.Source, "routeinfo_test.go:") {
+		t.Errorf("unexpected route source %q", user.Source)
+	}
+
+	rq := httptest.NewRequest("GET", "/routes", nil)
+	rq.Header.Set("Accept", "application/json")
+	rw := httptest.NewRecorder()
+	a.ServeHTTP(rw, rq)
+	var routes []RouteInfo
+	if err := json.Unmarshal(rw.Body.Bytes(), &routes); err != nil || len(routes) != len(a.Routes()) {
+		t.Errorf("unexpected JSON route table %d %q %v", rw.Code, rw.Body.String(), err)
+	}
+
+	rw = httptest.NewRecorder()
+	a.ServeHTTP(rw, httptest.NewRequest("GET", "/routes", nil))
+	if body := rw.Body.String(); !strings.HasPrefix(body, "METHOD") || !strings.Contains(body, "/admin/items") {
+		t.Errorf("unexpected route table %q", body)
+	}
+
+	p := New("testRouteInfoProduction", Mode("production", true))
+	p.GET("/routes", RouteTable)
+	p.Configure()
+	rw = httptest.NewRecorder()
+	p.ServeHTTP(rw, httptest.NewRequest("GET", "/routes", nil))
+	if rw.Code != 404 {
+		t.Errorf("expected a 404 for the route table in production, got %d", rw.Code)
+	}
+}
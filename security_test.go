@@ -0,0 +1,47 @@
+package flotilla
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	var nonce string
+
+	exp, _ := NewExpectation(
+		200,
+		"GET",
+		"/secure",
+		func(t *testing.T) Manage { return DefaultSecurityHeaders().Manage },
+		func(t *testing.T) Manage {
+			return func(c Ctx) {
+				nonce = CSPNonce(c)
+				if again := CSPNonce(c); again != nonce {
+					t.Errorf("CSP nonce changed within a request: %s, %s", nonce, again)
+				}
+				c.Call("serveplain", 200, nonce)
+			}
+		},
+	)
+	exp.SetPost(
+		func(t *testing.T, r *httptest.ResponseRecorder) {
+			csp := r.Header().Get("Content-Security-Policy")
+			for _, directive := range []string{"script-src 'self' 'nonce-", "style-src 'self' 'nonce-"} {
+				if !strings.Contains(csp, directive+nonce+"'") {
+					t.Errorf("Content-Security-Policy %q does not contain %s%s'", csp, directive, nonce)
+				}
+			}
+			if r.Header().Get("X-Content-Type-Options") != "nosniff" {
+				t.Errorf("X-Content-Type-Options header was not set: %v", r.Header())
+			}
+			if r.Body.String() != nonce || nonce == "" {
+				t.Errorf("Expected response body to be the nonce %q, but was %q", nonce, r.Body.String())
+			}
+		},
+	)
+
+	a := testApp(t, "testSecurityHeaders")
+
+	SimplePerformer(t, a, exp).Perform()
+}
@@ -0,0 +1,124 @@
+package flotilla
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/thrisp/flotilla/xrr"
+)
+
+var IsDirectory = xrr.NewXrror("%s is a directory").Out
+
+// ratewriter paces writes to rate bytes per second from its first write,
+// until done is closed.
+type ratewriter struct {
+	http.ResponseWriter
+	rate    int64
+	start   time.Time
+	written int64
+	done    <-chan struct{}
+}
+
+func newratewriter(w http.ResponseWriter, rate int64, done <-chan struct{}) *ratewriter {
+	return &ratewriter{ResponseWriter: w, rate: rate, done: done}
+}
+
+func (w *ratewriter) Write(p []byte) (int, error) {
+	if w.start.IsZero() {
+		w.start = time.Now()
+	}
+	chunk := int(w.rate / 10)
+	if chunk < 1 {
+		chunk = 1
+	}
+	var n int
+	for len(p) > 0 {
+		size := chunk
+		if size > len(p) {
+			size = len(p)
+		}
+		m, err := w.ResponseWriter.Write(p[:size])
+		n += m
+		w.written += int64(m)
+		if err != nil {
+			return n, err
+		}
+		p = p[size:]
+		due := w.start.Add(time.Duration(w.written * int64(time.Second) / w.rate))
+		if wait := time.Until(due); wait > 0 {
+			t := time.NewTimer(wait)
+			select {
+			case <-t.C:
+			case <-w.done:
+				t.Stop()
+				return n, http.ErrAbortHandler
+			}
+		}
+	}
+	return n, nil
+}
+
+func (w *ratewriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func openfile(a *App, path string) (http.File, error) {
+	if f, err := os.Open(path); err == nil {
+		return f, nil
+	}
+	return a.Assets.Get(path)
+}
+
+// sendfile serves the file at path on disk, or else the named file of the
+// App Assets, with the disposition and download name, at most rate bytes per
+// second if rate is positive.
+func sendfile(a *App, c *ctx, path, name, disposition string, rate int64) error {
+	if name == "" {
+		name = filepath.Base(path)
+	}
+	f, err := openfile(a, path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		return IsDirectory(path)
+	}
+	return sendcontent(c, name, fi.ModTime(), f, disposition, rate)
+}
+
+func sendfilefunc(a *App) func(*ctx, string, int64) error {
+	return func(c *ctx, path string, rate int64) error {
+		return sendfile(a, c, path, "", Inline, rate)
+	}
+}
+
+func attachmentfunc(a *App) func(*ctx, string, string, int64) error {
+	return func(c *ctx, path, name string, rate int64) error {
+		return sendfile(a, c, path, name, Attachment, rate)
+	}
+}
+
+// SendFile serves the file at path on disk, or else the file of the App
+// Assets with the name, inline, with its Content-Type, Content-Length, and
+// support for Range requests, at most rate bytes per second if rate is
+// positive. The path is served as provided, and should not come from the
+// request unchecked.
+func SendFile(c Ctx, path string, rate int64) error {
+	_, err := c.Call("sendfile", path, rate)
+	return err
+}
+
+// SendAttachment serves a file as SendFile as an attachment downloaded with
+// the name, or the base of the path if name is empty, with an RFC 5987
+// encoded filename for names that are not ASCII.
+func SendAttachment(c Ctx, path, name string, rate int64) error {
+	_, err := c.Call("attachment", path, name, rate)
+	return err
+}
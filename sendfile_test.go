@@ -0,0 +1,54 @@
+package flotilla
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSendFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "report.txt")
+	content := strings.Repeat("flotilla ", 100)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	a := testApp(t, "sendfile", WithAssets(TestAsset))
+	a.GET("/inline", func(c Ctx) { SendFile(c, path, 0) })
+	a.GET("/download", func(c Ctx) { SendAttachment(c, path, "résumé 2024.txt", 0) })
+	a.GET("/slow", func(c Ctx) { SendFile(c, path, 3000) })
+	a.GET("/asset", func(c Ctx) { SendAttachment(c, "test_asset.html", "", 0) })
+	a.GET("/directory", func(c Ctx) {
+		if err := SendFile(c, dir, 0); err == nil {
+			t.Error("expected an error sending a directory")
+		}
+	})
+	client := a.TestClient()
+
+	res := client.Get("/inline")
+	if res.Status != 200 || string(res.Body) != content || res.Header.Get("Content-Length") != "900" ||
+		!strings.HasPrefix(res.Header.Get("Content-Type"), "text/plain") || res.Header.Get("Content-Disposition") != "inline; filename=report.txt" {
+		t.Errorf("unexpected inline file response %d %v", res.Status, res.Header)
+	}
+
+	res = client.Get("/download")
+	expected := `attachment; filename="r_sum_ 2024.txt"; filename*=UTF-8''r%C3%A9sum%C3%A9%202024.txt`
+	if res.Status != 200 || res.Header.Get("Content-Disposition") != expected {
+		t.Errorf("unexpected attachment disposition %q", res.Header.Get("Content-Disposition"))
+	}
+
+	start := time.Now()
+	if res = client.Get("/slow"); string(res.Body) != content {
+		t.Errorf("unexpected rate limited body %q", res.Body)
+	}
+	if elapsed := time.Since(start); elapsed < 250*time.Millisecond {
+		t.Errorf("expected a rate limited response to take at least 250ms, took %s", elapsed)
+	}
+
+	if res = client.Get("/asset"); res.Status != 200 || res.Header.Get("Content-Disposition") != "attachment; filename=test_asset.html" {
+		t.Errorf("unexpected asset attachment %d %v", res.Status, res.Header)
+	}
+	client.Get("/directory")
+}
@@ -0,0 +1,394 @@
+//go:build bolt
+// +build bolt
+
+package session
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	boltpder = &BoltProvider{}
+
+	// boltindex maps each session id to the day bucket holding the session.
+	boltindex = []byte("_index")
+)
+
+const boltday = "2006-01-02"
+
+type (
+	BoltSessionStore struct {
+		sid    string
+		values map[interface{}]interface{}
+		lock   sync.RWMutex
+		pder   *BoltProvider
+	}
+
+	// BoltProvider stores sessions in an embedded bbolt database file, for
+	// durable sessions without an external service. Sessions are kept in a
+	// bucket per day of expiry, so gc deletes whole buckets of expired
+	// sessions. Build with the "bolt" tag to include the provider.
+	BoltProvider struct {
+		clock
+		maxlifetime int64
+		config      *boltConfig
+		db          *bolt.DB
+		serializer  Serializer
+		reads       int64
+		writes      int64
+		reclaimed   int64
+	}
+
+	boltConfig struct {
+		Path       string `json:"path"`
+		Serializer string `json:"serializer"`
+		Timeout    int    `json:"timeout"`
+	}
+)
+
+// Set value to bolt session.
+func (st *BoltSessionStore) Set(key, value interface{}) error {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	st.values[key] = value
+	return nil
+}
+
+// Get value from bolt session.
+func (st *BoltSessionStore) Get(key interface{}) interface{} {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	return st.values[key]
+}
+
+// Delete value in bolt session.
+func (st *BoltSessionStore) Delete(key interface{}) error {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	delete(st.values, key)
+	return nil
+}
+
+// Clean all values in bolt session.
+func (st *BoltSessionStore) Flush() error {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	st.values = make(map[interface{}]interface{})
+	return nil
+}
+
+// Keys returns the keys of the bolt session values.
+func (st *BoltSessionStore) Keys() []interface{} {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	return keysof(st.values)
+}
+
+// Export returns a copy of the bolt session values.
+func (st *BoltSessionStore) Export() map[interface{}]interface{} {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	return copyvalues(st.values)
+}
+
+// Return id of this bolt session.
+func (st *BoltSessionStore) SessionID() string {
+	return st.sid
+}
+
+// Save the bolt session values to the database.
+func (st *BoltSessionStore) SessionRelease(w http.ResponseWriter) {
+	st.Save()
+}
+
+// Save the bolt session values to the database, returning any error.
+func (st *BoltSessionStore) Save() error {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	return st.pder.save(st.sid, st.values)
+}
+
+// Init bolt session provider with max lifetime and config json, opening the
+// database file.
+// json config:
+//
+//	path - database file path, default "flotilla_sessions.db"
+//	serializer - registered Serializer name, default "gob"
+//	timeout - seconds to wait for the database file lock, default 1
+func (pder *BoltProvider) SessionInit(maxlifetime int64, config string) error {
+	pder.config = &boltConfig{}
+	if err := json.Unmarshal([]byte(config), pder.config); err != nil {
+		return err
+	}
+	if pder.config.Path == "" {
+		pder.config.Path = "flotilla_sessions.db"
+	}
+	if pder.config.Serializer == "" {
+		pder.config.Serializer = "gob"
+	}
+	if pder.config.Timeout <= 0 {
+		pder.config.Timeout = 1
+	}
+	s, ok := serializers[pder.config.Serializer]
+	if !ok {
+		return fmt.Errorf("session: unknown serializer %q", pder.config.Serializer)
+	}
+	pder.serializer = s
+
+	if pder.db != nil {
+		pder.db.Close()
+	}
+	db, err := bolt.Open(pder.config.Path, 0600, &bolt.Options{Timeout: time.Duration(pder.config.Timeout) * time.Second})
+	if err != nil {
+		return err
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltindex)
+		return err
+	}); err != nil {
+		db.Close()
+		return err
+	}
+	pder.db = db
+	pder.maxlifetime = maxlifetime
+	return nil
+}
+
+// Close closes the bolt database.
+func (pder *BoltProvider) Close() error {
+	if pder.db == nil {
+		return nil
+	}
+	return pder.db.Close()
+}
+
+func dayof(unix int64) []byte {
+	return []byte(time.Unix(unix, 0).UTC().Format(boltday))
+}
+
+// boltget returns the stored value of sid and its expiry, or false when the
+// session does not exist.
+func boltget(tx *bolt.Tx, sid []byte) ([]byte, int64, bool) {
+	day := tx.Bucket(boltindex).Get(sid)
+	if day == nil {
+		return nil, 0, false
+	}
+	b := tx.Bucket(day)
+	if b == nil {
+		return nil, 0, false
+	}
+	v := b.Get(sid)
+	if len(v) < 8 {
+		return nil, 0, false
+	}
+	return v[8:], int64(binary.BigEndian.Uint64(v[:8])), true
+}
+
+func boltremove(tx *bolt.Tx, sid []byte) error {
+	index := tx.Bucket(boltindex)
+	if day := index.Get(sid); day != nil {
+		if b := tx.Bucket(day); b != nil {
+			if err := b.Delete(sid); err != nil {
+				return err
+			}
+		}
+	}
+	return index.Delete(sid)
+}
+
+func (pder *BoltProvider) put(tx *bolt.Tx, sid []byte, data []byte) error {
+	expiry := pder.Now().Unix() + pder.maxlifetime
+	day := dayof(expiry)
+	index := tx.Bucket(boltindex)
+	if old := index.Get(sid); old != nil && !bytes.Equal(old, day) {
+		if b := tx.Bucket(old); b != nil {
+			if err := b.Delete(sid); err != nil {
+				return err
+			}
+		}
+	}
+	b, err := tx.CreateBucketIfNotExists(day)
+	if err != nil {
+		return err
+	}
+	v := make([]byte, 8, 8+len(data))
+	binary.BigEndian.PutUint64(v, uint64(expiry))
+	if err := b.Put(sid, append(v, data...)); err != nil {
+		return err
+	}
+	return index.Put(sid, day)
+}
+
+func (pder *BoltProvider) save(sid string, values map[interface{}]interface{}) error {
+	data, err := pder.serializer.Encode(values)
+	if err != nil {
+		return err
+	}
+	atomic.AddInt64(&pder.writes, 1)
+	return pder.db.Update(func(tx *bolt.Tx) error {
+		return pder.put(tx, []byte(sid), data)
+	})
+}
+
+// Get SessionStore from the database, creating the session if it does not
+// exist or has expired.
+func (pder *BoltProvider) SessionRead(sid string) (SessionStore, error) {
+	atomic.AddInt64(&pder.reads, 1)
+	var data []byte
+	var found bool
+	err := pder.db.View(func(tx *bolt.Tx) error {
+		v, expiry, ok := boltget(tx, []byte(sid))
+		if ok && expiry >= pder.Now().Unix() {
+			data, found = append([]byte(nil), v...), true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var values map[interface{}]interface{}
+	switch {
+	case !found:
+		values = make(map[interface{}]interface{})
+		if err := pder.save(sid, values); err != nil {
+			return nil, err
+		}
+	case len(data) == 0:
+		values = make(map[interface{}]interface{})
+	default:
+		if values, err = pder.serializer.Decode(data); err != nil {
+			return nil, err
+		}
+	}
+	return &BoltSessionStore{sid: sid, values: values, pder: pder}, nil
+}
+
+// Check bolt session exists and has not expired.
+func (pder *BoltProvider) SessionExist(sid string) bool {
+	var exists bool
+	pder.db.View(func(tx *bolt.Tx) error {
+		_, expiry, ok := boltget(tx, []byte(sid))
+		exists = ok && expiry >= pder.Now().Unix()
+		return nil
+	})
+	return exists
+}
+
+// Move the session with oldsid to sid, returning a SessionStore for sid.
+func (pder *BoltProvider) SessionRegenerate(oldsid, sid string) (SessionStore, error) {
+	err := pder.db.Update(func(tx *bolt.Tx) error {
+		v, _, ok := boltget(tx, []byte(oldsid))
+		if !ok {
+			return nil
+		}
+		data := append([]byte(nil), v...)
+		if err := boltremove(tx, []byte(oldsid)); err != nil {
+			return err
+		}
+		return pder.put(tx, []byte(sid), data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pder.SessionRead(sid)
+}
+
+// Delete the bolt session by id.
+func (pder *BoltProvider) SessionDestroy(sid string) error {
+	return pder.db.Update(func(tx *bolt.Tx) error {
+		return boltremove(tx, []byte(sid))
+	})
+}
+
+// Delete expired bolt sessions, deleting the buckets of past days whole and
+// checking the expiry of sessions in the bucket of the current day.
+func (pder *BoltProvider) SessionGC() {
+	now := pder.Now().Unix()
+	today := dayof(now)
+	var reclaimed int64
+	pder.db.Update(func(tx *bolt.Tx) error {
+		index := tx.Bucket(boltindex)
+		var expired [][]byte
+		tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			if bytes.Equal(name, boltindex) || bytes.Compare(name, today) >= 0 {
+				return nil
+			}
+			expired = append(expired, append([]byte(nil), name...))
+			return b.ForEach(func(k, v []byte) error {
+				reclaimed++
+				return index.Delete(k)
+			})
+		})
+		for _, name := range expired {
+			if err := tx.DeleteBucket(name); err != nil {
+				return err
+			}
+		}
+		if b := tx.Bucket(today); b != nil {
+			var keys [][]byte
+			b.ForEach(func(k, v []byte) error {
+				if len(v) < 8 || int64(binary.BigEndian.Uint64(v[:8])) < now {
+					keys = append(keys, append([]byte(nil), k...))
+				}
+				return nil
+			})
+			for _, k := range keys {
+				if err := boltremove(tx, k); err != nil {
+					return err
+				}
+				reclaimed++
+			}
+		}
+		return nil
+	})
+	atomic.AddInt64(&pder.reclaimed, reclaimed)
+}
+
+// Count bolt sessions, including any expired sessions not yet removed by gc.
+func (pder *BoltProvider) SessionAll() int {
+	var n int
+	pder.db.View(func(tx *bolt.Tx) error {
+		n = tx.Bucket(boltindex).Stats().KeyN
+		return nil
+	})
+	return n
+}
+
+// Stats returns counts of bolt session reads, writes, and sessions deleted by
+// gc.
+func (pder *BoltProvider) Stats() Stats {
+	return Stats{
+		Reads:       atomic.LoadInt64(&pder.reads),
+		Writes:      atomic.LoadInt64(&pder.writes),
+		GCReclaimed: atomic.LoadInt64(&pder.reclaimed),
+	}
+}
+
+// SessionIDs returns the ids of unexpired bolt sessions.
+func (pder *BoltProvider) SessionIDs() ([]string, error) {
+	var ret []string
+	now := pder.Now().Unix()
+	err := pder.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltindex).ForEach(func(k, v []byte) error {
+			if _, expiry, ok := boltget(tx, k); ok && expiry >= now {
+				ret = append(ret, string(k))
+			}
+			return nil
+		})
+	})
+	return ret, err
+}
+
+func init() {
+	Register("bolt", boltpder)
+}
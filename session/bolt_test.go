@@ -0,0 +1,52 @@
+//go:build bolt
+// +build bolt
+
+package session
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBoltProvider(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	p := &BoltProvider{}
+	p.SetClock(func() time.Time { return start })
+	if err := p.SessionInit(3600, fmt.Sprintf(`{"path":%q}`, filepath.Join(t.TempDir(), "sessions.db"))); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	st, err := p.SessionRead("sid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.Set("key", "value")
+	st.SessionRelease(nil)
+
+	if st, _ := p.SessionRead("sid"); st.Get("key") != "value" {
+		t.Error("bolt session value was not stored")
+	}
+	if _, err := p.SessionRegenerate("sid", "newsid"); err != nil {
+		t.Fatal(err)
+	}
+	if p.SessionExist("sid") || !p.SessionExist("newsid") {
+		t.Error("bolt session was not moved to the regenerated id")
+	}
+	if st, _ := p.SessionRead("newsid"); st.Get("key") != "value" {
+		t.Error("regenerated bolt session did not keep its values")
+	}
+
+	p.SetClock(func() time.Time { return start.Add(12 * time.Hour) })
+	p.SessionRead("later")
+	p.SetClock(func() time.Time { return start.Add(48 * time.Hour) })
+	p.SessionGC()
+	if p.SessionExist("newsid") || p.SessionExist("later") || p.SessionAll() != 0 {
+		t.Errorf("bolt gc did not remove expired sessions, %d remain", p.SessionAll())
+	}
+	if p.Stats().GCReclaimed != 2 {
+		t.Errorf("bolt gc reclaimed %d sessions, expected 2", p.Stats().GCReclaimed)
+	}
+}
@@ -3,7 +3,9 @@ package session
 import (
 	"crypto/aes"
 	"crypto/cipher"
-
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"net/http"
 	"net/url"
@@ -22,18 +24,32 @@ type (
 	}
 
 	CookieProvider struct {
+		clock
 		maxlifetime int64
 		config      *cookieConfig
-		block       cipher.Block
+		keys        []cookiekey
+		samesite    http.SameSite
+		payload     Payload
+	}
+
+	// cookiekey is a security key with the block cipher and security name
+	// used with it, newest first.
+	cookiekey struct {
+		hash  string
+		name  string
+		block cipher.Block
 	}
 
 	cookieConfig struct {
-		SecurityKey  string `json:"securityKey"`
-		BlockKey     string `json:"blockKey"`
-		SecurityName string `json:"securityName"`
-		CookieName   string `json:"cookieName"`
-		Secure       bool   `json:"secure"`
-		Maxage       int    `json:"maxage"`
+		SecurityKey  string   `json:"securityKey"`
+		SecurityKeys []string `json:"securityKeys"`
+		BlockKey     string   `json:"blockKey"`
+		SecurityName string   `json:"securityName"`
+		CookieName   string   `json:"cookieName"`
+		Secure       bool     `json:"secure"`
+		Maxage       int      `json:"maxage"`
+		SameSite     string   `json:"sameSite"`
+		Partitioned  bool     `json:"partitioned"`
 	}
 )
 
@@ -73,26 +89,50 @@ func (st *CookieSessionStore) Flush() error {
 	return nil
 }
 
+// Keys returns the keys of the cookie session values.
+func (st *CookieSessionStore) Keys() []interface{} {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	return keysof(st.values)
+}
+
+// Export returns a copy of the cookie session values.
+func (st *CookieSessionStore) Export() map[interface{}]interface{} {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	return copyvalues(st.values)
+}
+
 // Return id of this cookie session
 func (st *CookieSessionStore) SessionID() string {
 	return st.sid
 }
 
-// Write cookie session to http response cookie
+// Write cookie session to http response cookie, unless the cookie exceeds
+// the maximum payload size.
 func (st *CookieSessionStore) SessionRelease(w http.ResponseWriter) {
-	str, err := encodeCookie(cookiepder.block,
-		cookiepder.config.SecurityKey,
-		cookiepder.config.SecurityName,
-		st.values)
+	st.lock.RLock()
+	b, err := EncodeGob(st.values)
+	st.lock.RUnlock()
+	if err != nil {
+		return
+	}
+	str, err := cookiepder.payload.encode(b, func(b []byte) (string, error) {
+		key := cookiepder.keys[0]
+		str, err := sealCookie(key.block, key.hash, key.name, b, cookiepder.Now())
+		return url.QueryEscape(str), err
+	})
 	if err != nil {
 		return
 	}
 	cookie := &http.Cookie{Name: cookiepder.config.CookieName,
-		Value:    url.QueryEscape(str),
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   cookiepder.config.Secure,
-		MaxAge:   cookiepder.config.Maxage}
+		Value:       str,
+		Path:        "/",
+		HttpOnly:    true,
+		Secure:      cookiepder.config.Secure || cookiepder.samesite == http.SameSiteNoneMode,
+		SameSite:    cookiepder.samesite,
+		Partitioned: cookiepder.config.Partitioned,
+		MaxAge:      cookiepder.config.Maxage}
 	http.SetCookie(w, cookie)
 	return
 }
@@ -100,38 +140,86 @@ func (st *CookieSessionStore) SessionRelease(w http.ResponseWriter) {
 // Init cookie session provider with max lifetime and config json.
 // maxlifetime is ignored.
 // json config:
-// 	securityKey - hash string
-// 	blockKey - gob encode hash string. it's saved as aes crypto.
-// 	securityName - recognized name in encoded cookie string
-// 	cookieName - cookie name
-// 	maxage - cookie max life time.
+//
+//	securityKey - hash string
+//	securityKeys - previous hash strings, accepted when reading cookies
+//	blockKey - gob encode hash string. it's saved as aes crypto. derived
+//	           from each security key when not provided.
+//	securityName - recognized name in encoded cookie string, derived from
+//	               each security key when not provided.
+//	cookieName - cookie name
+//	maxage - cookie max life time.
+//	sameSite - cookie SameSite mode; lax, strict, or none
+//	partitioned - cookie Partitioned (CHIPS) flag
 func (pder *CookieProvider) SessionInit(maxlifetime int64, config string) error {
 	pder.config = &cookieConfig{}
 	err := json.Unmarshal([]byte(config), pder.config)
 	if err != nil {
 		return err
 	}
-	if pder.config.BlockKey == "" {
-		pder.config.BlockKey = string(generateRandomKey(16))
+	if pder.keys, err = cookiekeys(pder.config); err != nil {
+		return err
 	}
-	if pder.config.SecurityName == "" {
-		pder.config.SecurityName = string(generateRandomKey(20))
+	if pder.samesite, err = ParseSameSite(pder.config.SameSite); err != nil {
+		return err
 	}
-	pder.block, err = aes.NewCipher([]byte(pder.config.BlockKey))
-	if err != nil {
+	secure := pder.config.Secure || pder.samesite == http.SameSiteNoneMode
+	if err = checkcookie(pder.config.CookieName, secure, "", pder.config.Partitioned); err != nil {
 		return err
 	}
 	pder.maxlifetime = maxlifetime
 	return nil
 }
 
+// cookiekeys returns the security key and any previous security keys with
+// their block ciphers and security names, derived from each key unless the
+// blockKey or securityName is configured, so cookies remain readable across
+// processes and key rotation. Without a security key the block key and
+// security name are random.
+func cookiekeys(config *cookieConfig) ([]cookiekey, error) {
+	var keys []cookiekey
+	for _, hash := range append([]string{config.SecurityKey}, config.SecurityKeys...) {
+		blockkey, name := config.BlockKey, config.SecurityName
+		if blockkey == "" {
+			blockkey = string(derivekey(hash, "block", 16))
+		}
+		if name == "" {
+			name = hex.EncodeToString(derivekey(hash, "name", 10))
+		}
+		block, err := aes.NewCipher([]byte(blockkey))
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, cookiekey{hash: hash, name: name, block: block})
+	}
+	return keys, nil
+}
+
+// derivekey returns n bytes derived from key for purpose, or n random bytes
+// for an empty key.
+func derivekey(key, purpose string, n int) []byte {
+	if key == "" {
+		return generateRandomKey(n)
+	}
+	h := hmac.New(sha256.New, []byte(key))
+	h.Write([]byte(purpose))
+	return h.Sum(nil)[:n]
+}
+
+// SetPayload sets the maximum size and compression of session cookies.
+func (pder *CookieProvider) SetPayload(p Payload) {
+	pder.payload = p
+}
+
 // Get SessionStore in cooke.
 // decode cookie string to map and put into SessionStore with sid.
 func (pder *CookieProvider) SessionRead(sid string) (SessionStore, error) {
-	maps, _ := decodeCookie(pder.block,
-		pder.config.SecurityKey,
-		pder.config.SecurityName,
-		sid, pder.maxlifetime)
+	var maps map[interface{}]interface{}
+	for _, key := range pder.keys {
+		if maps, _ = decodeCookie(key.block, key.hash, key.name, sid, pder.maxlifetime, pder.Now()); maps != nil {
+			break
+		}
+	}
 	if maps == nil {
 		maps = make(map[interface{}]interface{})
 	}
@@ -0,0 +1,273 @@
+package session
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+var (
+	encryptedcookiepder = &EncryptedCookieProvider{}
+)
+
+type (
+	EncryptedCookieSessionStore struct {
+		sid    string
+		values map[interface{}]interface{}
+		lock   sync.RWMutex
+		pder   *EncryptedCookieProvider
+	}
+
+	// EncryptedCookieProvider keeps session values in a cookie encrypted and
+	// authenticated with AES-GCM, so clients can neither read nor modify them.
+	EncryptedCookieProvider struct {
+		clock
+		maxlifetime int64
+		config      *encryptedCookieConfig
+		aeads       []cipher.AEAD
+		samesite    http.SameSite
+		payload     Payload
+	}
+
+	encryptedCookieConfig struct {
+		Keys        []string `json:"keys"`
+		CookieName  string   `json:"cookieName"`
+		Secure      bool     `json:"secure"`
+		Maxage      int      `json:"maxage"`
+		SameSite    string   `json:"sameSite"`
+		Partitioned bool     `json:"partitioned"`
+	}
+)
+
+var (
+	NoEncryptionKeys   = errors.New("session: encrypted cookie provider requires at least one key")
+	InvalidCookieValue = errors.New("session: encrypted cookie value is invalid")
+)
+
+// Set value to encrypted cookie session.
+func (st *EncryptedCookieSessionStore) Set(key, value interface{}) error {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	st.values[key] = value
+	return nil
+}
+
+// Get value from encrypted cookie session.
+func (st *EncryptedCookieSessionStore) Get(key interface{}) interface{} {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	return st.values[key]
+}
+
+// Delete value in encrypted cookie session.
+func (st *EncryptedCookieSessionStore) Delete(key interface{}) error {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	delete(st.values, key)
+	return nil
+}
+
+// Clean all values in encrypted cookie session.
+func (st *EncryptedCookieSessionStore) Flush() error {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	st.values = make(map[interface{}]interface{})
+	return nil
+}
+
+// Keys returns the keys of the encrypted cookie session values.
+func (st *EncryptedCookieSessionStore) Keys() []interface{} {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	return keysof(st.values)
+}
+
+// Export returns a copy of the encrypted cookie session values.
+func (st *EncryptedCookieSessionStore) Export() map[interface{}]interface{} {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	return copyvalues(st.values)
+}
+
+// Return id of this encrypted cookie session.
+func (st *EncryptedCookieSessionStore) SessionID() string {
+	return st.sid
+}
+
+// Write encrypted cookie session to http response cookie, unless the cookie
+// exceeds the maximum payload size.
+func (st *EncryptedCookieSessionStore) SessionRelease(w http.ResponseWriter) {
+	st.lock.RLock()
+	b, err := EncodeGob(st.values)
+	st.lock.RUnlock()
+	if err != nil {
+		return
+	}
+	str, err := st.pder.payload.encode(b, func(b []byte) (string, error) {
+		str, err := st.pder.seal(b)
+		return url.QueryEscape(str), err
+	})
+	if err != nil {
+		return
+	}
+	cookie := &http.Cookie{Name: st.pder.config.CookieName,
+		Value:       str,
+		Path:        "/",
+		HttpOnly:    true,
+		Secure:      st.pder.config.Secure || st.pder.samesite == http.SameSiteNoneMode,
+		SameSite:    st.pder.samesite,
+		Partitioned: st.pder.config.Partitioned,
+		MaxAge:      st.pder.config.Maxage}
+	http.SetCookie(w, cookie)
+}
+
+// encryptionkey uses a 16, 24, or 32 byte key as is, selecting AES-128,
+// AES-192, or AES-256, and derives a 32 byte key from any other string.
+func encryptionkey(key string) []byte {
+	switch len(key) {
+	case 16, 24, 32:
+		return []byte(key)
+	}
+	sum := sha256.Sum256([]byte(key))
+	return sum[:]
+}
+
+// Init encrypted cookie session provider with max lifetime and config json.
+// json config:
+//
+//	keys - encryption keys; values are encrypted with the first key, and
+//	       decrypted with any key, so keys may be rotated by prepending
+//	cookieName - cookie name
+//	secure - cookie secure flag
+//	maxage - cookie max life time
+//	sameSite - cookie SameSite mode; lax, strict, or none
+//	partitioned - cookie Partitioned (CHIPS) flag
+func (pder *EncryptedCookieProvider) SessionInit(maxlifetime int64, config string) error {
+	pder.config = &encryptedCookieConfig{}
+	if err := json.Unmarshal([]byte(config), pder.config); err != nil {
+		return err
+	}
+	if len(pder.config.Keys) == 0 {
+		return NoEncryptionKeys
+	}
+	var err error
+	if pder.samesite, err = ParseSameSite(pder.config.SameSite); err != nil {
+		return err
+	}
+	secure := pder.config.Secure || pder.samesite == http.SameSiteNoneMode
+	if err = checkcookie(pder.config.CookieName, secure, "", pder.config.Partitioned); err != nil {
+		return err
+	}
+	pder.aeads = pder.aeads[:0]
+	for _, key := range pder.config.Keys {
+		block, err := aes.NewCipher(encryptionkey(key))
+		if err != nil {
+			return err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return err
+		}
+		pder.aeads = append(pder.aeads, aead)
+	}
+	pder.maxlifetime = maxlifetime
+	return nil
+}
+
+// seal encrypts the gob encoded, and possibly compressed, values b with the
+// first key, prefixed with the time of encryption, authenticating the cookie
+// name as additional data.
+func (pder *EncryptedCookieProvider) seal(b []byte) (string, error) {
+	plain := make([]byte, 8, 8+len(b))
+	binary.BigEndian.PutUint64(plain, uint64(pder.Now().Unix()))
+	plain = append(plain, b...)
+
+	aead := pder.aeads[0]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, plain, []byte(pder.config.CookieName))
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// open decrypts a value sealed with any of the provider keys, rejecting values
+// older than the provider max lifetime.
+func (pder *EncryptedCookieProvider) open(value string) (map[interface{}]interface{}, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, InvalidCookieValue
+	}
+	for _, aead := range pder.aeads {
+		ns := aead.NonceSize()
+		if len(sealed) < ns+aead.Overhead()+8 {
+			continue
+		}
+		plain, err := aead.Open(nil, sealed[:ns], sealed[ns:], []byte(pder.config.CookieName))
+		if err != nil {
+			continue
+		}
+		sealedat := int64(binary.BigEndian.Uint64(plain[:8]))
+		if pder.maxlifetime > 0 && sealedat < pder.Now().Unix()-pder.maxlifetime {
+			return nil, InvalidCookieValue
+		}
+		b, err := gunzipped(plain[8:])
+		if err != nil {
+			return nil, InvalidCookieValue
+		}
+		return DecodeGob(b)
+	}
+	return nil, InvalidCookieValue
+}
+
+// SetPayload sets the maximum size and compression of session cookies.
+func (pder *EncryptedCookieProvider) SetPayload(p Payload) {
+	pder.payload = p
+}
+
+// Get SessionStore from the encrypted cookie value, with empty values if the
+// cookie cannot be decrypted or has expired.
+func (pder *EncryptedCookieProvider) SessionRead(sid string) (SessionStore, error) {
+	values, err := pder.open(sid)
+	if err != nil || values == nil {
+		values = make(map[interface{}]interface{})
+	}
+	return &EncryptedCookieSessionStore{sid: sid, values: values, pder: pder}, nil
+}
+
+// Encrypted cookie session always exists.
+func (pder *EncryptedCookieProvider) SessionExist(sid string) bool {
+	return true
+}
+
+// Method not implemented.
+func (pder *EncryptedCookieProvider) SessionRegenerate(oldsid, sid string) (SessionStore, error) {
+	return nil, nil
+}
+
+// Method not implemented.
+func (pder *EncryptedCookieProvider) SessionDestroy(sid string) error {
+	return nil
+}
+
+// Method not implemented.
+func (pder *EncryptedCookieProvider) SessionGC() {
+	return
+}
+
+// Implement method, return 0.
+func (pder *EncryptedCookieProvider) SessionAll() int {
+	return 0
+}
+
+func init() {
+	Register("encryptedcookie", encryptedcookiepder)
+}
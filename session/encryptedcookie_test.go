@@ -0,0 +1,102 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func encryptedprovider(t *testing.T, keys string) *EncryptedCookieProvider {
+	p := &EncryptedCookieProvider{}
+	if err := p.SessionInit(3600, `{"cookieName":"session","keys":[`+keys+`]}`); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestEncryptedCookieProvider(t *testing.T) {
+	old := encryptedprovider(t, `"old key"`)
+	rotated := encryptedprovider(t, `"new key","old key"`)
+	replaced := encryptedprovider(t, `"new key"`)
+
+	st, _ := old.SessionRead("")
+	st.Set("user", "test")
+	w := httptest.NewRecorder()
+	st.SessionRelease(w)
+	value, _ := url.QueryUnescape(w.Result().Cookies()[0].Value)
+
+	if strings.Contains(value, "test") {
+		t.Errorf("encrypted cookie value contains plain session values")
+	}
+
+	rs, _ := rotated.SessionRead(value)
+	if rs.Get("user") != "test" {
+		t.Errorf("rotated keys could not decrypt a value sealed with an older key")
+	}
+	if rs, _ := replaced.SessionRead(value); rs.Get("user") != nil {
+		t.Errorf("a value sealed with a removed key was decrypted")
+	}
+
+	tampered := []byte(value)
+	tampered[len(tampered)/2] ^= 'A' ^ 'B'
+	if _, err := rotated.open(string(tampered)); err == nil {
+		t.Errorf("a tampered value was decrypted")
+	}
+
+	rotated.SetClock(func() time.Time { return time.Now().Add(2 * time.Hour) })
+	if _, err := rotated.open(value); err == nil {
+		t.Errorf("an expired value was decrypted")
+	}
+}
+
+func TestEncryptedCookieManager(t *testing.T) {
+	m, err := NewManager("encryptedcookie", `{"cookieName":"session","enableSetCookie":false,"gclifetime":3600,"ProviderConfig":"{\"cookieName\":\"session\",\"keys\":[\"manager key\"]}"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	st, err := m.SessionStart(w, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.Set("key", "value")
+	st.SessionRelease(w)
+
+	r2, _ := http.NewRequest("GET", "/", nil)
+	r2.AddCookie(w.Result().Cookies()[0])
+	st2, _ := m.SessionStart(httptest.NewRecorder(), r2)
+	if st2.Get("key") != "value" {
+		t.Errorf("session value was not kept by the encrypted cookie")
+	}
+}
+
+func TestEncryptedCookiePayload(t *testing.T) {
+	p := encryptedprovider(t, `"key"`)
+	var rejected int
+	release := func(compress bool) *httptest.ResponseRecorder {
+		p.SetPayload(Payload{MaxBytes: 1024, Compress: compress, Rejected: func(size int) { rejected = size }})
+		st, _ := p.SessionRead("")
+		st.Set("large", strings.Repeat("session ", 512))
+		w := httptest.NewRecorder()
+		st.SessionRelease(w)
+		return w
+	}
+
+	if w := release(false); len(w.Result().Cookies()) != 0 || rejected <= 1024 {
+		t.Errorf("expected an oversized payload rejected, rejected size %d", rejected)
+	}
+
+	w := release(true)
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || len(cookies[0].Value) > 1024 {
+		t.Fatalf("expected a compressed cookie within the maximum payload size, got %v", cookies)
+	}
+	value, _ := url.QueryUnescape(cookies[0].Value)
+	if rs, _ := p.SessionRead(value); rs.Get("large") != strings.Repeat("session ", 512) {
+		t.Error("compressed session values were not read")
+	}
+}
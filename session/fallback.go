@@ -0,0 +1,336 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	fallbackpder = &FallbackProvider{}
+)
+
+type (
+	// Saver is implemented by a SessionStore able to report an error saving
+	// its values to the provider, which SessionRelease cannot.
+	Saver interface {
+		Save() error
+	}
+
+	// ProviderFactory is implemented by a registered Provider returning a new
+	// Provider for each Manager, so that Managers of the provider name with
+	// different configs do not share, or reconfigure, one Provider.
+	ProviderFactory interface {
+		NewProvider() Provider
+	}
+
+	// FallbackProvider uses a primary Provider, failing over to a fallback
+	// Provider when the primary returns an error, e.g. a remote store then a
+	// local one during an outage. The primary is retried after the retry
+	// interval, and sessions written to the fallback meanwhile are copied to
+	// the primary once it recovers.
+	FallbackProvider struct {
+		clock
+		Primary  Provider
+		Fallback Provider
+		Retry    time.Duration
+
+		lock       sync.Mutex
+		down       bool
+		recovering bool
+		retryat    time.Time
+		pending    map[string]struct{}
+	}
+
+	fallbackConfig struct {
+		Primary        string `json:"primary"`
+		PrimaryConfig  string `json:"primaryConfig"`
+		Fallback       string `json:"fallback"`
+		FallbackConfig string `json:"fallbackConfig"`
+		Retry          int64  `json:"retry"`
+	}
+
+	fallbackstore struct {
+		SessionStore
+		pder    *FallbackProvider
+		primary bool
+	}
+)
+
+// NewFallbackProvider returns a FallbackProvider for the provided Providers,
+// to register by name with Register.
+func NewFallbackProvider(primary, fallback Provider, retry time.Duration) *FallbackProvider {
+	return &FallbackProvider{Primary: primary, Fallback: fallback, Retry: retry}
+}
+
+// NewProvider returns a FallbackProvider of the same Providers and retry
+// interval, for each Manager of the registered "fallback" provider.
+func (pder *FallbackProvider) NewProvider() Provider {
+	return &FallbackProvider{Primary: pder.Primary, Fallback: pder.Fallback, Retry: pder.Retry}
+}
+
+// SetClock sets the time of the FallbackProvider and of its Providers.
+func (pder *FallbackProvider) SetClock(now func() time.Time) {
+	pder.clock.SetClock(now)
+	for _, p := range []Provider{pder.Primary, pder.Fallback} {
+		if cp, ok := p.(ClockProvider); ok {
+			cp.SetClock(now)
+		}
+	}
+}
+
+// SetLogger sets the Logger of any LoggerProvider of the FallbackProvider.
+func (pder *FallbackProvider) SetLogger(l Logger) {
+	for _, p := range []Provider{pder.Primary, pder.Fallback} {
+		if lp, ok := p.(LoggerProvider); ok {
+			lp.SetLogger(l)
+		}
+	}
+}
+
+// Init the primary and fallback providers with max lifetime. The config json
+// is used only by the registered "fallback" provider, to select providers by
+// name:
+//
+//	primary - primary provider name
+//	primaryConfig - primary provider config json
+//	fallback - fallback provider name
+//	fallbackConfig - fallback provider config json
+//	retry - seconds before retrying a failed primary, default 30
+func (pder *FallbackProvider) SessionInit(maxlifetime int64, config string) error {
+	cfg := &fallbackConfig{}
+	if config != "" {
+		if err := json.Unmarshal([]byte(config), cfg); err != nil {
+			return err
+		}
+	}
+	var err error
+	if pder.Primary == nil {
+		if pder.Primary, err = provider(cfg.Primary); err != nil {
+			return err
+		}
+	}
+	if pder.Fallback == nil {
+		if pder.Fallback, err = provider(cfg.Fallback); err != nil {
+			return err
+		}
+	}
+	if cfg.Retry > 0 {
+		pder.Retry = time.Duration(cfg.Retry) * time.Second
+	}
+	if pder.Retry <= 0 {
+		pder.Retry = 30 * time.Second
+	}
+	pder.pending = make(map[string]struct{})
+	if err := pder.Primary.SessionInit(maxlifetime, cfg.PrimaryConfig); err != nil {
+		pder.failed()
+	}
+	return pder.Fallback.SessionInit(maxlifetime, cfg.FallbackConfig)
+}
+
+func provider(name string) (Provider, error) {
+	p, ok := provides[name]
+	if !ok || name == "fallback" {
+		return nil, fmt.Errorf("session: unknown fallback provider %q", name)
+	}
+	return newprovider(p), nil
+}
+
+// Down reports whether the primary provider has failed and not recovered.
+func (pder *FallbackProvider) Down() bool {
+	pder.lock.Lock()
+	defer pder.lock.Unlock()
+	return pder.down
+}
+
+func (pder *FallbackProvider) failed() {
+	pder.lock.Lock()
+	defer pder.lock.Unlock()
+	pder.down = true
+	pder.retryat = pder.Now().Add(pder.Retry)
+}
+
+func (pder *FallbackProvider) wrote(sid string) {
+	pder.lock.Lock()
+	defer pder.lock.Unlock()
+	pder.pending[sid] = struct{}{}
+}
+
+// primary reports whether to use the primary provider, copying any sessions
+// written to the fallback to the primary when retrying a failed primary. The
+// sessions are copied outside the lock by one caller, while others keep using
+// the fallback.
+func (pder *FallbackProvider) primary() bool {
+	pder.lock.Lock()
+	if !pder.down {
+		pder.lock.Unlock()
+		return true
+	}
+	if pder.recovering || pder.Now().Before(pder.retryat) {
+		pder.lock.Unlock()
+		return false
+	}
+	pder.recovering = true
+	sids := make([]string, 0, len(pder.pending))
+	for sid := range pder.pending {
+		sids = append(sids, sid)
+	}
+	pder.lock.Unlock()
+
+	var err error
+	copied := sids[:0]
+	for _, sid := range sids {
+		if err = copysession(pder.Fallback, pder.Primary, sid); err != nil {
+			break
+		}
+		pder.Fallback.SessionDestroy(sid)
+		copied = append(copied, sid)
+	}
+
+	pder.lock.Lock()
+	defer pder.lock.Unlock()
+	pder.recovering = false
+	for _, sid := range copied {
+		delete(pder.pending, sid)
+	}
+	if err != nil {
+		pder.retryat = pder.Now().Add(pder.Retry)
+		return false
+	}
+	if len(pder.pending) > 0 {
+		return false
+	}
+	pder.down = false
+	return true
+}
+
+// copysession copies the values of session sid from one provider to another.
+func copysession(from, to Provider, sid string) error {
+	src, err := from.SessionRead(sid)
+	if err != nil {
+		return err
+	}
+	return savevalues(to, sid, src.Export())
+}
+
+func savevalues(to Provider, sid string, values map[interface{}]interface{}) error {
+	dst, err := to.SessionRead(sid)
+	if err != nil {
+		return err
+	}
+	for k, v := range values {
+		dst.Set(k, v)
+	}
+	if s, ok := dst.(Saver); ok {
+		return s.Save()
+	}
+	dst.SessionRelease(discardwriter{})
+	return nil
+}
+
+func (pder *FallbackProvider) wrap(st SessionStore, primary bool) SessionStore {
+	if st == nil {
+		return nil
+	}
+	return &fallbackstore{SessionStore: st, pder: pder, primary: primary}
+}
+
+// Get SessionStore from the primary provider, or the fallback provider when
+// the primary has failed.
+func (pder *FallbackProvider) SessionRead(sid string) (SessionStore, error) {
+	if pder.primary() {
+		st, err := pder.Primary.SessionRead(sid)
+		if err == nil {
+			return pder.wrap(st, true), nil
+		}
+		pder.failed()
+	}
+	st, err := pder.Fallback.SessionRead(sid)
+	if err != nil {
+		return nil, err
+	}
+	pder.wrote(sid)
+	return pder.wrap(st, false), nil
+}
+
+// Check the session exists with the provider in use.
+func (pder *FallbackProvider) SessionExist(sid string) bool {
+	if pder.Down() {
+		return pder.Fallback.SessionExist(sid)
+	}
+	return pder.Primary.SessionExist(sid)
+}
+
+// Regenerate the session with the primary provider, or the fallback provider
+// when the primary has failed.
+func (pder *FallbackProvider) SessionRegenerate(oldsid, sid string) (SessionStore, error) {
+	if pder.primary() {
+		st, err := pder.Primary.SessionRegenerate(oldsid, sid)
+		if err == nil {
+			return pder.wrap(st, true), nil
+		}
+		pder.failed()
+	}
+	st, err := pder.Fallback.SessionRegenerate(oldsid, sid)
+	if err != nil {
+		return nil, err
+	}
+	pder.wrote(sid)
+	return pder.wrap(st, false), nil
+}
+
+// Destroy the session with both providers.
+func (pder *FallbackProvider) SessionDestroy(sid string) error {
+	pder.Fallback.SessionDestroy(sid)
+	pder.lock.Lock()
+	delete(pder.pending, sid)
+	pder.lock.Unlock()
+	if pder.Down() {
+		return nil
+	}
+	if err := pder.Primary.SessionDestroy(sid); err != nil {
+		pder.failed()
+		return err
+	}
+	return nil
+}
+
+// Count sessions of the provider in use.
+func (pder *FallbackProvider) SessionAll() int {
+	if pder.Down() {
+		return pder.Fallback.SessionAll()
+	}
+	return pder.Primary.SessionAll()
+}
+
+// Gc both providers.
+func (pder *FallbackProvider) SessionGC() {
+	if !pder.Down() {
+		pder.Primary.SessionGC()
+	}
+	pder.Fallback.SessionGC()
+}
+
+// SessionRelease saves a primary session, saving its values with the
+// fallback provider instead if the primary reports an error.
+func (st *fallbackstore) SessionRelease(w http.ResponseWriter) {
+	s, ok := st.SessionStore.(Saver)
+	if !st.primary || !ok {
+		st.SessionStore.SessionRelease(w)
+		return
+	}
+	if err := s.Save(); err == nil {
+		return
+	}
+	st.pder.failed()
+	sid := st.SessionID()
+	if savevalues(st.pder.Fallback, sid, st.SessionStore.Export()) == nil {
+		st.pder.wrote(sid)
+	}
+}
+
+func init() {
+	Register("fallback", fallbackpder)
+}
@@ -0,0 +1,129 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type flakyprovider struct {
+	memprovider
+	fail bool
+}
+
+var errflaky = errors.New("flaky provider failed")
+
+func (p *flakyprovider) SessionRead(sid string) (SessionStore, error) {
+	if p.fail {
+		return nil, errflaky
+	}
+	return p.memprovider.SessionRead(sid)
+}
+
+func TestFallbackProvider(t *testing.T) {
+	now := time.Now()
+	primary, fallback := &flakyprovider{}, &memprovider{}
+	pder := NewFallbackProvider(primary, fallback, time.Minute)
+	pder.SetClock(func() time.Time { return now })
+	if err := pder.SessionInit(3600, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	st, _ := pder.SessionRead("a")
+	st.Set("k", "primary")
+	if !primary.SessionExist("a") || fallback.SessionExist("a") {
+		t.Fatal("expected session in the primary provider only")
+	}
+
+	primary.fail = true
+	st, err := pder.SessionRead("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.Set("k", "fallback")
+	if !pder.Down() || !fallback.SessionExist("b") {
+		t.Fatal("expected failover to the fallback provider")
+	}
+
+	primary.fail = false
+	pder.SessionRead("b")
+	if !pder.Down() {
+		t.Error("expected the primary not to be retried before the retry interval")
+	}
+
+	now = now.Add(2 * time.Minute)
+	st, _ = pder.SessionRead("b")
+	if pder.Down() {
+		t.Fatal("expected the primary to recover after the retry interval")
+	}
+	if v := st.Get("k"); v != "fallback" {
+		t.Errorf("expected the fallback session copied to the primary, got %v", v)
+	}
+	if fallback.SessionExist("b") {
+		t.Error("expected the resynchronized session removed from the fallback")
+	}
+}
+
+func TestFallbackProviderConfig(t *testing.T) {
+	pder := &FallbackProvider{}
+	if err := pder.SessionInit(3600, `{"primary":"memorytest","fallback":"nonesuch"}`); err == nil {
+		t.Error("expected an error for an unknown fallback provider")
+	}
+}
+
+type lockcheckprovider struct {
+	flakyprovider
+	pder   *FallbackProvider
+	locked bool
+}
+
+func (p *lockcheckprovider) SessionRead(sid string) (SessionStore, error) {
+	if p.pder != nil && !p.fail {
+		if p.pder.lock.TryLock() {
+			p.pder.lock.Unlock()
+		} else {
+			p.locked = true
+		}
+	}
+	return p.flakyprovider.SessionRead(sid)
+}
+
+func TestFallbackProviderRecoveryUnlocked(t *testing.T) {
+	now := time.Now()
+	primary, fallback := &lockcheckprovider{}, &memprovider{}
+	pder := NewFallbackProvider(primary, fallback, time.Minute)
+	pder.SetClock(func() time.Time { return now })
+	pder.SessionInit(3600, "")
+	primary.pder = pder
+
+	primary.fail = true
+	st, _ := pder.SessionRead("a")
+	st.Set("k", "v")
+	primary.fail = false
+	now = now.Add(2 * time.Minute)
+	if st, _ := pder.SessionRead("a"); pder.Down() || st.Get("k") != "v" {
+		t.Fatal("expected the primary to recover with the fallback session")
+	}
+	if primary.locked {
+		t.Error("expected sessions copied to the primary outside the provider lock")
+	}
+}
+
+func TestFallbackProviderPerManager(t *testing.T) {
+	config := func(retry int) string {
+		return fmt.Sprintf(`{"cookieName":"gosessionid","gclifetime":3600,"ProviderConfig":"{\"primary\":\"memorytest\",\"fallback\":\"memorytest\",\"retry\":%d}"}`, retry)
+	}
+	m1, err := NewManager("fallback", config(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m2, err := NewManager("fallback", config(20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p1, p2 := m1.provider.(*FallbackProvider), m2.provider.(*FallbackProvider)
+	if p1 == p2 || p1.Retry != 10*time.Second || p2.Retry != 20*time.Second {
+		t.Errorf("expected a fallback provider of each config, got retry %s and %s", p1.Retry, p2.Retry)
+	}
+}
@@ -0,0 +1,60 @@
+package session
+
+import "net/http"
+
+type (
+	// CreateHook receives each session created by a Manager.
+	CreateHook func(r *http.Request, session SessionStore)
+
+	// DestroyHook receives the id of each session destroyed by a Manager,
+	// including sessions expired by an idle or absolute lifetime. Sessions
+	// destroyed outside of a request, with Destroy or DestroyAllFor, pass a nil
+	// r, so hooks must check r before using it.
+	DestroyHook func(r *http.Request, sid string)
+
+	// RegenerateHook receives the previous and new id of each session
+	// regenerated by a Manager.
+	RegenerateHook func(r *http.Request, oldsid, sid string)
+
+	hooks struct {
+		create     []CreateHook
+		destroy    []DestroyHook
+		regenerate []RegenerateHook
+	}
+)
+
+// OnCreate adds hooks called when the Manager creates a session. Hooks should
+// be added before the Manager is used.
+func (manager *Manager) OnCreate(fns ...CreateHook) {
+	manager.hooks.create = append(manager.hooks.create, fns...)
+}
+
+// OnDestroy adds hooks called when the Manager destroys a session. Hooks
+// should be added before the Manager is used.
+func (manager *Manager) OnDestroy(fns ...DestroyHook) {
+	manager.hooks.destroy = append(manager.hooks.destroy, fns...)
+}
+
+// OnRegenerate adds hooks called when the Manager regenerates a session id.
+// Hooks should be added before the Manager is used.
+func (manager *Manager) OnRegenerate(fns ...RegenerateHook) {
+	manager.hooks.regenerate = append(manager.hooks.regenerate, fns...)
+}
+
+func (h *hooks) created(r *http.Request, session SessionStore) {
+	for _, fn := range h.create {
+		fn(r, session)
+	}
+}
+
+func (h *hooks) destroyed(r *http.Request, sid string) {
+	for _, fn := range h.destroy {
+		fn(r, sid)
+	}
+}
+
+func (h *hooks) regenerated(r *http.Request, oldsid, sid string) {
+	for _, fn := range h.regenerate {
+		fn(r, oldsid, sid)
+	}
+}
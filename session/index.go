@@ -0,0 +1,154 @@
+package session
+
+import (
+	"net/http"
+	"sync"
+)
+
+type (
+	// SessionIndex maps an application principal id, e.g. a user id, to the
+	// ids of the principal's sessions. An index is useful only with providers
+	// storing sessions server side, where destroying a session id revokes it.
+	SessionIndex interface {
+		Add(principal, sid string) error
+		Remove(sid string) error
+		Rename(oldsid, sid string) error
+		Sessions(principal string) ([]string, error)
+	}
+
+	// MemoryIndex is a SessionIndex held in memory, for a single process.
+	MemoryIndex struct {
+		lock       sync.RWMutex
+		sessions   map[string]map[string]struct{}
+		principals map[string]string
+	}
+)
+
+// NewMemoryIndex returns an empty MemoryIndex.
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{
+		sessions:   make(map[string]map[string]struct{}),
+		principals: make(map[string]string),
+	}
+}
+
+// Add associates sid with principal, replacing any previous principal of sid.
+func (idx *MemoryIndex) Add(principal, sid string) error {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+	idx.remove(sid)
+	if idx.sessions[principal] == nil {
+		idx.sessions[principal] = make(map[string]struct{})
+	}
+	idx.sessions[principal][sid] = struct{}{}
+	idx.principals[sid] = principal
+	return nil
+}
+
+// Remove removes sid from the index.
+func (idx *MemoryIndex) Remove(sid string) error {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+	idx.remove(sid)
+	return nil
+}
+
+func (idx *MemoryIndex) remove(sid string) {
+	principal, ok := idx.principals[sid]
+	if !ok {
+		return
+	}
+	delete(idx.principals, sid)
+	delete(idx.sessions[principal], sid)
+	if len(idx.sessions[principal]) == 0 {
+		delete(idx.sessions, principal)
+	}
+}
+
+// Rename replaces oldsid with sid for the principal of oldsid, if any.
+func (idx *MemoryIndex) Rename(oldsid, sid string) error {
+	idx.lock.Lock()
+	principal, ok := idx.principals[oldsid]
+	idx.lock.Unlock()
+	if !ok {
+		return nil
+	}
+	return idx.Add(principal, sid)
+}
+
+// Sessions returns the session ids of principal.
+func (idx *MemoryIndex) Sessions(principal string) ([]string, error) {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+	ret := make([]string, 0, len(idx.sessions[principal]))
+	for sid := range idx.sessions[principal] {
+		ret = append(ret, sid)
+	}
+	return ret, nil
+}
+
+// SetIndex sets the SessionIndex of the Manager, which is kept current as the
+// Manager destroys and regenerates sessions.
+func (manager *Manager) SetIndex(idx SessionIndex) {
+	manager.index = idx
+	manager.OnDestroy(func(r *http.Request, sid string) {
+		if manager.index == idx {
+			idx.Remove(sid)
+		}
+	})
+	manager.OnRegenerate(func(r *http.Request, oldsid, sid string) {
+		if manager.index == idx {
+			idx.Rename(oldsid, sid)
+		}
+	})
+}
+
+// Bind associates the session with principal in the Manager SessionIndex,
+// e.g. on login.
+func (manager *Manager) Bind(principal string, session SessionStore) error {
+	if manager.index == nil {
+		return NoSessionIndex
+	}
+	return manager.index.Add(principal, session.SessionID())
+}
+
+// SessionsFor returns the ids of the existing sessions of principal, removing
+// the ids of sessions no longer existing, e.g. after gc, from the index.
+func (manager *Manager) SessionsFor(principal string) ([]string, error) {
+	if manager.index == nil {
+		return nil, NoSessionIndex
+	}
+	sids, err := manager.index.Sessions(principal)
+	if err != nil {
+		return nil, err
+	}
+	ret := sids[:0]
+	for _, sid := range sids {
+		if manager.provider.SessionExist(sid) {
+			ret = append(ret, sid)
+		} else {
+			manager.index.Remove(sid)
+		}
+	}
+	return ret, nil
+}
+
+// DestroyAllFor destroys every session of principal, e.g. to log a user out
+// everywhere after a password change. Any DestroyHook is called with a nil
+// request.
+func (manager *Manager) DestroyAllFor(principal string) error {
+	if manager.index == nil {
+		return NoSessionIndex
+	}
+	sids, err := manager.index.Sessions(principal)
+	if err != nil {
+		return err
+	}
+	for _, sid := range sids {
+		if err := manager.provider.SessionDestroy(sid); err != nil {
+			return err
+		}
+		manager.hooks.destroyed(nil, sid)
+	}
+	return nil
+}
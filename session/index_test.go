@@ -0,0 +1,182 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type memstore struct {
+	sid    string
+	values map[interface{}]interface{}
+}
+
+func (st *memstore) Set(key, value interface{}) error    { st.values[key] = value; return nil }
+func (st *memstore) Get(key interface{}) interface{}     { return st.values[key] }
+func (st *memstore) Delete(key interface{}) error        { delete(st.values, key); return nil }
+func (st *memstore) SessionID() string                   { return st.sid }
+func (st *memstore) SessionRelease(http.ResponseWriter)  {}
+func (st *memstore) Keys() []interface{}                 { return keysof(st.values) }
+func (st *memstore) Export() map[interface{}]interface{} { return copyvalues(st.values) }
+func (st *memstore) Flush() error {
+	st.values = make(map[interface{}]interface{})
+	return nil
+}
+
+type memprovider struct {
+	lock     sync.Mutex
+	sessions map[string]*memstore
+}
+
+func (p *memprovider) SessionInit(int64, string) error {
+	p.sessions = make(map[string]*memstore)
+	return nil
+}
+
+func (p *memprovider) SessionRead(sid string) (SessionStore, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if _, ok := p.sessions[sid]; !ok {
+		p.sessions[sid] = &memstore{sid: sid, values: make(map[interface{}]interface{})}
+	}
+	return p.sessions[sid], nil
+}
+
+func (p *memprovider) SessionExist(sid string) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	_, ok := p.sessions[sid]
+	return ok
+}
+
+func (p *memprovider) SessionRegenerate(oldsid, sid string) (SessionStore, error) {
+	p.lock.Lock()
+	if st, ok := p.sessions[oldsid]; ok {
+		delete(p.sessions, oldsid)
+		st.sid = sid
+		p.sessions[sid] = st
+	}
+	p.lock.Unlock()
+	return p.SessionRead(sid)
+}
+
+func (p *memprovider) SessionDestroy(sid string) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	delete(p.sessions, sid)
+	return nil
+}
+
+func (p *memprovider) SessionAll() int { return len(p.sessions) }
+func (p *memprovider) SessionGC()      {}
+
+func init() {
+	Register("memorytest", &memprovider{})
+}
+
+func TestSessionIndex(t *testing.T) {
+	m, err := NewManager("memorytest", `{"cookieName":"session","gclifetime":3600}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.DestroyAllFor("user"); err != NoSessionIndex {
+		t.Errorf("expected NoSessionIndex without an index, got %v", err)
+	}
+	m.SetIndex(NewMemoryIndex())
+	var destroyed []string
+	m.OnDestroy(func(r *http.Request, sid string) {
+		if r != nil {
+			t.Errorf("expected a nil request destroying %s outside of a request", sid)
+		}
+		destroyed = append(destroyed, sid)
+	})
+
+	login := func(principal string) *http.Request {
+		r, _ := http.NewRequest("GET", "/", nil)
+		st, _ := m.SessionStart(httptest.NewRecorder(), r)
+		if err := m.Bind(principal, st); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	r1, _, other := login("user"), login("user"), login("other")
+
+	m.SessionRegenerateId(httptest.NewRecorder(), r1)
+	regenerated, _ := r1.Cookie("session")
+
+	sids, _ := m.SessionsFor("user")
+	sort.Strings(sids)
+	if len(sids) != 2 || (sids[0] != regenerated.Value && sids[1] != regenerated.Value) {
+		t.Errorf("expected 2 sessions including the regenerated session, got %v", sids)
+	}
+
+	if err := m.DestroyAllFor("user"); err != nil {
+		t.Fatal(err)
+	}
+	if sort.Strings(destroyed); strings.Join(destroyed, " ") != strings.Join(sids, " ") {
+		t.Errorf("expected destroy hooks for %v, got %v", sids, destroyed)
+	}
+	for _, sid := range sids {
+		if m.provider.SessionExist(sid) {
+			t.Errorf("session %s was not destroyed", sid)
+		}
+	}
+	if sids, _ := m.SessionsFor("user"); len(sids) != 0 {
+		t.Errorf("expected no sessions after DestroyAllFor, got %v", sids)
+	}
+	ck, _ := other.Cookie("session")
+	if !m.provider.SessionExist(ck.Value) {
+		t.Error("session of another principal was destroyed")
+	}
+	if err := m.Destroy(ck.Value); err != nil || len(destroyed) != 3 || destroyed[2] != ck.Value {
+		t.Errorf("expected a destroy hook for Destroy, got %v %v", err, destroyed)
+	}
+}
+
+func TestSessionHeaderTransport(t *testing.T) {
+	m, err := NewManager("memorytest", `{"cookieName":"session","enableSetCookie":false,"gclifetime":3600,"sessionHeader":"Authorization","sessionHeaderScheme":"Session","sessionQuery":"sid"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	st, _ := m.SessionStart(w, r)
+	st.Set("key", "value")
+	auth := w.Header().Get("Authorization")
+	if auth != "Session "+st.SessionID() {
+		t.Fatalf("expected the session id in the response header, got %q", auth)
+	}
+	if len(w.Result().Cookies()) != 0 {
+		t.Error("expected no session cookie")
+	}
+
+	r2, _ := http.NewRequest("GET", "/", nil)
+	r2.Header.Set("Authorization", auth)
+	if st2, _ := m.SessionStart(httptest.NewRecorder(), r2); st2.Get("key") != "value" {
+		t.Error("session was not read from the request header")
+	}
+
+	sid := st.SessionID()
+	r3, _ := http.NewRequest("GET", "/?sid="+sid, nil)
+	w3 := httptest.NewRecorder()
+	st3, _ := m.SessionStart(w3, r3)
+	if st3.Get("key") != "value" {
+		t.Error("session was not read from the query parameter")
+	}
+	if st3.SessionID() == sid || w3.Header().Get("Authorization") != "Session "+st3.SessionID() {
+		t.Errorf("expected a session id from the query parameter to be regenerated, got %q", w3.Header().Get("Authorization"))
+	}
+	r5, _ := http.NewRequest("GET", "/?sid="+sid, nil)
+	if st5, _ := m.SessionStart(httptest.NewRecorder(), r5); st5.Get("key") != nil {
+		t.Error("session was read from a query parameter already used")
+	}
+
+	r4, _ := http.NewRequest("GET", "/", nil)
+	r4.Header.Set("Authorization", "Bearer "+st3.SessionID())
+	if st4, _ := m.SessionStart(httptest.NewRecorder(), r4); st4.Get("key") != nil {
+		t.Error("session was read from a header with another scheme")
+	}
+}
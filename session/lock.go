@@ -0,0 +1,113 @@
+package session
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// SessionLocked is returned starting a session still locked by another
+// request after the Manager lock timeout.
+var SessionLocked = errors.New("session: timed out waiting for session lock")
+
+type (
+	// LockingProvider is implemented by a Provider able to lock a session id,
+	// so concurrent requests for the same session do not race on writes.
+	LockingProvider interface {
+		SessionLock(sid string, timeout time.Duration) error
+		SessionUnlock(sid string) error
+	}
+
+	// Unlocker is implemented by a SessionStore holding a session lock, which
+	// Unlock releases. Unlock may be called more than once.
+	Unlocker interface {
+		Unlock()
+	}
+
+	// MemoryLocks are session locks for a single process, usable with any
+	// Provider through Manager.SetLocks.
+	MemoryLocks struct {
+		lock sync.Mutex
+		held map[string]chan struct{}
+	}
+
+	lockedstore struct {
+		SessionStore
+		once   sync.Once
+		unlock func()
+	}
+)
+
+// NewMemoryLocks returns MemoryLocks with no sessions locked.
+func NewMemoryLocks() *MemoryLocks {
+	return &MemoryLocks{held: make(map[string]chan struct{})}
+}
+
+// SessionLock locks sid, waiting at most timeout for any current lock.
+func (l *MemoryLocks) SessionLock(sid string, timeout time.Duration) error {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		l.lock.Lock()
+		released, held := l.held[sid]
+		if !held {
+			l.held[sid] = make(chan struct{})
+			l.lock.Unlock()
+			return nil
+		}
+		l.lock.Unlock()
+		select {
+		case <-released:
+		case <-deadline.C:
+			return SessionLocked
+		}
+	}
+}
+
+// SessionUnlock unlocks sid.
+func (l *MemoryLocks) SessionUnlock(sid string) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if released, held := l.held[sid]; held {
+		delete(l.held, sid)
+		close(released)
+	}
+	return nil
+}
+
+// SetLocks sets session locks used by the Manager for a Provider that is not a
+// LockingProvider.
+func (manager *Manager) SetLocks(l LockingProvider) {
+	manager.locks = l
+}
+
+func (manager *Manager) locker() LockingProvider {
+	if l, ok := manager.provider.(LockingProvider); ok {
+		return l
+	}
+	return manager.locks
+}
+
+// lock locks sid when the Manager has a lock timeout and a LockingProvider,
+// returning a function unlocking it.
+func (manager *Manager) lock(sid string) (func(), error) {
+	l := manager.locker()
+	if l == nil || manager.config.LockTimeout <= 0 || sid == "" {
+		return nil, nil
+	}
+	if err := l.SessionLock(sid, time.Duration(manager.config.LockTimeout)*time.Millisecond); err != nil {
+		return nil, err
+	}
+	return func() { l.SessionUnlock(sid) }, nil
+}
+
+func (st *lockedstore) Unlock() {
+	st.once.Do(st.unlock)
+}
+
+// SessionRelease saves the session and then releases the session lock.
+func (st *lockedstore) SessionRelease(w http.ResponseWriter) {
+	st.SessionStore.SessionRelease(w)
+	st.Unlock()
+}
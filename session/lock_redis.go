@@ -0,0 +1,81 @@
+package session
+
+import (
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/thrisp/flotilla/internal/redis"
+)
+
+// redisUnlockScript deletes the lock KEYS[1] only if it still holds the
+// token ARGV[1], so a lock expired and taken by another process is kept.
+const redisUnlockScript = `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`
+
+// RedisLocks are session locks held in a Redis server, shared by every
+// process using the server, usable with any Provider through
+// Manager.SetLocks. A lock not released, e.g. by a process exiting, expires
+// after Expiry.
+type RedisLocks struct {
+	*redis.Client
+	Prefix string
+	Expiry time.Duration
+	// Poll is the interval between attempts to take a held lock.
+	Poll   time.Duration
+	lock   sync.Mutex
+	tokens map[string]string
+}
+
+// NewRedisLocks returns RedisLocks for the Redis server at addr, prefixing
+// lock keys with "flotilla:session:lock:".
+func NewRedisLocks(addr, password string, db int) *RedisLocks {
+	return &RedisLocks{
+		Client: redis.NewClient(addr, password, db),
+		Prefix: "flotilla:session:lock:",
+		Expiry: 30 * time.Second,
+		Poll:   10 * time.Millisecond,
+		tokens: make(map[string]string),
+	}
+}
+
+// SessionLock locks sid, waiting at most timeout for any current lock.
+func (l *RedisLocks) SessionLock(sid string, timeout time.Duration) error {
+	token := hex.EncodeToString(generateRandomKey(16))
+	expiry := strconv.FormatInt(int64(l.Expiry/time.Millisecond), 10)
+	deadline := time.Now().Add(timeout)
+	for {
+		reply, err := l.Do("SET", l.Prefix+sid, token, "NX", "PX", expiry)
+		if err != nil {
+			return err
+		}
+		if reply == "OK" {
+			l.lock.Lock()
+			l.tokens[sid] = token
+			l.lock.Unlock()
+			return nil
+		}
+		if time.Now().Add(l.Poll).After(deadline) {
+			return SessionLocked
+		}
+		time.Sleep(l.Poll)
+	}
+}
+
+// SessionUnlock unlocks sid, if locked by this RedisLocks.
+func (l *RedisLocks) SessionUnlock(sid string) error {
+	l.lock.Lock()
+	token, ok := l.tokens[sid]
+	delete(l.tokens, sid)
+	l.lock.Unlock()
+	if !ok {
+		return nil
+	}
+	_, err := l.Do("EVAL", redisUnlockScript, "1", l.Prefix+sid, token)
+	return err
+}
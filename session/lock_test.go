@@ -0,0 +1,178 @@
+package session
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thrisp/flotilla/internal/redis"
+)
+
+func TestMemoryLocks(t *testing.T) {
+	l := NewMemoryLocks()
+	if err := l.SessionLock("sid", time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SessionLock("sid", 10*time.Millisecond); err != SessionLocked {
+		t.Errorf("expected SessionLocked for a held lock, got %v", err)
+	}
+	if err := l.SessionLock("other", 10*time.Millisecond); err != nil {
+		t.Errorf("lock of another session failed: %v", err)
+	}
+	acquired := make(chan error)
+	go func() { acquired <- l.SessionLock("sid", time.Second) }()
+	time.Sleep(10 * time.Millisecond)
+	l.SessionUnlock("sid")
+	if err := <-acquired; err != nil {
+		t.Errorf("waiting lock was not acquired after unlock: %v", err)
+	}
+}
+
+func TestManagerSessionLock(t *testing.T) {
+	m, err := NewManager("memorytest", `{"cookieName":"session","gclifetime":3600,"lockTimeout":20}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetLocks(NewMemoryLocks())
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	st, _ := m.SessionStart(httptest.NewRecorder(), r)
+	st.SessionRelease(httptest.NewRecorder())
+	cookie, _ := r.Cookie("session")
+
+	request := func() *http.Request {
+		r, _ := http.NewRequest("GET", "/", nil)
+		r.AddCookie(cookie)
+		return r
+	}
+	first, err := m.SessionStart(httptest.NewRecorder(), request())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.SessionStart(httptest.NewRecorder(), request()); err != SessionLocked {
+		t.Errorf("expected SessionLocked for a concurrent request, got %v", err)
+	}
+	first.SessionRelease(httptest.NewRecorder())
+	second, err := m.SessionStart(httptest.NewRecorder(), request())
+	if err != nil {
+		t.Errorf("session was not unlocked on release: %v", err)
+	}
+	second.(Unlocker).Unlock()
+	if _, err := m.SessionStart(httptest.NewRecorder(), request()); err != nil {
+		t.Errorf("session was not unlocked by Unlock: %v", err)
+	}
+}
+
+func TestRegenerateSessionKeepsLock(t *testing.T) {
+	m, err := NewManager("memorytest", `{"cookieName":"session","gclifetime":3600,"lockTimeout":20}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetLocks(NewMemoryLocks())
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	st, _ := m.SessionStart(httptest.NewRecorder(), r)
+	st.SessionRelease(httptest.NewRecorder())
+	cookie, _ := r.Cookie("session")
+	request := func(ck *http.Cookie) *http.Request {
+		r, _ := http.NewRequest("GET", "/", nil)
+		r.AddCookie(ck)
+		return r
+	}
+
+	r = request(cookie)
+	st, err = m.SessionStart(httptest.NewRecorder(), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	regenerated, err := m.RegenerateSession(w, r, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.locks.SessionLock(cookie.Value, time.Millisecond); err != SessionLocked {
+		t.Errorf("expected the old session id locked until the regenerated session is released, got %v", err)
+	}
+	if err := m.locks.SessionLock(regenerated.SessionID(), time.Millisecond); err != SessionLocked {
+		t.Errorf("expected the regenerated session id locked, got %v", err)
+	}
+	regenerated.SessionRelease(w)
+	if _, err := m.SessionStart(httptest.NewRecorder(), request(w.Result().Cookies()[0])); err != nil {
+		t.Errorf("regenerated session was not unlocked on release: %v", err)
+	}
+	if err := m.locks.SessionLock(cookie.Value, time.Millisecond); err != nil {
+		t.Errorf("old session id was not unlocked on release: %v", err)
+	}
+}
+
+func TestRedisLocks(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+	var mu sync.Mutex
+	held := make(map[string]string)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				c := redis.NewConn(conn)
+				for {
+					cmd, err := c.Reply()
+					if err != nil {
+						return
+					}
+					var args []string
+					for _, arg := range cmd.([]interface{}) {
+						args = append(args, string(arg.([]byte)))
+					}
+					mu.Lock()
+					switch args[0] {
+					case "SET":
+						if _, ok := held[args[1]]; ok {
+							conn.Write([]byte("$-1\r\n"))
+						} else {
+							held[args[1]] = args[2]
+							conn.Write([]byte("+OK\r\n"))
+						}
+					case "EVAL":
+						if held[args[3]] == args[4] {
+							delete(held, args[3])
+						}
+						conn.Write([]byte(":1\r\n"))
+					}
+					mu.Unlock()
+				}
+			}()
+		}
+	}()
+
+	l, other := NewRedisLocks(ln.Addr().String(), "", 0), NewRedisLocks(ln.Addr().String(), "", 0)
+	if err := l.SessionLock("sid", time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.SessionLock("sid", 30*time.Millisecond); err != SessionLocked {
+		t.Errorf("expected SessionLocked for a lock held by another process, got %v", err)
+	}
+	other.SessionUnlock("sid")
+	acquired := make(chan error)
+	go func() { acquired <- other.SessionLock("sid", time.Second) }()
+	time.Sleep(20 * time.Millisecond)
+	l.SessionUnlock("sid")
+	if err := <-acquired; err != nil {
+		t.Errorf("waiting lock was not acquired after unlock: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if held["flotilla:session:lock:sid"] == "" {
+		t.Error("expected the lock held by the waiting process")
+	}
+}
@@ -0,0 +1,80 @@
+package session
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+)
+
+var PayloadTooLarge = errors.New("session: payload exceeds the maximum payload size")
+
+type (
+	// Payload limits the size of session values a provider writes to the
+	// client, e.g. in a cookie browsers drop beyond about 4096 bytes.
+	Payload struct {
+		// MaxBytes is the maximum size of an encoded payload, or 0 for no
+		// maximum. Payloads over the maximum are not written.
+		MaxBytes int
+
+		// Compress gzip compresses payloads over MaxBytes, or all payloads
+		// when there is no maximum.
+		Compress bool
+
+		// Rejected, if set, is called with the size of a payload not
+		// written.
+		Rejected func(size int)
+	}
+
+	// PayloadProvider is implemented by a Provider writing session values to
+	// the client, configured by the Manager from the maxPayloadBytes and
+	// enableCompression options.
+	PayloadProvider interface {
+		SetPayload(Payload)
+	}
+)
+
+// encode encodes b with enc, compressing b as configured, returning
+// PayloadTooLarge for an encoded value over the maximum size.
+func (p Payload) encode(b []byte, enc func([]byte) (string, error)) (string, error) {
+	if p.Compress && p.MaxBytes <= 0 {
+		return enc(gzipped(b))
+	}
+	s, err := enc(b)
+	if err != nil || p.MaxBytes <= 0 || len(s) <= p.MaxBytes {
+		return s, err
+	}
+	if p.Compress {
+		if s, err = enc(gzipped(b)); err != nil {
+			return "", err
+		}
+		if len(s) <= p.MaxBytes {
+			return s, nil
+		}
+	}
+	if p.Rejected != nil {
+		p.Rejected(len(s))
+	}
+	return "", PayloadTooLarge
+}
+
+func gzipped(b []byte) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(b)
+	zw.Close()
+	return buf.Bytes()
+}
+
+// gunzipped decompresses gzip compressed b, returning any other b as is.
+func gunzipped(b []byte) ([]byte, error) {
+	if len(b) < 3 || b[0] != 0x1f || b[1] != 0x8b || b[2] != 8 {
+		return b, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
@@ -1,12 +1,18 @@
 package session
 
 import (
+	"context"
 	"crypto/rand"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -14,6 +20,16 @@ var (
 	provides = make(map[string]Provider)
 )
 
+// NoSessionIndex is returned using a SessionIndex with a Manager without one.
+var NoSessionIndex = errors.New("session: manager has no SessionIndex")
+
+const (
+	// CreatedKey and AccessedKey hold the unix time a session was created and
+	// last used, stored when an idle or absolute lifetime is configured.
+	CreatedKey  = "_session_created"
+	AccessedKey = "_session_accessed"
+)
+
 type (
 	// SessionStore contains all data for one session process with specific id.
 	SessionStore interface {
@@ -23,6 +39,8 @@ type (
 		SessionID() string                    //back current sessionID
 		SessionRelease(w http.ResponseWriter) //release the resource & save data to provider & return the data
 		Flush() error                         //delete all data
+		Keys() []interface{}                  //keys of all session values
+		Export() map[interface{}]interface{}  //copy of all session values
 	}
 
 	// Provider contains global session methods and saved SessionStores.
@@ -37,10 +55,33 @@ type (
 		SessionGC()
 	}
 
+	// SessionIDFunc returns a new session id for the request.
+	SessionIDFunc func(r *http.Request) (string, error)
+
+	// Logger receives messages from a Manager, e.g. from session gc.
+	Logger interface {
+		Debug(msg string, fields ...interface{})
+		Error(msg string, fields ...interface{})
+	}
+
 	// Manager contains Provider and its configuration.
 	Manager struct {
-		provider Provider
-		config   *managerConfig
+		provider   Provider
+		config     *managerConfig
+		samesite   http.SameSite
+		logger     Logger
+		logmu      sync.RWMutex
+		hooks      hooks
+		index      SessionIndex
+		regenerate sync.Mutex
+		locks      LockingProvider
+		counts     counts
+		idfunc     SessionIDFunc
+		random     io.Reader
+		gcstop     chan struct{}
+		gconce     sync.Once
+		gcwg       sync.WaitGroup
+		clock      clock
 	}
 
 	managerConfig struct {
@@ -53,6 +94,48 @@ type (
 		ProviderConfig  string `json:"providerConfig"`
 		Domain          string `json:"domain"`
 		SessionIdLength int64  `json:"sessionIdLength"`
+		// SessionIdEncoding encodes random session ids as "hex", the default,
+		// or "base64url".
+		SessionIdEncoding string `json:"sessionIdEncoding"`
+		SameSite          string `json:"sameSite"`
+		// Partitioned sets the Partitioned (CHIPS) cookie attribute, keeping a
+		// separate session per top level site for embedded contexts; it
+		// requires a secure cookie.
+		Partitioned bool `json:"partitioned"`
+		// SessionHeader names a request header accepted in place of the session
+		// cookie, e.g. "X-Session-Token" or "Authorization", also set on
+		// responses creating or regenerating a session.
+		SessionHeader string `json:"sessionHeader"`
+		// SessionHeaderScheme prefixes the SessionHeader value, e.g. "Session"
+		// for "Authorization: Session <id>".
+		SessionHeaderScheme string `json:"sessionHeaderScheme"`
+		// SessionQuery names a query parameter accepted in place of the
+		// session cookie. It is off unless named; a session id read from the
+		// query parameter is regenerated on use, and the new id is sent only
+		// as a cookie or SessionHeader.
+		SessionQuery string `json:"sessionQuery"`
+		// IdleLifetime, in seconds, expires sessions without a request for the
+		// duration, refreshing the session cookie on every request.
+		IdleLifetime int64 `json:"idleLifetime"`
+		// AbsoluteLifetime, in seconds, expires sessions this long after they
+		// were created, regardless of activity.
+		AbsoluteLifetime int64 `json:"absoluteLifetime"`
+		// WriteBehind holds session changes made during a request, applying
+		// them once when the session is released, for providers where each
+		// SessionStore call is a round trip.
+		WriteBehind bool `json:"writeBehind"`
+		// LockTimeout, in milliseconds, enables session locking with a
+		// LockingProvider, or Manager locks set with SetLocks, waiting at
+		// most the timeout for concurrent requests for the session to
+		// release it.
+		LockTimeout int64 `json:"lockTimeout"`
+		// MaxPayloadBytes is the maximum size of session values written to
+		// the client by a PayloadProvider, e.g. the cookie providers; larger
+		// sessions are not written, and logged.
+		MaxPayloadBytes int `json:"maxPayloadBytes"`
+		// EnableCompression gzip compresses session values over
+		// MaxPayloadBytes written by a PayloadProvider.
+		EnableCompression bool `json:"enableCompression"`
 	}
 )
 
@@ -69,6 +152,15 @@ func Register(name string, provide Provider) {
 	provides[name] = provide
 }
 
+// newprovider returns a new Provider of a ProviderFactory, or the registered
+// Provider.
+func newprovider(p Provider) Provider {
+	if f, ok := p.(ProviderFactory); ok {
+		return f.NewProvider()
+	}
+	return p
+}
+
 // Create new Manager with provider name and json config string
 // where provider is an existing valid provider(e.g. "cookie") and a
 // json config.
@@ -77,6 +169,7 @@ func NewManager(provideName, config string) (*Manager, error) {
 	if !ok {
 		return nil, fmt.Errorf("session: unknown provide: %q (forgotten import?)", provideName)
 	}
+	provider = newprovider(provider)
 	cf := new(managerConfig)
 	cf.EnableSetCookie = true
 	err := json.Unmarshal([]byte(config), cf)
@@ -86,6 +179,9 @@ func NewManager(provideName, config string) (*Manager, error) {
 	if cf.Maxlifetime == 0 {
 		cf.Maxlifetime = cf.Gclifetime
 	}
+	if cf.IdleLifetime > 0 && cf.IdleLifetime < cf.Maxlifetime {
+		cf.Maxlifetime = cf.IdleLifetime
+	}
 	err = provider.SessionInit(cf.Maxlifetime, cf.ProviderConfig)
 	if err != nil {
 		return nil, err
@@ -94,80 +190,157 @@ func NewManager(provideName, config string) (*Manager, error) {
 	if cf.SessionIdLength == 0 {
 		cf.SessionIdLength = 16
 	}
+	switch cf.SessionIdEncoding {
+	case "", "hex", "base64url":
+	default:
+		return nil, fmt.Errorf("session: unknown session id encoding %q", cf.SessionIdEncoding)
+	}
+	samesite, err := ParseSameSite(cf.SameSite)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkcookie(cf.CookieName, cf.Secure || samesite == http.SameSiteNoneMode, cf.Domain, cf.Partitioned); err != nil {
+		return nil, err
+	}
+
+	manager := &Manager{
+		provider: provider,
+		config:   cf,
+		samesite: samesite,
+		gcstop:   make(chan struct{}),
+	}
+	if pp, ok := provider.(PayloadProvider); ok {
+		pp.SetPayload(Payload{
+			MaxBytes: cf.MaxPayloadBytes,
+			Compress: cf.EnableCompression,
+			Rejected: manager.rejected,
+		})
+	}
+	manager.OnCreate(manager.counts.create)
+	manager.OnDestroy(manager.counts.destroy)
+	return manager, nil
+}
 
-	return &Manager{
-		provider,
-		cf,
-	}, nil
+// newcookie returns the session cookie for sid.
+func (manager *Manager) newcookie(sid string) *http.Cookie {
+	cookie := &http.Cookie{Name: manager.config.CookieName,
+		Value:       url.QueryEscape(sid),
+		Path:        "/",
+		HttpOnly:    true,
+		Secure:      manager.config.Secure || manager.samesite == http.SameSiteNoneMode,
+		Domain:      manager.config.Domain,
+		SameSite:    manager.samesite,
+		Partitioned: manager.config.Partitioned}
+	if manager.config.CookieLifeTime > 0 {
+		cookie.MaxAge = manager.config.CookieLifeTime
+	} else if manager.config.IdleLifetime > 0 {
+		cookie.MaxAge = int(manager.config.IdleLifetime)
+	}
+	return cookie
+}
+
+// create reads a new session, setting its cookie.
+func (manager *Manager) create(w http.ResponseWriter, r *http.Request) (SessionStore, error) {
+	sid, err := manager.sessionId(r)
+	if err != nil {
+		return nil, err
+	}
+	session, err := manager.provider.SessionRead(sid)
+	if err != nil {
+		return nil, err
+	}
+	manager.setsid(w, r, sid, false)
+	manager.hooks.created(r, session)
+	return session, nil
 }
 
 // Start session. generate or read the session id from http request.
 // if session id exists, return SessionStore with this id.
 func (manager *Manager) SessionStart(w http.ResponseWriter, r *http.Request) (session SessionStore, err error) {
-	cookie, errs := r.Cookie(manager.config.CookieName)
-	if errs != nil || cookie.Value == "" {
-		sid, errs := manager.sessionId(r)
-		if errs != nil {
-			return nil, errs
-		}
-		session, err = manager.provider.SessionRead(sid)
-		cookie = &http.Cookie{Name: manager.config.CookieName,
-			Value:    url.QueryEscape(sid),
-			Path:     "/",
-			HttpOnly: true,
-			Secure:   manager.config.Secure,
-			Domain:   manager.config.Domain}
-		if manager.config.CookieLifeTime >= 0 {
-			cookie.MaxAge = manager.config.CookieLifeTime
+	sid, query, err := manager.sidsource(r)
+	if err != nil {
+		return nil, err
+	}
+	var unlock func()
+	if sid != "" && manager.provider.SessionExist(sid) {
+		if unlock, err = manager.lock(sid); err != nil {
+			return nil, err
 		}
-		if manager.config.EnableSetCookie {
-			http.SetCookie(w, cookie)
+		if query {
+			session, err = manager.exchange(w, r, sid)
+		} else {
+			session, err = manager.provider.SessionRead(sid)
 		}
-		r.AddCookie(cookie)
 	} else {
-		sid, errs := url.QueryUnescape(cookie.Value)
-		if errs != nil {
-			return nil, errs
+		session, err = manager.create(w, r)
+	}
+	if err == nil && session != nil {
+		session, err = manager.expire(w, r, session)
+	}
+	if err != nil || session == nil {
+		if unlock != nil {
+			unlock()
 		}
-		if manager.provider.SessionExist(sid) {
-			session, err = manager.provider.SessionRead(sid)
-		} else {
-			sid, err = manager.sessionId(r)
-			if err != nil {
-				return nil, errs
-			}
-			session, err = manager.provider.SessionRead(sid)
-			cookie = &http.Cookie{Name: manager.config.CookieName,
-				Value:    url.QueryEscape(sid),
-				Path:     "/",
-				HttpOnly: true,
-				Secure:   manager.config.Secure,
-				Domain:   manager.config.Domain}
-			if manager.config.CookieLifeTime >= 0 {
-				cookie.MaxAge = manager.config.CookieLifeTime
-			}
-			if manager.config.EnableSetCookie {
-				http.SetCookie(w, cookie)
-			}
-			r.AddCookie(cookie)
+		return nil, err
+	}
+	if manager.config.WriteBehind {
+		session = writebehind(session, manager.releasefailed)
+	}
+	if unlock != nil {
+		session = &lockedstore{SessionStore: session, unlock: unlock}
+	}
+	return session, nil
+}
+
+// expire enforces any configured idle and absolute lifetime, replacing an
+// expired session with a new session, and recording activity on the session,
+// which refreshes the provider expiry when the session is released. With an
+// idle lifetime the session cookie is refreshed on every request.
+func (manager *Manager) expire(w http.ResponseWriter, r *http.Request, session SessionStore) (SessionStore, error) {
+	idle, absolute := manager.config.IdleLifetime, manager.config.AbsoluteLifetime
+	if idle <= 0 && absolute <= 0 {
+		return session, nil
+	}
+	now := manager.clock.Now().Unix()
+	created, _ := session.Get(CreatedKey).(int64)
+	accessed, _ := session.Get(AccessedKey).(int64)
+	if created > 0 && ((absolute > 0 && now-created >= absolute) || (idle > 0 && now-accessed >= idle)) {
+		manager.provider.SessionDestroy(session.SessionID())
+		manager.hooks.destroyed(r, session.SessionID())
+		session.Flush()
+		var err error
+		if session, err = manager.create(w, r); err != nil {
+			return nil, err
 		}
+		created = 0
 	}
-	return
+	if created == 0 {
+		session.Set(CreatedKey, now)
+	} else if idle > 0 {
+		manager.setsid(w, r, session.SessionID(), false)
+	}
+	session.Set(AccessedKey, now)
+	return session, nil
 }
 
-// Destroy session by its id in http request cookie.
+// Destroy session by its id in http request cookie, header, or query.
 func (manager *Manager) SessionDestroy(w http.ResponseWriter, r *http.Request) {
-	cookie, err := r.Cookie(manager.config.CookieName)
-	if err != nil || cookie.Value == "" {
+	sid, err := manager.requestsid(r)
+	if err != nil || sid == "" {
 		return
 	} else {
-		manager.provider.SessionDestroy(cookie.Value)
-		expiration := time.Now()
+		manager.provider.SessionDestroy(sid)
+		manager.hooks.destroyed(r, sid)
+		expiration := manager.clock.Now()
 		cookie := http.Cookie{Name: manager.config.CookieName,
-			Path:     "/",
-			HttpOnly: true,
-			Expires:  expiration,
-			MaxAge:   -1}
+			Path:        "/",
+			HttpOnly:    true,
+			Secure:      manager.config.Secure || manager.samesite == http.SameSiteNoneMode,
+			Domain:      manager.config.Domain,
+			SameSite:    manager.samesite,
+			Partitioned: manager.config.Partitioned,
+			Expires:     expiration,
+			MaxAge:      -1}
 		http.SetCookie(w, &cookie)
 	}
 }
@@ -178,11 +351,54 @@ func (manager *Manager) GetSessionStore(sid string) (sessions SessionStore, err
 	return
 }
 
-// Start session gc process.
-// it can do gc in times after gc lifetime.
+// GC runs session gc immediately and then every gc lifetime, returning when
+// StopGC is called.
 func (manager *Manager) GC() {
+	manager.GCContext(context.Background())
+}
+
+// GCContext runs session gc immediately and then every gc lifetime, returning
+// when the context is done or StopGC is called.
+func (manager *Manager) GCContext(ctx context.Context) {
+	manager.gcwg.Add(1)
+	defer manager.gcwg.Done()
+	select {
+	case <-manager.gcstop:
+		return
+	case <-ctx.Done():
+		return
+	default:
+	}
+	manager.gc()
+	if manager.config.Gclifetime <= 0 {
+		return
+	}
+	ticker := time.NewTicker(time.Duration(manager.config.Gclifetime) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			manager.gc()
+		case <-manager.gcstop:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// StopGC stops any running session gc, waiting for it to return. Session gc
+// does not run for the Manager once stopped.
+func (manager *Manager) StopGC() {
+	manager.gconce.Do(func() { close(manager.gcstop) })
+	manager.gcwg.Wait()
+}
+
+func (manager *Manager) gc() {
 	manager.provider.SessionGC()
-	time.AfterFunc(time.Duration(manager.config.Gclifetime)*time.Second, func() { manager.GC() })
+	if l := manager.log(); l != nil {
+		l.Debug("session gc", "active", manager.provider.SessionAll())
+	}
 }
 
 // Regenerate a session id for this SessionStore who's id is saving in http request.
@@ -191,47 +407,191 @@ func (manager *Manager) SessionRegenerateId(w http.ResponseWriter, r *http.Reque
 	if err != nil {
 		return
 	}
-	cookie, err := r.Cookie(manager.config.CookieName)
-	if err != nil && cookie.Value == "" {
+	oldsid, err := manager.requestsid(r)
+	if err != nil || oldsid == "" {
 		session, _ = manager.provider.SessionRead(sid)
-		cookie = &http.Cookie{Name: manager.config.CookieName,
-			Value:    url.QueryEscape(sid),
-			Path:     "/",
-			HttpOnly: true,
-			Secure:   manager.config.Secure,
-			Domain:   manager.config.Domain,
-		}
+		defer manager.hooks.created(r, session)
 	} else {
-		oldsid, _ := url.QueryUnescape(cookie.Value)
 		session, _ = manager.provider.SessionRegenerate(oldsid, sid)
-		cookie.Value = url.QueryEscape(sid)
-		cookie.HttpOnly = true
-		cookie.Path = "/"
+		defer manager.hooks.regenerated(r, oldsid, sid)
 	}
-	if manager.config.CookieLifeTime >= 0 {
-		cookie.MaxAge = manager.config.CookieLifeTime
+	manager.setsid(w, r, sid, true)
+	if manager.config.WriteBehind {
+		session = writebehind(session, manager.releasefailed)
 	}
-	http.SetCookie(w, cookie)
-	r.AddCookie(cookie)
 	return
 }
 
+// RegenerateSession moves the current session to a new id, keeping its values,
+// e.g. on login to prevent session fixation. The current session is saved
+// first, so values set during the request are kept. Providers keeping
+// sessions only in cookies have no server side id to replace, and return the
+// current session.
+func (manager *Manager) RegenerateSession(w http.ResponseWriter, r *http.Request, current SessionStore) (SessionStore, error) {
+	manager.regenerate.Lock()
+	defer manager.regenerate.Unlock()
+	sid, err := manager.sessionId(r)
+	if err != nil {
+		return nil, err
+	}
+	oldsid, err := manager.requestsid(r)
+	if err != nil {
+		return nil, err
+	}
+	// a locked session is saved keeping its lock, held with a lock of the
+	// new id until the regenerated session is released
+	locked, _ := current.(*lockedstore)
+	if locked != nil {
+		locked.SessionStore.SessionRelease(discardwriter{})
+	} else if current != nil {
+		current.SessionRelease(discardwriter{})
+	}
+	session, err := manager.provider.SessionRegenerate(oldsid, sid)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return current, nil
+	}
+	manager.setsid(w, r, sid, true)
+	manager.hooks.regenerated(r, oldsid, sid)
+	if manager.config.WriteBehind {
+		session = writebehind(session, manager.releasefailed)
+	}
+	if locked != nil {
+		unlock, err := manager.lock(sid)
+		if err != nil {
+			locked.Unlock()
+			return nil, err
+		}
+		session = &lockedstore{SessionStore: session, unlock: func() {
+			if unlock != nil {
+				unlock()
+			}
+			locked.Unlock()
+		}}
+	}
+	return session, nil
+}
+
+// discardwriter is an http.ResponseWriter discarding any response, used to
+// save a session without sending any cookie it sets.
+type discardwriter struct{}
+
+func (discardwriter) Header() http.Header         { return make(http.Header) }
+func (discardwriter) Write(b []byte) (int, error) { return len(b), nil }
+func (discardwriter) WriteHeader(int)             {}
+
 // Get all active sessions count number.
 func (manager *Manager) GetActiveSession() int {
 	return manager.provider.SessionAll()
 }
 
+// rejected logs a session payload over the maximum payload size.
+func (manager *Manager) rejected(size int) {
+	if l := manager.log(); l != nil {
+		l.Error("session payload too large", "size", size, "max", manager.config.MaxPayloadBytes)
+	}
+}
+
+// releasefailed logs an error storing the changes of a write behind session.
+func (manager *Manager) releasefailed(err error) {
+	if l := manager.log(); l != nil {
+		l.Error("session write behind failed", "error", err)
+	}
+}
+
+// SetClock sets the time used by the Manager, and any ClockProvider, for
+// session expiry, e.g. to freeze time in tests.
+func (manager *Manager) SetClock(now func() time.Time) {
+	manager.clock.SetClock(now)
+	if cp, ok := manager.provider.(ClockProvider); ok {
+		cp.SetClock(now)
+	}
+}
+
+// SetLogger sets a Logger receiving messages from the Manager, and from any
+// LoggerProvider.
+func (manager *Manager) SetLogger(l Logger) {
+	manager.logmu.Lock()
+	manager.logger = l
+	manager.logmu.Unlock()
+	if lp, ok := manager.provider.(LoggerProvider); ok {
+		lp.SetLogger(l)
+	}
+}
+
+func (manager *Manager) log() Logger {
+	manager.logmu.RLock()
+	defer manager.logmu.RUnlock()
+	return manager.logger
+}
+
 // Set cookie with https.
 func (manager *Manager) SetSecure(secure bool) {
 	manager.config.Secure = secure
 }
 
-// generate session id with rand string, unix nano time, remote addr by hash function.
+// ParseSameSite returns the http.SameSite mode for "lax", "strict", or
+// "none", or the default of no SameSite attribute for an empty string.
+func ParseSameSite(s string) (http.SameSite, error) {
+	switch strings.ToLower(s) {
+	case "":
+		return 0, nil
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	}
+	return 0, fmt.Errorf("session: invalid SameSite mode %q", s)
+}
+
+// SetSessionIDFunc sets a function generating new session ids, used instead
+// of encoding random bytes.
+func (manager *Manager) SetSessionIDFunc(fn SessionIDFunc) {
+	manager.idfunc = fn
+}
+
+// SetRandom sets the source of random bytes for session ids, by default
+// crypto/rand.Reader.
+func (manager *Manager) SetRandom(r io.Reader) {
+	manager.random = r
+}
+
+// checkcookie validates the constraints of the __Secure- and __Host- cookie
+// name prefixes, and of the Partitioned attribute: a secure cookie, and for
+// __Host- no domain. Session cookies always have the path "/".
+func checkcookie(name string, secure bool, domain string, partitioned bool) error {
+	switch {
+	case strings.HasPrefix(name, "__Host-") && (!secure || domain != ""):
+		return fmt.Errorf("session: cookie %q requires a secure cookie without a domain", name)
+	case strings.HasPrefix(name, "__Secure-") && !secure:
+		return fmt.Errorf("session: cookie %q requires a secure cookie", name)
+	case partitioned && !secure:
+		return fmt.Errorf("session: partitioned cookie %q requires a secure cookie", name)
+	}
+	return nil
+}
+
+// generate session id from sessionIdLength random bytes, encoded as hex or
+// base64url per the sessionIdEncoding config, or with any SessionIDFunc.
 func (manager *Manager) sessionId(r *http.Request) (sid string, err error) {
+	if manager.idfunc != nil {
+		return manager.idfunc(r)
+	}
+	random := manager.random
+	if random == nil {
+		random = rand.Reader
+	}
 	b := make([]byte, manager.config.SessionIdLength)
-	n, err := rand.Read(b)
+	n, err := io.ReadFull(random, b)
 	if n != len(b) || err != nil {
 		return "", fmt.Errorf("Could not successfully read from the system CSPRNG.")
 	}
+	if manager.config.SessionIdEncoding == "base64url" {
+		return base64.RawURLEncoding.EncodeToString(b), nil
+	}
 	return hex.EncodeToString(b), nil
 }
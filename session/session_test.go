@@ -1,12 +1,14 @@
 package session
 
 import (
+	"context"
 	"crypto/aes"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 type User struct {
@@ -32,12 +34,12 @@ func TestCookieEncodeDecode(t *testing.T) {
 	val := make(map[interface{}]interface{})
 	val["tag"] = "hello"
 	val["color"] = "blue"
-	str, err := encodeCookie(block, hashKey, securityName, val)
+	str, err := encodeCookie(block, hashKey, securityName, val, time.Now())
 	if err != nil {
 		t.Fatal("encodeCookie:", err)
 	}
 	dst := make(map[interface{}]interface{})
-	dst, err = decodeCookie(block, hashKey, securityName, str, 3600)
+	dst, err = decodeCookie(block, hashKey, securityName, str, 3600, time.Now())
 	if err != nil {
 		t.Fatal("decodeCookie", err)
 	}
@@ -126,3 +128,209 @@ func TestCookie(t *testing.T) {
 		}
 	}
 }
+
+func TestSessionLifetimes(t *testing.T) {
+	start := time.Now()
+
+	for _, tc := range []struct {
+		name     string
+		config   string
+		requests []int64
+		kept     []bool
+	}{
+		{"idle", `"idleLifetime":60`, []int64{50, 100, 170}, []bool{true, true, false}},
+		{"absolute", `"absoluteLifetime":100`, []int64{50, 90, 101}, []bool{true, true, false}},
+	} {
+		m, err := NewManager("encryptedcookie", `{"cookieName":"session","enableSetCookie":false,"gclifetime":3600,`+tc.config+`,"ProviderConfig":"{\"cookieName\":\"session\",\"keys\":[\"lifetime key\"]}"}`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.SetClock(func() time.Time { return start })
+		r, _ := http.NewRequest("GET", "/", nil)
+		w := httptest.NewRecorder()
+		st, _ := m.SessionStart(w, r)
+		st.Set("key", "value")
+		st.SessionRelease(w)
+		cookie := w.Result().Cookies()[0]
+
+		for i, offset := range tc.requests {
+			m.SetClock(func() time.Time { return start.Add(time.Duration(offset) * time.Second) })
+			r, _ := http.NewRequest("GET", "/", nil)
+			r.AddCookie(cookie)
+			w := httptest.NewRecorder()
+			st, _ := m.SessionStart(w, r)
+			if kept := st.Get("key") == "value"; kept != tc.kept[i] {
+				t.Errorf("%s lifetime: session value kept %t after %ds, expected %t", tc.name, kept, offset, tc.kept[i])
+			}
+			st.SessionRelease(w)
+			cookie = w.Result().Cookies()[0]
+		}
+	}
+}
+
+func TestSessionSameSite(t *testing.T) {
+	m, err := NewManager("encryptedcookie", `{"cookieName":"session","gclifetime":3600,"sameSite":"None","ProviderConfig":"{\"cookieName\":\"session\",\"keys\":[\"samesite key\"],\"sameSite\":\"strict\"}"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	st, _ := m.SessionStart(w, r)
+	st.SessionRelease(w)
+	m.SessionRegenerateId(w, r)
+	m.SessionDestroy(w, r)
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 4 {
+		t.Fatalf("expected 4 cookies, got %d", len(cookies))
+	}
+	for i, expected := range []http.SameSite{http.SameSiteNoneMode, http.SameSiteStrictMode, http.SameSiteNoneMode, http.SameSiteNoneMode} {
+		if cookies[i].SameSite != expected {
+			t.Errorf("cookie %d SameSite was %v, expected %v", i, cookies[i].SameSite, expected)
+		}
+		if expected == http.SameSiteNoneMode && !cookies[i].Secure {
+			t.Errorf("cookie %d with SameSite=None was not Secure", i)
+		}
+	}
+
+	if _, err := NewManager("encryptedcookie", `{"cookieName":"session","sameSite":"sometimes","ProviderConfig":"{\"keys\":[\"k\"]}"}`); err == nil {
+		t.Error("expected an error for an invalid SameSite mode")
+	}
+}
+
+type gcprovider struct {
+	EncryptedCookieProvider
+	runs chan struct{}
+}
+
+func (p *gcprovider) SessionGC() {
+	p.runs <- struct{}{}
+}
+
+func TestManagerStopGC(t *testing.T) {
+	p := &gcprovider{runs: make(chan struct{}, 4)}
+	Register("gctest", p)
+	m, err := NewManager("gctest", `{"cookieName":"session","gclifetime":3600,"ProviderConfig":"{\"keys\":[\"gc key\"]}"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go m.GC()
+	select {
+	case <-p.runs:
+	case <-time.After(time.Second):
+		t.Fatal("session gc did not run")
+	}
+	stopped := make(chan struct{})
+	go func() {
+		m.StopGC()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("StopGC did not return")
+	}
+
+	m.GC()
+	if len(p.runs) != 0 {
+		t.Error("session gc ran after StopGC")
+	}
+
+	m2, _ := NewManager("gctest", `{"cookieName":"session","gclifetime":3600,"ProviderConfig":"{\"keys\":[\"gc key\"]}"}`)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		m2.GCContext(ctx)
+		close(done)
+	}()
+	<-p.runs
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("GCContext did not return when the context was canceled")
+	}
+}
+
+func TestManagerHooks(t *testing.T) {
+	m, err := NewManager("encryptedcookie", `{"cookieName":"session","gclifetime":3600,"ProviderConfig":"{\"cookieName\":\"session\",\"keys\":[\"hook key\"]}"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []string
+	m.OnCreate(func(r *http.Request, s SessionStore) { events = append(events, "create:"+s.SessionID()) })
+	m.OnRegenerate(func(r *http.Request, oldsid, sid string) { events = append(events, "regenerate:"+oldsid+":"+sid) })
+	m.OnDestroy(func(r *http.Request, sid string) { events = append(events, "destroy:"+sid) })
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	st, _ := m.SessionStart(w, r)
+	created := st.SessionID()
+	m.SessionStart(w, r)
+	m.SessionRegenerateId(w, r)
+	regenerated, _ := r.Cookie("session")
+	m.SessionDestroy(w, r)
+
+	expected := []string{
+		"create:" + created,
+		"regenerate:" + created + ":" + regenerated.Value,
+		"destroy:" + regenerated.Value,
+	}
+	if strings.Join(events, " ") != strings.Join(expected, " ") {
+		t.Errorf("session hooks received %v, expected %v", events, expected)
+	}
+}
+
+func TestSessionIDGeneration(t *testing.T) {
+	r, _ := http.NewRequest("GET", "/", nil)
+
+	m, err := NewManager("memorytest", `{"cookieName":"session","sessionIdEncoding":"base64url","sessionIdLength":3}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetRandom(strings.NewReader("\xfb\xff\xfe"))
+	if sid, _ := m.sessionId(r); sid != "-__-" {
+		t.Errorf("base64url session id from the random source was %q", sid)
+	}
+
+	m.SetSessionIDFunc(func(*http.Request) (string, error) { return "app-1", nil })
+	st, _ := m.SessionStart(httptest.NewRecorder(), r)
+	if st.SessionID() != "app-1" {
+		t.Errorf("session id was %q, expected the SessionIDFunc id", st.SessionID())
+	}
+
+	if _, err := NewManager("memorytest", `{"cookieName":"session","sessionIdEncoding":"base32"}`); err == nil {
+		t.Error("expected an error for an unknown session id encoding")
+	}
+}
+
+func TestSessionCookiePrefixes(t *testing.T) {
+	for config, valid := range map[string]bool{
+		`"cookieName":"__Host-session","secure":true`:                        true,
+		`"cookieName":"__Host-session"`:                                      false,
+		`"cookieName":"__Host-session","secure":true,"domain":"example.com"`: false,
+		`"cookieName":"__Secure-session","sameSite":"none"`:                  true,
+		`"cookieName":"__Secure-session"`:                                    false,
+		`"cookieName":"session","partitioned":true`:                          false,
+	} {
+		_, err := NewManager("memorytest", "{"+config+"}")
+		if (err == nil) != valid {
+			t.Errorf("manager config %s valid %t, expected %t: %v", config, err == nil, valid, err)
+		}
+	}
+
+	m, err := NewManager("memorytest", `{"cookieName":"__Host-session","secure":true,"partitioned":true}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	m.SessionStart(w, r)
+	header := w.Header().Get("Set-Cookie")
+	for _, attr := range []string{"__Host-session=", "Path=/", "Secure", "Partitioned"} {
+		if !strings.Contains(header, attr) {
+			t.Errorf("session cookie %q does not contain %s", header, attr)
+		}
+	}
+}
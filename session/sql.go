@@ -0,0 +1,475 @@
+package session
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	sqlpder = &SQLProvider{}
+
+	serializers = map[string]Serializer{"gob": GobSerializer{}}
+)
+
+type (
+	// Serializer encodes and decodes session values for storage by a Provider.
+	Serializer interface {
+		Encode(map[interface{}]interface{}) ([]byte, error)
+		Decode([]byte) (map[interface{}]interface{}, error)
+	}
+
+	// GobSerializer is the default Serializer, encoding values with gob.
+	GobSerializer struct{}
+
+	SQLSessionStore struct {
+		sid     string
+		values  map[interface{}]interface{}
+		lock    sync.RWMutex
+		pder    *SQLProvider
+		batched bool
+	}
+
+	// SQLProvider stores sessions in a relational database through
+	// database/sql, for PostgreSQL, MySQL, or SQLite drivers.
+	SQLProvider struct {
+		clock
+		maxlifetime int64
+		config      *sqlConfig
+		db          *sql.DB
+		dialect     *sqlDialect
+		serializer  Serializer
+		logmu       sync.RWMutex
+		logger      Logger
+		reads       int64
+		writes      int64
+		reclaimed   int64
+	}
+
+	sqlConfig struct {
+		Driver     string `json:"driver"`
+		DSN        string `json:"dsn"`
+		Table      string `json:"table"`
+		Serializer string `json:"serializer"`
+		GCBatch    int    `json:"gcBatch"`
+	}
+
+	sqlDialect struct {
+		name     string
+		blob     string
+		dollar   bool
+		upsert   string
+		inlineix bool
+	}
+)
+
+func (GobSerializer) Encode(values map[interface{}]interface{}) ([]byte, error) {
+	return EncodeGob(values)
+}
+
+func (GobSerializer) Decode(b []byte) (map[interface{}]interface{}, error) {
+	return DecodeGob(b)
+}
+
+// RegisterSerializer makes a Serializer available by name to providers, e.g.
+// with the sql provider config "serializer".
+func RegisterSerializer(name string, s Serializer) {
+	serializers[name] = s
+}
+
+var (
+	postgresDialect = &sqlDialect{
+		name:   "postgres",
+		blob:   "BYTEA",
+		dollar: true,
+		upsert: "ON CONFLICT (session_key) DO UPDATE SET session_data = EXCLUDED.session_data, session_expiry = EXCLUDED.session_expiry",
+	}
+	mysqlDialect = &sqlDialect{
+		name:     "mysql",
+		blob:     "LONGBLOB",
+		upsert:   "ON DUPLICATE KEY UPDATE session_data = VALUES(session_data), session_expiry = VALUES(session_expiry)",
+		inlineix: true,
+	}
+	sqliteDialect = &sqlDialect{
+		name:   "sqlite",
+		blob:   "BLOB",
+		upsert: "ON CONFLICT (session_key) DO UPDATE SET session_data = excluded.session_data, session_expiry = excluded.session_expiry",
+	}
+
+	validTable = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+func dialectFor(driver string) (*sqlDialect, error) {
+	switch strings.ToLower(driver) {
+	case "postgres", "pgx", "pq":
+		return postgresDialect, nil
+	case "mysql":
+		return mysqlDialect, nil
+	case "sqlite", "sqlite3":
+		return sqliteDialect, nil
+	}
+	return nil, fmt.Errorf("session: sql provider does not support driver %q", driver)
+}
+
+// query replaces ? placeholders for dialects using numbered placeholders.
+func (d *sqlDialect) query(q string) string {
+	if !d.dollar {
+		return q
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range q {
+		if r == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func (d *sqlDialect) migrations(table string) []string {
+	create := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	session_key VARCHAR(128) NOT NULL PRIMARY KEY,
+	session_data %s,
+	session_expiry BIGINT NOT NULL`, table, d.blob)
+	if d.inlineix {
+		return []string{create + fmt.Sprintf(",\n\tINDEX %s_expiry (session_expiry)\n)", table)}
+	}
+	return []string{
+		create + "\n)",
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_expiry ON %s (session_expiry)", table, table),
+	}
+}
+
+// Set value to sql session.
+func (st *SQLSessionStore) Set(key, value interface{}) error {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	st.values[key] = value
+	return nil
+}
+
+// Get value from sql session.
+func (st *SQLSessionStore) Get(key interface{}) interface{} {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	return st.values[key]
+}
+
+// Delete value in sql session.
+func (st *SQLSessionStore) Delete(key interface{}) error {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	delete(st.values, key)
+	return nil
+}
+
+// Clean all values in sql session.
+func (st *SQLSessionStore) Flush() error {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	st.values = make(map[interface{}]interface{})
+	return nil
+}
+
+// Keys returns the keys of the sql session values.
+func (st *SQLSessionStore) Keys() []interface{} {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	return keysof(st.values)
+}
+
+// Export returns a copy of the sql session values.
+func (st *SQLSessionStore) Export() map[interface{}]interface{} {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	return copyvalues(st.values)
+}
+
+// Return id of this sql session.
+func (st *SQLSessionStore) SessionID() string {
+	return st.sid
+}
+
+// Save the sql session values to the database, unless saved by SetBatch,
+// reporting any error to the provider Logger.
+func (st *SQLSessionStore) SessionRelease(w http.ResponseWriter) {
+	st.lock.Lock()
+	batched := st.batched
+	st.batched = false
+	st.lock.Unlock()
+	if batched {
+		return
+	}
+	if err := st.Save(); err != nil {
+		st.pder.savefailed(err)
+	}
+}
+
+// SetBatch applies the changes held by a write behind session to the sql
+// session values and saves them in a single statement.
+func (st *SQLSessionStore) SetBatch(set map[interface{}]interface{}, deleted []interface{}, flushed bool) error {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	if flushed {
+		st.values = make(map[interface{}]interface{})
+	}
+	for _, key := range deleted {
+		delete(st.values, key)
+	}
+	for key, value := range set {
+		st.values[key] = value
+	}
+	if err := st.pder.save(st.sid, st.values); err != nil {
+		return err
+	}
+	st.batched = true
+	return nil
+}
+
+// Save the sql session values to the database, returning any error.
+func (st *SQLSessionStore) Save() error {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	return st.pder.save(st.sid, st.values)
+}
+
+// SetDB sets an existing database for the provider, used instead of opening
+// the config driver and dsn. The config driver still selects the dialect.
+func (pder *SQLProvider) SetDB(db *sql.DB) {
+	pder.db = db
+}
+
+// SetLogger sets the Logger receiving errors saving released sessions.
+func (pder *SQLProvider) SetLogger(l Logger) {
+	pder.logmu.Lock()
+	defer pder.logmu.Unlock()
+	pder.logger = l
+}
+
+func (pder *SQLProvider) savefailed(err error) {
+	pder.logmu.RLock()
+	l := pder.logger
+	pder.logmu.RUnlock()
+	if l != nil {
+		l.Error("sql session save failed", "error", err)
+	}
+}
+
+// SetSQLDB sets an existing database for the registered "sql" provider.
+func SetSQLDB(db *sql.DB) {
+	sqlpder.SetDB(db)
+}
+
+// Init sql session provider with max lifetime and config json, creating the
+// session table if it does not exist.
+// json config:
+//
+//	driver - database/sql driver name; postgres, pgx, mysql, sqlite, or sqlite3
+//	dsn - data source name, unless a database is set with SetDB
+//	table - session table name, default "flotilla_sessions"
+//	serializer - registered Serializer name, default "gob"
+//	gcBatch - maximum sessions deleted per gc statement, default 500
+func (pder *SQLProvider) SessionInit(maxlifetime int64, config string) error {
+	pder.config = &sqlConfig{}
+	if err := json.Unmarshal([]byte(config), pder.config); err != nil {
+		return err
+	}
+	if pder.config.Table == "" {
+		pder.config.Table = "flotilla_sessions"
+	}
+	if !validTable.MatchString(pder.config.Table) {
+		return fmt.Errorf("session: invalid sql session table name %q", pder.config.Table)
+	}
+	if pder.config.Serializer == "" {
+		pder.config.Serializer = "gob"
+	}
+	if pder.config.GCBatch <= 0 {
+		pder.config.GCBatch = 500
+	}
+
+	s, ok := serializers[pder.config.Serializer]
+	if !ok {
+		return fmt.Errorf("session: unknown serializer %q", pder.config.Serializer)
+	}
+	pder.serializer = s
+
+	var err error
+	if pder.dialect, err = dialectFor(pder.config.Driver); err != nil {
+		return err
+	}
+	if pder.db == nil {
+		if pder.db, err = sql.Open(pder.config.Driver, pder.config.DSN); err != nil {
+			return err
+		}
+	}
+	for _, m := range pder.dialect.migrations(pder.config.Table) {
+		if _, err = pder.db.Exec(m); err != nil {
+			return err
+		}
+	}
+	pder.maxlifetime = maxlifetime
+	return nil
+}
+
+func (pder *SQLProvider) exec(q string, args ...interface{}) (sql.Result, error) {
+	return pder.db.Exec(pder.dialect.query(q), args...)
+}
+
+func (pder *SQLProvider) expiry() int64 {
+	return pder.Now().Unix() + pder.maxlifetime
+}
+
+func (pder *SQLProvider) save(sid string, values map[interface{}]interface{}) error {
+	data, err := pder.serializer.Encode(values)
+	if err != nil {
+		return err
+	}
+	atomic.AddInt64(&pder.writes, 1)
+	_, err = pder.exec(fmt.Sprintf(
+		"INSERT INTO %s (session_key, session_data, session_expiry) VALUES (?, ?, ?) %s",
+		pder.config.Table, pder.dialect.upsert),
+		sid, data, pder.expiry())
+	return err
+}
+
+// Get SessionStore from the database, or an empty SessionStore if the session
+// does not exist or has expired, inserted when first saved.
+func (pder *SQLProvider) SessionRead(sid string) (SessionStore, error) {
+	var data []byte
+	var expiry int64
+	atomic.AddInt64(&pder.reads, 1)
+	row := pder.db.QueryRow(pder.dialect.query(fmt.Sprintf(
+		"SELECT session_data, session_expiry FROM %s WHERE session_key = ?", pder.config.Table)), sid)
+	err := row.Scan(&data, &expiry)
+
+	var values map[interface{}]interface{}
+	switch {
+	case errors.Is(err, sql.ErrNoRows) || (err == nil && expiry < pder.Now().Unix()):
+		values = make(map[interface{}]interface{})
+	case err != nil:
+		return nil, err
+	case len(data) == 0:
+		values = make(map[interface{}]interface{})
+	default:
+		if values, err = pder.serializer.Decode(data); err != nil {
+			return nil, err
+		}
+	}
+	return &SQLSessionStore{sid: sid, values: values, pder: pder}, nil
+}
+
+// Check sql session exists and has not expired.
+func (pder *SQLProvider) SessionExist(sid string) bool {
+	var n int
+	row := pder.db.QueryRow(pder.dialect.query(fmt.Sprintf(
+		"SELECT COUNT(*) FROM %s WHERE session_key = ? AND session_expiry >= ?", pder.config.Table)),
+		sid, pder.Now().Unix())
+	return row.Scan(&n) == nil && n > 0
+}
+
+// Move the session with oldsid to sid, returning a SessionStore for sid.
+func (pder *SQLProvider) SessionRegenerate(oldsid, sid string) (SessionStore, error) {
+	_, err := pder.exec(fmt.Sprintf(
+		"UPDATE %s SET session_key = ?, session_expiry = ? WHERE session_key = ?", pder.config.Table),
+		sid, pder.expiry(), oldsid)
+	if err != nil {
+		return nil, err
+	}
+	return pder.SessionRead(sid)
+}
+
+// Delete the sql session by id.
+func (pder *SQLProvider) SessionDestroy(sid string) error {
+	_, err := pder.exec(fmt.Sprintf("DELETE FROM %s WHERE session_key = ?", pder.config.Table), sid)
+	return err
+}
+
+// Delete expired sql sessions, in batches of at most gcBatch sessions so
+// large deletes do not hold long locks.
+func (pder *SQLProvider) SessionGC() {
+	now := pder.Now().Unix()
+	for {
+		rows, err := pder.db.Query(pder.dialect.query(fmt.Sprintf(
+			"SELECT session_key FROM %s WHERE session_expiry < ? LIMIT %d",
+			pder.config.Table, pder.config.GCBatch)), now)
+		if err != nil {
+			return
+		}
+		var keys []interface{}
+		for rows.Next() {
+			var key string
+			if rows.Scan(&key) == nil {
+				keys = append(keys, key)
+			}
+		}
+		rows.Close()
+		if len(keys) == 0 {
+			return
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(keys)), ", ")
+		res, err := pder.exec(fmt.Sprintf(
+			"DELETE FROM %s WHERE session_key IN (%s)", pder.config.Table, placeholders), keys...)
+		if err != nil {
+			return
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			atomic.AddInt64(&pder.reclaimed, n)
+		}
+		if len(keys) < pder.config.GCBatch {
+			return
+		}
+	}
+}
+
+// Count unexpired sql sessions.
+func (pder *SQLProvider) SessionAll() int {
+	var n int
+	row := pder.db.QueryRow(pder.dialect.query(fmt.Sprintf(
+		"SELECT COUNT(*) FROM %s WHERE session_expiry >= ?", pder.config.Table)), pder.Now().Unix())
+	if row.Scan(&n) != nil {
+		return 0
+	}
+	return n
+}
+
+// Stats returns counts of sql session reads, writes, and sessions deleted by
+// gc.
+func (pder *SQLProvider) Stats() Stats {
+	return Stats{
+		Reads:       atomic.LoadInt64(&pder.reads),
+		Writes:      atomic.LoadInt64(&pder.writes),
+		GCReclaimed: atomic.LoadInt64(&pder.reclaimed),
+	}
+}
+
+// SessionIDs returns the ids of unexpired sql sessions.
+func (pder *SQLProvider) SessionIDs() ([]string, error) {
+	rows, err := pder.db.Query(pder.dialect.query(fmt.Sprintf(
+		"SELECT session_key FROM %s WHERE session_expiry >= ?", pder.config.Table)), pder.Now().Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ret []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		ret = append(ret, key)
+	}
+	return ret, rows.Err()
+}
+
+func init() {
+	Register("sql", sqlpder)
+}
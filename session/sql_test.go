@@ -0,0 +1,273 @@
+package session
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSQLDialect(t *testing.T) {
+	d, err := dialectFor("pgx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := d.query("UPDATE s SET session_key = ?, session_expiry = ? WHERE session_key = ?")
+	if q != "UPDATE s SET session_key = $1, session_expiry = $2 WHERE session_key = $3" {
+		t.Errorf("postgres placeholders were not numbered: %s", q)
+	}
+
+	m, _ := dialectFor("mysql")
+	if q := m.query("SELECT ? FROM s"); q != "SELECT ? FROM s" {
+		t.Errorf("mysql placeholders were modified: %s", q)
+	}
+	if ms := m.migrations("sessions"); len(ms) != 1 || !strings.Contains(ms[0], "INDEX sessions_expiry") {
+		t.Errorf("mysql migration did not include the expiry index: %v", ms)
+	}
+
+	if _, err := dialectFor("oracle"); err == nil {
+		t.Errorf("expected an error for an unsupported driver")
+	}
+}
+
+func TestGobSerializer(t *testing.T) {
+	var s Serializer = GobSerializer{}
+	b, err := s.Encode(map[interface{}]interface{}{"user": "test", "count": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := s.Decode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v["user"] != "test" || v["count"] != 2 {
+		t.Errorf("decoded values did not match: %v", v)
+	}
+}
+
+// memsql is a database/sql driver keeping the rows of the sql provider
+// session table in memory, for the statements of the sqlite dialect.
+type memsql struct {
+	mu   sync.Mutex
+	rows map[string]memsqlrow
+	fail bool
+}
+
+type memsqlrow struct {
+	data   []byte
+	expiry int64
+}
+
+var errmemsql = errors.New("memsql: write failed")
+
+func (d *memsql) Open(string) (driver.Conn, error) { return memsqlconn{d}, nil }
+
+type memsqlconn struct{ d *memsql }
+
+func (c memsqlconn) Prepare(q string) (driver.Stmt, error) { return memsqlstmt{c.d, q}, nil }
+func (c memsqlconn) Close() error                          { return nil }
+func (c memsqlconn) Begin() (driver.Tx, error)             { return nil, errors.New("memsql: no transactions") }
+
+type memsqlstmt struct {
+	d *memsql
+	q string
+}
+
+func (s memsqlstmt) Close() error  { return nil }
+func (s memsqlstmt) NumInput() int { return -1 }
+
+func (s memsqlstmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	switch {
+	case strings.HasPrefix(s.q, "CREATE"):
+	case s.d.fail:
+		return nil, errmemsql
+	case strings.HasPrefix(s.q, "INSERT"):
+		s.d.rows[args[0].(string)] = memsqlrow{args[1].([]byte), args[2].(int64)}
+	case strings.HasPrefix(s.q, "UPDATE"):
+		if row, ok := s.d.rows[args[2].(string)]; ok {
+			delete(s.d.rows, args[2].(string))
+			row.expiry = args[1].(int64)
+			s.d.rows[args[0].(string)] = row
+		}
+	case strings.HasPrefix(s.q, "DELETE"):
+		for _, arg := range args {
+			delete(s.d.rows, arg.(string))
+		}
+	default:
+		return nil, fmt.Errorf("memsql: unsupported statement %s", s.q)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+var memsqllimit = regexp.MustCompile(`LIMIT \d+`)
+
+func (s memsqlstmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	r := &memsqlrows{}
+	switch {
+	case strings.HasPrefix(s.q, "SELECT session_data"):
+		r.columns = []string{"session_data", "session_expiry"}
+		if row, ok := s.d.rows[args[0].(string)]; ok {
+			r.values = append(r.values, []driver.Value{row.data, row.expiry})
+		}
+	case strings.HasPrefix(s.q, "SELECT COUNT(*)") && strings.Contains(s.q, "session_key = ?"):
+		r.columns = []string{"count"}
+		n := int64(0)
+		if row, ok := s.d.rows[args[0].(string)]; ok && row.expiry >= args[1].(int64) {
+			n = 1
+		}
+		r.values = append(r.values, []driver.Value{n})
+	case strings.HasPrefix(s.q, "SELECT COUNT(*)"):
+		r.columns = []string{"count"}
+		n := int64(0)
+		for _, row := range s.d.rows {
+			if row.expiry >= args[0].(int64) {
+				n++
+			}
+		}
+		r.values = append(r.values, []driver.Value{n})
+	case strings.HasPrefix(s.q, "SELECT session_key"):
+		r.columns = []string{"session_key"}
+		expired := memsqllimit.MatchString(s.q)
+		for key, row := range s.d.rows {
+			if (row.expiry < args[0].(int64)) == expired {
+				r.values = append(r.values, []driver.Value{key})
+			}
+		}
+	default:
+		return nil, fmt.Errorf("memsql: unsupported query %s", s.q)
+	}
+	return r, nil
+}
+
+type memsqlrows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *memsqlrows) Columns() []string { return r.columns }
+func (r *memsqlrows) Close() error      { return nil }
+
+func (r *memsqlrows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+var (
+	memsqldriver   = &memsql{rows: make(map[string]memsqlrow)}
+	memsqlprovider = &SQLProvider{}
+)
+
+func init() {
+	sql.Register("memsql", memsqldriver)
+	db, _ := sql.Open("memsql", "")
+	memsqlprovider.SetDB(db)
+	Register("sqltest", memsqlprovider)
+}
+
+type testlogger struct {
+	mu     sync.Mutex
+	errors []string
+}
+
+func (l *testlogger) Debug(string, ...interface{}) {}
+
+func (l *testlogger) Error(msg string, fields ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errors = append(l.errors, fmt.Sprint(append([]interface{}{msg}, fields...)...))
+}
+
+func TestSQLWriteBehind(t *testing.T) {
+	m, err := NewManager("sqltest", `{"cookieName":"session","gclifetime":3600,"writeBehind":true,"ProviderConfig":"{\"driver\":\"sqlite\"}"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := &testlogger{}
+	m.SetLogger(l)
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	st, err := m.SessionStart(w, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.Set("kept", "value")
+	st.Set("deleted", "value")
+	st.Delete("deleted")
+
+	writes := memsqlprovider.Stats().Writes
+	st.SessionRelease(w)
+	if n := memsqlprovider.Stats().Writes - writes; n != 1 {
+		t.Errorf("expected the held changes written in a single statement, got %d writes", n)
+	}
+	stored, _ := memsqlprovider.SessionRead(st.SessionID())
+	if stored.Get("kept") != "value" || stored.Get("deleted") != nil {
+		t.Errorf("expected the held changes stored, got %v", stored.Export())
+	}
+
+	st, _ = m.SessionStart(httptest.NewRecorder(), r)
+	st.Set("failed", "value")
+	memsqldriver.mu.Lock()
+	memsqldriver.fail = true
+	memsqldriver.mu.Unlock()
+	st.SessionRelease(w)
+	memsqldriver.mu.Lock()
+	memsqldriver.fail = false
+	memsqldriver.mu.Unlock()
+	if len(l.errors) == 0 || !strings.Contains(l.errors[0], errmemsql.Error()) {
+		t.Errorf("expected the write behind error to be logged, got %v", l.errors)
+	}
+}
+
+func TestSQLInsertOnSave(t *testing.T) {
+	m, err := NewManager("sqltest", `{"cookieName":"session","gclifetime":3600,"ProviderConfig":"{\"driver\":\"sqlite\"}"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := &testlogger{}
+	m.SetLogger(l)
+
+	st, err := memsqlprovider.SessionRead("unsaved")
+	if err != nil {
+		t.Fatal(err)
+	}
+	memsqldriver.mu.Lock()
+	_, inserted := memsqldriver.rows["unsaved"]
+	memsqldriver.mu.Unlock()
+	if inserted || memsqlprovider.SessionExist("unsaved") {
+		t.Error("expected no row inserted reading a missing session")
+	}
+	st.Set("user", "test")
+	st.SessionRelease(httptest.NewRecorder())
+	if stored, _ := memsqlprovider.SessionRead("unsaved"); !memsqlprovider.SessionExist("unsaved") || stored.Get("user") != "test" {
+		t.Error("expected the session inserted when first saved")
+	}
+
+	memsqldriver.mu.Lock()
+	memsqldriver.fail = true
+	memsqldriver.mu.Unlock()
+	st.SessionRelease(httptest.NewRecorder())
+	memsqldriver.mu.Lock()
+	memsqldriver.fail = false
+	memsqldriver.mu.Unlock()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.errors) != 1 || !strings.Contains(l.errors[0], errmemsql.Error()) {
+		t.Errorf("expected the error saving the released session logged, got %v", l.errors)
+	}
+}
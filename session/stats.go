@@ -0,0 +1,105 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+)
+
+var (
+	// NotSupported is returned for operations the Provider does not support.
+	NotSupported = errors.New("session: operation not supported by the provider")
+
+	// NoSession is returned inspecting a session id that does not exist.
+	NoSession = errors.New("session: session does not exist")
+)
+
+type (
+	// Stats are counts of session activity. Reads, Writes, and GCReclaimed are
+	// reported by a StatsProvider, and zero for other providers.
+	Stats struct {
+		Active      int   `json:"active"`
+		Created     int64 `json:"created"`
+		Destroyed   int64 `json:"destroyed"`
+		Reads       int64 `json:"reads"`
+		Writes      int64 `json:"writes"`
+		GCReclaimed int64 `json:"gc_reclaimed"`
+	}
+
+	// StatsProvider is implemented by a Provider counting its activity.
+	StatsProvider interface {
+		Stats() Stats
+	}
+
+	// ListingProvider is implemented by a Provider able to list the ids of
+	// its active sessions.
+	ListingProvider interface {
+		SessionIDs() ([]string, error)
+	}
+
+	counts struct {
+		created   int64
+		destroyed int64
+	}
+)
+
+// Stats returns counts of session activity for the Manager and its Provider.
+func (manager *Manager) Stats() Stats {
+	var s Stats
+	if sp, ok := manager.provider.(StatsProvider); ok {
+		s = sp.Stats()
+	}
+	s.Active = manager.provider.SessionAll()
+	s.Created = atomic.LoadInt64(&manager.counts.created)
+	s.Destroyed = atomic.LoadInt64(&manager.counts.destroyed)
+	return s
+}
+
+// SessionIDs returns the ids of the active sessions of a ListingProvider.
+func (manager *Manager) SessionIDs() ([]string, error) {
+	if lp, ok := manager.provider.(ListingProvider); ok {
+		return lp.SessionIDs()
+	}
+	return nil, NotSupported
+}
+
+// Inspect returns the values of the session with the provided id, keyed by
+// the string form of each key.
+func (manager *Manager) Inspect(sid string) (map[string]interface{}, error) {
+	if !manager.provider.SessionExist(sid) {
+		return nil, NoSession
+	}
+	st, err := manager.provider.SessionRead(sid)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[string]interface{})
+	for k, v := range st.Export() {
+		ret[fmt.Sprint(k)] = v
+	}
+	return ret, nil
+}
+
+// Exists reports whether the session with the provided id exists.
+func (manager *Manager) Exists(sid string) bool {
+	return manager.provider.SessionExist(sid)
+}
+
+// Destroy destroys the session with the provided id, calling any DestroyHook
+// with a nil request.
+func (manager *Manager) Destroy(sid string) error {
+	if err := manager.provider.SessionDestroy(sid); err != nil {
+		return err
+	}
+	manager.hooks.destroyed(nil, sid)
+	return nil
+}
+
+func (c *counts) create(*http.Request, SessionStore) {
+	atomic.AddInt64(&c.created, 1)
+}
+
+func (c *counts) destroy(*http.Request, string) {
+	atomic.AddInt64(&c.destroyed, 1)
+}
@@ -0,0 +1,89 @@
+package session
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// requestsid returns the session id of the request from the session cookie,
+// or from any configured session header or query parameter, or an empty
+// string when the request has no session id.
+func (manager *Manager) requestsid(r *http.Request) (string, error) {
+	sid, _, err := manager.sidsource(r)
+	return sid, err
+}
+
+// sidsource returns the session id of the request as requestsid, and whether
+// it was read from the query parameter.
+func (manager *Manager) sidsource(r *http.Request) (string, bool, error) {
+	if cookie, err := r.Cookie(manager.config.CookieName); err == nil && cookie.Value != "" {
+		sid, err := url.QueryUnescape(cookie.Value)
+		return sid, false, err
+	}
+	if name := manager.config.SessionHeader; name != "" {
+		v := strings.TrimSpace(r.Header.Get(name))
+		if scheme := manager.config.SessionHeaderScheme; scheme != "" {
+			if len(v) <= len(scheme) || !strings.EqualFold(v[:len(scheme)], scheme) || v[len(scheme)] != ' ' {
+				v = ""
+			} else {
+				v = strings.TrimSpace(v[len(scheme):])
+			}
+		}
+		if v != "" {
+			return v, false, nil
+		}
+	}
+	if name := manager.config.SessionQuery; name != "" {
+		if v := r.URL.Query().Get(name); v != "" {
+			return v, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// exchange regenerates the id of a session read from the query parameter,
+// so an id exposed in a URL, e.g. in logs or a Referer header, is used once
+// and a session id fixed by a link is not kept.
+func (manager *Manager) exchange(w http.ResponseWriter, r *http.Request, oldsid string) (SessionStore, error) {
+	sid, err := manager.sessionId(r)
+	if err != nil {
+		return nil, err
+	}
+	session, err := manager.provider.SessionRegenerate(oldsid, sid)
+	if err != nil || session == nil {
+		return session, err
+	}
+	manager.setsid(w, r, sid, false)
+	manager.hooks.regenerated(r, oldsid, sid)
+	return session, nil
+}
+
+// setsid sends the session id of a new or regenerated session, as a cookie
+// when enabled and a response header when configured, and sets the session
+// cookie of the request.
+func (manager *Manager) setsid(w http.ResponseWriter, r *http.Request, sid string, force bool) {
+	cookie := manager.newcookie(sid)
+	if force || manager.config.EnableSetCookie {
+		http.SetCookie(w, cookie)
+	}
+	if name := manager.config.SessionHeader; name != "" {
+		v := sid
+		if scheme := manager.config.SessionHeaderScheme; scheme != "" {
+			v = scheme + " " + sid
+		}
+		w.Header().Set(name, v)
+	}
+	replacecookie(r, cookie)
+}
+
+func replacecookie(r *http.Request, cookie *http.Cookie) {
+	cookies := r.Cookies()
+	r.Header.Del("Cookie")
+	for _, c := range cookies {
+		if c.Name != cookie.Name {
+			r.AddCookie(c)
+		}
+	}
+	r.AddCookie(cookie)
+}
@@ -14,9 +14,48 @@ import (
 	"io"
 	mr "math/rand"
 	"strconv"
+	"sync"
 	"time"
 )
 
+// ClockProvider is implemented by a Provider using the time of a Manager
+// clock, set with Manager.SetClock, for session expiry.
+type ClockProvider interface {
+	SetClock(now func() time.Time)
+}
+
+// LoggerProvider is implemented by a Provider reporting errors it can not
+// return, e.g. saving a released session, to the Logger of a Manager, set with
+// Manager.SetLogger.
+type LoggerProvider interface {
+	SetLogger(l Logger)
+}
+
+// clock is the time source of a Manager or Provider for session expiry,
+// time.Now unless set with SetClock, e.g. to freeze time in tests.
+type clock struct {
+	mu  sync.RWMutex
+	now func() time.Time
+}
+
+// SetClock sets the function returning the current time.
+func (c *clock) SetClock(now func() time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// Now returns the current time of the clock.
+func (c *clock) Now() time.Time {
+	c.mu.RLock()
+	now := c.now
+	c.mu.RUnlock()
+	if now == nil {
+		return time.Now()
+	}
+	return now()
+}
+
 func init() {
 	gob.Register([]interface{}{})
 	gob.Register(map[int]interface{}{})
@@ -28,6 +67,22 @@ func init() {
 	gob.Register(map[int]int64{})
 }
 
+func keysof(values map[interface{}]interface{}) []interface{} {
+	ret := make([]interface{}, 0, len(values))
+	for k := range values {
+		ret = append(ret, k)
+	}
+	return ret
+}
+
+func copyvalues(values map[interface{}]interface{}) map[interface{}]interface{} {
+	ret := make(map[interface{}]interface{}, len(values))
+	for k, v := range values {
+		ret[k] = v
+	}
+	return ret
+}
+
 // RandomCreateBytes generate random []byte by specify chars.
 func RandomCreateBytes(n int, alphabets ...byte) []byte {
 	const alphanum = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
@@ -107,20 +162,26 @@ func decode(value []byte) ([]byte, error) {
 	return decoded[:b], nil
 }
 
-func encodeCookie(block cipher.Block, hashKey, name string, value map[interface{}]interface{}) (string, error) {
-	var err error
-	var b []byte
+func encodeCookie(block cipher.Block, hashKey, name string, value map[interface{}]interface{}, now time.Time) (string, error) {
 	// 1. EncodeGob.
-	if b, err = EncodeGob(value); err != nil {
+	b, err := EncodeGob(value)
+	if err != nil {
 		return "", err
 	}
+	return sealCookie(block, hashKey, name, b, now)
+}
+
+// sealCookie encrypts and signs the gob encoded, and possibly compressed,
+// cookie value b, sealed at now.
+func sealCookie(block cipher.Block, hashKey, name string, b []byte, now time.Time) (string, error) {
+	var err error
 	// 2. Encrypt (optional).
 	if b, err = encrypt(block, b); err != nil {
 		return "", err
 	}
 	b = encode(b)
 	// 3. Create MAC for "name|date|value". Extra pipe to be used later.
-	b = []byte(fmt.Sprintf("%s|%d|%s|", name, time.Now().UTC().Unix(), b))
+	b = []byte(fmt.Sprintf("%s|%d|%s|", name, now.UTC().Unix(), b))
 	h := hmac.New(sha1.New, []byte(hashKey))
 	h.Write(b)
 	sig := h.Sum(nil)
@@ -132,7 +193,7 @@ func encodeCookie(block cipher.Block, hashKey, name string, value map[interface{
 	return string(b), nil
 }
 
-func decodeCookie(block cipher.Block, hashKey, name, value string, gcmaxlifetime int64) (map[interface{}]interface{}, error) {
+func decodeCookie(block cipher.Block, hashKey, name, value string, gcmaxlifetime int64, now time.Time) (map[interface{}]interface{}, error) {
 	// 1. Decode from base64.
 	b, err := decode([]byte(value))
 	if err != nil {
@@ -156,7 +217,7 @@ func decodeCookie(block cipher.Block, hashKey, name, value string, gcmaxlifetime
 	if t1, err = strconv.ParseInt(string(parts[0]), 10, 64); err != nil {
 		return nil, errors.New("Decode: invalid timestamp")
 	}
-	t2 := time.Now().UTC().Unix()
+	t2 := now.UTC().Unix()
 	if t1 > t2 {
 		return nil, errors.New("Decode: timestamp is too new")
 	}
@@ -171,7 +232,11 @@ func decodeCookie(block cipher.Block, hashKey, name, value string, gcmaxlifetime
 	if b, err = decrypt(block, b); err != nil {
 		return nil, err
 	}
-	// 5. DecodeGob.
+	// 5. Decompress (optional).
+	if b, err = gunzipped(b); err != nil {
+		return nil, err
+	}
+	// 6. DecodeGob.
 	if dst, err := DecodeGob(b); err != nil {
 		return nil, err
 	} else {
@@ -0,0 +1,137 @@
+package session
+
+import (
+	"net/http"
+	"sync"
+)
+
+// BatchStore is implemented by a SessionStore able to apply the changes of a
+// request at once, e.g. in a single round trip to a remote provider. flushed
+// reports the session was flushed before the set and deleted values.
+type BatchStore interface {
+	SetBatch(set map[interface{}]interface{}, deleted []interface{}, flushed bool) error
+}
+
+// writebehindstore holds the Set, Delete, and Flush calls made on a
+// SessionStore during a request, applying them when the session is released.
+type writebehindstore struct {
+	SessionStore
+	failed  func(error)
+	lock    sync.RWMutex
+	set     map[interface{}]interface{}
+	deleted map[interface{}]struct{}
+	flushed bool
+}
+
+// writebehind holds the changes to st until it is released, reporting any
+// error applying them to failed.
+func writebehind(st SessionStore, failed func(error)) SessionStore {
+	if st == nil {
+		return nil
+	}
+	return &writebehindstore{
+		SessionStore: st,
+		failed:       failed,
+		set:          make(map[interface{}]interface{}),
+		deleted:      make(map[interface{}]struct{}),
+	}
+}
+
+func (st *writebehindstore) Set(key, value interface{}) error {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	delete(st.deleted, key)
+	st.set[key] = value
+	return nil
+}
+
+func (st *writebehindstore) Get(key interface{}) interface{} {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	if v, ok := st.set[key]; ok {
+		return v
+	}
+	if _, ok := st.deleted[key]; ok || st.flushed {
+		return nil
+	}
+	return st.SessionStore.Get(key)
+}
+
+func (st *writebehindstore) Delete(key interface{}) error {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	delete(st.set, key)
+	st.deleted[key] = struct{}{}
+	return nil
+}
+
+func (st *writebehindstore) Flush() error {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	st.set = make(map[interface{}]interface{})
+	st.deleted = make(map[interface{}]struct{})
+	st.flushed = true
+	return nil
+}
+
+// Export returns a copy of the session values with the held changes applied.
+func (st *writebehindstore) Export() map[interface{}]interface{} {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	ret := make(map[interface{}]interface{})
+	if !st.flushed {
+		ret = st.SessionStore.Export()
+	}
+	for key := range st.deleted {
+		delete(ret, key)
+	}
+	for key, value := range st.set {
+		ret[key] = value
+	}
+	return ret
+}
+
+// Keys returns the keys of the session values with the held changes applied.
+func (st *writebehindstore) Keys() []interface{} {
+	return keysof(st.Export())
+}
+
+// SessionRelease applies the held changes to the SessionStore, at once for a
+// BatchStore, and releases it. Any error applying the changes is reported.
+func (st *writebehindstore) SessionRelease(w http.ResponseWriter) {
+	st.lock.Lock()
+	if err := st.apply(); err != nil && st.failed != nil {
+		st.failed(err)
+	}
+	st.set = make(map[interface{}]interface{})
+	st.deleted = make(map[interface{}]struct{})
+	st.flushed = false
+	st.lock.Unlock()
+	st.SessionStore.SessionRelease(w)
+}
+
+func (st *writebehindstore) apply() error {
+	deleted := make([]interface{}, 0, len(st.deleted))
+	for key := range st.deleted {
+		deleted = append(deleted, key)
+	}
+	if b, ok := st.SessionStore.(BatchStore); ok {
+		return b.SetBatch(st.set, deleted, st.flushed)
+	}
+	var err error
+	keep := func(e error) {
+		if err == nil {
+			err = e
+		}
+	}
+	if st.flushed {
+		keep(st.SessionStore.Flush())
+	}
+	for _, key := range deleted {
+		keep(st.SessionStore.Delete(key))
+	}
+	for key, value := range st.set {
+		keep(st.SessionStore.Set(key, value))
+	}
+	return err
+}
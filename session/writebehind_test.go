@@ -0,0 +1,48 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteBehind(t *testing.T) {
+	m, err := NewManager("memorytest", `{"cookieName":"session","gclifetime":3600,"writeBehind":true}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	st, _ := m.SessionStart(w, r)
+	st.Set("kept", "value")
+	st.Set("deleted", "value")
+	st.Delete("deleted")
+
+	stored, _ := m.provider.SessionRead(st.SessionID())
+	if stored.Get("kept") != nil {
+		t.Error("session value was stored before the session was released")
+	}
+	if st.Get("kept") != "value" || st.Get("deleted") != nil {
+		t.Error("session did not return values held for release")
+	}
+	if v := st.Export(); len(v) != 1 || v["kept"] != "value" {
+		t.Errorf("expected exported values with held changes, got %v", v)
+	}
+	if k := st.Keys(); len(k) != 1 || k[0] != "kept" {
+		t.Errorf("expected keys with held changes, got %v", k)
+	}
+
+	st.SessionRelease(w)
+	if stored.Get("kept") != "value" || stored.Get("deleted") != nil {
+		t.Error("session values were not stored on release")
+	}
+
+	st.Flush()
+	if st.Get("kept") != nil {
+		t.Error("flushed session returned a stored value")
+	}
+	st.SessionRelease(w)
+	if stored.Get("kept") != nil {
+		t.Error("session flush was not stored on release")
+	}
+}
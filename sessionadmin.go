@@ -0,0 +1,96 @@
+package flotilla
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/thrisp/flotilla/engine"
+	"github.com/thrisp/flotilla/session"
+)
+
+func writejson(c Ctx, code int, v interface{}) {
+	rw, _ := c.Call("responsewriter")
+	w := rw.(ResponseWriter)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func adminsid(c Ctx) string {
+	params, _ := c.Call("params")
+	return params.(engine.Params).ByName("sid")
+}
+
+func adminerror(c Ctx, err error) {
+	code := 500
+	switch err {
+	case session.NoSession:
+		code = 404
+	case session.NotSupported:
+		code = 501
+	}
+	writejson(c, code, map[string]string{"error": err.Error()})
+}
+
+func sessionadminstats(a *App) Manage {
+	return func(c Ctx) {
+		ret := map[string]interface{}{"stats": a.SessionManager.Stats()}
+		if sids, err := a.SessionManager.SessionIDs(); err == nil {
+			ret["sessions"] = sids
+		}
+		writejson(c, 200, ret)
+	}
+}
+
+func sessionadmininspect(a *App) Manage {
+	return func(c Ctx) {
+		values, err := a.SessionManager.Inspect(adminsid(c))
+		if err != nil {
+			adminerror(c, err)
+			return
+		}
+		writejson(c, 200, values)
+	}
+}
+
+func sessionadmindestroy(a *App) Manage {
+	return func(c Ctx) {
+		sid := adminsid(c)
+		if !a.SessionManager.Exists(sid) {
+			adminerror(c, session.NoSession)
+			return
+		}
+		if err := a.SessionManager.Destroy(sid); err != nil {
+			adminerror(c, err)
+			return
+		}
+		Audit(c, AuditSessionDestroy, map[string]string{"session": sid})
+		writejson(c, 200, map[string]string{"destroyed": sid})
+	}
+}
+
+// EnableSessionAdmin mounts session support endpoints on a Blueprint at the
+// provided prefix, running any provided Manage functions (e.g. authentication)
+// first:
+//
+//	GET    prefix/      session statistics, and session ids if listable
+//	GET    prefix/:sid  the values of a session
+//	DELETE prefix/:sid  destroys a session
+//
+// Listing and inspecting sessions requires provider support, and responds 501
+// otherwise. The endpoints expose session contents, and must be protected: at
+// least one Manage function is required, and EnableSessionAdmin panics
+// without one.
+func (a *App) EnableSessionAdmin(prefix string, managers ...Manage) *Blueprint {
+	if len(managers) == 0 {
+		panic(fmt.Sprintf("[FLOTILLA] session admin at %s requires a Manage function protecting it", prefix))
+	}
+	b := a.NewBlueprint(prefix, managers...)
+	b.GET("/", sessionadminstats(a))
+	b.GET("/:sid", sessionadmininspect(a))
+	b.DELETE("/:sid", sessionadmindestroy(a))
+	if a.Configured {
+		b.Register(a)
+	}
+	return b
+}
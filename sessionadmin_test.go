@@ -0,0 +1,47 @@
+package flotilla
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEnableSessionAdmin(t *testing.T) {
+	a := New("testSessionAdmin", Mode("testing", true), memorySessions)
+	a.GET("/set", func(c Ctx) { c.Call("setsession", "key", "value") })
+	a.Configure()
+	a.EnableSessionAdmin("/admin/sessions", func(c Ctx) {
+		if CurrentRequest(c).Header.Get("X-Admin") != "yes" {
+			c.Call("status", 401)
+		}
+	})
+
+	client := a.TestClient()
+	client.Get("/set")
+	ck, _ := client.Cookie("session")
+
+	client.Get("/admin/sessions/").AssertStatus(t, 401)
+
+	client.Header.Set("X-Admin", "yes")
+	client.Get("/admin/sessions/").
+		AssertStatus(t, 200).
+		AssertJSON(t, "stats.active", 1).
+		AssertJSON(t, "stats.created", 1).
+		AssertJSON(t, "sessions.0", ck.Value)
+	client.Get("/admin/sessions/"+ck.Value).
+		AssertStatus(t, 200).
+		AssertJSON(t, "key", "value")
+
+	client.Do(httptest.NewRequest("DELETE", "/admin/sessions/"+ck.Value, nil)).AssertStatus(t, 200)
+	client.Get("/admin/sessions/"+ck.Value).AssertStatus(t, 404)
+	client.Get("/admin/sessions/").AssertJSON(t, "stats.destroyed", 1)
+}
+
+func TestEnableSessionAdminUnprotected(t *testing.T) {
+	a := New("testSessionAdminUnprotected", Mode("testing", true), memorySessions)
+	defer func() {
+		if recover() == nil {
+			t.Error("expected session admin endpoints without a Manage function to panic")
+		}
+	}()
+	a.EnableSessionAdmin("/admin/sessions")
+}
@@ -0,0 +1,126 @@
+package flotilla
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/thrisp/flotilla/session"
+	"github.com/thrisp/flotilla/xrr"
+)
+
+// SessionValues wraps a session.SessionStore with typed accessors, returning
+// false for missing values or values of another type.
+type SessionValues struct {
+	session.SessionStore
+}
+
+// CurrentSession returns the session of the current request with typed
+// accessors.
+func CurrentSession(c Ctx) SessionValues {
+	return SessionValues{Session(c)}
+}
+
+var InvalidSessionValue = xrr.NewXrror("session value %q is missing or is not a %s").Out
+
+// GetString returns the string value of key.
+func (s SessionValues) GetString(key string) (string, bool) {
+	v, ok := s.Get(key).(string)
+	return v, ok
+}
+
+// GetInt returns the integer value of key, accepting any integer type, or a
+// float64 without a fractional part, e.g. a value decoded from JSON.
+func (s SessionValues) GetInt(key string) (int, bool) {
+	switch v := s.Get(key).(type) {
+	case int:
+		return v, true
+	case int8:
+		return int(v), true
+	case int16:
+		return int(v), true
+	case int32:
+		return int(v), true
+	case int64:
+		return int(v), true
+	case uint:
+		return int(v), true
+	case uint8:
+		return int(v), true
+	case uint16:
+		return int(v), true
+	case uint32:
+		return int(v), true
+	case uint64:
+		return int(v), true
+	case float64:
+		if v == float64(int(v)) {
+			return int(v), true
+		}
+	}
+	return 0, false
+}
+
+// GetBool returns the boolean value of key.
+func (s SessionValues) GetBool(key string) (bool, bool) {
+	v, ok := s.Get(key).(bool)
+	return v, ok
+}
+
+// GetTime returns the time value of key, accepting a time.Time, unix seconds
+// as an int64, or an RFC 3339 string.
+func (s SessionValues) GetTime(key string) (time.Time, bool) {
+	switch v := s.Get(key).(type) {
+	case time.Time:
+		return v, true
+	case int64:
+		return time.Unix(v, 0), true
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t, true
+		}
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.Unix(i, 0), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// MustGetString returns the string value of key, panicking with an
+// InvalidSessionValue error if the value is missing or not a string.
+func (s SessionValues) MustGetString(key string) string {
+	v, ok := s.GetString(key)
+	if !ok {
+		panic(InvalidSessionValue(key, "string"))
+	}
+	return v
+}
+
+// MustGetInt returns the integer value of key, panicking with an
+// InvalidSessionValue error if the value is missing or not an integer.
+func (s SessionValues) MustGetInt(key string) int {
+	v, ok := s.GetInt(key)
+	if !ok {
+		panic(InvalidSessionValue(key, "int"))
+	}
+	return v
+}
+
+// MustGetBool returns the boolean value of key, panicking with an
+// InvalidSessionValue error if the value is missing or not a bool.
+func (s SessionValues) MustGetBool(key string) bool {
+	v, ok := s.GetBool(key)
+	if !ok {
+		panic(InvalidSessionValue(key, "bool"))
+	}
+	return v
+}
+
+// MustGetTime returns the time value of key, panicking with an
+// InvalidSessionValue error if the value is missing or not a time.
+func (s SessionValues) MustGetTime(key string) time.Time {
+	v, ok := s.GetTime(key)
+	if !ok {
+		panic(InvalidSessionValue(key, "time"))
+	}
+	return v
+}
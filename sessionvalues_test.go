@@ -0,0 +1,54 @@
+package flotilla
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionValues(t *testing.T) {
+	c, _ := NewTestCtx(nil, nil)
+	now := time.Unix(1500000000, 0)
+	for k, v := range map[string]interface{}{
+		"string": "value",
+		"int":    int64(42),
+		"float":  float64(7),
+		"bool":   true,
+		"time":   now,
+		"unix":   now.Unix(),
+		"rfc":    now.Format(time.RFC3339),
+	} {
+		c.Call("setsession", k, v)
+	}
+
+	s := CurrentSession(c)
+	if v, ok := s.GetString("string"); !ok || v != "value" {
+		t.Errorf("GetString returned %q %t", v, ok)
+	}
+	if _, ok := s.GetString("int"); ok {
+		t.Error("GetString returned an int64 value")
+	}
+	if v, ok := s.GetInt("int"); !ok || v != 42 {
+		t.Errorf("GetInt returned %d %t", v, ok)
+	}
+	if v, ok := s.GetInt("float"); !ok || v != 7 {
+		t.Errorf("GetInt returned %d %t for a float", v, ok)
+	}
+	if v, ok := s.GetBool("bool"); !ok || !v {
+		t.Errorf("GetBool returned %t %t", v, ok)
+	}
+	for _, key := range []string{"time", "unix", "rfc"} {
+		if v, ok := s.GetTime(key); !ok || !v.Equal(now) {
+			t.Errorf("GetTime returned %v %t for %s", v, ok, key)
+		}
+	}
+	if s.MustGetInt("int") != 42 || s.MustGetString("string") != "value" {
+		t.Error("MustGet did not return session values")
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("MustGetBool did not panic for a missing value")
+		}
+	}()
+	s.MustGetBool("missing")
+}
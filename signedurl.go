@@ -0,0 +1,131 @@
+package flotilla
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/thrisp/flotilla/xrr"
+)
+
+const (
+	signedExpires   = "expires"
+	signedSignature = "signature"
+)
+
+func urlsignature(secret string, u *url.URL) string {
+	q := u.Query()
+	q.Del(signedSignature)
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(u.Path))
+	h.Write([]byte("?"))
+	h.Write([]byte(q.Encode()))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// SignUrl adds an expiry and an HMAC-SHA256 signature over the url path, query
+// parameters, and expiry to the provided url string.
+func SignUrl(secret string, rawurl string, expires time.Time) (string, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set(signedExpires, strconv.FormatInt(expires.Unix(), 10))
+	u.RawQuery = q.Encode()
+	q.Set(signedSignature, urlsignature(secret, u))
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+var InvalidSignature = xrr.NewXrror("url %s has an invalid signature").Out
+
+var ExpiredSignature = xrr.NewXrror("signed url %s expired at %s").Out
+
+var NoSignedUrlKey = xrr.NewXrror("urls are not signed with the default secret key, set SECRET_KEYS").Out
+
+// VerifyUrl checks the signature and expiry of a url signed with SignUrl.
+func VerifyUrl(secret string, u *url.URL) error {
+	return verifyurl([]string{secret}, u, time.Now())
+}
+
+// verifyurl checks the expiry of a url signed with any of the secrets.
+func verifyurl(secrets []string, u *url.URL, now time.Time) error {
+	q := u.Query()
+	sig, err := hex.DecodeString(q.Get(signedSignature))
+	if err != nil || len(sig) == 0 {
+		return InvalidSignature(u.Path)
+	}
+	valid := false
+	for _, secret := range secrets {
+		expected, _ := hex.DecodeString(urlsignature(secret, u))
+		if hmac.Equal(sig, expected) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return InvalidSignature(u.Path)
+	}
+	exp, err := strconv.ParseInt(q.Get(signedExpires), 10, 64)
+	if err != nil {
+		return InvalidSignature(u.Path)
+	}
+	if expires := time.Unix(exp, 0); now.After(expires) {
+		return ExpiredSignature(u.Path, expires)
+	}
+	return nil
+}
+
+// signedurlkeys returns the keys signing urls, derived from the KeyRing of
+// the App, refusing a KeyRing of only the DefaultSecretKey.
+func signedurlkeys(c Ctx) (*KeyRing, error) {
+	ring := keyring(ctxlookup(c))
+	if ring.Default() {
+		return nil, NoSignedUrlKey()
+	}
+	return ring.Derive("flotilla signed url"), nil
+}
+
+func signedurlfor(c *ctx, route string, external bool, expires time.Duration, params []string) (string, error) {
+	ring, err := signedurlkeys(c)
+	if err != nil {
+		return "", err
+	}
+	_, key, ok := ring.Current()
+	if !ok {
+		return "", NoSignedUrlKey()
+	}
+	u, err := c.Call("urlfor", route, external, params)
+	if err != nil {
+		return "", err
+	}
+	return SignUrl(key, u.(string), CurrentTime(c).Add(expires))
+}
+
+// SignedUrlFor returns a url for the named route, signed with the current key
+// of the App KeyRing and valid for the provided duration. Apps with only the
+// DefaultSecretKey can not sign urls.
+func SignedUrlFor(c Ctx, route string, external bool, expires time.Duration, params ...string) (string, error) {
+	u, err := c.Call("signedurlfor", route, external, expires, params)
+	if err != nil {
+		return "", err
+	}
+	return u.(string), nil
+}
+
+// SignedUrl is a Manage function rejecting requests whose url lacks a valid,
+// unexpired signature by any key of the App KeyRing with a 403 status.
+func SignedUrl(c Ctx) {
+	ring, err := signedurlkeys(c)
+	if err == nil {
+		err = verifyurl(ring.Keys(), CurrentRequest(c).URL, CurrentTime(c))
+	}
+	if err != nil {
+		Audit(c, AuditPermissionDenied, map[string]string{"reason": err.Error()})
+		c.Call("abort", 403)
+	}
+}
@@ -0,0 +1,88 @@
+package flotilla
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestSignUrl(t *testing.T) {
+	signed, err := SignUrl("secret", "/download/file.txt?user=1", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("SignUrl error: %s", err)
+	}
+	u, _ := url.Parse(signed)
+	if err := VerifyUrl("secret", u); err != nil {
+		t.Errorf("signed url %s did not verify: %s", signed, err)
+	}
+	if err := VerifyUrl("other secret", u); err == nil {
+		t.Errorf("signed url %s verified with the wrong secret", signed)
+	}
+	tampered, _ := url.Parse(signed + "&user=2")
+	if err := VerifyUrl("secret", tampered); err == nil {
+		t.Errorf("tampered url %s verified", tampered)
+	}
+	expired, _ := SignUrl("secret", "/download/file.txt", time.Now().Add(-time.Hour))
+	u, _ = url.Parse(expired)
+	if err := VerifyUrl("secret", u); err == nil {
+		t.Errorf("expired url %s verified", expired)
+	}
+}
+
+func TestSignedUrl(t *testing.T) {
+	var generated string
+	var generror error
+
+	generate := func(c Ctx) {
+		generated, generror = SignedUrlFor(c, `\confirm\{p}\get`, false, time.Hour, "abc")
+	}
+	confirm := func(c Ctx) { c.Call("serveplain", 200, "confirmed") }
+
+	old := testApp(t, "testSignedUrlOld", EnvItem("SECRET_KEYS:old"))
+	old.GET("/generate", generate)
+	old.GET("/confirm/:token", SignedUrl, confirm)
+
+	a := testApp(t, "testSignedUrl", EnvItem("SECRET_KEYS:new,old"))
+	a.GET("/generate", generate)
+	a.GET("/confirm/:token", SignedUrl, confirm)
+	client := a.TestClient()
+
+	old.TestClient().Get("/generate")
+	if generror != nil {
+		t.Fatalf("signedurlfor error: %s", generror)
+	}
+	if res := client.Get(generated); res.Status != 200 {
+		t.Errorf("a url signed with a rotated key was refused, got %d", res.Status)
+	}
+
+	client.Get("/generate")
+	if generror != nil {
+		t.Fatalf("signedurlfor error: %s", generror)
+	}
+	if res := client.Get(generated); res.Status != 200 {
+		t.Errorf("a url signed with the current key was refused, got %d", res.Status)
+	}
+	if res := client.Get("/confirm/abc"); res.Status != 403 {
+		t.Errorf("an unsigned url was accepted, got %d", res.Status)
+	}
+	if res := client.Get(generated + "x"); res.Status != 403 {
+		t.Errorf("a tampered url was accepted, got %d", res.Status)
+	}
+	u, _ := url.Parse(generated)
+	if err := VerifyUrl(a.Env.Store["SECRET_KEY"].Value, u); err == nil {
+		t.Error("a signed url verified with the underived SECRET_KEY")
+	}
+
+	d := testApp(t, "testSignedUrlDefault")
+	d.GET("/generate", generate)
+	d.GET("/confirm/:token", SignedUrl, confirm)
+	dc := d.TestClient()
+	dc.Get("/generate")
+	if generror == nil {
+		t.Error("a url was signed with the default secret key")
+	}
+	forged, _ := SignUrl(NewKeyRing(DefaultSecretKey).Derive("flotilla signed url").Keys()[0], "/confirm/abc", time.Now().Add(time.Hour))
+	if res := dc.Get(forged); res.Status != 403 {
+		t.Errorf("a url signed with the default secret key was accepted, got %d", res.Status)
+	}
+}
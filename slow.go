@@ -0,0 +1,85 @@
+package flotilla
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// SlowRequests logs requests taking longer than Threshold, tagged with route
+// and request id. With Stack, a dump of all goroutine stacks is captured when
+// a request passes the threshold, showing where the request was spending time.
+type SlowRequests struct {
+	Threshold time.Duration
+	Stack     bool
+}
+
+func stacksample() string {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return string(buf[:n])
+		}
+		if len(buf) >= 8<<20 {
+			return string(buf)
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+func (s *SlowRequests) watch(c Ctx, l Logger) {
+	var (
+		mu    sync.Mutex
+		stack string
+		timer *time.Timer
+	)
+	if s.Stack {
+		timer = time.AfterFunc(s.Threshold, func() {
+			sample := stacksample()
+			mu.Lock()
+			stack = sample
+			mu.Unlock()
+		})
+	}
+	c.Call("pushfinal", func(c Ctx) {
+		if timer != nil {
+			timer.Stop()
+		}
+		start, err := c.Call("started")
+		if err != nil {
+			return
+		}
+		latency := time.Since(start.(time.Time))
+		if latency < s.Threshold {
+			return
+		}
+		rq := CurrentRequest(c)
+		fields := []interface{}{
+			"method", rq.Method,
+			"path", rq.URL.Path,
+			"request_id", RequestID(c),
+			"latency", latency,
+			"threshold", s.Threshold,
+		}
+		if rt := CurrentRoute(c); rt != nil {
+			fields = append(fields, "route", rt.Name())
+		}
+		mu.Lock()
+		if stack != "" {
+			fields = append(fields, "stack", stack)
+		}
+		mu.Unlock()
+		l.Warn("slow request", fields...)
+	})
+}
+
+// LogSlowRequests logs every request of the App taking longer than the
+// provided threshold, optionally with a goroutine stack sample captured once
+// the threshold passes.
+func LogSlowRequests(threshold time.Duration, stack bool) Configuration {
+	return func(a *App) error {
+		a.Env.SlowRequests = &SlowRequests{Threshold: threshold, Stack: stack}
+		return nil
+	}
+}
@@ -0,0 +1,29 @@
+package flotilla
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogSlowRequests(t *testing.T) {
+	var b logbuffer
+
+	a := New("testLogSlowRequests", WithLogger(NewLogger(&b, "info", false)), LogSlowRequests(20*time.Millisecond, true))
+	a.GET("/fast", func(c Ctx) {})
+	a.GET("/slow", func(c Ctx) { time.Sleep(40 * time.Millisecond) })
+	a.Configure()
+
+	ZeroExpectationPerformer(t, a, 200, "GET", "/fast").Perform()
+	if strings.Contains(b.String(), "slow request") {
+		t.Errorf("Fast request was logged as slow: %s", b.String())
+	}
+
+	ZeroExpectationPerformer(t, a, 200, "GET", "/slow").Perform()
+	out := b.String()
+	for _, expect := range []string{"slow request", "path=/slow", "request_id=", "stack="} {
+		if !strings.Contains(out, expect) {
+			t.Errorf("Slow request log %s does not contain %s", out, expect)
+		}
+	}
+}
@@ -1,9 +1,19 @@
 package flotilla
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"mime"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thrisp/flotilla/engine"
 )
 
 type (
@@ -24,6 +34,16 @@ type (
 	staticor struct {
 		app        *App
 		staticDirs []string
+		mu         sync.Mutex
+		etags      map[string]staticetag
+	}
+
+	// staticetag is the strong ETag of a static file of a modification time
+	// and size.
+	staticetag struct {
+		modified time.Time
+		size     int64
+		etag     string
 	}
 )
 
@@ -36,7 +56,7 @@ func StaticorInit(a *App) {
 
 // NewStaticor returns a new default flotilla Staticor.
 func NewStaticor(a *App) *staticor {
-	s := &staticor{app: a}
+	s := &staticor{app: a, etags: make(map[string]staticetag)}
 	s.StaticDirs(s.app.Env.Store["STATIC_DIRECTORIES"].List()...)
 	return s
 }
@@ -58,48 +78,284 @@ func (s *staticor) StaticDirs(dirs ...string) []string {
 	return s.staticDirs
 }
 
-func (s *staticor) appStaticFile(requested string, c Ctx) bool {
-	exists := false
+// staticopener opens a file found by the staticor, or a sibling of it with
+// the suffix, e.g. a precompressed ".gz" variant; key identifies the file for
+// caching its ETag.
+type staticopener func(suffix string) (f http.File, key string, err error)
+
+// appStaticFile opens the first file of the name in any static directory,
+// skipping files and directories beginning with a dot unless dotfiles.
+func (s *staticor) appStaticFile(requested string, dotfiles bool) (staticopener, bool) {
+	var found string
 	for _, dir := range s.app.StaticDirs() {
-		filepath.Walk(dir, func(path string, _ os.FileInfo, _ error) (err error) {
-			if filepath.Base(path) == requested {
-				f, _ := os.Open(path)
-				servestatic(c, f)
-				exists = true
+		filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info == nil || found != "" {
+				return nil
 			}
-			return err
+			if !dotfiles && path != dir && strings.HasPrefix(info.Name(), ".") {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !info.IsDir() && info.Name() == requested {
+				found = path
+			}
+			return nil
 		})
+		if found != "" {
+			return func(suffix string) (http.File, string, error) {
+				f, err := os.Open(found + suffix)
+				return f, found + suffix, err
+			}, true
+		}
+	}
+	return nil, false
+}
+
+// appAssetFile opens the first Asset of the name, unless the path of the
+// Asset has a part beginning with a dot and not dotfiles.
+func (s *staticor) appAssetFile(requested string, dotfiles bool) (staticopener, bool) {
+	for _, x := range s.app.Assets {
+		for _, filename := range x.AssetNames() {
+			if path.Base(filename) != requested {
+				continue
+			}
+			if !dotfiles && dotted(filename) {
+				break
+			}
+			x := x
+			open := func(suffix string) (http.File, string, error) {
+				f, err := x.HttpAsset(requested + suffix)
+				return f, "assets:" + filename + suffix, err
+			}
+			if f, _, err := open(""); err == nil {
+				f.Close()
+				return open, true
+			}
+			break
+		}
+	}
+	return nil, false
+}
+
+// appStaticPath opens the file of the requested path relative to a static
+// directory.
+func (s *staticor) appStaticPath(requested string) (staticopener, bool) {
+	for _, dir := range s.app.StaticDirs() {
+		found := filepath.Join(dir, filepath.FromSlash(requested))
+		if info, err := os.Stat(found); err == nil && info.Mode().IsRegular() {
+			return func(suffix string) (http.File, string, error) {
+				f, err := os.Open(found + suffix)
+				return f, found + suffix, err
+			}, true
+		}
+	}
+	return nil, false
+}
+
+// appAssetPath opens the Asset of the requested path.
+func (s *staticor) appAssetPath(requested string) (staticopener, bool) {
+	open := func(suffix string) (http.File, string, error) {
+		b, err := s.app.Assets.GetByte(requested + suffix)
+		if err != nil {
+			return nil, "", err
+		}
+		return NewAssetFile(requested+suffix, b), "assets:" + requested + suffix, nil
+	}
+	if _, err := s.app.Assets.GetByte(requested); err != nil {
+		return nil, false
 	}
-	return exists
+	return open, true
 }
 
-func (s *staticor) appAssetFile(requested string, c Ctx) bool {
-	exists := false
-	f, err := s.app.Assets.Get(requested)
-	if err == nil {
-		servestatic(c, f)
-		exists = true
+// find returns the opener of the requested file of the static directories
+// before the Assets, or the Assets first in Production mode, by its path, or
+// else by its name in any directory unless exact. Files and directories
+// beginning with a dot are found by name only if dotfiles.
+func (s *staticor) find(requested string, exact, dotfiles bool) (staticopener, bool) {
+	requested = strings.TrimPrefix(path.Clean("/"+requested), "/")
+	lookup := []func(string) (staticopener, bool){s.appStaticPath, s.appAssetPath}
+	if !exact {
+		name := filepath.Base(requested)
+		lookup = append(lookup,
+			func(string) (staticopener, bool) { return s.appStaticFile(name, dotfiles) },
+			func(string) (staticopener, bool) { return s.appAssetFile(name, dotfiles) },
+		)
 	}
-	return exists
+	if s.app.Env.assetsfirst() {
+		for i := 0; i < len(lookup); i += 2 {
+			lookup[i], lookup[i+1] = lookup[i+1], lookup[i]
+		}
+	}
+	for _, l := range lookup {
+		if open, ok := l(requested); ok {
+			return open, true
+		}
+	}
+	return nil, false
 }
 
+// Exists serves the requested file, a path relative to the static
+// directories or Assets, or else the file of the same name in any of their
+// directories not beginning with a dot, from the static directories before
+// the Assets, or the Assets first in Production mode, e.g. files embedded in
+// the binary with WithFS.
 func (s *staticor) Exists(c Ctx, requested string) bool {
-	exists := s.appStaticFile(requested, c)
-	if !exists {
-		exists = s.appAssetFile(requested, c)
+	return s.exists(c, requested, false)
+}
+
+func (s *staticor) exists(c Ctx, requested string, dotfiles bool) bool {
+	if open, ok := s.find(requested, false, dotfiles); ok {
+		return s.serve(c, requested, open) == nil
 	}
-	return exists
+	return false
+}
+
+// precompressed are the suffixes of precompressed variants of static files,
+// by content coding, in order of preference.
+var precompressed = []struct{ encoding, suffix string }{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// serve serves the requested file, or its precompressed variant of the
+// content coding negotiated from the request Accept-Encoding, with a strong
+// ETag and the Cache-Control of its directory. Conditional and range
+// requests are answered by servefile.
+func (s *staticor) serve(c Ctx, requested string, open staticopener) error {
+	rq := CurrentRequest(c)
+	rw, _ := c.Call("responsewriter")
+	h := rw.(ResponseWriter).Header()
+	f, key, err := open("")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if cc := s.app.Env.staticcachecontrol(rq.URL.Path); cc != "" {
+		h.Set("Cache-Control", cc)
+	}
+	if s.app.Env.Store["STATIC_PRECOMPRESSED"].Bool() {
+		if vf, vkey, encoding := s.variant(rq, h, open); vf != nil {
+			defer vf.Close()
+			ct := mime.TypeByExtension(filepath.Ext(requested))
+			if ct == "" {
+				ct = "application/octet-stream"
+			}
+			h.Set("Content-Type", ct)
+			h.Set("Content-Encoding", encoding)
+			f, key = vf, vkey
+		}
+	}
+	etag, err := s.etag(f, key)
+	if err != nil {
+		return err
+	}
+	h.Set("ETag", etag)
+	servestatic(c, f)
+	return nil
+}
+
+// variant opens the precompressed variant of the file preferred by the
+// request Accept-Encoding, adding a Vary header if any variant exists.
+func (s *staticor) variant(rq *http.Request, h http.Header, open staticopener) (http.File, string, string) {
+	files := make(map[string]http.File)
+	keys := make(map[string]string)
+	var offered []string
+	for _, p := range precompressed {
+		if f, key, err := open(p.suffix); err == nil {
+			files[p.encoding], keys[p.encoding] = f, key
+			offered = append(offered, p.encoding)
+		}
+	}
+	if len(offered) == 0 {
+		return nil, "", ""
+	}
+	h.Add("Vary", "Accept-Encoding")
+	encoding := negotiateencoding(rq.Header.Get("Accept-Encoding"), offered)
+	for enc, f := range files {
+		if enc != encoding {
+			f.Close()
+		}
+	}
+	if encoding == "" {
+		return nil, "", ""
+	}
+	return files[encoding], keys[encoding], encoding
+}
+
+// etag returns the strong ETag of the content of the file, a hash cached
+// until the modification time or size of the file changes.
+func (s *staticor) etag(f http.File, key string) (string, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	e, ok := s.etags[key]
+	s.mu.Unlock()
+	if ok && e.modified.Equal(fi.ModTime()) && e.size == fi.Size() {
+		return e.etag, nil
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	sum := hash.Sum(nil)
+	e = staticetag{fi.ModTime(), fi.Size(), `"` + hex.EncodeToString(sum[:8]) + `"`}
+	s.mu.Lock()
+	s.etags[key] = e
+	s.mu.Unlock()
+	return e.etag, nil
+}
+
+// StaticCacheControl sets the Cache-Control policy of static files requested
+// in the directory, a url path, e.g. "/static/css", over the policy of any
+// parent directory and the STATIC_CACHECONTROL default.
+func (env *Env) StaticCacheControl(dir, policy string) {
+	if env.staticcache == nil {
+		env.staticcache = make(map[string]string)
+	}
+	env.staticcache[strings.TrimSuffix(dir, "/")] = policy
+}
+
+// staticcachecontrol returns the Cache-Control policy of the nearest
+// directory of the requested path.
+func (env *Env) staticcachecontrol(path string) string {
+	for dir := filepath.ToSlash(filepath.Dir(path)); ; dir = filepath.ToSlash(filepath.Dir(dir)) {
+		if policy, ok := env.staticcache[strings.TrimSuffix(dir, "/")]; ok {
+			return policy
+		}
+		if dir == "/" || dir == "." {
+			break
+		}
+	}
+	return env.Store["STATIC_CACHECONTROL"].Value
 }
 
 func (s *staticor) Manage(c Ctx) {
-	if !s.Exists(c, requestedfile(c)) {
+	s.manage(c, false)
+}
+
+func (s *staticor) manage(c Ctx, dotfiles bool) {
+	if !s.exists(c, requestedfile(c), dotfiles) {
 		abortstatic(c)
 	} else {
 		c.Call("headernow")
 	}
 }
 
+// requestedfile returns the path of the requested file relative to its
+// STATIC mount, or the name of the requested file.
 func requestedfile(c Ctx) string {
+	if ps, err := c.Call("params"); err == nil {
+		if fp := strings.TrimPrefix(ps.(engine.Params).ByName("filepath"), "/"); fp != "" {
+			return fp
+		}
+	}
 	rq, _ := c.Call("request")
 	return filepath.Base(rq.(*http.Request).URL.Path)
 }
@@ -1,7 +1,9 @@
 package flotilla
 
 import (
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -51,3 +53,52 @@ func TestStaticor(t *testing.T) {
 		t.Errorf(`Test external staticor did not return "from external staticor", returned %s`, b)
 	}
 }
+
+func TestStaticCaching(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "site.css"), []byte("body{}"), 0644)
+	os.WriteFile(filepath.Join(dir, "site.css.gz"), []byte("gzipped"), 0644)
+	os.WriteFile(filepath.Join(dir, "site.css.br"), []byte("brotli"), 0644)
+	a := New("testStaticCaching", Mode("testing", true))
+	a.StaticDirs(dir)
+	a.StaticCacheControl("/static", "public, max-age=60")
+	a.StaticCacheControl("/static/nocache", "no-cache")
+	a.Configure()
+
+	client := a.TestClient()
+
+	res := client.Get("/static/site.css")
+	etag := res.Header.Get("ETag")
+	if res.Status != 200 || string(res.Body) != "body{}" || etag == "" || strings.HasPrefix(etag, "W/") {
+		t.Errorf("expected the static file with a strong ETag, got %d %q %q", res.Status, res.Body, etag)
+	}
+	if cc := res.Header.Get("Cache-Control"); cc != "public, max-age=60" {
+		t.Errorf("expected the Cache-Control of the static directory, got %q", cc)
+	}
+	if cc := client.Get("/static/nocache/site.css").Header.Get("Cache-Control"); cc != "no-cache" {
+		t.Errorf("expected the Cache-Control of the nested directory, got %q", cc)
+	}
+
+	if res := client.Get("/static/site.css", "If-None-Match", etag); res.Status != 304 {
+		t.Errorf("expected 304 for a matching If-None-Match, got %d", res.Status)
+	}
+
+	for accept, expected := range map[string][2]string{
+		"gzip":            {"gzip", "gzipped"},
+		"gzip, br":        {"br", "brotli"},
+		"br;q=0.5, gzip":  {"gzip", "gzipped"},
+		"identity, *;q=0": {"", "body{}"},
+	} {
+		res := client.Get("/static/site.css", "Accept-Encoding", accept)
+		h := res.Header
+		if h.Get("Content-Encoding") != expected[0] || string(res.Body) != expected[1] {
+			t.Errorf("Accept-Encoding %q: expected %q encoded %q, got %q encoded %q", accept, expected[1], expected[0], res.Body, h.Get("Content-Encoding"))
+		}
+		if !strings.HasPrefix(h.Get("Content-Type"), "text/css") || h.Get("Vary") != "Accept-Encoding" {
+			t.Errorf("Accept-Encoding %q: expected a text/css response varying by Accept-Encoding, got %q %q", accept, h.Get("Content-Type"), h.Get("Vary"))
+		}
+		if expected[0] != "" && h.Get("ETag") == etag {
+			t.Errorf("Accept-Encoding %q: expected an ETag of the precompressed variant", accept)
+		}
+	}
+}
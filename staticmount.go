@@ -0,0 +1,198 @@
+package flotilla
+
+import (
+	"html/template"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/thrisp/flotilla/engine"
+)
+
+// StaticMount configures how a STATIC mount serves the requests of its
+// path. By default a mount serves the index.html of a requested directory,
+// does not list directories, and denies files and directories beginning
+// with a dot.
+type StaticMount struct {
+	// Index are the names of the files served for a requested directory, in
+	// order.
+	Index []string
+
+	// Listing lists a requested directory without an index file.
+	Listing bool
+
+	// ListingTemplate is the template rendering directory listings, with the
+	// Path and Entries of the directory, instead of the default listing page.
+	ListingTemplate string
+
+	// Dotfiles serves files and directories beginning with a dot.
+	Dotfiles bool
+
+	app *App
+}
+
+// StaticOption configures a StaticMount.
+type StaticOption func(*StaticMount)
+
+// IndexFiles sets the names of the files served for a requested directory,
+// or none.
+func IndexFiles(names ...string) StaticOption {
+	return func(m *StaticMount) {
+		m.Index = names
+	}
+}
+
+// DirectoryListing sets whether directories without an index file are listed,
+// with the named template if any, or the default listing page.
+func DirectoryListing(on bool, template ...string) StaticOption {
+	return func(m *StaticMount) {
+		m.Listing = on
+		if len(template) > 0 {
+			m.ListingTemplate = template[0]
+		}
+	}
+}
+
+// Dotfiles sets whether files and directories beginning with a dot are
+// served and listed.
+func Dotfiles(on bool) StaticOption {
+	return func(m *StaticMount) {
+		m.Dotfiles = on
+	}
+}
+
+func newStaticMount(a *App, opts ...StaticOption) *StaticMount {
+	m := &StaticMount{Index: []string{"index.html"}, app: a}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// StaticEntry is a file or directory of a directory listing.
+type StaticEntry struct {
+	Name string
+	Dir  bool
+}
+
+func dotted(requested string) bool {
+	for _, part := range strings.Split(requested, "/") {
+		if strings.HasPrefix(part, ".") && part != "." && part != ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// Manage is a flotilla.Manage function of the mount, serving requested
+// directories by its options and files with the App Staticor. Directories
+// are left to a Staticor other than the default.
+func (m *StaticMount) Manage(c Ctx) {
+	ps, _ := c.Call("params")
+	fp := ps.(engine.Params).ByName("filepath")
+	if !m.Dotfiles && dotted(fp) {
+		abortstatic(c)
+		return
+	}
+	s, ok := m.app.Staticor.(*staticor)
+	switch {
+	case !ok:
+		m.app.Staticor.Manage(c)
+	case fp == "" || strings.HasSuffix(fp, "/"):
+		m.directory(c, s, strings.Trim(fp, "/"))
+	default:
+		s.manage(c, m.Dotfiles)
+	}
+}
+
+func (m *StaticMount) directory(c Ctx, s *staticor, dir string) {
+	for _, index := range m.Index {
+		requested := path.Join(dir, index)
+		if open, ok := s.find(requested, true, m.Dotfiles); ok && s.serve(c, requested, open) == nil {
+			c.Call("headernow")
+			return
+		}
+	}
+	if m.Listing {
+		if entries, ok := m.entries(dir); ok {
+			m.list(c, CurrentRequest(c).URL.Path, entries)
+			return
+		}
+	}
+	abortstatic(c)
+}
+
+// entries returns the entries of the directory of the static directories and
+// Assets, and whether it exists.
+func (m *StaticMount) entries(dir string) ([]StaticEntry, bool) {
+	dir = strings.TrimPrefix(path.Clean("/"+dir), "/")
+	found := false
+	seen := make(map[string]StaticEntry)
+	add := func(name string, isdir bool) {
+		if m.Dotfiles || !strings.HasPrefix(name, ".") {
+			seen[name] = StaticEntry{name, isdir}
+		}
+	}
+	for _, sd := range m.app.StaticDirs() {
+		if des, err := os.ReadDir(filepath.Join(sd, filepath.FromSlash(dir))); err == nil {
+			found = true
+			for _, de := range des {
+				add(de.Name(), de.IsDir())
+			}
+		}
+	}
+	for _, fs := range m.app.Assets {
+		ad := dir
+		if ad == "" {
+			ad = "."
+		}
+		if children, err := fs.AssetDir(ad); err == nil {
+			found = true
+			for _, child := range children {
+				_, err := fs.AssetDir(path.Join(dir, child))
+				add(child, err == nil)
+			}
+		}
+	}
+	entries := make([]StaticEntry, 0, len(seen))
+	for _, e := range seen {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Dir != entries[j].Dir {
+			return entries[i].Dir
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, found
+}
+
+var staticlisting = template.Must(template.New("listing").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{ .Path }}</title></head>
+<body>
+<h1>{{ .Path }}</h1>
+<ul>
+{{- range .Entries }}
+<li><a href="{{ .Name }}{{ if .Dir }}/{{ end }}">{{ .Name }}{{ if .Dir }}/{{ end }}</a></li>
+{{- end }}
+</ul>
+</body>
+</html>
+`))
+
+// list renders the listing of the directory entries, with the
+// ListingTemplate if any.
+func (m *StaticMount) list(c Ctx, urlpath string, entries []StaticEntry) {
+	data := map[string]interface{}{"Path": urlpath, "Entries": entries}
+	if m.ListingTemplate != "" {
+		c.Call("rendertemplate", m.ListingTemplate, data)
+		return
+	}
+	rw, _ := c.Call("responsewriter")
+	w := rw.(ResponseWriter)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	staticlisting.Execute(w, data)
+}
@@ -0,0 +1,65 @@
+package flotilla
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStaticMount(t *testing.T) {
+	dir, tpls := t.TempDir(), t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "docs"), 0755)
+	os.MkdirAll(filepath.Join(dir, "pub", "sub"), 0755)
+	os.WriteFile(filepath.Join(dir, "docs", "index.html"), []byte("docs index"), 0644)
+	os.WriteFile(filepath.Join(dir, "pub", "a.txt"), []byte("a"), 0644)
+	os.WriteFile(filepath.Join(dir, "pub", ".secret"), []byte("secret"), 0644)
+	os.WriteFile(filepath.Join(dir, ".env"), []byte("env"), 0644)
+	os.MkdirAll(filepath.Join(dir, ".git"), 0755)
+	os.WriteFile(filepath.Join(dir, ".git", "config"), []byte("config"), 0644)
+	os.WriteFile(filepath.Join(tpls, "listing.html"), []byte(`{{ range .Entries }}{{ .Name }};{{ end }}`), 0644)
+
+	a := New(
+		"testStaticMount",
+		Mode("testing", true),
+		TemplateDriver("html"),
+		EnvItem("TEMPLATE_DIRECTORIES:"+tpls),
+	)
+	a.StaticDirs(dir)
+	a.STATIC("/files/*filepath", DirectoryListing(true))
+	a.STATIC("/dots/*filepath", Dotfiles(true), IndexFiles())
+	a.STATIC("/listed/*filepath", DirectoryListing(true, "listing.html"))
+	client := a.TestClient()
+
+	for path, expected := range map[string]int{
+		"/static/docs/":       200,
+		"/static/pub/":        404,
+		"/static/pub/a.txt":   200,
+		"/static/.env":        404,
+		"/static/pub/.secret": 404,
+		"/dots/.env":          200,
+		"/static/.git/config": 404,
+		"/static/config":      404,
+		"/dots/config":        200,
+		"/dots/docs/":         404,
+		"/files/nodir/":       404,
+	} {
+		if res := client.Get(path); res.Status != expected {
+			t.Errorf("%s: expected %d, got %d", path, expected, res.Status)
+		}
+	}
+
+	if body := string(client.Get("/static/docs/").Body); body != "docs index" {
+		t.Errorf("expected the index file of the directory, got %q", body)
+	}
+
+	res := client.Get("/files/pub/")
+	body := string(res.Body)
+	if res.Status != 200 || !strings.Contains(body, `href="sub/"`) || !strings.Contains(body, `href="a.txt"`) || strings.Contains(body, ".secret") {
+		t.Errorf("expected a directory listing without dotfiles, got %d %q", res.Status, body)
+	}
+
+	if body := string(client.Get("/listed/pub/").Body); body != "sub;a.txt;" {
+		t.Errorf("expected a directory listing of the listing template, got %q", body)
+	}
+}
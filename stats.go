@@ -0,0 +1,161 @@
+package flotilla
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// RouteStat is a snapshot of the rolling latency and error rate of a Route.
+// Count and Errors are totals; ErrorRate and the latency percentiles cover
+// the most recent requests within the RouteStats window.
+type RouteStat struct {
+	Route     string        `json:"route"`
+	Count     int64         `json:"count"`
+	Errors    int64         `json:"errors"`
+	ErrorRate float64       `json:"error_rate"`
+	P50       time.Duration `json:"p50"`
+	P90       time.Duration `json:"p90"`
+	P99       time.Duration `json:"p99"`
+	Max       time.Duration `json:"max"`
+}
+
+type routestat struct {
+	count     int64
+	errors    int64
+	latencies []time.Duration
+	failed    []bool
+	next      int
+}
+
+func (r *routestat) add(window int, latency time.Duration, failed bool) {
+	r.count++
+	if failed {
+		r.errors++
+	}
+	if len(r.latencies) < window {
+		r.latencies = append(r.latencies, latency)
+		r.failed = append(r.failed, failed)
+		return
+	}
+	r.latencies[r.next] = latency
+	r.failed[r.next] = failed
+	r.next = (r.next + 1) % window
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(p*float64(len(sorted))+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
+
+func (r *routestat) stat(name string) RouteStat {
+	s := RouteStat{Route: name, Count: r.count, Errors: r.errors}
+	if len(r.latencies) == 0 {
+		return s
+	}
+	sorted := make([]time.Duration, len(r.latencies))
+	copy(sorted, r.latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var failed int
+	for _, f := range r.failed {
+		if f {
+			failed++
+		}
+	}
+	s.ErrorRate = float64(failed) / float64(len(r.failed))
+	s.P50 = percentile(sorted, 0.50)
+	s.P90 = percentile(sorted, 0.90)
+	s.P99 = percentile(sorted, 0.99)
+	s.Max = sorted[len(sorted)-1]
+	return s
+}
+
+// RouteStats tracks rolling per-route latency percentiles and error rates
+// in-process, over a window of the most recent requests to each route. Routes
+// are recorded by method and path, e.g. "GET /user/:name".
+type RouteStats struct {
+	mu     sync.Mutex
+	window int
+	routes map[string]*routestat
+}
+
+// NewRouteStats returns a RouteStats keeping the provided number of recent
+// requests per route, defaulting to 1000.
+func NewRouteStats(window int) *RouteStats {
+	if window <= 0 {
+		window = 1000
+	}
+	return &RouteStats{window: window, routes: make(map[string]*routestat)}
+}
+
+// Record adds a request to the named route with the provided latency and
+// status, counting statuses of 500 and above as errors.
+func (s *RouteStats) Record(route string, latency time.Duration, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.routes[route]
+	if !ok {
+		r = &routestat{}
+		s.routes[route] = r
+	}
+	r.add(s.window, latency, status >= 500)
+}
+
+// Route returns a snapshot of the named route, if any requests were recorded.
+func (s *RouteStats) Route(route string) (RouteStat, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.routes[route]; ok {
+		return r.stat(route), true
+	}
+	return RouteStat{}, false
+}
+
+// Snapshot returns a snapshot of every recorded route, sorted by route name.
+func (s *RouteStats) Snapshot() []RouteStat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ret := make([]RouteStat, 0, len(s.routes))
+	for name, r := range s.routes {
+		ret = append(ret, r.stat(name))
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Route < ret[j].Route })
+	return ret
+}
+
+func (s *RouteStats) manage(c Ctx) {
+	rt := CurrentRoute(c)
+	if rt == nil {
+		return
+	}
+	rw, _ := c.Call("responsewriter")
+	status := rw.(ResponseWriter).Status()
+	if len(Panics(c)) > 0 && status < 500 {
+		status = 500
+	}
+	var latency time.Duration
+	if start, err := c.Call("started"); err == nil {
+		latency = time.Since(start.(time.Time))
+	}
+	s.Record(rt.Method+" "+rt.Path, latency, status)
+}
+
+// TrackRoutes enables per-route latency and error rate tracking for every
+// route of the App, keeping the provided number of recent requests per route.
+// Statistics are available from Env.RouteStats and the EnableDebug stats
+// endpoint.
+func TrackRoutes(window int) Configuration {
+	return func(a *App) error {
+		a.Env.RouteStats = NewRouteStats(window)
+		return nil
+	}
+}
@@ -0,0 +1,64 @@
+package flotilla
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRouteStatsWindow(t *testing.T) {
+	s := NewRouteStats(4)
+	for i := 1; i <= 6; i++ {
+		status := 200
+		if i <= 2 {
+			status = 500
+		}
+		s.Record("route", time.Duration(i)*time.Millisecond, status)
+	}
+	st, ok := s.Route("route")
+	if !ok {
+		t.Fatal("RouteStats did not record route")
+	}
+	if st.Count != 6 || st.Errors != 2 {
+		t.Errorf("Expected 6 requests and 2 errors, but were %d and %d", st.Count, st.Errors)
+	}
+	if st.ErrorRate != 0 {
+		t.Errorf("Errors outside the window were included in the error rate: %f", st.ErrorRate)
+	}
+	if st.Max != 6*time.Millisecond || st.P50 != 4*time.Millisecond {
+		t.Errorf("Unexpected latency percentiles: %+v", st)
+	}
+}
+
+func TestTrackRoutes(t *testing.T) {
+	a := New("testTrackRoutes", TrackRoutes(10))
+	a.GET("/ok", func(c Ctx) {})
+	a.GET("/fail", func(c Ctx) { c.Call("status", 500) })
+	a.EnableDebug("/debug")
+	a.Configure()
+
+	ZeroExpectationPerformer(t, a, 200, "GET", "/ok").Perform()
+	ZeroExpectationPerformer(t, a, 500, "GET", "/fail").Perform()
+	ZeroExpectationPerformer(t, a, 500, "GET", "/fail").Perform()
+
+	rw := httptest.NewRecorder()
+	rq, _ := http.NewRequest("GET", "/debug/stats", nil)
+	a.ServeHTTP(rw, rq)
+
+	var stats []RouteStat
+	if err := json.Unmarshal(rw.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("Stats endpoint returned invalid json: %s", err)
+	}
+	found := make(map[string]RouteStat)
+	for _, st := range stats {
+		found[st.Route] = st
+	}
+	if st := found["GET /fail"]; st.Count != 2 || st.ErrorRate != 1 {
+		t.Errorf("Unexpected stats for failing route: %+v", st)
+	}
+	if st := found["GET /ok"]; st.Count != 1 || st.Errors != 0 {
+		t.Errorf("Unexpected stats for ok route: %+v", st)
+	}
+}
@@ -47,11 +47,45 @@ type (
 
 func defaultStore() Store {
 	s := make(Store)
-	s.addDefault("upload", "size", "10000000")             // bytes
-	s.addDefault("secret", "key", "Flotilla;Secret;Key;1") // weak default value
+	s.addDefault("upload", "size", "10000000")      // bytes
+	s.addDefault("upload", "memory", "1048576")     // bytes, held in memory across uploads
+	s.addDefault("upload", "types", "")             // allowed content types, e.g. image/*,text/plain
+	s.addDefault("upload", "directory", "")         // temporary files, defaulting to os.TempDir
+	s.addDefault("body", "buffer", "1048576")       // bytes, limit of buffered request bodies
+	s.addDefault("secret", "key", DefaultSecretKey) // weak default value
+	s.addDefault("cookie", "lifetime", "2629743")   // seconds, secure cookie value expiry
+	s.addDefault("jwt", "lifetime", "3600")         // seconds
+	s.addDefault("json", "pretty", "")              // true or false, by default in Development mode
+	s.addDefault("json", "prefix", "")
+	s.addDefault("json", "callback", "callback")
+	s.addDefault("negotiate", "formats", "json,xml,html,text")
+	s.addDefault("response", "maxsize", "0")           // bytes, unlimited by default
+	s.addDefault("response", "writetimeout", "0")      // seconds, none by default
+	s.addDefault("websocket", "maxmessage", "1048576") // bytes, 0 caps messages at 32 MiB
+	s.addDefault("websocket", "origins", "")
 	s.addDefault("session", "cookiename", "session")
 	s.addDefault("session", "lifetime", "2629743")
+	s.addDefault("session", "idlelifetime", "0")
+	s.addDefault("session", "absolutelifetime", "0")
+	s.addDefault("session", "provider", "cookie")
+	s.addDefault("session", "samesite", "lax")
+	s.addDefault("session", "authenticatedkey", UserSessionKey)
+	s.addDefault("session", "maxpayloadbytes", "0") // bytes
+	s.addDefault("session", "enablecompression", "false")
+	s.addDefault("method", "override", "")    // overridable methods of POST requests, e.g. PUT,PATCH,DELETE
+	s.addDefault("routes", "strict", "false") // true fails Configure, and Run, on route conflicts
+	s.addDefault("log", "level", "info")
+	s.addDefault("log", "format", "text")
+	s.addDefault("password", "algorithm", "bcrypt")
+	s.addDefault("password", "bcryptcost", "10")
+	s.addDefault("markdown", "extensions", "gfm")   // goldmark extensions, e.g. gfm,footnote,typographer
+	s.addDefault("markdown", "policy", "ugc")       // bluemonday sanitizer policy, ugc or strict
+	s.addDefault("static", "cachecontrol", "")      // Cache-Control of static files, e.g. public, max-age=86400
+	s.addDefault("static", "precompressed", "true") // serve .br and .gz variants of static files when accepted
+	s.addDefault("static", "url", "/static")        // url of static files resolved by the asset template function
 	s.add("static", "directories", workingStatic)
+	s.addDefault("template", "driver", "djinn")        // registered Templator driver, e.g. djinn or html
+	s.addDefault("template", "reloadinterval", "1000") // milliseconds between checks for changed templates in Development mode
 	s.add("template", "directories", workingTemplates)
 	return s
 }
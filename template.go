@@ -4,8 +4,8 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/thrisp/djinn"
 	"github.com/thrisp/flotilla/xrr"
@@ -21,7 +21,17 @@ type (
 
 	templator struct {
 		*djinn.Djinn
+		env          *Env
+		loader       *Loader
 		TemplateDirs []string
+		cache        TemplateCache
+	}
+
+	// cachedloader is the djinn Loader of a templator, caching template
+	// sources in Production mode.
+	cachedloader struct {
+		*Loader
+		t *templator
 	}
 
 	Loader struct {
@@ -30,10 +40,27 @@ type (
 	}
 )
 
-// TemplatorInit intializes the default Templator if none is listed with the Env.
+// TemplatorInit intializes the Templator of the TEMPLATE_DRIVER, or the
+// default Templator, if none is listed with the Env, reloading changed
+// templates in Development mode, and precompiles the templates of a Templator
+// able to in Production mode.
 func TemplatorInit(a *App) {
 	if a.Env.Templator == nil {
-		a.Env.Templator = NewTemplator(a.Env)
+		driver := "djinn"
+		if item, ok := a.Env.Store["TEMPLATE_DRIVER"]; ok && item.Value != "" {
+			driver = item.Value
+		}
+		t, err := OpenTemplator(driver, a.Env)
+		if err != nil {
+			a.Env.Log().Warn("templator error", "driver", driver, "error", err)
+			driver, t = "djinn", NewTemplator(a.Env)
+		}
+		a.Env.Templator = newReloadTemplator(a.Env, driver, t)
+	}
+	if p, ok := a.Env.Templator.(interface{ Precompile() error }); ok && a.Env.Mode.Production {
+		if err := p.Precompile(); err != nil {
+			a.Env.Log().Warn("template precompile error", "error", err)
+		}
 	}
 }
 
@@ -48,14 +75,45 @@ func (env *Env) TemplateDirs(dirs ...string) []string {
 	return storedirs
 }
 
-// NewTemplator returns a new default templator.
+// NewTemplator returns a new default templator, rendering djinn templates of
+// the template directories and Assets of the Env, caching template sources in
+// Production mode.
 func NewTemplator(env *Env) *templator {
-	j := &templator{Djinn: djinn.Empty()}
+	j := &templator{Djinn: djinn.Empty(), env: env, loader: NewLoader(env)}
 	j.UpdateTemplateDirs(env.Store["TEMPLATE_DIRECTORIES"].List()...)
-	j.SetConf(djinn.Loaders(NewLoader(env)), djinn.TemplateFunctions(env.tplfunctions))
+	j.SetConf(djinn.Loaders(&cachedloader{Loader: j.loader, t: j}), djinn.TemplateFunctions(env.tplfunctions))
 	return j
 }
 
+// Load returns the cached source of the named template, or the source loaded
+// by the Loader, cached in Production mode.
+func (l *cachedloader) Load(name string) (string, error) {
+	if src, ok := l.t.cache.Get(name); ok {
+		return src.(string), nil
+	}
+	src, err := l.Loader.Load(name)
+	if err != nil {
+		return "", err
+	}
+	if l.t.env.Mode.Production {
+		l.t.cache.Add(name, src)
+	}
+	return src, nil
+}
+
+// Precompile loads and caches the source of every template of the template
+// directories and Assets.
+func (t *templator) Precompile() error {
+	for _, name := range t.loader.names() {
+		src, err := t.loader.Load(name)
+		if err != nil {
+			return err
+		}
+		t.cache.Add(name, src)
+	}
+	return nil
+}
+
 // ListTemplateDirs lists template directories attached to the templator.
 func (t *templator) ListTemplateDirs() []string {
 	return t.TemplateDirs
@@ -123,25 +181,57 @@ func (fl *Loader) ListTemplates() interface{} {
 	return ret
 }
 
+// names returns the names of the templates listed by the Loader, relative to
+// their template directory.
+func (fl *Loader) names() []string {
+	var ret []string
+	for _, f := range fl.ListTemplates().([]string) {
+		name := f
+		for _, dir := range fl.env.TemplateDirs() {
+			if rel, err := filepath.Rel(dir, f); err == nil && !strings.HasPrefix(rel, "..") {
+				name = rel
+			}
+		}
+		ret = append(ret, name)
+	}
+	return ret
+}
+
 var TemplateDoesNotExist = xrr.NewXrror("Template %s does not exist.").Out
 
-// Load a template by string name from the flotilla Loader.
+// Load a template by string name from the flotilla Loader, from the template
+// directories before the Assets, or the Assets first in Production mode, e.g.
+// templates embedded in the binary with WithFS.
 func (fl *Loader) Load(name string) (string, error) {
-	for _, p := range fl.env.TemplateDirs() {
-		f := filepath.Join(p, name)
-		if fl.ValidFileExtension(filepath.Ext(f)) {
-			// existing template dirs
-			if _, err := os.Stat(f); err == nil {
-				file, err := os.Open(f)
-				r, err := ioutil.ReadAll(file)
-				return string(r), err
-			}
-			// binary assets
-			if r, err := fl.env.Assets.Get(name); err == nil {
-				r, err := ioutil.ReadAll(r)
-				return string(r), err
-			}
+	if !fl.ValidFileExtension(filepath.Ext(name)) {
+		return "", TemplateDoesNotExist(name)
+	}
+	sources := []func(string) ([]byte, error){fl.loadfile, fl.loadasset}
+	if fl.env.assetsfirst() {
+		sources[0], sources[1] = sources[1], sources[0]
+	}
+	for _, load := range sources {
+		if b, err := load(name); err == nil {
+			return string(b), nil
 		}
 	}
 	return "", TemplateDoesNotExist(name)
 }
+
+func (fl *Loader) loadfile(name string) ([]byte, error) {
+	for _, p := range fl.env.TemplateDirs() {
+		if b, err := ioutil.ReadFile(filepath.Join(p, name)); err == nil {
+			return b, nil
+		}
+	}
+	return nil, TemplateDoesNotExist(name)
+}
+
+func (fl *Loader) loadasset(name string) ([]byte, error) {
+	f, err := fl.env.Assets.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ioutil.ReadAll(f)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"html/template"
 	"reflect"
+	"time"
 )
 
 type (
@@ -26,8 +27,11 @@ func NewTemplateData(c *ctx, any interface{}) TemplateData {
 	t["Request"] = c.Request
 	t["Session"] = c.Session
 	for k, v := range c.Data {
-		t[k] = v
+		if _, exists := t[k]; !exists {
+			t[k] = v
+		}
 	}
+	t["Data"] = c.Data
 	t["Flash"] = c.Flasher
 	t.setCtxProcessors(c)
 	return t
@@ -44,9 +48,54 @@ func (t TemplateData) UrlFor(route string, external bool, params ...string) stri
 	return fmt.Sprintf("Unable to return a url from: %s, %s, external(%t)", route, params, external)
 }
 
+func (t TemplateData) SignedUrlFor(route string, external bool, seconds int, params ...string) string {
+	if c, ok := t["Ctx"].(Ctx); ok {
+		ret, err := SignedUrlFor(c, route, external, time.Duration(seconds)*time.Second, params...)
+		if err != nil {
+			return err.Error()
+		}
+		return ret
+	}
+	return fmt.Sprintf("Unable to return a signed url from: %s, %s, external(%t)", route, params, external)
+}
+
+// CSPNonce returns the Content-Security-Policy nonce of the rendering request,
+// for use in script and style tag nonce attributes.
+func (t TemplateData) CSPNonce() string {
+	if c, ok := t["Ctx"].(Ctx); ok {
+		return CSPNonce(c)
+	}
+	return ""
+}
+
+// Flashes returns and clears the flash messages for the provided categories,
+// or all flash messages, e.g. {{ range $k, $v := .Flashes }}.
+func (t TemplateData) Flashes(categories ...string) Flashes {
+	if c, ok := t["Ctx"].(Ctx); ok {
+		return GetFlashes(c, categories...)
+	}
+	return nil
+}
+
+// CookieFlashes returns and clears the cookie flash messages for the provided
+// categories, or all cookie flash messages.
+func (t TemplateData) CookieFlashes(categories ...string) Flashes {
+	if c, ok := t["Ctx"].(Ctx); ok {
+		return GetCookieFlashes(c, categories...)
+	}
+	return nil
+}
+
+// FormTimestamp returns a signed render timestamp for a FormGuard guarded form.
+func (t TemplateData) FormTimestamp() string {
+	if c, ok := t["Ctx"].(Ctx); ok {
+		return FormTimestamp(c)
+	}
+	return ""
+}
+
 func (t TemplateData) HTML(name string) template.HTML {
-	if fn, ok := t.getCtxProcessor(name); ok {
-		res, err := call(fn)
+	if res, ok, err := t.processed(name); ok {
 		if err != nil {
 			return template.HTML(err.Error())
 		}
@@ -58,8 +107,7 @@ func (t TemplateData) HTML(name string) template.HTML {
 }
 
 func (t TemplateData) STRING(name string) string {
-	if fn, ok := t.getCtxProcessor(name); ok {
-		res, err := call(fn)
+	if res, ok, err := t.processed(name); ok {
 		if err != nil {
 			return err.Error()
 		}
@@ -71,30 +119,22 @@ func (t TemplateData) STRING(name string) string {
 }
 
 func (t TemplateData) CALL(name string) interface{} {
-	if fn, ok := t.getCtxProcessor(name); ok {
-		if res, err := call(fn); err == nil {
-			return res
-		} else {
+	if res, ok, err := t.processed(name); ok {
+		if err != nil {
 			return err
 		}
+		return res
 	}
 	return fmt.Sprintf("context processor %s cannot be processed by CALL", name)
 }
 
-func (t TemplateData) getCtxProcessor(name string) (reflect.Value, bool) {
-	if fn, ok := t[name]; ok {
-		if fn, ok := fn.(reflect.Value); ok {
-			return fn, true
-		}
+// processed returns the result of the named ctxprocessor for the render.
+func (t TemplateData) processed(name string) (interface{}, bool, error) {
+	res, ok := t[name]
+	if err, isErr := res.(error); isErr {
+		return nil, ok, err
 	}
-	return reflect.Value{}, false
-}
-
-func (t TemplateData) setCtxProcessor(fn reflect.Value, c *ctx) reflect.Value {
-	newfn := func() (interface{}, error) {
-		return call(fn, c)
-	}
-	return valueFunc(newfn)
+	return res, ok, nil
 }
 
 func processorsFromEnv(c *ctx) map[string]reflect.Value {
@@ -105,8 +145,75 @@ func processorsFromEnv(c *ctx) map[string]reflect.Value {
 	return nil
 }
 
+// setCtxProcessors runs the ctxprocessors of the Env, then those of the
+// Blueprint of the route and the Blueprints it was made from, setting the
+// result, or error, of each by name and merging the keys of any map result
+// not already set by the render data.
 func (t TemplateData) setCtxProcessors(c *ctx) {
-	for k, fn := range processorsFromEnv(c) {
-		t[k] = t.setCtxProcessor(fn, c)
+	run := func(fns map[string]reflect.Value) {
+		for k, fn := range fns {
+			res, err := call(fn, c)
+			if err != nil {
+				t[k] = err
+				continue
+			}
+			t[k] = res
+			if m, ok := res.(map[string]interface{}); ok {
+				t.merge(m)
+			} else if m, ok := res.(TemplateData); ok {
+				t.merge(m)
+			}
+		}
+	}
+	run(processorsFromEnv(c))
+	if c.route != nil && c.route.Blueprint != nil {
+		c.route.Blueprint.processors.each(run)
+	}
+}
+
+func (t TemplateData) merge(m map[string]interface{}) {
+	for k, v := range m {
+		if _, exists := t[k]; !exists {
+			t[k] = v
+		}
+	}
+}
+
+// ctxprocessors are the ctxprocessors of a Blueprint, following those of the
+// Blueprint it was made from.
+type ctxprocessors struct {
+	parent *ctxprocessors
+	fns    map[string]reflect.Value
+}
+
+func (p *ctxprocessors) each(fn func(map[string]reflect.Value)) {
+	if p == nil {
+		return
+	}
+	p.parent.each(fn)
+	if len(p.fns) > 0 {
+		fn(p.fns)
+	}
+}
+
+// CtxProcessor adds a ctxprocessor run on each render of a template by the
+// routes of the Blueprint, and of the Blueprints made from it, after those of
+// the App, e.g. b.CtxProcessor("nav", func(c Ctx) map[string]interface{} {...}),
+// where a map result is merged into the render data.
+func (b *Blueprint) CtxProcessor(name string, fn interface{}) {
+	if b.processors == nil {
+		b.processors = &ctxprocessors{}
+	}
+	if b.processors.fns == nil {
+		b.processors.fns = make(map[string]reflect.Value)
+	}
+	b.processors.fns[name] = valueFunc(fn)
+}
+
+// CtxProcessors adds ctxprocessors to the Blueprint from a map of string keyed
+// interfaces.
+func (b *Blueprint) CtxProcessors(fns map[string]interface{}) {
+	for k, v := range fns {
+		b.CtxProcessor(k, v)
 	}
 }
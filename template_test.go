@@ -205,3 +205,31 @@ func TestTemplator(t *testing.T) {
 	)
 	SimplePerformer(t, a, exp).Perform()
 }
+
+func TestCtxProcessorPipeline(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "page.html"), []byte(`{{ .User }}|{{ .Section }}|{{ .STRING "version" }}|{{ .Title }}`), 0644)
+	var runs int
+	a := New("testCtxProcessorPipeline", Mode("testing", true), TemplateDriver("html"), EnvItem("TEMPLATE_DIRECTORIES:"+dir),
+		CtxProcessor("version", func(c Ctx) string { runs++; return "v1" }),
+		CtxProcessor("user", func(c Ctx) map[string]interface{} {
+			return map[string]interface{}{"User": "gopher", "Title": "processed"}
+		}),
+	)
+	a.GET("/page", func(c Ctx) { c.Call("rendertemplate", "page.html", map[string]interface{}{"Title": "page"}) })
+	admin := a.NewBlueprint("/admin")
+	child := admin.NewBlueprint("/child")
+	admin.CtxProcessor("section", func(c Ctx) TemplateData { return TemplateData{"Section": "admin"} })
+	child.GET("/page", func(c Ctx) { c.Call("rendertemplate", "page.html", nil) })
+	client := a.TestClient()
+
+	if body := string(client.Get("/page").Body); body != "gopher||v1|page" {
+		t.Errorf("expected the App processors merged under the render data, got %q", body)
+	}
+	if body := string(client.Get("/admin/child/page").Body); body != "gopher|admin|v1|processed" {
+		t.Errorf("expected the processors of the Blueprint for its children, got %q", body)
+	}
+	if runs != 2 {
+		t.Errorf("expected processors run once a render, got %d", runs)
+	}
+}
@@ -0,0 +1,192 @@
+package flotilla
+
+import (
+	"html/template"
+	"io"
+	"sync"
+
+	"github.com/thrisp/flotilla/xrr"
+)
+
+// TemplatorDriver returns a Templator rendering the templates of the template
+// directories and Assets of the Env, with its template functions.
+type TemplatorDriver func(*Env) (Templator, error)
+
+var (
+	UnknownTemplator = xrr.NewXrror("unknown templator driver %q (forgotten import?)").Out
+
+	templatordrivers = map[string]TemplatorDriver{
+		"djinn": func(env *Env) (Templator, error) { return NewTemplator(env), nil },
+		"html":  func(env *Env) (Templator, error) { return NewHTMLTemplator(env), nil },
+	}
+	templatormu sync.RWMutex
+)
+
+// RegisterTemplator makes a Templator driver available by name, as the
+// TEMPLATE_DRIVER of an App or with UseTemplator on a Blueprint, e.g. from the
+// init function of an adapter package. It panics if the driver is nil or the
+// name is already registered.
+func RegisterTemplator(name string, driver TemplatorDriver) {
+	templatormu.Lock()
+	defer templatormu.Unlock()
+	if driver == nil {
+		panic("flotilla: RegisterTemplator driver is nil")
+	}
+	if _, dup := templatordrivers[name]; dup {
+		panic("flotilla: RegisterTemplator called twice for driver " + name)
+	}
+	templatordrivers[name] = driver
+}
+
+// OpenTemplator returns a Templator of the named driver for the Env.
+func OpenTemplator(name string, env *Env) (Templator, error) {
+	templatormu.RLock()
+	driver, ok := templatordrivers[name]
+	templatormu.RUnlock()
+	if !ok {
+		return nil, UnknownTemplator(name)
+	}
+	return driver(env)
+}
+
+// TemplateDriver sets the TEMPLATE_DRIVER of the Store, the name of the
+// registered Templator driver of the App, djinn by default.
+func TemplateDriver(name string) Configuration {
+	return func(a *App) error {
+		a.Env.Store.add("template", "driver", name)
+		return nil
+	}
+}
+
+// TplFuncs returns the template functions of the Env, for Templator drivers.
+func (env *Env) TplFuncs() map[string]interface{} {
+	return env.tplfunctions
+}
+
+// blueprinttemplator is the Templator of a Blueprint, shared with the
+// children of the Blueprint.
+type blueprinttemplator struct {
+	Templator
+}
+
+// UseTemplator renders the templates of the routes of the Blueprint, and of
+// its children made after, with the named Templator driver in place of the
+// Templator of the App.
+func (b *Blueprint) UseTemplator(name string) {
+	bt := &blueprinttemplator{}
+	b.templator = bt
+	b.push(func() {
+		t, err := OpenTemplator(name, b.app.Env)
+		if err != nil {
+			b.app.Env.Log().Warn("blueprint templator error", "prefix", b.Prefix, "error", err)
+			return
+		}
+		bt.Templator = newReloadTemplator(b.app.Env, name, t)
+	}, nil)
+}
+
+// currenttemplator returns the Templator of the Blueprint of the route of the
+// ctx, if any, or of the App.
+func currenttemplator(a *App, c *ctx) Templator {
+	if rt := c.route; rt != nil && rt.Blueprint != nil {
+		if bt := rt.Blueprint.templator; bt != nil && bt.Templator != nil {
+			return bt.Templator
+		}
+	}
+	return a.Templator
+}
+
+// TemplateDirs are the template directories of a Templator, embedded by
+// Templator drivers for their ListTemplateDirs and UpdateTemplateDirs.
+type TemplateDirs []string
+
+// ListTemplateDirs lists template directories attached to the templator.
+func (d *TemplateDirs) ListTemplateDirs() []string {
+	return *d
+}
+
+// UpdateTemplateDirs adds the provided directories not already attached.
+func (d *TemplateDirs) UpdateTemplateDirs(dirs ...string) {
+	for _, dir := range dirs {
+		*d = doAdd(dir, *d)
+	}
+}
+
+// HTMLTemplate returns the cached html/template template of the name, or the
+// template returned by parse, cached in Production mode.
+func (tc *TemplateCache) HTMLTemplate(env *Env, name string, parse func(string) (*template.Template, error)) (*template.Template, error) {
+	if tpl, ok := tc.Get(name); ok {
+		return tpl.(*template.Template), nil
+	}
+	tpl, err := parse(name)
+	if err != nil {
+		return nil, err
+	}
+	if env.Mode.Production {
+		tc.Add(name, tpl)
+	}
+	return tpl, nil
+}
+
+// htmltemplator is a Templator of html/template templates.
+type htmltemplator struct {
+	TemplateDirs
+	env    *Env
+	loader *Loader
+	cache  TemplateCache
+}
+
+// NewHTMLTemplator returns a Templator parsing html/template templates, with
+// the layouts they extend, from the template directories and Assets of the
+// Env, with its template functions, caching parsed templates in Production
+// mode.
+func NewHTMLTemplator(env *Env) *htmltemplator {
+	t := &htmltemplator{env: env, loader: NewLoader(env)}
+	t.UpdateTemplateDirs(env.Store["TEMPLATE_DIRECTORIES"].List()...)
+	return t
+}
+
+func (t *htmltemplator) template(name string) (*template.Template, error) {
+	return t.cache.HTMLTemplate(t.env, name, t.parse)
+}
+
+func (t *htmltemplator) parse(name string) (*template.Template, error) {
+	return layouttemplate(t.loader.Load, t.env.TplFuncs(), name)
+}
+
+// Precompile parses and caches every template of the template directories and
+// Assets.
+func (t *htmltemplator) Precompile() error {
+	for _, name := range t.loader.names() {
+		tpl, err := t.parse(name)
+		if err != nil {
+			return err
+		}
+		t.cache.Add(name, tpl)
+	}
+	return nil
+}
+
+// Render renders the named template with data to w.
+func (t *htmltemplator) Render(w io.Writer, name string, data interface{}) error {
+	tpl, err := t.template(name)
+	if err != nil {
+		return err
+	}
+	return tpl.Execute(w, data)
+}
+
+// RenderPartial renders the named block of the template with data to w,
+// without the layouts the template extends.
+func (t *htmltemplator) RenderPartial(w io.Writer, name, block string, data interface{}) error {
+	tpl, err := t.template(name)
+	if err != nil {
+		return err
+	}
+	return tpl.ExecuteTemplate(w, block, data)
+}
+
+// ListTemplates returns the templates of the template directories and Assets.
+func (t *htmltemplator) ListTemplates() []string {
+	return t.loader.ListTemplates().([]string)
+}
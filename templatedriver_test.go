@@ -0,0 +1,44 @@
+package flotilla
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func init() {
+	RegisterTemplator("testdriver", func(env *Env) (Templator, error) { return &testtemplator{}, nil })
+}
+
+func TestTemplatorDrivers(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "greet.html"), []byte(`<p>{{ Hello .Any }}</p>`), 0644)
+
+	a := New("testTemplatorDrivers", Mode("testing", true), TemplateDriver("html"), tplfuncsconf(tplfuncs))
+	a.Env.TemplateDirs(dir)
+	a.GET("/greet", func(c Ctx) { c.Call("rendertemplate", "greet.html", "gopher") })
+	b := a.NewBlueprint("/other")
+	b.UseTemplator("testdriver")
+	b.NewBlueprint("/child").GET("/greet", func(c Ctx) { c.Call("rendertemplate", "greet.html", "gopher") })
+	client := a.TestClient()
+
+	if r, ok := a.Env.Templator.(*reloadtemplator); !ok || r.driver != "html" {
+		t.Fatalf("expected the html Templator of the TEMPLATE_DRIVER, got %T", a.Env.Templator)
+	}
+	if body := string(client.Get("/greet").Body); !strings.Contains(body, "<p>Hello World!: gopher</p>") {
+		t.Errorf("expected an html/template rendered with template functions, got %q", body)
+	}
+	if body := string(client.Get("/other/child/greet").Body); body != "test templator" {
+		t.Errorf("expected the Templator of the Blueprint for its children, got %q", body)
+	}
+	if _, err := OpenTemplator("unregistered", a.Env); err == nil {
+		t.Error("expected an error opening an unregistered Templator driver")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("expected registering a Templator driver twice to panic")
+		}
+	}()
+	RegisterTemplator("html", func(env *Env) (Templator, error) { return nil, nil })
+}
@@ -0,0 +1,83 @@
+package flotilla
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// templatecontext is the number of lines of source shown before and after
+// the failing line of a template error page.
+const templatecontext = 3
+
+// templateerrorline matches the template name and line of html/template and
+// text/template errors, e.g. `template: page.html:3:12: executing ...`.
+var templateerrorline = regexp.MustCompile(`template: ([^:\s]+):(\d+)`)
+
+// templateerrorsource returns the source lines around the line of the
+// template error, the failing line in bold, if the template and line of the
+// error are known.
+func templateerrorsource(env *Env, name string, err error) string {
+	line := 0
+	if m := templateerrorline.FindStringSubmatch(err.Error()); m != nil {
+		name = m[1]
+		line, _ = strconv.Atoi(m[2])
+	}
+	l := NewLoader(env)
+	l.FileExtensions = []string{filepath.Ext(name)}
+	src, lerr := l.Load(name)
+	if lerr != nil || line == 0 {
+		return ""
+	}
+	var b bytes.Buffer
+	lines := strings.Split(src, "\n")
+	for i := line - templatecontext; i <= line+templatecontext; i++ {
+		if i < 1 || i > len(lines) {
+			continue
+		}
+		text := fmt.Sprintf("%4d  %s", i, template.HTMLEscapeString(lines[i-1]))
+		if i == line {
+			text = "<b>" + text + "</b>"
+		}
+		fmt.Fprintf(&b, "<p>%s</p>\n", text)
+	}
+	return b.String()
+}
+
+// templateerrorpage returns a page of the template error with the name of
+// the template and the source around the failing line.
+func templateerrorpage(env *Env, name string, err error) string {
+	title := template.HTMLEscapeString(fmt.Sprintf("template %s: %s", name, err))
+	block := fmt.Sprintf(panicBlock, title, templateerrorsource(env, name, err))
+	return fmt.Sprintf(panicHtml, block)
+}
+
+// rendertemplated renders the named template to a buffer, writing it to the
+// response only if rendering succeeds. On failure the error is recorded and
+// the response is a page of the error and template source in Development
+// mode, or else the 500 status, aborting any deferred functions pending.
+func rendertemplated(a *App, c *ctx, name string, render func(io.Writer) error) bool {
+	var buf bytes.Buffer
+	err := render(&buf)
+	if err == nil {
+		c.RW.Write(buf.Bytes())
+		return true
+	}
+	recorderror(c, err)
+	a.Env.Log().Error("template render error", "template", name, "error", err)
+	if m := a.Env.Mode; m.Development && !m.Production {
+		c.abortwith(func() error {
+			headerwrite(c, 500, []string{"Content-Type", "text/html; charset=utf-8"})
+			_, werr := c.RW.Write([]byte(templateerrorpage(a.Env, name, err)))
+			return werr
+		})
+		return false
+	}
+	c.abort(500)
+	return false
+}
@@ -0,0 +1,47 @@
+package flotilla
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTemplateErrorPage(t *testing.T) {
+	tpls := t.TempDir()
+	os.WriteFile(filepath.Join(tpls, "bad.html"), []byte("one\ntwo\n{{ index \"abc\" 5 }}\nfour\n"), 0644)
+
+	app := func(modes ...Configuration) *App {
+		a := New("testTemplateErrorPage", append(modes, TemplateDriver("html"), EnvItem("TEMPLATE_DIRECTORIES:"+tpls))...)
+		a.GET("/bad", func(c Ctx) { c.Call("rendertemplate", "bad.html", nil) })
+		a.GET("/after", func(c Ctx) {
+			c.Call("rendertemplate", "bad.html", nil)
+			c.Call("serveplain", 200, "AFTER")
+		})
+		a.STATUS(500, func(c Ctx) { c.Call("serveplain", 500, "custom 500") })
+		return a
+	}
+
+	client := app(Mode("testing", true)).TestClient()
+	res := client.Get("/bad")
+	body := string(res.Body)
+	if res.Status != 500 || strings.HasPrefix(body, "one") {
+		t.Errorf("expected a 500 without the partly rendered template, got %d %q", res.Status, body)
+	}
+	for _, expected := range []string{"template bad.html", "<p>   1  one</p>", "<b>   3  {{ index &#34;abc&#34; 5 }}</b>", "<p>   4  four</p>"} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("expected %q in the Development error page, got %q", expected, body)
+		}
+	}
+
+	if res := client.Get("/after"); res.Status != 500 || strings.Contains(string(res.Body), "AFTER") {
+		t.Errorf("expected no response deferred after the failing render in Development mode, got %d %q", res.Status, res.Body)
+	}
+
+	production := app(Mode("production", true), Mode("development", false)).TestClient()
+	for _, path := range []string{"/bad", "/after"} {
+		if res := production.Get(path); res.Status != 500 || string(res.Body) != "custom 500" {
+			t.Errorf("%s: expected the custom 500 status in Production mode, got %d %q", path, res.Status, res.Body)
+		}
+	}
+}
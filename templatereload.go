@@ -0,0 +1,179 @@
+package flotilla
+
+import (
+	"hash/fnv"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TemplateCache is a cache of compiled templates for Templator drivers, read
+// without locking; adding a template copies the cache.
+type TemplateCache struct {
+	mu        sync.Mutex
+	templates atomic.Value
+}
+
+// Get returns the compiled template of the name, if cached.
+func (tc *TemplateCache) Get(name string) (interface{}, bool) {
+	m, _ := tc.templates.Load().(map[string]interface{})
+	t, ok := m[name]
+	return t, ok
+}
+
+// Add caches the compiled template of the name.
+func (tc *TemplateCache) Add(name string, t interface{}) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	m, _ := tc.templates.Load().(map[string]interface{})
+	nm := make(map[string]interface{}, len(m)+1)
+	for k, v := range m {
+		nm[k] = v
+	}
+	nm[name] = t
+	tc.templates.Store(nm)
+}
+
+// reloadtemplator is the Templator of a driver, reopened in Development mode
+// when a file of its template directories changes, so that edited templates
+// render without a restart. The directories are checked by a goroutine every
+// TEMPLATE_RELOADINTERVAL milliseconds, started with the first render, rather
+// than on render, and stopped when the App shuts down.
+type reloadtemplator struct {
+	env     *Env
+	driver  string
+	current atomic.Value
+	mu      sync.Mutex
+	dirs    []string
+	stamp   uint64
+	watched sync.Once
+}
+
+func newReloadTemplator(env *Env, driver string, t Templator) *reloadtemplator {
+	r := &reloadtemplator{env: env, driver: driver}
+	r.current.Store(t)
+	r.stamp = r.sum()
+	return r
+}
+
+func (r *reloadtemplator) templator() Templator {
+	return r.current.Load().(Templator)
+}
+
+func (r *reloadtemplator) interval() time.Duration {
+	if item, ok := r.env.Store["TEMPLATE_RELOADINTERVAL"]; ok {
+		if ms, err := strconv.ParseInt(item.Value, 10, 64); err == nil && ms > 0 {
+			return time.Duration(ms) * time.Millisecond
+		}
+	}
+	return time.Second
+}
+
+// sum returns a hash of the names, sizes, and modification times of the
+// files of the template directories.
+func (r *reloadtemplator) sum() uint64 {
+	h := fnv.New64a()
+	for _, dir := range r.ListTemplateDirs() {
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				io.WriteString(h, path)
+				io.WriteString(h, strconv.FormatInt(info.Size(), 10))
+				io.WriteString(h, strconv.FormatInt(info.ModTime().UnixNano(), 10))
+			}
+			return nil
+		})
+	}
+	return h.Sum64()
+}
+
+// watch starts the goroutine reloading changed templates, once.
+func (r *reloadtemplator) watch() {
+	r.watched.Do(func() {
+		done := r.env.done()
+		go func() {
+			ticker := time.NewTicker(r.interval())
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					r.reload()
+				}
+			}
+		}()
+	})
+}
+
+// reload reopens the Templator of the driver if its templates changed.
+func (r *reloadtemplator) reload() {
+	stamp := r.sum()
+	if stamp == r.stamp {
+		return
+	}
+	t, err := OpenTemplator(r.driver, r.env)
+	if err != nil {
+		r.env.Log().Warn("template reload error", "driver", r.driver, "error", err)
+		return
+	}
+	r.mu.Lock()
+	t.UpdateTemplateDirs(r.dirs...)
+	r.mu.Unlock()
+	r.current.Store(t)
+	r.stamp = stamp
+}
+
+// Render renders the named template with data to w, with the current
+// Templator of the driver.
+func (r *reloadtemplator) Render(w io.Writer, name string, data interface{}) error {
+	if r.env.Mode.Development {
+		r.watch()
+	}
+	return r.templator().Render(w, name, data)
+}
+
+// RenderPartial renders the named block of the template with the Templator
+// of the driver, if it is a PartialTemplator.
+func (r *reloadtemplator) RenderPartial(w io.Writer, name, block string, data interface{}) error {
+	if r.env.Mode.Development {
+		r.watch()
+	}
+	pt, ok := r.templator().(PartialTemplator)
+	if !ok {
+		return PartialUnsupported(r.templator())
+	}
+	return pt.RenderPartial(w, name, block, data)
+}
+
+func (r *reloadtemplator) ListTemplateDirs() []string {
+	return r.templator().ListTemplateDirs()
+}
+
+func (r *reloadtemplator) ListTemplates() []string {
+	return r.templator().ListTemplates()
+}
+
+func (r *reloadtemplator) UpdateTemplateDirs(dirs ...string) {
+	r.mu.Lock()
+	for _, dir := range dirs {
+		r.dirs = doAdd(dir, r.dirs)
+	}
+	r.mu.Unlock()
+	r.templator().UpdateTemplateDirs(dirs...)
+}
+
+// Precompile precompiles the templates of the Templator of the driver, if it
+// is able to.
+func (r *reloadtemplator) Precompile() error {
+	if p, ok := r.templator().(interface{ Precompile() error }); ok {
+		return p.Precompile()
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package flotilla
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTemplateReload(t *testing.T) {
+	for _, driver := range []string{"html", "djinn"} {
+		t.Run(driver, func(t *testing.T) { testTemplateReload(t, driver) })
+	}
+}
+
+func testTemplateReload(t *testing.T, driver string) {
+	render := func(mode string, modes ...Configuration) func(string) string {
+		dir := t.TempDir()
+		tpl := filepath.Join(dir, "page.html")
+		os.WriteFile(tpl, []byte(`first`), 0644)
+		conf := append(modes, TemplateDriver(driver), EnvItem("TEMPLATE_DIRECTORIES:"+dir, "TEMPLATE_RELOADINTERVAL:10"))
+		a := New("testTemplateReload"+mode, conf...)
+		a.GET("/page", func(c Ctx) { c.Call("rendertemplate", "page.html", nil) })
+		a.Configure()
+		return func(content string) string {
+			if content != "" {
+				os.WriteFile(tpl, []byte(content), 0644)
+				later := time.Now().Add(time.Second)
+				os.Chtimes(tpl, later, later)
+			}
+			rw := httptest.NewRecorder()
+			a.ServeHTTP(rw, httptest.NewRequest("GET", "/page", nil))
+			return rw.Body.String()
+		}
+	}
+
+	dev := render("development", Mode("development", true))
+	if body := dev(""); body != "first" {
+		t.Fatalf("expected the template rendered, got %q", body)
+	}
+	body := dev("second")
+	for deadline := time.Now().Add(time.Second); body != "second" && time.Now().Before(deadline); body = dev("") {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if body != "second" {
+		t.Errorf("expected an edited template reloaded in Development mode, got %q", body)
+	}
+
+	prod := render("production", Mode("production", true), Mode("development", false))
+	if body := prod(""); body != "first" {
+		t.Fatalf("expected the precompiled template rendered, got %q", body)
+	}
+	if body := prod("second"); body != "first" {
+		t.Errorf("expected the compiled template cached in Production mode, got %q", body)
+	}
+}
@@ -0,0 +1,80 @@
+// Package amber provides a flotilla Templator driver for Amber templates,
+// registered as "amber", e.g.
+//
+//	import _ "github.com/thrisp/flotilla/templator/amber"
+//
+//	a := flotilla.New("app", flotilla.TemplateDriver("amber"))
+//
+// The package depends on github.com/eknkc/amber, fetched with
+//
+//	go get github.com/eknkc/amber
+package amber
+
+import (
+	"html/template"
+	"io"
+
+	"github.com/eknkc/amber"
+	"github.com/thrisp/flotilla"
+)
+
+// FileExtensions are the extensions of Amber templates.
+var FileExtensions = []string{".amber"}
+
+func init() {
+	flotilla.RegisterTemplator("amber", New)
+}
+
+// Templator renders Amber templates, compiled to html/template templates, of
+// the template directories and Assets of a flotilla Env, with its template
+// functions.
+type Templator struct {
+	flotilla.TemplateDirs
+	env    *flotilla.Env
+	loader *flotilla.Loader
+	cache  flotilla.TemplateCache
+}
+
+// New returns an Amber Templator for the Env, caching compiled templates in
+// Production mode.
+func New(env *flotilla.Env) (flotilla.Templator, error) {
+	t := &Templator{env: env, loader: flotilla.NewLoader(env)}
+	t.loader.FileExtensions = FileExtensions
+	t.UpdateTemplateDirs(env.Store["TEMPLATE_DIRECTORIES"].List()...)
+	return t, nil
+}
+
+func (t *Templator) template(name string) (*template.Template, error) {
+	return t.cache.HTMLTemplate(t.env, name, t.parse)
+}
+
+func (t *Templator) parse(name string) (*template.Template, error) {
+	src, err := t.loader.Load(name)
+	if err != nil {
+		return nil, err
+	}
+	c := amber.New()
+	if err := c.Parse(src); err != nil {
+		return nil, err
+	}
+	compiled, err := c.CompileString()
+	if err != nil {
+		return nil, err
+	}
+	return template.New(name).Funcs(amber.FuncMap).Funcs(t.env.TplFuncs()).Parse(compiled)
+}
+
+// Render renders the named template with data to w.
+func (t *Templator) Render(w io.Writer, name string, data interface{}) error {
+	tpl, err := t.template(name)
+	if err != nil {
+		return err
+	}
+	return tpl.Execute(w, data)
+}
+
+// ListTemplates returns the Amber templates of the template directories and
+// Assets.
+func (t *Templator) ListTemplates() []string {
+	return t.loader.ListTemplates().([]string)
+}
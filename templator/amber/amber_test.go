@@ -0,0 +1,39 @@
+package amber
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/thrisp/flotilla"
+)
+
+func TestAmber(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "page.amber"), []byte("p first\n"), 0644)
+
+	a := flotilla.New(
+		"testAmber",
+		flotilla.Mode("testing", true),
+		flotilla.Mode("production", true),
+		flotilla.Mode("development", false),
+		flotilla.TemplateDriver("amber"),
+		flotilla.EnvItem("TEMPLATE_DIRECTORIES:"+dir),
+	)
+	a.GET("/page", func(c flotilla.Ctx) { c.Call("rendertemplate", "page.amber", nil) })
+	client := a.TestClient()
+
+	if body := string(client.Get("/page").Body); body != "<p>first</p>" {
+		t.Errorf("expected the compiled amber template, got %q", body)
+	}
+	os.WriteFile(filepath.Join(dir, "page.amber"), []byte("p second\n"), 0644)
+	if body := string(client.Get("/page").Body); body != "<p>first</p>" {
+		t.Errorf("expected the amber template cached in Production mode, got %q", body)
+	}
+	if tpls := a.Env.Templator.ListTemplates(); len(tpls) != 1 || filepath.Base(tpls[0]) != "page.amber" {
+		t.Errorf("expected the amber templates of the template directories, got %v", tpls)
+	}
+	if dirs := a.Env.Templator.ListTemplateDirs(); len(dirs) != 1 || dirs[0] != dir {
+		t.Errorf("expected the template directories of the Store, got %v", dirs)
+	}
+}
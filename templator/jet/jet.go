@@ -0,0 +1,94 @@
+// Package jet provides a flotilla Templator driver for Jet templates,
+// registered as "jet", e.g.
+//
+//	import _ "github.com/thrisp/flotilla/templator/jet"
+//
+//	a := flotilla.New("app", flotilla.TemplateDriver("jet"))
+//
+// The package depends on github.com/CloudyKit/jet/v6, fetched with
+//
+//	go get github.com/CloudyKit/jet/v6
+package jet
+
+import (
+	"io"
+	"strings"
+
+	"github.com/CloudyKit/jet/v6"
+	"github.com/thrisp/flotilla"
+)
+
+// FileExtensions are the extensions of Jet templates.
+var FileExtensions = []string{".jet", ".html"}
+
+func init() {
+	flotilla.RegisterTemplator("jet", New)
+}
+
+// Templator renders Jet templates of the template directories and Assets of a
+// flotilla Env, with its template functions as globals.
+type Templator struct {
+	flotilla.TemplateDirs
+	env    *flotilla.Env
+	loader *flotilla.Loader
+	set    *jet.Set
+}
+
+// New returns a Jet Templator for the Env, caching parsed templates outside
+// Development mode.
+func New(env *flotilla.Env) (flotilla.Templator, error) {
+	t := &Templator{env: env, loader: flotilla.NewLoader(env)}
+	t.loader.FileExtensions = FileExtensions
+	var opts []jet.Option
+	if env.Mode.Development {
+		opts = append(opts, jet.InDevelopmentMode())
+	}
+	t.set = jet.NewSet(&loader{t.loader}, opts...)
+	for name, fn := range env.TplFuncs() {
+		t.set.AddGlobal(name, fn)
+	}
+	t.UpdateTemplateDirs(env.Store["TEMPLATE_DIRECTORIES"].List()...)
+	return t, nil
+}
+
+// Render renders the named template with data, a flotilla TemplateData, to
+// w, as the context of the template and with each key of the data as a
+// variable.
+func (t *Templator) Render(w io.Writer, name string, data interface{}) error {
+	tpl, err := t.set.GetTemplate(name)
+	if err != nil {
+		return err
+	}
+	vars := make(jet.VarMap)
+	if td, ok := data.(flotilla.TemplateData); ok {
+		for k, v := range td {
+			vars.Set(k, v)
+		}
+	}
+	return tpl.Execute(w, vars, data)
+}
+
+// ListTemplates returns the Jet templates of the template directories and
+// Assets.
+func (t *Templator) ListTemplates() []string {
+	return t.loader.ListTemplates().([]string)
+}
+
+// loader is a Jet Loader of a flotilla Loader, of template paths that Jet
+// roots at /.
+type loader struct {
+	*flotilla.Loader
+}
+
+func (l *loader) Exists(name string) bool {
+	_, err := l.Load(strings.TrimPrefix(name, "/"))
+	return err == nil
+}
+
+func (l *loader) Open(name string) (io.ReadCloser, error) {
+	src, err := l.Load(strings.TrimPrefix(name, "/"))
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(strings.NewReader(src)), nil
+}
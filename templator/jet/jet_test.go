@@ -0,0 +1,36 @@
+package jet
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/thrisp/flotilla"
+)
+
+func TestJet(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "page.jet"), []byte("Hello {{ Name }}"), 0644)
+
+	a := flotilla.New(
+		"testJet",
+		flotilla.Mode("testing", true),
+		flotilla.TemplateDriver("jet"),
+		flotilla.EnvItem("TEMPLATE_DIRECTORIES:"+dir),
+	)
+	a.GET("/page", func(c flotilla.Ctx) {
+		c.Call("rendertemplate", "page.jet", map[string]interface{}{"Name": "jet"})
+	})
+	client := a.TestClient()
+
+	if body := string(client.Get("/page").Body); body != "Hello jet" {
+		t.Errorf("expected the jet template rendered with the template data, got %q", body)
+	}
+	if tpls := a.Env.Templator.ListTemplates(); len(tpls) != 1 || filepath.Base(tpls[0]) != "page.jet" {
+		t.Errorf("expected the jet templates of the template directories, got %v", tpls)
+	}
+	a.Env.Templator.UpdateTemplateDirs(dir, dir)
+	if dirs := a.Env.Templator.ListTemplateDirs(); len(dirs) != 1 || dirs[0] != dir {
+		t.Errorf("expected the template directories of the Store, without duplicates, got %v", dirs)
+	}
+}
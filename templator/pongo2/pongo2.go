@@ -0,0 +1,94 @@
+// Package pongo2 provides a flotilla Templator driver for pongo2 templates,
+// registered as "pongo2", e.g.
+//
+//	import _ "github.com/thrisp/flotilla/templator/pongo2"
+//
+//	a := flotilla.New("app", flotilla.TemplateDriver("pongo2"))
+//
+// The package depends on github.com/flosch/pongo2/v6, fetched with
+//
+//	go get github.com/flosch/pongo2/v6
+package pongo2
+
+import (
+	"io"
+	"path"
+	"strings"
+
+	"github.com/flosch/pongo2/v6"
+	"github.com/thrisp/flotilla"
+)
+
+// FileExtensions are the extensions of pongo2 templates.
+var FileExtensions = []string{".html", ".pongo2", ".tpl"}
+
+func init() {
+	flotilla.RegisterTemplator("pongo2", New)
+}
+
+// Templator renders pongo2 templates of the template directories and Assets
+// of a flotilla Env, with its template functions as globals.
+type Templator struct {
+	flotilla.TemplateDirs
+	env    *flotilla.Env
+	loader *flotilla.Loader
+	set    *pongo2.TemplateSet
+}
+
+// New returns a pongo2 Templator for the Env, caching parsed templates
+// outside Development mode.
+func New(env *flotilla.Env) (flotilla.Templator, error) {
+	t := &Templator{env: env, loader: flotilla.NewLoader(env)}
+	t.loader.FileExtensions = FileExtensions
+	t.set = pongo2.NewSet("flotilla", &loader{t.loader})
+	t.set.Debug = env.Mode.Development
+	for name, fn := range env.TplFuncs() {
+		t.set.Globals[name] = fn
+	}
+	t.UpdateTemplateDirs(env.Store["TEMPLATE_DIRECTORIES"].List()...)
+	return t, nil
+}
+
+// Render renders the named template with data, a flotilla TemplateData, to w.
+func (t *Templator) Render(w io.Writer, name string, data interface{}) error {
+	tpl, err := t.set.FromCache(name)
+	if err != nil {
+		return err
+	}
+	ctx := pongo2.Context{}
+	if td, ok := data.(flotilla.TemplateData); ok {
+		for k, v := range td {
+			ctx[k] = v
+		}
+	} else {
+		ctx["Any"] = data
+	}
+	return tpl.ExecuteWriter(ctx, w)
+}
+
+// ListTemplates returns the pongo2 templates of the template directories and
+// Assets.
+func (t *Templator) ListTemplates() []string {
+	return t.loader.ListTemplates().([]string)
+}
+
+// loader is a pongo2 TemplateLoader of a flotilla Loader, resolving included
+// and extended templates relative to the including template.
+type loader struct {
+	*flotilla.Loader
+}
+
+func (l *loader) Abs(base, name string) string {
+	if base == "" || strings.HasPrefix(name, "/") {
+		return strings.TrimPrefix(name, "/")
+	}
+	return path.Join(path.Dir(base), name)
+}
+
+func (l *loader) Get(name string) (io.Reader, error) {
+	src, err := l.Load(name)
+	if err != nil {
+		return nil, err
+	}
+	return strings.NewReader(src), nil
+}
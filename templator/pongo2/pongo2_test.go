@@ -0,0 +1,36 @@
+package pongo2
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/thrisp/flotilla"
+)
+
+func TestPongo2(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "page.pongo2"), []byte("Hello {{ Name }}"), 0644)
+
+	a := flotilla.New(
+		"testPongo2",
+		flotilla.Mode("testing", true),
+		flotilla.TemplateDriver("pongo2"),
+		flotilla.EnvItem("TEMPLATE_DIRECTORIES:"+dir),
+	)
+	a.GET("/page", func(c flotilla.Ctx) {
+		c.Call("rendertemplate", "page.pongo2", map[string]interface{}{"Name": "pongo2"})
+	})
+	client := a.TestClient()
+
+	if body := string(client.Get("/page").Body); body != "Hello pongo2" {
+		t.Errorf("expected the pongo2 template rendered with the template data, got %q", body)
+	}
+	if tpls := a.Env.Templator.ListTemplates(); len(tpls) != 1 || filepath.Base(tpls[0]) != "page.pongo2" {
+		t.Errorf("expected the pongo2 templates of the template directories, got %v", tpls)
+	}
+	a.Env.Templator.UpdateTemplateDirs(dir, dir)
+	if dirs := a.Env.Templator.ListTemplateDirs(); len(dirs) != 1 || dirs[0] != dir {
+		t.Errorf("expected the template directories of the Store, without duplicates, got %v", dirs)
+	}
+}
@@ -0,0 +1,20 @@
+package flotilla
+
+import "github.com/thrisp/flotilla/testing"
+
+// TestClient configures the App if needed and returns an in-process client
+// for it, recording the templates rendered for each request.
+func (a *App) TestClient() *testing.Client {
+	a.configured()
+	a.testclient.Do(func() { a.Env.Events.Connect(TemplateRendered, testclientrendered) })
+	return testing.NewClient(a)
+}
+
+// testclientrendered records a rendered template with the test Client
+// performing the request, if any; connected once for every Client of an App.
+func testclientrendered(c Ctx, payload interface{}) {
+	client, id := testing.RequestClient(CurrentRequest(c))
+	if rt, ok := payload.(*RenderedTemplate); ok && client != nil && id != "" {
+		client.Rendered(id, rt.Name)
+	}
+}
@@ -0,0 +1,43 @@
+package flotilla
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestTestClient(t *testing.T) {
+	a := New("testTestClient", Mode("testing", true), WithTemplator(&testtemplator{}))
+	a.GET("/json", func(c Ctx) {
+		c.Call("headerwrite", 200, []string{"Content-Type", "application/json"})
+		c.Call("writetoresponse", `{"user":{"name":"test","roles":["a","b"]},"count":2}`)
+	})
+	a.GET("/rendered", func(c Ctx) { c.Call("rendertemplate", "test.html", nil) })
+	a.POST("/session", func(c Ctx) {
+		c.Call("setsession", "value", CurrentRequest(c).FormValue("value"))
+	})
+	a.GET("/session", func(c Ctx) {
+		v, _ := c.Call("getsession", "value")
+		c.Call("serveplain", 200, v)
+	})
+
+	client := a.TestClient()
+
+	client.Get("/json").
+		AssertStatus(t, 200).
+		AssertHeader(t, "Content-Type", "application/json").
+		AssertJSON(t, "user.name", "test").
+		AssertJSON(t, "user.roles.1", "b").
+		AssertJSON(t, "count", 2)
+
+	client.Get("/rendered").AssertTemplate(t, "test.html")
+
+	client.PostForm("/session", url.Values{"value": {"kept"}}).AssertStatus(t, 200)
+	client.Get("/session").AssertBodyContains(t, "kept")
+
+	other := a.TestClient()
+	other.Get("/rendered").AssertTemplate(t, "test.html")
+	client.Get("/rendered").AssertTemplate(t, "test.html")
+	if n := len(a.Env.Events.receivers[TemplateRendered]); n != 1 {
+		t.Errorf("expected one TemplateRendered receiver for every TestClient, got %d", n)
+	}
+}
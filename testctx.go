@@ -0,0 +1,91 @@
+package flotilla
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/thrisp/flotilla/engine"
+)
+
+type testsession struct {
+	mu     sync.Mutex
+	sid    string
+	values map[interface{}]interface{}
+}
+
+func (s *testsession) Set(key, value interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+	return nil
+}
+
+func (s *testsession) Get(key interface{}) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.values[key]
+}
+
+func (s *testsession) Delete(key interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+	return nil
+}
+
+func (s *testsession) Keys() []interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ret := make([]interface{}, 0, len(s.values))
+	for k := range s.values {
+		ret = append(ret, k)
+	}
+	return ret
+}
+
+func (s *testsession) Export() map[interface{}]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ret := make(map[interface{}]interface{}, len(s.values))
+	for k, v := range s.values {
+		ret[k] = v
+	}
+	return ret
+}
+
+func (s *testsession) SessionID() string {
+	return s.sid
+}
+
+func (s *testsession) SessionRelease(w http.ResponseWriter) {}
+
+func (s *testsession) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values = make(map[interface{}]interface{})
+	return nil
+}
+
+// NewTestCtx returns a fully initialized Ctx for unit testing Manage functions
+// and Fxtensions, with the provided request (a GET for "/" if nil) and route
+// params, an in-memory session, and the Env of an App configured with the
+// provided Configuration, e.g. EnvItem for Store values. The returned recorder
+// receives everything written to the Ctx.
+//
+// Manage functions may be called with the Ctx directly; Run completes any
+// deferred and final functions they push.
+func NewTestCtx(rq *http.Request, params engine.Params, conf ...Configuration) (Ctx, *httptest.ResponseRecorder) {
+	if rq == nil {
+		rq = httptest.NewRequest("GET", "/", nil)
+	}
+	a := New("testctx", append([]Configuration{Mode("testing", true)}, conf...)...)
+	a.Configure()
+
+	rw := httptest.NewRecorder()
+	c := NewCtx(a.fxtensions, engine.NewResult(200, nil, params, false))
+	c.reset(rq, rw, nil)
+	c.Session = &testsession{sid: "testsession", values: make(map[interface{}]interface{})}
+	c.Data = make(map[string]interface{})
+	return c, rw
+}
@@ -0,0 +1,30 @@
+package flotilla
+
+import (
+	"testing"
+
+	"github.com/thrisp/flotilla/engine"
+)
+
+func TestNewTestCtx(t *testing.T) {
+	c, rw := NewTestCtx(nil, engine.Params{{Key: "name", Value: "test"}}, EnvItem("test_key:value"))
+
+	if item, err := c.Call("store", "TEST_KEY"); err != nil || item.(*StoreItem).Value != "value" {
+		t.Errorf("Test ctx Store did not contain the configured item: %v %v", item, err)
+	}
+
+	manage := func(c Ctx) {
+		ps, _ := c.Call("params")
+		c.Call("setsession", "seen", true)
+		c.Call("serveplain", 201, ps.(engine.Params).ByName("name"))
+	}
+	manage(c)
+	c.Run()
+
+	if rw.Code != 201 || rw.Body.String() != "test" {
+		t.Errorf("Test ctx response was %d %q", rw.Code, rw.Body.String())
+	}
+	if seen, _ := c.Call("getsession", "seen"); seen != true {
+		t.Errorf("Test ctx session value was not kept")
+	}
+}
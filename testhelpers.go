@@ -0,0 +1,96 @@
+package flotilla
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+)
+
+// UserSessionKey is the session key holding the logged in user.
+const UserSessionKey = "_user"
+
+// SessionCookies configures the App if needed and returns the cookies of a
+// new session holding the provided values, for sending with test requests.
+func (a *App) SessionCookies(values map[string]interface{}) ([]*http.Cookie, error) {
+	a.configured()
+	rq := httptest.NewRequest("GET", "/", nil)
+	rw := httptest.NewRecorder()
+	s, err := a.SessionManager.SessionStart(rw, rq)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range values {
+		s.Set(k, v)
+	}
+	s.SessionRelease(rw)
+
+	cookies := make(map[string]*http.Cookie)
+	for _, ck := range rq.Cookies() {
+		cookies[ck.Name] = ck
+	}
+	for _, ck := range rw.Result().Cookies() {
+		cookies[ck.Name] = ck
+	}
+	ret := make([]*http.Cookie, 0, len(cookies))
+	for _, ck := range cookies {
+		ret = append(ret, &http.Cookie{Name: ck.Name, Value: ck.Value})
+	}
+	return ret, nil
+}
+
+// SeedSession adds cookies for a new session holding the provided values to
+// a test request.
+func (a *App) SeedSession(rq *http.Request, values map[string]interface{}) error {
+	cookies, err := a.SessionCookies(values)
+	if err != nil {
+		return err
+	}
+	for _, ck := range cookies {
+		rq.AddCookie(ck)
+	}
+	return nil
+}
+
+// SeedUser adds cookies for a new session with the provided logged in user,
+// and any other values, to a test request.
+func (a *App) SeedUser(rq *http.Request, user interface{}, values map[string]interface{}) error {
+	seeded := map[string]interface{}{UserSessionKey: user}
+	for k, v := range values {
+		seeded[k] = v
+	}
+	return a.SeedSession(rq, seeded)
+}
+
+// SessionValue returns the value of key in the session identified by the
+// provided cookies, e.g. those set by a test response.
+func (a *App) SessionValue(cookies []*http.Cookie, key string) interface{} {
+	a.configured()
+	rq := httptest.NewRequest("GET", "/", nil)
+	for _, ck := range cookies {
+		rq.AddCookie(ck)
+	}
+	s, err := a.SessionManager.SessionStart(httptest.NewRecorder(), rq)
+	if err != nil {
+		return nil
+	}
+	return s.Get(key)
+}
+
+// ReadSecureCookie returns the value of a cookie set with securecookie, and
+// whether it is valid for a key of the App KeyRing.
+func (a *App) ReadSecureCookie(ck *http.Cookie) (string, bool) {
+	_, maxage := securecookiekeys(func(key string) (*StoreItem, bool) {
+		item, ok := a.Env.Store[key]
+		return item, ok
+	})
+	return openvalue(a.Env.KeyRing(), maxage, ck.Name, ck.Value, a.Env.Now())
+}
+
+// FreezeTime sets the time of the App, used by its expiry related functions
+// and session Manager, to t, returning a function restoring the previous time.
+func (a *App) FreezeTime(t time.Time) (restore func()) {
+	previous := a.Env.clock.set(func() time.Time { return t })
+	return func() {
+		a.Env.clock.set(previous)
+	}
+}
@@ -0,0 +1,69 @@
+package flotilla
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSeedSession(t *testing.T) {
+	var seen, user interface{}
+
+	a := New("testSeedSession", Mode("testing", true))
+	a.GET("/seeded", func(c Ctx) {
+		seen, _ = c.Call("getsession", "value")
+		user, _ = c.Call("getsession", UserSessionKey)
+		c.Call("setsession", "set", "by handler")
+		c.Call("securecookie", "signed", "signed value")
+	})
+
+	rq, _ := http.NewRequest("GET", "/seeded", nil)
+	if err := a.SeedUser(rq, "alice", map[string]interface{}{"value": "seeded"}); err != nil {
+		t.Fatal(err)
+	}
+	rw := httptest.NewRecorder()
+	a.ServeHTTP(rw, rq)
+
+	if seen != "seeded" || user != "alice" {
+		t.Errorf("Seeded session values were not available: %v %v", seen, user)
+	}
+
+	cookies := rw.Result().Cookies()
+	if v := a.SessionValue(cookies, "set"); v != "by handler" {
+		t.Errorf("Expected session value set by handler, but was %v", v)
+	}
+	for _, ck := range cookies {
+		if ck.Name == "signed" {
+			if v, ok := a.ReadSecureCookie(ck); !ok || v != "signed value" {
+				t.Errorf("Secure cookie was not decoded: %q %t", v, ok)
+			}
+			ck.Value = ck.Value + "0"
+			if _, ok := a.ReadSecureCookie(ck); ok {
+				t.Errorf("A tampered secure cookie was reported valid")
+			}
+		}
+	}
+}
+
+func TestFreezeTime(t *testing.T) {
+	a := New("testFreezeTime", Mode("testing", true), EnvItem("SECRET_KEYS:frozen"))
+	a.GET("/signed", SignedUrl, func(c Ctx) { c.Call("serveplain", 200, "signed") })
+	client := a.TestClient()
+
+	_, key, _ := a.Env.KeyRing().Derive("flotilla signed url").Current()
+	signed, _ := SignUrl(key, "/signed", time.Now().Add(time.Minute))
+
+	restore := a.FreezeTime(time.Now().Add(time.Hour))
+	if res := client.Get(signed); res.Status != 403 {
+		t.Errorf("Signed url did not expire with frozen time, got %d", res.Status)
+	}
+	if other := New("testFreezeTimeOther", Mode("testing", true)); time.Since(other.Env.Now()) > time.Minute {
+		t.Errorf("Frozen time of an App was used by another App")
+	}
+	restore()
+
+	if res := client.Get(signed); res.Status != 200 {
+		t.Errorf("Signed url was not valid after restoring time, got %d", res.Status)
+	}
+}
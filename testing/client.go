@@ -0,0 +1,153 @@
+// Package testing provides an in-process client for testing flotilla Apps, or
+// any http.Handler, without a network listener.
+package testing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// RequestHeader identifies each request made by a Client, correlating
+// rendered templates reported with Rendered to their response.
+const RequestHeader = "X-Test-Request"
+
+// T is the subset of testing.TB used by Response assertions.
+type T interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// Client performs requests against an http.Handler, keeping cookies, and so
+// sessions, between requests.
+type Client struct {
+	Handler http.Handler
+	BaseURL *url.URL
+	Header  http.Header
+	Jar     http.CookieJar
+
+	mu        sync.Mutex
+	next      int
+	templates map[string][]string
+}
+
+// NewClient returns a Client for the provided http.Handler.
+func NewClient(h http.Handler) *Client {
+	jar, _ := cookiejar.New(nil)
+	base, _ := url.Parse("http://localhost")
+	return &Client{
+		Handler:   h,
+		BaseURL:   base,
+		Header:    make(http.Header),
+		Jar:       jar,
+		templates: make(map[string][]string),
+	}
+}
+
+type clientkey struct{}
+
+// RequestClient returns the Client performing the request, if any, and the
+// RequestHeader value identifying the request to it.
+func RequestClient(rq *http.Request) (*Client, string) {
+	c, _ := rq.Context().Value(clientkey{}).(*Client)
+	return c, rq.Header.Get(RequestHeader)
+}
+
+// Rendered records a template rendered for the request with the provided
+// RequestHeader value.
+func (c *Client) Rendered(id string, name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.templates[id]; ok {
+		c.templates[id] = append(c.templates[id], name)
+	}
+}
+
+// Do performs the request, sending any Client headers and cookies and storing
+// any cookies set by the response. Redirects are not followed.
+func (c *Client) Do(rq *http.Request) *Response {
+	rq.URL = c.BaseURL.ResolveReference(rq.URL)
+	rq.Host = rq.URL.Host
+	for k, v := range c.Header {
+		if _, exists := rq.Header[k]; !exists {
+			rq.Header[k] = v
+		}
+	}
+	for _, ck := range c.Jar.Cookies(rq.URL) {
+		rq.AddCookie(ck)
+	}
+
+	c.mu.Lock()
+	c.next++
+	id := strconv.Itoa(c.next)
+	c.templates[id] = nil
+	c.mu.Unlock()
+	rq.Header.Set(RequestHeader, id)
+	rq = rq.WithContext(context.WithValue(rq.Context(), clientkey{}, c))
+
+	rw := httptest.NewRecorder()
+	c.Handler.ServeHTTP(rw, rq)
+	result := rw.Result()
+	c.Jar.SetCookies(rq.URL, result.Cookies())
+
+	c.mu.Lock()
+	templates := c.templates[id]
+	delete(c.templates, id)
+	c.mu.Unlock()
+
+	return &Response{
+		Status:    rw.Code,
+		Header:    rw.Header(),
+		Body:      rw.Body.Bytes(),
+		Cookies:   result.Cookies(),
+		Templates: templates,
+	}
+}
+
+// Request performs a request with the provided method, path, and body.
+func (c *Client) Request(method, path string, body io.Reader, headers ...string) *Response {
+	rq := httptest.NewRequest(method, path, body)
+	for i := 0; i+1 < len(headers); i += 2 {
+		rq.Header.Set(headers[i], headers[i+1])
+	}
+	return c.Do(rq)
+}
+
+// Get performs a GET request for the path.
+func (c *Client) Get(path string, headers ...string) *Response {
+	return c.Request("GET", path, nil, headers...)
+}
+
+// PostForm performs a POST request for the path with url encoded form values.
+func (c *Client) PostForm(path string, values url.Values) *Response {
+	return c.Request("POST", path, strings.NewReader(values.Encode()), "Content-Type", "application/x-www-form-urlencoded")
+}
+
+// PostJSON performs a POST request for the path with v encoded as JSON.
+func (c *Client) PostJSON(path string, v interface{}) *Response {
+	b, _ := json.Marshal(v)
+	return c.Request("POST", path, bytes.NewReader(b), "Content-Type", "application/json")
+}
+
+// SetCookies stores cookies with the Client, e.g. to seed a session.
+func (c *Client) SetCookies(cookies ...*http.Cookie) {
+	c.Jar.SetCookies(c.BaseURL, cookies)
+}
+
+// Cookie returns the named cookie stored by the Client, if any.
+func (c *Client) Cookie(name string) (*http.Cookie, bool) {
+	for _, ck := range c.Jar.Cookies(c.BaseURL) {
+		if ck.Name == name {
+			return ck, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,115 @@
+package testing
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Response is the recorded response to a Client request.
+type Response struct {
+	Status    int
+	Header    http.Header
+	Body      []byte
+	Cookies   []*http.Cookie
+	Templates []string
+}
+
+// JSON decodes the response body and returns the value at the provided dotted
+// path, e.g. "user.name" or "items.0.id"; an empty path returns the document.
+func (r *Response) JSON(path string) (interface{}, bool) {
+	var doc interface{}
+	if err := json.Unmarshal(r.Body, &doc); err != nil {
+		return nil, false
+	}
+	if path == "" {
+		return doc, true
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch v := doc.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if doc, ok = v[key]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			doc = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return doc, true
+}
+
+// AssertStatus checks the response status.
+func (r *Response) AssertStatus(t T, code int) *Response {
+	t.Helper()
+	if r.Status != code {
+		t.Errorf("response status was %d, expected %d", r.Status, code)
+	}
+	return r
+}
+
+// AssertHeader checks the value of a response header.
+func (r *Response) AssertHeader(t T, key, value string) *Response {
+	t.Helper()
+	if v := r.Header.Get(key); v != value {
+		t.Errorf("response header %s was %q, expected %q", key, v, value)
+	}
+	return r
+}
+
+// AssertBodyContains checks the response body contains s.
+func (r *Response) AssertBodyContains(t T, s string) *Response {
+	t.Helper()
+	if !strings.Contains(string(r.Body), s) {
+		t.Errorf("response body %q does not contain %q", r.Body, s)
+	}
+	return r
+}
+
+// AssertJSON checks the value at the dotted path of a JSON response body.
+// Numbers are compared as float64 after decoding, so expected integers may be
+// provided as any numeric type.
+func (r *Response) AssertJSON(t T, path string, expected interface{}) *Response {
+	t.Helper()
+	v, ok := r.JSON(path)
+	if !ok {
+		t.Errorf("response json has no value at %q: %s", path, r.Body)
+		return r
+	}
+	if !reflect.DeepEqual(v, normalize(expected)) {
+		t.Errorf("response json at %q was %v, expected %v", path, v, expected)
+	}
+	return r
+}
+
+// AssertTemplate checks a template with the provided name was rendered.
+func (r *Response) AssertTemplate(t T, name string) *Response {
+	t.Helper()
+	for _, tmpl := range r.Templates {
+		if tmpl == name {
+			return r
+		}
+	}
+	t.Errorf("template %q was not rendered, rendered %v", name, r.Templates)
+	return r
+}
+
+func normalize(v interface{}) interface{} {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var n interface{}
+	if err := json.Unmarshal(b, &n); err != nil {
+		return v
+	}
+	return n
+}
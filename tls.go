@@ -0,0 +1,103 @@
+package flotilla
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/thrisp/flotilla/xrr"
+)
+
+var NoCertificates = xrr.NewXrror("no PEM certificates could be read from %s").Out
+
+// ClientAuth configures the App TLS listener to request or require client
+// certificates per the provided tls.ClientAuthType, verified against CA
+// certificates read from the provided PEM files.
+func ClientAuth(auth tls.ClientAuthType, cafiles ...string) Configuration {
+	return func(a *App) error {
+		if a.Env.TLS == nil {
+			a.Env.TLS = &tls.Config{}
+		}
+		if a.Env.TLS.ClientCAs == nil && len(cafiles) > 0 {
+			a.Env.TLS.ClientCAs = x509.NewCertPool()
+		}
+		for _, f := range cafiles {
+			pem, err := ioutil.ReadFile(f)
+			if err != nil {
+				return err
+			}
+			if !a.Env.TLS.ClientCAs.AppendCertsFromPEM(pem) {
+				return NoCertificates(f)
+			}
+		}
+		a.Env.TLS.ClientAuth = auth
+		return nil
+	}
+}
+
+// WithTLS sets the tls.Config used by App.RunTLS.
+func WithTLS(conf *tls.Config) Configuration {
+	return func(a *App) error {
+		a.Env.TLS = conf
+		return nil
+	}
+}
+
+// RunTLS configures the App if needed and listens for HTTPS connections on the
+// provided address with the certificate & key files, using any tls.Config set
+// in the App Env, logging and returning any configuration or listener error.
+func (a *App) RunTLS(addr, certFile, keyFile string) error {
+	if err := a.configure(); err != nil {
+		return err
+	}
+	srv := &http.Server{Addr: addr, Handler: a, TLSConfig: a.Env.TLS}
+	return a.listen(srv, func() error { return srv.ListenAndServeTLS(certFile, keyFile) }, "tls", true)
+}
+
+var tlsfxtension = map[string]interface{}{
+	"clientcertificate": clientcertificate,
+}
+
+var TLSFxtension Fxtension = MakeFxtension("tlsfxtension", tlsfxtension)
+
+var NoClientCertificate = xrr.NewXrror("request has no verified client certificate").Out
+
+func clientcertificate(c *ctx) (*x509.Certificate, error) {
+	if c.Request.TLS != nil {
+		for _, chain := range c.Request.TLS.VerifiedChains {
+			if len(chain) > 0 {
+				return chain[0], nil
+			}
+		}
+	}
+	return nil, NoClientCertificate()
+}
+
+// ClientCertificate returns the verified client certificate of the current
+// request and a boolean indicating its existence.
+func ClientCertificate(c Ctx) (*x509.Certificate, bool) {
+	cert, err := c.Call("clientcertificate")
+	if err != nil {
+		return nil, false
+	}
+	return cert.(*x509.Certificate), true
+}
+
+// ClientSubject returns the subject of the verified client certificate of the
+// current request, or an empty string.
+func ClientSubject(c Ctx) string {
+	if cert, ok := ClientCertificate(c); ok {
+		return cert.Subject.String()
+	}
+	return ""
+}
+
+// RequireClientCertificate is a Manage function rejecting requests without a
+// verified client certificate with a 401 status.
+func RequireClientCertificate(c Ctx) {
+	if _, ok := ClientCertificate(c); !ok {
+		Audit(c, AuditPermissionDenied, map[string]string{"reason": "no client certificate"})
+		c.Call("status", 401)
+	}
+}
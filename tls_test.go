@@ -0,0 +1,87 @@
+package flotilla
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testcertificate(t *testing.T, cn string) (*x509.Certificate, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestClientAuthConfiguration(t *testing.T) {
+	_, p := testcertificate(t, "test-ca")
+	dir, _ := ioutil.TempDir("", "flotilla-tls")
+	defer os.RemoveAll(dir)
+	f := filepath.Join(dir, "ca.pem")
+	ioutil.WriteFile(f, p, 0600)
+
+	a := testApp(t, "testClientAuth", ClientAuth(tls.RequireAndVerifyClientCert, f))
+	if a.Env.TLS == nil || a.Env.TLS.ClientAuth != tls.RequireAndVerifyClientCert || a.Env.TLS.ClientCAs == nil {
+		t.Errorf("ClientAuth did not configure the App tls.Config: %+v", a.Env.TLS)
+	}
+
+	bad := filepath.Join(dir, "bad.pem")
+	ioutil.WriteFile(bad, []byte("not a certificate"), 0600)
+	if err := ClientAuth(tls.RequireAndVerifyClientCert, bad)(a); err == nil {
+		t.Errorf("ClientAuth accepted a file without certificates")
+	}
+}
+
+func TestClientCertificate(t *testing.T) {
+	cert, _ := testcertificate(t, "service-a")
+
+	var subject string
+
+	exp, _ := NewExpectation(
+		200,
+		"GET",
+		"/mtls",
+		func(t *testing.T) Manage { return RequireClientCertificate },
+		func(t *testing.T) Manage {
+			return func(c Ctx) { subject = ClientSubject(c) }
+		},
+	)
+	exp.SetPre(func(t *testing.T, r *http.Request) {
+		r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	})
+
+	a := testApp(t, "testClientCertificate")
+
+	SimplePerformer(t, a, exp).Perform()
+
+	if subject != "CN=service-a" {
+		t.Errorf(`Expected client subject "CN=service-a", but received %q`, subject)
+	}
+
+	ZeroExpectationPerformer(t, a, 401, "GET", "/mtls").Perform()
+}
@@ -0,0 +1,102 @@
+// Package tracing provides OpenTelemetry instrumentation for flotilla Apps.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/thrisp/flotilla"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	instrumentation = "github.com/thrisp/flotilla/tracing"
+	contextData     = "_tracecontext"
+)
+
+func tracer() trace.Tracer {
+	return otel.GetTracerProvider().Tracer(instrumentation)
+}
+
+func spanname(method, path string) string {
+	return fmt.Sprintf("%s %s", method, path)
+}
+
+// Handler wraps an http.Handler, usually an App, in a server span carrying any
+// incoming trace context, so requests never reaching a route (e.g. 404 or 405
+// statuses from the engine) are traced as well.
+func Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, rq *http.Request) {
+		parent := otel.GetTextMapPropagator().Extract(rq.Context(), propagation.HeaderCarrier(rq.Header))
+		ctx, span := tracer().Start(parent, spanname(rq.Method, "engine"),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.method", rq.Method),
+				attribute.String("http.target", rq.URL.RequestURI()),
+			),
+		)
+		defer span.End()
+		h.ServeHTTP(rw, rq.WithContext(ctx))
+	})
+}
+
+// Manage is a flotilla.Manage function tracing the Ctx lifecycle in a span named
+// for the route path template, e.g. "GET /user/:name". A span already started
+// by Handler is renamed and reused; otherwise a new server span is started from
+// any incoming trace context. The span records the final status and any panics.
+func Manage(c flotilla.Ctx) {
+	rq := flotilla.CurrentRequest(c)
+	name := spanname(rq.Method, rq.URL.Path)
+	if rt := flotilla.CurrentRoute(c); rt != nil {
+		name = spanname(rq.Method, rt.Path)
+	}
+
+	ctx := rq.Context()
+	span := trace.SpanFromContext(ctx)
+	owned := !span.SpanContext().IsValid()
+	if owned {
+		parent := otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(rq.Header))
+		ctx, span = tracer().Start(parent, name, trace.WithSpanKind(trace.SpanKindServer))
+	} else {
+		span.SetName(name)
+	}
+	if rt := flotilla.CurrentRoute(c); rt != nil {
+		span.SetAttributes(attribute.String("http.route", rt.Path))
+	}
+	c.Call("set", contextData, ctx)
+
+	c.Call("pushfinal", func(c flotilla.Ctx) {
+		rw, _ := c.Call("responsewriter")
+		status := rw.(flotilla.ResponseWriter).Status()
+		span.SetAttributes(attribute.Int("http.status_code", status))
+		for _, p := range flotilla.Panics(c) {
+			span.RecordError(p)
+		}
+		if status >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		if owned {
+			span.End()
+		}
+	})
+}
+
+// Context returns the context.Context carrying the span of the Ctx, for passing
+// to instrumented clients.
+func Context(c flotilla.Ctx) context.Context {
+	if ctx, err := c.Call("get", contextData); err == nil {
+		return ctx.(context.Context)
+	}
+	return flotilla.CurrentRequest(c).Context()
+}
+
+// StartSpan starts a child span of the Ctx span, returning a context carrying
+// it. Spans started with StartSpan must be ended by the caller.
+func StartSpan(c flotilla.Ctx, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return tracer().Start(Context(c), name, opts...)
+}
@@ -0,0 +1,122 @@
+package tracing
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/thrisp/flotilla"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recorder records the spans of the test in memory, restoring the global
+// TracerProvider when the test ends.
+func recorder(t *testing.T) *tracetest.SpanRecorder {
+	sr := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return sr
+}
+
+func ended(sr *tracetest.SpanRecorder) map[string]sdktrace.ReadOnlySpan {
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, s := range sr.Ended() {
+		spans[s.Name()] = s
+	}
+	return spans
+}
+
+func attributes(s sdktrace.ReadOnlySpan) map[string]string {
+	attrs := make(map[string]string)
+	for _, kv := range s.Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	return attrs
+}
+
+func testApp() *flotilla.App {
+	a := flotilla.New("testTracing", flotilla.Mode("testing", true))
+	a.GET("/traced/:name", Manage, func(c flotilla.Ctx) {
+		_, span := StartSpan(c, "child")
+		span.End()
+	})
+	a.GET("/panic", Manage, func(c flotilla.Ctx) { panic("traced panic") })
+	a.Configure()
+	return a
+}
+
+func TestTracing(t *testing.T) {
+	sr := recorder(t)
+
+	rw := httptest.NewRecorder()
+	Handler(testApp()).ServeHTTP(rw, httptest.NewRequest("GET", "/traced/test", nil))
+	if rw.Code != 200 {
+		t.Errorf("Traced request status was %d, expected 200", rw.Code)
+	}
+
+	spans := ended(sr)
+	server, child := spans["GET /traced/:name"], spans["child"]
+	if server == nil || child == nil || len(spans) != 2 {
+		t.Fatalf("expected a server span renamed for the route and a child span, got %v", spans)
+	}
+	if server.SpanKind() != trace.SpanKindServer {
+		t.Errorf("expected a server span, got kind %v", server.SpanKind())
+	}
+	attrs := attributes(server)
+	for k, v := range map[string]string{
+		"http.method":      "GET",
+		"http.target":      "/traced/test",
+		"http.route":       "/traced/:name",
+		"http.status_code": "200",
+	} {
+		if attrs[k] != v {
+			t.Errorf("expected server span attribute %s %q, got %q", k, v, attrs[k])
+		}
+	}
+	if child.Parent().SpanID() != server.SpanContext().SpanID() || child.SpanContext().TraceID() != server.SpanContext().TraceID() {
+		t.Error("expected the span of StartSpan to be a child of the server span")
+	}
+}
+
+func TestTracingManageOnly(t *testing.T) {
+	sr := recorder(t)
+
+	a := testApp()
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/traced/test", nil))
+	spans := ended(sr)
+	server, child := spans["GET /traced/:name"], spans["child"]
+	if server == nil || child == nil || len(spans) != 2 {
+		t.Fatalf("expected a server span started and ended by Manage, got %v", spans)
+	}
+	if server.Parent().IsValid() || child.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Error("expected a root server span parenting the child span")
+	}
+
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/panic", nil))
+	failed := ended(sr)["GET /panic"]
+	if failed == nil {
+		t.Fatal("expected a span for the panicking route")
+	}
+	if attributes(failed)["http.status_code"] != "500" || failed.Status().Code != codes.Error {
+		t.Errorf("expected a 500 error span, got %v %v", attributes(failed), failed.Status())
+	}
+}
+
+func TestTracingUnrouted(t *testing.T) {
+	sr := recorder(t)
+
+	rw := httptest.NewRecorder()
+	Handler(testApp()).ServeHTTP(rw, httptest.NewRequest("GET", "/missing", nil))
+	spans := ended(sr)
+	engine := spans["GET engine"]
+	if rw.Code != 404 || engine == nil || len(spans) != 1 {
+		t.Fatalf("expected a single engine span for an unrouted request, got %d %v", rw.Code, spans)
+	}
+	if attrs := attributes(engine); attrs["http.target"] != "/missing" || attrs["http.route"] != "" {
+		t.Errorf("unexpected engine span attributes %v", attrs)
+	}
+}
@@ -0,0 +1,294 @@
+package flotilla
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/thrisp/flotilla/xrr"
+)
+
+const uploadsData = "uploads"
+
+var (
+	NoUpload         = xrr.NewXrror("no upload for field %s").Out
+	DisallowedUpload = xrr.NewXrror("upload %q has disallowed content type %s").Out
+)
+
+// Upload is a file uploaded in a multipart request, held in memory or, past
+// the UPLOAD_MEMORY, in a temporary file of the UPLOAD_DIRECTORY removed when
+// the request finishes unless saved.
+type Upload struct {
+	Field       string
+	Filename    string
+	ContentType string
+	Header      textproto.MIMEHeader
+	Size        int64
+	data        []byte
+	path        string
+	temp        bool
+}
+
+// Open returns a reader of the upload content.
+func (u *Upload) Open() (io.ReadCloser, error) {
+	if u.path != "" {
+		return os.Open(u.path)
+	}
+	return io.NopCloser(bytes.NewReader(u.data)), nil
+}
+
+// InMemory reports whether the upload content is held in memory.
+func (u *Upload) InMemory() bool {
+	return u.path == ""
+}
+
+// Save moves or writes the upload content to the file at path.
+func (u *Upload) Save(path string) error {
+	if u.path == "" {
+		return os.WriteFile(path, u.data, 0644)
+	}
+	if u.temp && os.Rename(u.path, path) == nil {
+		u.path, u.temp = path, false
+		return nil
+	}
+	src, err := os.Open(u.path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// uploadsettings are the UPLOAD_MEMORY in bytes held in memory across the
+// uploads of a request, the UPLOAD_TYPES allowed, and the UPLOAD_DIRECTORY
+// of temporary files.
+type uploadsettings struct {
+	memory int64
+	types  []string
+	dir    string
+}
+
+func uploadconfig(c *ctx) *uploadsettings {
+	s := &uploadsettings{}
+	if item, ok := CheckStore(c, "UPLOAD_MEMORY"); ok {
+		s.memory = item.Int64()
+	}
+	if item, ok := CheckStore(c, "UPLOAD_TYPES"); ok && item.Value != "" {
+		s.types = item.List()
+	}
+	if item, ok := CheckStore(c, "UPLOAD_DIRECTORY"); ok {
+		s.dir = item.Value
+	}
+	return s
+}
+
+// allowed reports whether the content type is one of the UPLOAD_TYPES, which
+// may be a type wildcard such as image/*, or any type if none are set.
+func (s *uploadsettings) allowed(contenttype string) bool {
+	if len(s.types) == 0 {
+		return true
+	}
+	for _, t := range s.types {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == contenttype || (strings.HasSuffix(t, "/*") && strings.HasPrefix(contenttype, t[:len(t)-1])) {
+			return true
+		}
+	}
+	return false
+}
+
+// uploadname returns the base name of a client provided file name.
+func uploadname(name string) string {
+	name = filepath.Base(filepath.Clean("/" + strings.ReplaceAll(name, `\`, "/")))
+	if name == "/" || name == "." {
+		return ""
+	}
+	return name
+}
+
+// receive reads a file part, checking its sniffed content type, into memory
+// while within the remaining memory, or else into a temporary file.
+func (s *uploadsettings) receive(p *multipart.Part, memory *int64) (*Upload, error) {
+	u := &Upload{Field: p.FormName(), Filename: uploadname(p.FileName()), Header: p.Header}
+	br := bufio.NewReader(p)
+	head, _ := br.Peek(512)
+	u.ContentType, _, _ = mime.ParseMediaType(http.DetectContentType(head))
+	if !s.allowed(u.ContentType) {
+		return nil, DisallowedUpload(u.Filename, u.ContentType)
+	}
+	var buf bytes.Buffer
+	n, err := io.CopyN(&buf, br, *memory+1)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if n <= *memory {
+		*memory -= n
+		u.data, u.Size = buf.Bytes(), n
+		return u, nil
+	}
+	f, err := os.CreateTemp(s.dir, "upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	u.path, u.temp = f.Name(), true
+	if u.Size, err = io.Copy(f, io.MultiReader(&buf, br)); err != nil {
+		os.Remove(u.path)
+		return nil, err
+	}
+	return u, nil
+}
+
+func removeuploads(uploads map[string][]*Upload) {
+	for _, us := range uploads {
+		for _, u := range us {
+			if u.temp {
+				os.Remove(u.path)
+			}
+		}
+	}
+}
+
+// parseduploads are the Uploads of a request, or the error parsing them, as
+// the request body is read only once.
+type parseduploads struct {
+	uploads map[string][]*Upload
+	err     error
+}
+
+// uploads streams the parts of a multipart request once, limited to the
+// UPLOAD_SIZE, keeping file parts as Uploads and setting the request form
+// values from the other parts. Later calls return the same Uploads, or the
+// same error.
+func uploads(c *ctx) (map[string][]*Upload, error) {
+	if p, ok := c.Data[uploadsData].(*parseduploads); ok {
+		return p.uploads, p.err
+	}
+	ret, err := parseuploads(c)
+	if err != nil {
+		ret = nil
+	}
+	setdata(c, uploadsData, &parseduploads{ret, err})
+	return ret, err
+}
+
+func parseuploads(c *ctx) (map[string][]*Upload, error) {
+	limit := limitbody(c)
+	mr, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	s := uploadconfig(c)
+	ret := make(map[string][]*Upload)
+	c.pushfinal(func(Ctx) { removeuploads(ret) })
+	values := make(url.Values)
+	memory := s.memory
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, bodyerror(err, limit)
+		}
+		name := p.FormName()
+		if name == "" {
+			continue
+		}
+		if p.FileName() == "" {
+			b, err := io.ReadAll(p)
+			if err != nil {
+				return nil, bodyerror(err, limit)
+			}
+			values[name] = append(values[name], string(b))
+			continue
+		}
+		u, err := s.receive(p, &memory)
+		if err != nil {
+			return nil, bodyerror(err, limit)
+		}
+		ret[name] = append(ret[name], u)
+	}
+	c.Request.MultipartForm = &multipart.Form{Value: values}
+	c.Request.PostForm = values
+	if c.Request.Form == nil {
+		c.Request.Form = c.Request.URL.Query()
+		for k, v := range values {
+			c.Request.Form[k] = append(c.Request.Form[k], v...)
+		}
+	}
+	return ret, nil
+}
+
+func formfile(c *ctx, field string) (*Upload, error) {
+	ret, err := uploads(c)
+	if err != nil {
+		return nil, err
+	}
+	if us := ret[field]; len(us) > 0 {
+		return us[0], nil
+	}
+	return nil, NoUpload(field)
+}
+
+// savefile saves the upload of the field to dst, or to its file name within
+// dst if dst is a directory, returning the saved path.
+func savefile(c *ctx, field, dst string) (string, error) {
+	u, err := formfile(c, field)
+	if err != nil {
+		return "", err
+	}
+	if fi, err := os.Stat(dst); err == nil && fi.IsDir() {
+		if u.Filename == "" {
+			return "", NoUpload(field)
+		}
+		dst = filepath.Join(dst, u.Filename)
+	}
+	return dst, u.Save(dst)
+}
+
+var uploadfxtension = map[string]interface{}{
+	"formfile": formfile,
+	"savefile": savefile,
+}
+
+// UploadFxtension streams multipart uploads within the UPLOAD_SIZE, holding
+// up to the UPLOAD_MEMORY in memory and the rest in temporary files of the
+// UPLOAD_DIRECTORY, and rejecting files not of the UPLOAD_TYPES.
+var UploadFxtension Fxtension = MakeFxtension("uploadfxtension", uploadfxtension)
+
+// FormFile returns the first upload of the named field in a multipart
+// request.
+func FormFile(c Ctx, field string) (*Upload, error) {
+	u, err := c.Call("formfile", field)
+	if err != nil {
+		return nil, err
+	}
+	return u.(*Upload), nil
+}
+
+// SaveFile saves the first upload of the named field to dst, or to its file
+// name within dst if dst is a directory, returning the saved path.
+func SaveFile(c Ctx, field, dst string) (string, error) {
+	path, err := c.Call("savefile", field, dst)
+	if err != nil {
+		return "", err
+	}
+	return path.(string), nil
+}
@@ -0,0 +1,97 @@
+package flotilla
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func multipartbody(fields map[string]string, files map[string][]byte) (*bytes.Buffer, string) {
+	var b bytes.Buffer
+	w := multipart.NewWriter(&b)
+	for k, v := range fields {
+		w.WriteField(k, v)
+	}
+	for name, data := range files {
+		fw, _ := w.CreateFormFile(name, "../"+name+".bin")
+		fw.Write(data)
+	}
+	w.Close()
+	return &b, w.FormDataContentType()
+}
+
+func TestUploads(t *testing.T) {
+	tmp, saved := t.TempDir(), t.TempDir()
+	a := New(
+		"testUploads",
+		Mode("testing", true),
+		EnvItem("UPLOAD_MEMORY:16", "UPLOAD_TYPES:image/*,text/plain", "UPLOAD_DIRECTORY:"+tmp),
+	)
+	var small, large *Upload
+	var path, title string
+	var uerr error
+	a.POST("/upload", func(c Ctx) {
+		small, large, path, title, uerr = nil, nil, "", "", nil
+		if small, uerr = FormFile(c, "small"); uerr != nil {
+			return
+		}
+		if large, uerr = FormFile(c, "large"); uerr != nil {
+			return
+		}
+		title = CurrentRequest(c).FormValue("title")
+		path, uerr = SaveFile(c, "large", saved)
+	})
+	var retried error
+	a.POST("/retry", func(c Ctx) {
+		_, uerr = FormFile(c, "small")
+		_, retried = FormFile(c, "small")
+	})
+	a.Configure()
+	postto := func(route string, files map[string][]byte) {
+		body, ct := multipartbody(map[string]string{"title": "uploaded"}, files)
+		rq := httptest.NewRequest("POST", route, body)
+		rq.Header.Set("Content-Type", ct)
+		a.ServeHTTP(httptest.NewRecorder(), rq)
+	}
+	post := func(files map[string][]byte) { postto("/upload", files) }
+
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 100)...)
+	post(map[string][]byte{"small": []byte("hello"), "large": png})
+	if uerr != nil {
+		t.Fatalf("unexpected upload error %v", uerr)
+	}
+	if !small.InMemory() || small.ContentType != "text/plain" || small.Filename != "small.bin" {
+		t.Errorf("unexpected small upload %+v", small)
+	}
+	if large.InMemory() || large.ContentType != "image/png" || large.Size != int64(len(png)) {
+		t.Errorf("unexpected large upload %+v", large)
+	}
+	if title != "uploaded" {
+		t.Errorf("expected form values from the multipart body, got %q", title)
+	}
+	if b, err := os.ReadFile(path); err != nil || !bytes.Equal(b, png) || filepath.Dir(path) != saved {
+		t.Errorf("expected the upload saved in %s, got %s %v", saved, path, err)
+	}
+	r, _ := small.Open()
+	if b, _ := io.ReadAll(r); string(b) != "hello" {
+		t.Errorf("unexpected small upload content %q", b)
+	}
+	if left, _ := os.ReadDir(tmp); len(left) != 0 {
+		t.Errorf("expected temporary uploads to be removed, found %d", len(left))
+	}
+
+	post(map[string][]byte{"small": []byte("%PDF-1.4 not allowed")})
+	if uerr == nil || !strings.Contains(uerr.Error(), "disallowed content type application/pdf") {
+		t.Errorf("expected a disallowed type error, got %v", uerr)
+	}
+
+	postto("/retry", map[string][]byte{"small": []byte("%PDF-1.4 not allowed")})
+	if uerr == nil || retried == nil || retried.Error() != uerr.Error() {
+		t.Errorf("expected the parse error again after a failed parse, got %v then %v", uerr, retried)
+	}
+}
@@ -4,10 +4,40 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/thrisp/flotilla/xrr"
 )
 
+// clock is the time source of an Env for expiry related functions, time.Now
+// unless set with WithClock or App.FreezeTime.
+type clock struct {
+	mu  sync.RWMutex
+	now func() time.Time
+}
+
+// set sets the function returning the current time, returning the previous
+// function.
+func (c *clock) set(now func() time.Time) func() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	previous := c.now
+	c.now = now
+	return previous
+}
+
+// Now returns the current time of the clock.
+func (c *clock) Now() time.Time {
+	c.mu.RLock()
+	now := c.now
+	c.mu.RUnlock()
+	if now == nil {
+		return time.Now()
+	}
+	return now()
+}
+
 var (
 	rferrorType = reflect.TypeOf((*error)(nil)).Elem()
 
@@ -132,6 +162,13 @@ func call(fn reflect.Value, args ...interface{}) (interface{}, error) {
 	if len(result) == 2 && !result[1].IsNil() {
 		return result[0].Interface(), result[1].Interface().(error)
 	}
+	// a function returning only an error returns it as the error
+	if len(result) == 1 && typ.Out(0) == rferrorType {
+		if result[0].IsNil() {
+			return nil, nil
+		}
+		return nil, result[0].Interface().(error)
+	}
 	return result[0].Interface(), nil
 }
 
@@ -197,3 +234,12 @@ func LogFmt(c *ctx) string {
 		c.Result.RPath,
 	)
 }
+
+// logrequest logs the status, method, path, latency, and requester of the
+// ctx as fields of the request Logger.
+func logrequest(c *ctx) {
+	if l, err := c.Call("logger"); err == nil {
+		r := c.Result
+		l.(Logger).Info("request", "status", r.RStatus, "method", r.RMethod, "path", r.RPath, "latency", r.RLatency, "requester", r.RRequester)
+	}
+}
@@ -0,0 +1,266 @@
+package flotilla
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thrisp/flotilla/xrr"
+)
+
+// ValidatorFunc reports whether the field value satisfies a validation rule
+// with the provided parameter, e.g. "3" for the rule "min=3".
+type ValidatorFunc func(v reflect.Value, param string) bool
+
+// FieldError is a field failing a validation rule.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors are the fields failing validation, as returned by
+// Validate and the bind extensions.
+type ValidationErrors []FieldError
+
+func (v ValidationErrors) Error() string {
+	msgs := make([]string, len(v))
+	for i, fe := range v {
+		msgs[i] = fe.Field + " " + fe.Message
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// For returns the messages of the named field, e.g. for template rendering
+// with {{ range .errors.For "email" }}.
+func (v ValidationErrors) For(field string) []string {
+	var ret []string
+	for _, fe := range v {
+		if fe.Field == field {
+			ret = append(ret, fe.Message)
+		}
+	}
+	return ret
+}
+
+// Map returns the messages of each failing field.
+func (v ValidationErrors) Map() map[string][]string {
+	ret := make(map[string][]string)
+	for _, fe := range v {
+		ret[fe.Field] = append(ret[fe.Field], fe.Message)
+	}
+	return ret
+}
+
+var UnknownValidator = xrr.NewXrror("unknown validation rule %q").Out
+
+var validators = map[string]ValidatorFunc{
+	"required": validaterequired,
+	"min":      validatemin,
+	"max":      validatemax,
+	"len":      validatelen,
+	"email":    validateemail,
+	"oneof":    validateoneof,
+}
+
+var validatormessages = map[string]string{
+	"required": "is required",
+	"min":      "must be at least %s",
+	"max":      "must be at most %s",
+	"len":      "must have length %s",
+	"email":    "must be an email address",
+	"oneof":    "must be one of %s",
+}
+
+func validaterequired(v reflect.Value, _ string) bool {
+	return !v.IsZero()
+}
+
+// measure returns the length of strings, slices, and maps, or the value of
+// numbers, compared by the min, max, and len rules.
+func measure(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(len([]rune(v.String()))), true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+func compare(v reflect.Value, param string, fn func(float64, float64) bool) bool {
+	n, ok := measure(v)
+	p, err := strconv.ParseFloat(param, 64)
+	return ok && err == nil && fn(n, p)
+}
+
+func validatemin(v reflect.Value, param string) bool {
+	return compare(v, param, func(n, p float64) bool { return n >= p })
+}
+
+func validatemax(v reflect.Value, param string) bool {
+	return compare(v, param, func(n, p float64) bool { return n <= p })
+}
+
+func validatelen(v reflect.Value, param string) bool {
+	return compare(v, param, func(n, p float64) bool { return n == p })
+}
+
+func validateemail(v reflect.Value, _ string) bool {
+	if v.Kind() != reflect.String {
+		return false
+	}
+	a, err := mail.ParseAddress(v.String())
+	return err == nil && a.Address == v.String()
+}
+
+func validateoneof(v reflect.Value, param string) bool {
+	s := fmt.Sprint(v.Interface())
+	for _, o := range strings.Fields(param) {
+		if s == o {
+			return true
+		}
+	}
+	return false
+}
+
+// AddValidator adds a ValidatorFunc for the named rule, used in validate
+// struct tags, e.g. `validate:"required,slug"`.
+func (env *Env) AddValidator(name string, fn ValidatorFunc) {
+	if env.validators == nil {
+		env.validators = make(map[string]ValidatorFunc)
+	}
+	env.validators[name] = fn
+}
+
+// Validator is a Configuration adding a ValidatorFunc for the named rule.
+func Validator(name string, fn ValidatorFunc) Configuration {
+	return func(a *App) error {
+		a.Env.AddValidator(name, fn)
+		return nil
+	}
+}
+
+func (env *Env) validator(rule string) (ValidatorFunc, bool) {
+	if fn, ok := env.validators[rule]; ok {
+		return fn, true
+	}
+	fn, ok := validators[rule]
+	return fn, ok
+}
+
+// Validate checks the struct v, or v points to, against the rules of its
+// validate struct tags, returning ValidationErrors for failing fields. Rules
+// other than required are skipped for zero values, and fields are named by
+// their json tag.
+func (env *Env) Validate(v interface{}) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	var errs ValidationErrors
+	if err := env.validatestruct(rv, "", &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+var validateTime = reflect.TypeOf(time.Time{})
+
+func (env *Env) validatestruct(rv reflect.Value, prefix string, errs *ValidationErrors) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		fv := rv.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			name = f.Name
+		}
+		if f.Anonymous {
+			name = ""
+		}
+		name = prefix + name
+		if tag := f.Tag.Get("validate"); tag != "" && tag != "-" {
+			if err := env.validatefield(fv, name, tag, errs); err != nil {
+				return err
+			}
+		}
+		if sv := reflect.Indirect(fv); sv.Kind() == reflect.Struct && sv.Type() != validateTime {
+			p := name + "."
+			if name == "" {
+				p = prefix
+			}
+			if err := env.validatestruct(sv, p, errs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (env *Env) validatefield(fv reflect.Value, name, tag string, errs *ValidationErrors) error {
+	for _, rule := range strings.Split(tag, ",") {
+		rule, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		fn, ok := env.validator(rule)
+		if !ok {
+			return UnknownValidator(rule)
+		}
+		v := fv
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if rule == "required" {
+					*errs = append(*errs, fielderror(name, rule, param))
+				}
+				continue
+			}
+			v = v.Elem()
+		}
+		if rule != "required" && v.IsZero() {
+			continue
+		}
+		if !fn(v, param) {
+			*errs = append(*errs, fielderror(name, rule, param))
+		}
+	}
+	return nil
+}
+
+func fielderror(field, rule, param string) FieldError {
+	msg, ok := validatormessages[rule]
+	if !ok {
+		msg = "must satisfy " + rule
+	}
+	if strings.Contains(msg, "%s") {
+		msg = fmt.Sprintf(msg, param)
+	}
+	return FieldError{Field: field, Rule: rule, Param: param, Message: msg}
+}
+
+func validatefunc(a *App) func(*ctx, interface{}) error {
+	return func(c *ctx, v interface{}) error {
+		return a.Env.Validate(v)
+	}
+}
+
+// Validate checks v against the rules of its validate struct tags, with the
+// validators of the App, returning ValidationErrors for failing fields.
+func Validate(c Ctx, v interface{}) error {
+	_, err := c.Call("validate", v)
+	return err
+}
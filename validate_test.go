@@ -0,0 +1,67 @@
+package flotilla
+
+import (
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type validateaddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type validatetarget struct {
+	Name    string          `json:"name" validate:"required,min=3,max=10"`
+	Email   string          `json:"email" validate:"email"`
+	Role    string          `json:"role" validate:"oneof=admin user"`
+	Code    string          `json:"code" validate:"len=4"`
+	Age     *int            `json:"age" validate:"required,min=18"`
+	Slug    string          `json:"slug" validate:"slug"`
+	Address validateaddress `json:"address"`
+}
+
+func TestValidate(t *testing.T) {
+	slug := func(v reflect.Value, _ string) bool {
+		return !strings.ContainsAny(v.String(), " /")
+	}
+	a := New("testValidate", Mode("testing", true), Validator("slug", slug))
+	var verr error
+	a.POST("/validate", func(c Ctx) {
+		var v validatetarget
+		verr = BindJSON(c, &v)
+	})
+	a.Configure()
+	post := func(body string) {
+		rq := httptest.NewRequest("POST", "/validate", strings.NewReader(body))
+		rq.Header.Set("Content-Type", "application/json")
+		a.ServeHTTP(httptest.NewRecorder(), rq)
+	}
+
+	post(`{"name":"valid","email":"a@b.com","role":"user","code":"abcd","age":20,"slug":"ok","address":{"city":"x"}}`)
+	if verr != nil {
+		t.Errorf("unexpected validation error %v", verr)
+	}
+
+	post(`{"name":"ab","email":"nope","role":"root","code":"abc","slug":"not ok"}`)
+	var ve ValidationErrors
+	if !errors.As(verr, &ve) {
+		t.Fatalf("expected ValidationErrors, got %v", verr)
+	}
+	m := ve.Map()
+	for _, field := range []string{"name", "email", "role", "code", "age", "slug", "address.city"} {
+		if len(m[field]) != 1 {
+			t.Errorf("expected a single error for %s, got %v", field, m[field])
+		}
+	}
+	if got := ve.For("name"); len(got) != 1 || got[0] != "must be at least 3" {
+		t.Errorf("unexpected name errors %v", got)
+	}
+
+	if err := a.Env.Validate(&struct {
+		Name string `validate:"unknown"`
+	}{"x"}); err == nil || errors.As(err, &ve) {
+		t.Errorf("expected an unknown rule error, got %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package flotilla
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/thrisp/flotilla/engine"
+)
+
+// VersionBy is how the Version Blueprints of a Blueprint are selected.
+type VersionBy int
+
+const (
+	// VersionPath selects a version by path prefix, e.g. /v1/users.
+	VersionPath VersionBy = iota
+	// VersionHeader selects a version by a vendor media type of the Accept
+	// header, e.g. Accept: application/vnd.x.v1+json, responding with a 406
+	// status to an unknown version.
+	VersionHeader
+	// VersionQuery selects a version by a query value, e.g.
+	// /users?version=v1, responding with a 404 status to an unknown version.
+	VersionQuery
+)
+
+// versions holds the Version Blueprints of a Blueprint, and the routes of each
+// version with the same method, host, and path, dispatched by the version of
+// the request.
+type versions struct {
+	by       VersionBy
+	key      string
+	names    []string
+	dispatch map[string]map[string]*Route
+}
+
+// Versioning sets how the Version Blueprints of the Blueprint, made after, are
+// selected: by path prefix, the default; by the vendor media type of the
+// Accept header, of the key vendor or any if empty; or by the query value of
+// the key, version if empty.
+func (b *Blueprint) Versioning(by VersionBy, key string) {
+	if by == VersionQuery && key == "" {
+		key = "version"
+	}
+	b.versions = &versions{by: by, key: key, dispatch: make(map[string]map[string]*Route)}
+}
+
+// Version returns a child Blueprint of the routes of a version of the
+// Blueprint, with the managers, selected as set with Versioning, e.g.
+// b.Version("v1").GET("/users", h). Requests selecting no version are
+// dispatched to the first Version of the Blueprint.
+func (b *Blueprint) Version(version string, managers ...Manage) *Blueprint {
+	if b.versions == nil || b.versions.by == VersionPath {
+		return b.NewBlueprint(version, managers...)
+	}
+	b.versions.names = append(b.versions.names, version)
+	vb := b.NewBlueprint("", managers...)
+	vb.version = version
+	return vb
+}
+
+// handle adds the route to the engine of the App, or to the dispatch of its
+// version by header or query.
+func (b *Blueprint) handle(rt *Route) bool {
+	if b.version == "" {
+		return b.app.handle(rt)
+	}
+	return b.versions.handle(b.app, b.version, rt)
+}
+
+func (vs *versions) handle(a *App, version string, rt *Route) bool {
+	key := strings.Join([]string{rt.Host, rt.Method, rt.Path}, " ")
+	d, ok := vs.dispatch[key]
+	if !ok {
+		d = make(map[string]*Route)
+		if !a.handleRule(rt.Host, rt.Method, rt.Path, vs.rule(a, d)) {
+			return false
+		}
+		vs.dispatch[key] = d
+	}
+	if _, ok := d[version]; ok {
+		a.conflict(DuplicateRoute(rt.Method, rt.Path))
+		return false
+	}
+	if rt.name == "" {
+		rt.name = Named(rt) + `\` + version
+	}
+	d[version] = rt
+	return true
+}
+
+// rule returns an engine Rule running the route of the version of the
+// request, or of the first version if none.
+func (vs *versions) rule(a *App, d map[string]*Route) engine.Rule {
+	return func(rw http.ResponseWriter, rq *http.Request, rs *engine.Result) {
+		if vs.by == VersionHeader {
+			rw.Header().Add("Vary", "Accept")
+		}
+		version, requested := vs.requested(rq)
+		if !requested && len(vs.names) > 0 {
+			version = vs.names[0]
+		}
+		if rt, ok := d[version]; ok {
+			rt.rule(rw, rq, rs)
+			return
+		}
+		code := 404
+		if requested && vs.by == VersionHeader {
+			code = 406
+		}
+		s, _ := HasCustomStatus(a, code)
+		statusrule(a, s)(rw, rq, engine.NewResult(code, nil, rs.Params, false))
+	}
+}
+
+// requested returns the version selected by the request, if any.
+func (vs *versions) requested(rq *http.Request) (string, bool) {
+	switch vs.by {
+	case VersionHeader:
+		for _, mt := range strings.Split(rq.Header.Get("Accept"), ",") {
+			mt = strings.TrimSpace(strings.SplitN(mt, ";", 2)[0])
+			if !strings.HasPrefix(mt, "application/vnd.") {
+				continue
+			}
+			mt = strings.SplitN(strings.TrimPrefix(mt, "application/vnd."), "+", 2)[0]
+			if i := strings.LastIndexByte(mt, '.'); i > 0 && (vs.key == "" || mt[:i] == vs.key) {
+				return mt[i+1:], true
+			}
+		}
+	case VersionQuery:
+		if v := rq.URL.Query().Get(vs.key); v != "" {
+			return v, true
+		}
+	}
+	return "", false
+}
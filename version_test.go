@@ -0,0 +1,60 @@
+package flotilla
+
+import "testing"
+
+func TestVersion(t *testing.T) {
+	var routed string
+	handler := func(name string) Manage {
+		return func(c Ctx) { routed = name }
+	}
+
+	p := New("testVersionPath", Mode("testing", true))
+	api := p.NewBlueprint("/api")
+	api.Version("v1").GET("/users", handler("v1"))
+	api.Version("v2").GET("/users", handler("v2"))
+	if p.TestClient().Get("/api/v2/users"); routed != "v2" {
+		t.Errorf("expected a path version routed, got %q", routed)
+	}
+
+	h := New("testVersionHeader", Mode("testing", true))
+	api = h.NewBlueprint("/api")
+	api.Versioning(VersionHeader, "x")
+	var v1ran bool
+	api.Version("v1", func(c Ctx) { v1ran = true }).GET("/users", handler("v1"))
+	api.Version("v2").GET("/users", handler("v2"))
+	client := h.TestClient()
+	if client.Get("/api/users", "Accept", "application/vnd.x.v2+json"); routed != "v2" || v1ran {
+		t.Errorf("expected a header version routed to its managers, got %q %t", routed, v1ran)
+	}
+	if client.Get("/api/users"); routed != "v1" || !v1ran {
+		t.Errorf("expected the first version without a requested version, got %q", routed)
+	}
+	routed = ""
+	if res := client.Get("/api/users", "Accept", "application/vnd.x.v3+json"); res.Status != 406 || routed != "" {
+		t.Errorf("expected a 406 for an unknown header version, got %d %q", res.Status, routed)
+	}
+	if client.Get("/api/users", "Accept", "application/vnd.other.v2+json"); routed != "v1" {
+		t.Errorf("expected the media type of another vendor ignored, got %q", routed)
+	}
+	var named int
+	for _, rt := range h.Routes() {
+		if rt.Path == "/api/users" {
+			named++
+		}
+	}
+	if named != 2 {
+		t.Errorf("expected each version of a route named apart, got %d", named)
+	}
+
+	q := New("testVersionQuery", Mode("testing", true))
+	q.Versioning(VersionQuery, "")
+	q.Version("v1").GET("/users", handler("v1"))
+	q.Version("v2").GET("/users", handler("v2"))
+	client = q.TestClient()
+	if client.Get("/users?version=v2"); routed != "v2" {
+		t.Errorf("expected a query version routed, got %q", routed)
+	}
+	if res := client.Get("/users?version=v9"); res.Status != 404 {
+		t.Errorf("expected a 404 for an unknown query version, got %d", res.Status)
+	}
+}
@@ -0,0 +1,416 @@
+package flotilla
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thrisp/flotilla/xrr"
+)
+
+// WebSocket message types, the opcodes of RFC 6455.
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+)
+
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxWebSocketMessage caps the messages of a WebSocket without a MaxMessage.
+const maxWebSocketMessage = 32 << 20
+
+var (
+	NotWebSocket       = xrr.NewXrror("not a websocket upgrade request: %s").Out
+	WebSocketOrigin    = xrr.NewXrror("websocket origin %q is not allowed").Out
+	WebSocketClosed    = xrr.NewXrror("websocket connection closed").Out
+	WebSocketFrame     = xrr.NewXrror("invalid websocket frame: %s").Out
+	WebSocketTooLarge  = xrr.NewXrror("websocket message exceeds %d bytes").Out
+	UnknownMessageType = xrr.NewXrror("unknown websocket message type %d").Out
+)
+
+// WebSocket is a server websocket connection, reading and writing whole
+// messages of at most MaxMessage bytes, or 32 MiB if not set. Reads are not
+// safe for concurrent use; writes are.
+type WebSocket struct {
+	conn       net.Conn
+	br         *bufio.Reader
+	wlock      sync.Mutex
+	closed     bool
+	Protocol   string
+	MaxMessage int64
+}
+
+func headertoken(h http.Header, key, token string) bool {
+	for _, v := range h.Values(key) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// websocketorigin reports whether the Origin of the request is its host, or
+// listed in the WEBSOCKET_ORIGINS, where "*" allows any origin. Requests
+// without an Origin header, i.e. not from a browser, are allowed.
+func websocketorigin(c *ctx) bool {
+	origin := c.Request.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, c.Request.Host) {
+		return true
+	}
+	if item, ok := CheckStore(c, "WEBSOCKET_ORIGINS"); ok && item.Value != "" {
+		for _, o := range item.List() {
+			if o == "*" || strings.EqualFold(o, origin) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func websocketaccept(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// upgrade performs the websocket handshake, selecting the first of protocols
+// requested by the client, and hijacks the connection from the Ctx
+// ResponseWriter, marking the response written.
+func upgrade(c *ctx, protocols ...string) (*WebSocket, error) {
+	rq := c.Request
+	switch {
+	case rq.Method != "GET":
+		return nil, NotWebSocket("method is not GET")
+	case !headertoken(rq.Header, "Connection", "upgrade"):
+		return nil, NotWebSocket("missing connection upgrade")
+	case !headertoken(rq.Header, "Upgrade", "websocket"):
+		return nil, NotWebSocket("missing websocket upgrade")
+	case rq.Header.Get("Sec-Websocket-Version") != "13":
+		return nil, NotWebSocket("unsupported version")
+	case rq.Header.Get("Sec-Websocket-Key") == "":
+		return nil, NotWebSocket("missing key")
+	}
+	if !websocketorigin(c) {
+		c.Call("status", 403)
+		return nil, WebSocketOrigin(rq.Header.Get("Origin"))
+	}
+	ws := &WebSocket{}
+	for _, p := range protocols {
+		if headertoken(rq.Header, "Sec-Websocket-Protocol", p) {
+			ws.Protocol = p
+			break
+		}
+	}
+	if item, ok := CheckStore(c, "WEBSOCKET_MAXMESSAGE"); ok {
+		ws.MaxMessage = item.Int64()
+	}
+	h := c.RW.Header()
+	h.Set("Upgrade", "websocket")
+	h.Set("Connection", "Upgrade")
+	h.Set("Sec-WebSocket-Accept", websocketaccept(rq.Header.Get("Sec-Websocket-Key")))
+	if ws.Protocol != "" {
+		h.Set("Sec-WebSocket-Protocol", ws.Protocol)
+	}
+	conn, brw, err := c.RW.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	c.rw.status, c.rw.size = http.StatusSwitchingProtocols, 0
+	fmt.Fprintf(brw, "HTTP/1.1 101 Switching Protocols\r\n")
+	h.Write(brw)
+	brw.WriteString("\r\n")
+	if err := brw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	ws.conn, ws.br = conn, brw.Reader
+	return ws, nil
+}
+
+// Upgrade upgrades the request to a websocket connection, selecting the
+// first of the provided subprotocols the client requests. Origins other than
+// the request host must be listed in the WEBSOCKET_ORIGINS.
+func Upgrade(c Ctx, protocols ...string) (*WebSocket, error) {
+	args := make([]interface{}, len(protocols))
+	for i, p := range protocols {
+		args[i] = p
+	}
+	ws, err := c.Call("upgrade", args...)
+	if err != nil {
+		return nil, err
+	}
+	return ws.(*WebSocket), nil
+}
+
+// SetReadDeadline sets the deadline for reading the next message.
+func (ws *WebSocket) SetReadDeadline(t time.Time) error {
+	return ws.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the deadline for writing messages.
+func (ws *WebSocket) SetWriteDeadline(t time.Time) error {
+	return ws.conn.SetWriteDeadline(t)
+}
+
+// RemoteAddr returns the address of the client.
+func (ws *WebSocket) RemoteAddr() net.Addr {
+	return ws.conn.RemoteAddr()
+}
+
+// maxmessage returns the MaxMessage of the WebSocket, or maxWebSocketMessage.
+func (ws *WebSocket) maxmessage() int64 {
+	if ws.MaxMessage > 0 {
+		return ws.MaxMessage
+	}
+	return maxWebSocketMessage
+}
+
+type wsframe struct {
+	fin     bool
+	opcode  int
+	payload []byte
+}
+
+func (ws *WebSocket) readframe(limit int64) (*wsframe, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(ws.br, head[:]); err != nil {
+		return nil, err
+	}
+	f := &wsframe{fin: head[0]&0x80 != 0, opcode: int(head[0] & 0x0f)}
+	if head[0]&0x70 != 0 {
+		return nil, WebSocketFrame("reserved bits set")
+	}
+	if head[1]&0x80 == 0 {
+		return nil, WebSocketFrame("client frame not masked")
+	}
+	length := int64(head[1] & 0x7f)
+	switch length {
+	case 126:
+		var b [2]byte
+		if _, err := io.ReadFull(ws.br, b[:]); err != nil {
+			return nil, err
+		}
+		length = int64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		if _, err := io.ReadFull(ws.br, b[:]); err != nil {
+			return nil, err
+		}
+		length = int64(binary.BigEndian.Uint64(b[:]))
+	}
+	if f.opcode >= CloseMessage && (length > 125 || !f.fin) {
+		return nil, WebSocketFrame("invalid control frame")
+	}
+	if length < 0 || length > limit {
+		return nil, WebSocketTooLarge(ws.maxmessage())
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(ws.br, mask[:]); err != nil {
+		return nil, err
+	}
+	// the payload grows as it is read, not to the length the client declares
+	var payload bytes.Buffer
+	if _, err := io.CopyN(&payload, ws.br, length); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	f.payload = payload.Bytes()
+	for i := range f.payload {
+		f.payload[i] ^= mask[i%4]
+	}
+	return f, nil
+}
+
+// ReadMessage returns the type and data of the next text or binary message,
+// answering pings and close frames while reading. WebSocketClosed is returned
+// once the client closes the connection.
+func (ws *WebSocket) ReadMessage() (int, []byte, error) {
+	var opcode int
+	var data []byte
+	for {
+		limit := ws.maxmessage() - int64(len(data))
+		if limit <= 0 {
+			return 0, nil, WebSocketTooLarge(ws.maxmessage())
+		}
+		f, err := ws.readframe(limit)
+		if err != nil {
+			return 0, nil, err
+		}
+		switch f.opcode {
+		case PingMessage:
+			if err := ws.WriteMessage(PongMessage, f.payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			continue
+		case CloseMessage:
+			ws.WriteMessage(CloseMessage, f.payload)
+			ws.conn.Close()
+			return 0, nil, WebSocketClosed()
+		case 0:
+			if opcode == 0 {
+				return 0, nil, WebSocketFrame("unexpected continuation")
+			}
+		case TextMessage, BinaryMessage:
+			if opcode != 0 {
+				return 0, nil, WebSocketFrame("expected continuation")
+			}
+			opcode = f.opcode
+		default:
+			return 0, nil, WebSocketFrame("unknown opcode")
+		}
+		data = append(data, f.payload...)
+		if f.fin {
+			return opcode, data, nil
+		}
+	}
+}
+
+// WriteMessage writes a single frame message of the provided type.
+func (ws *WebSocket) WriteMessage(messageType int, data []byte) error {
+	switch messageType {
+	case TextMessage, BinaryMessage, CloseMessage, PingMessage, PongMessage:
+	default:
+		return UnknownMessageType(messageType)
+	}
+	ws.wlock.Lock()
+	defer ws.wlock.Unlock()
+	if ws.closed {
+		return WebSocketClosed()
+	}
+	head := []byte{0x80 | byte(messageType), 0}
+	switch n := len(data); {
+	case n <= 125:
+		head[1] = byte(n)
+	case n <= 0xffff:
+		head[1] = 126
+		head = binary.BigEndian.AppendUint16(head, uint16(n))
+	default:
+		head[1] = 127
+		head = binary.BigEndian.AppendUint64(head, uint64(n))
+	}
+	if _, err := ws.conn.Write(append(head, data...)); err != nil {
+		return err
+	}
+	if messageType == CloseMessage {
+		ws.closed = true
+	}
+	return nil
+}
+
+// Close sends a normal closure to the client and closes the connection.
+func (ws *WebSocket) Close() error {
+	ws.WriteMessage(CloseMessage, []byte{0x03, 0xe8})
+	return ws.conn.Close()
+}
+
+// Hub is a set of websocket connections receiving broadcast messages.
+type Hub struct {
+	mu    sync.RWMutex
+	conns map[*WebSocket]struct{}
+}
+
+// NewHub returns an empty Hub.
+func NewHub() *Hub {
+	return &Hub{conns: make(map[*WebSocket]struct{})}
+}
+
+// Add adds the connection to the Hub.
+func (h *Hub) Add(ws *WebSocket) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.conns[ws] = struct{}{}
+}
+
+// Remove removes the connection from the Hub.
+func (h *Hub) Remove(ws *WebSocket) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.conns, ws)
+}
+
+// Len returns the number of connections in the Hub.
+func (h *Hub) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.conns)
+}
+
+// Broadcast writes the message to every connection of the Hub, removing and
+// closing any connection failing the write.
+func (h *Hub) Broadcast(messageType int, data []byte) {
+	h.mu.RLock()
+	conns := make([]*WebSocket, 0, len(h.conns))
+	for ws := range h.conns {
+		conns = append(conns, ws)
+	}
+	h.mu.RUnlock()
+	for _, ws := range conns {
+		if err := ws.WriteMessage(messageType, data); err != nil {
+			h.Remove(ws)
+			ws.conn.Close()
+		}
+	}
+}
+
+// Hubs are the named Hubs of an App.
+type Hubs struct {
+	mu   sync.Mutex
+	hubs map[string]*Hub
+}
+
+func newHubs() *Hubs {
+	return &Hubs{hubs: make(map[string]*Hub)}
+}
+
+// Hub returns the named Hub, created on first use.
+func (h *Hubs) Hub(name string) *Hub {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hub, ok := h.hubs[name]
+	if !ok {
+		hub = NewHub()
+		h.hubs[name] = hub
+	}
+	return hub
+}
+
+func hubfunc(a *App) func(*ctx, string) *Hub {
+	return func(c *ctx, name string) *Hub {
+		return a.Env.Hubs.Hub(name)
+	}
+}
+
+// GetHub returns the named Hub of the App, created on first use.
+func GetHub(c Ctx, name string) *Hub {
+	hub, _ := c.Call("hub", name)
+	return hub.(*Hub)
+}
+
+var websocketfxtension = map[string]interface{}{
+	"upgrade": upgrade,
+}
+
+// WebSocketFxtension upgrades requests to websocket connections.
+var WebSocketFxtension Fxtension = MakeFxtension("websocketfxtension", websocketfxtension)
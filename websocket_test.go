@@ -0,0 +1,171 @@
+package flotilla
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type wsclient struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func dialwebsocket(t *testing.T, srv *httptest.Server, path, origin string) (*wsclient, *http.Response) {
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rq, _ := http.NewRequest("GET", srv.URL+path, nil)
+	rq.Header.Set("Connection", "Upgrade")
+	rq.Header.Set("Upgrade", "websocket")
+	rq.Header.Set("Sec-WebSocket-Version", "13")
+	rq.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	rq.Header.Set("Sec-WebSocket-Protocol", "other, chat")
+	if origin != "" {
+		rq.Header.Set("Origin", origin)
+	}
+	rq.Write(conn)
+	br := bufio.NewReader(conn)
+	rs, err := http.ReadResponse(br, rq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &wsclient{conn, br}, rs
+}
+
+func (w *wsclient) write(opcode int, fin bool, data []byte) {
+	b := byte(opcode)
+	if fin {
+		b |= 0x80
+	}
+	mask := []byte{1, 2, 3, 4}
+	frame := []byte{b, 0x80 | byte(len(data))}
+	frame = append(frame, mask...)
+	for i, d := range data {
+		frame = append(frame, d^mask[i%4])
+	}
+	w.conn.Write(frame)
+}
+
+func (w *wsclient) read() (int, []byte) {
+	w.conn.SetReadDeadline(time.Now().Add(time.Second))
+	var head [2]byte
+	io.ReadFull(w.br, head[:])
+	n := int(head[1] & 0x7f)
+	if n == 126 {
+		var b [2]byte
+		io.ReadFull(w.br, b[:])
+		n = int(binary.BigEndian.Uint16(b[:]))
+	}
+	data := make([]byte, n)
+	io.ReadFull(w.br, data)
+	return int(head[0] & 0x0f), data
+}
+
+func TestWebSocket(t *testing.T) {
+	a := New("testWebSocket", Mode("testing", true))
+	done := make(chan error, 1)
+	a.GET("/ws", func(c Ctx) {
+		ws, err := Upgrade(c, "chat")
+		if err != nil {
+			done <- err
+			return
+		}
+		hub := GetHub(c, "room")
+		hub.Add(ws)
+		defer hub.Remove(ws)
+		if ws.Protocol != "chat" {
+			t.Errorf("expected the chat subprotocol, got %q", ws.Protocol)
+		}
+		ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+		for {
+			mt, data, err := ws.ReadMessage()
+			if err != nil {
+				done <- err
+				return
+			}
+			hub.Broadcast(mt, append([]byte("echo "), data...))
+		}
+	})
+	a.Configure()
+	srv := httptest.NewServer(a)
+	defer srv.Close()
+
+	cl, rs := dialwebsocket(t, srv, "/ws", srv.URL)
+	if rs.StatusCode != 101 || rs.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("unexpected handshake %d %v", rs.StatusCode, rs.Header)
+	}
+	cl.write(TextMessage, false, []byte("hel"))
+	cl.write(PingMessage, true, []byte("p"))
+	cl.write(0, true, []byte("lo"))
+	if op, data := cl.read(); op != PongMessage || string(data) != "p" {
+		t.Errorf("expected a pong, got %d %q", op, data)
+	}
+	if op, data := cl.read(); op != TextMessage || string(data) != "echo hello" {
+		t.Errorf("expected an echo, got %d %q", op, data)
+	}
+	if n := a.Env.Hubs.Hub("room").Len(); n != 1 {
+		t.Errorf("expected a single hub connection, got %d", n)
+	}
+	cl.write(CloseMessage, true, []byte{0x03, 0xe8})
+	if op, _ := cl.read(); op != CloseMessage {
+		t.Errorf("expected a close frame, got %d", op)
+	}
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "closed") {
+			t.Errorf("expected a closed connection, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("websocket handler did not return")
+	}
+	cl.conn.Close()
+
+	cl, rs = dialwebsocket(t, srv, "/ws", "http://elsewhere.example")
+	if rs.StatusCode != 403 {
+		t.Errorf("expected a cross origin upgrade to be refused, got %d", rs.StatusCode)
+	}
+	cl.conn.Close()
+	if err := <-done; err == nil || !strings.Contains(err.Error(), "origin") {
+		t.Errorf("expected an origin error, got %v", err)
+	}
+}
+
+func TestWebSocketMaxMessage(t *testing.T) {
+	a := New("testWebSocketMaxMessage", Mode("testing", true), EnvItem("WEBSOCKET_MAXMESSAGE:0"))
+	done := make(chan error, 1)
+	a.GET("/ws", func(c Ctx) {
+		ws, err := Upgrade(c)
+		if err != nil {
+			done <- err
+			return
+		}
+		ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, _, err = ws.ReadMessage()
+		done <- err
+	})
+	a.Configure()
+	srv := httptest.NewServer(a)
+	defer srv.Close()
+
+	cl, _ := dialwebsocket(t, srv, "/ws", srv.URL)
+	defer cl.conn.Close()
+	frame := []byte{0x80 | BinaryMessage, 0x80 | 127}
+	frame = binary.BigEndian.AppendUint64(frame, 1<<40)
+	cl.conn.Write(append(frame, 1, 2, 3, 4))
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "exceeds 33554432 bytes") {
+			t.Errorf("expected a frame declaring 1 TiB to exceed the default cap, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("websocket handler did not return")
+	}
+}